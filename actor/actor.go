@@ -33,6 +33,8 @@ type Option func(*actorOptions)
 type actorOptions struct {
 	inboxBuffer   int
 	outcomeBuffer int
+	agentOptions  []setup.Option
+	mcp           *setup.MCPToolset
 }
 
 // WithInboxBuffer sets the inbox channel buffer size (default 16).
@@ -45,12 +47,25 @@ func WithOutcomeBuffer(n int) Option {
 	return func(o *actorOptions) { o.outcomeBuffer = n }
 }
 
+// WithAgentOptions appends options passed to setup.NewAgent on every run.
+func WithAgentOptions(opts ...setup.Option) Option {
+	return func(o *actorOptions) { o.agentOptions = append(o.agentOptions, opts...) }
+}
+
+// WithMCP makes the actor use set instead of connecting its own MCP servers.
+// The caller closes set once the actor has shut down.
+func WithMCP(set *setup.MCPToolset) Option {
+	return func(o *actorOptions) { o.mcp = set }
+}
+
 // Actor is a per-session concurrent execution entity.
 //
 // Lifecycle: Idle → (inbox message arrives) → Processing → Idle → ... → Shutdown.
 // Every transition out of Processing reloads a fresh core Agent through
 // setup.NewAgent so there is no cross-run state leakage; persistence is the
-// session store's job (history.jsonl on disk).
+// session store's job (history.jsonl on disk). MCP servers are the exception:
+// they are connected on the first run and shared by every later one until
+// Shutdown, unless WithMCP supplies them.
 type Actor struct {
 	SessionID string
 
@@ -61,8 +76,11 @@ type Actor struct {
 	lastActive atomic.Int64 // UnixNano
 	seq        atomic.Int64
 
-	sessMgr setup.SessionManager
-	cfg     *config.Config
+	sessMgr   setup.SessionManager
+	cfg       *config.Config
+	agentOpts []setup.Option
+	mcp       *setup.MCPToolset
+	ownsMCP   bool
 
 	ctx    context.Context
 	cancel context.CancelFunc
@@ -83,6 +101,8 @@ func New(sessionID string, sessMgr setup.SessionManager, cfg *config.Config, opt
 		outcome:   make(chan Event, options.outcomeBuffer),
 		sessMgr:   sessMgr,
 		cfg:       cfg,
+		agentOpts: options.agentOptions,
+		mcp:       options.mcp,
 		ctx:       ctx,
 		cancel:    cancel,
 		done:      make(chan struct{}),
@@ -128,6 +148,11 @@ func (a *Actor) loop() {
 	defer close(a.done)
 	defer close(a.outcome)
 	defer a.state.Store(int32(StateShutdown))
+	defer func() {
+		if a.ownsMCP {
+			a.mcp.Close()
+		}
+	}()
 
 	for {
 		select {
@@ -175,7 +200,12 @@ func (a *Actor) runAgent(msgs []Message) {
 		"msg_count": len(msgs),
 	}})
 
-	agentCtx, err := setup.NewAgent(a.sessMgr, a.cfg, setup.WithSessionID(a.SessionID))
+	if a.mcp == nil {
+		a.mcp = setup.ConnectMCP(a.ctx, a.cfg)
+		a.ownsMCP = true
+	}
+	agentOpts := append([]setup.Option{setup.WithSessionID(a.SessionID), setup.WithMCP(a.mcp)}, a.agentOpts...)
+	agentCtx, err := setup.NewAgent(a.sessMgr, a.cfg, agentOpts...)
 	if err != nil {
 		a.emit(Event{Type: EventRunError, RunID: runID, Data: map[string]any{
 			"message": err.Error(),
@@ -0,0 +1,58 @@
+package actor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// chatSubscriptionBuffer is the subscriber buffer used by Registry.Chat.
+const chatSubscriptionBuffer = 64
+
+// Chat sends text to the actor for sessionID and blocks until the run
+// finishes, returning the concatenated assistant text. It is the synchronous
+// counterpart of Send + Subscribe for request/response adapters such as MCP.
+func (r *Registry) Chat(ctx context.Context, sessionID, text string) (string, error) {
+	act := r.GetOrCreate(sessionID)
+
+	events, unsubscribe, err := r.Subscribe(sessionID, chatSubscriptionBuffer)
+	if err != nil {
+		return "", fmt.Errorf("subscribe actor events: %w", err)
+	}
+	defer unsubscribe()
+
+	if !act.Send(MessageFromText(text)) {
+		return "", errors.New("actor inbox full")
+	}
+
+	var (
+		textBuf strings.Builder
+		runErr  string
+	)
+	for {
+		select {
+		case evt, ok := <-events:
+			if !ok {
+				return textBuf.String(), errors.New("actor stream closed unexpectedly")
+			}
+			switch evt.Type {
+			case EventTextMessageContent:
+				delta, _ := evt.Data["delta"].(string)
+				textBuf.WriteString(delta)
+			case EventRunError:
+				runErr, _ = evt.Data["message"].(string)
+				if runErr == "" {
+					runErr = "run failed"
+				}
+			case EventRunFinished:
+				if runErr != "" {
+					return textBuf.String(), errors.New(runErr)
+				}
+				return strings.TrimSpace(textBuf.String()), nil
+			}
+		case <-ctx.Done():
+			return textBuf.String(), ctx.Err()
+		}
+	}
+}
@@ -27,6 +27,12 @@ type RegistryConfig struct {
 	// before forwarding events to API-layer subscribers. Leaving this nil
 	// means events are only delivered to direct subscribers.
 	OnEvent func(sessionID string, evt Event)
+	// AgentOptions are passed to setup.NewAgent on every actor run, e.g.
+	// setup.WithApprover for interactive frontends.
+	AgentOptions []setup.Option
+	// MCP, if non-nil, is shared by every actor instead of each connecting
+	// its own MCP servers. The caller closes it after ShutdownAll.
+	MCP *setup.MCPToolset
 }
 
 // DefaultRegistryConfig returns a sensible default configuration.
@@ -93,6 +99,8 @@ func (r *Registry) GetOrCreate(sessionID string) *Actor {
 	a = New(sessionID, r.sessMgr, r.appCfg,
 		WithInboxBuffer(r.cfg.InboxBuffer),
 		WithOutcomeBuffer(r.cfg.OutcomeBuffer),
+		WithAgentOptions(r.cfg.AgentOptions...),
+		WithMCP(r.cfg.MCP),
 	)
 	stream := newSessionPubSub()
 	r.actors[sessionID] = a
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/basenana/friday/sandbox"
+)
+
+var changesApplyForce bool
+
+// changesCmd represents the changes command
+var changesCmd = &cobra.Command{
+	Use:   "changes",
+	Short: "Review changes made in the sandbox overlay",
+	Long: `List the files the agent changed in the sandbox overlay of the current
+directory. With sandbox.overlay enabled, commands and file tools work on a
+shadow copy of the workdir; nothing reaches the real tree until it is applied.
+
+Changes marked with '!' conflict: the real file was also edited since the
+overlay was created.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		overlay := loadOverlay()
+		changes, err := overlay.Changes()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to list changes: %v\n", err)
+			os.Exit(1)
+		}
+		if len(changes) == 0 {
+			fmt.Println("No changes")
+			return
+		}
+		for _, c := range changes {
+			fmt.Println(c)
+		}
+	},
+}
+
+// changesDiffCmd represents the changes diff command
+var changesDiffCmd = &cobra.Command{
+	Use:   "diff [paths...]",
+	Short: "Show the overlay changes as a unified diff",
+	Long:  `Show the overlay changes, or those under the given paths, as a unified diff against the real tree.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		overlay := loadOverlay()
+		diff, err := overlay.Diff(args...)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to diff changes: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Print(diff)
+	},
+}
+
+// changesApplyCmd represents the changes apply command
+var changesApplyCmd = &cobra.Command{
+	Use:   "apply [paths...]",
+	Short: "Copy overlay changes into the real tree",
+	Long: `Copy the overlay changes, or those under the given paths, into the real tree.
+Conflicting changes are skipped unless --force is given.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		overlay := loadOverlay()
+		applied, err := overlay.Apply(changesApplyForce, args...)
+		for _, c := range applied {
+			fmt.Printf("applied  %s\n", c)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to apply changes: %v\n", err)
+			os.Exit(1)
+		}
+		remaining, err := overlay.Changes()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to list changes: %v\n", err)
+			os.Exit(1)
+		}
+		conflicts := 0
+		for _, c := range remaining {
+			if c.Conflict {
+				conflicts++
+			}
+		}
+		if conflicts > 0 && !changesApplyForce {
+			fmt.Printf("%d conflicting change(s) skipped; review them and use --force to overwrite\n", conflicts)
+		}
+	},
+}
+
+// changesDiscardCmd represents the changes discard command
+var changesDiscardCmd = &cobra.Command{
+	Use:   "discard [paths...]",
+	Short: "Drop overlay changes",
+	Long:  `Drop the overlay changes, or those under the given paths, restoring the shadow copy from the real tree.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		overlay := loadOverlay()
+		discarded, err := overlay.Discard(args...)
+		for _, c := range discarded {
+			fmt.Printf("discarded  %s\n", c)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to discard changes: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// loadOverlay returns the overlay of the current directory, exiting when
+// there is none.
+func loadOverlay() *sandbox.Overlay {
+	workdir, _ := os.Getwd()
+	overlay, err := sandbox.LoadOverlay(cfg.OverlaysPath(), workdir)
+	if os.IsNotExist(err) {
+		fmt.Fprintln(os.Stderr, "no overlay for this directory; enable sandbox.overlay to create one")
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load overlay: %v\n", err)
+		os.Exit(1)
+	}
+	return overlay
+}
+
+func init() {
+	changesApplyCmd.Flags().BoolVar(&changesApplyForce, "force", false, "also apply changes that conflict with edits to the real tree")
+	changesCmd.AddCommand(changesDiffCmd)
+	changesCmd.AddCommand(changesApplyCmd)
+	changesCmd.AddCommand(changesDiscardCmd)
+	rootCmd.AddCommand(changesCmd)
+}
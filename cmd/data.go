@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/basenana/friday/setup"
+	"github.com/basenana/friday/utils/crypt"
+)
+
+// dataCmd represents the data command
+var dataCmd = &cobra.Command{
+	Use:   "data",
+	Short: "Manage stored data",
+	Long:  `Manage the sessions, memory logs and agent state Friday keeps in its data directory.`,
+	// The data commands open no session store, which fails while encryption
+	// is enabled without its key.
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return loadConfig()
+	},
+}
+
+// dataEncryptCmd represents the data encrypt command
+var dataEncryptCmd = &cobra.Command{
+	Use:   "encrypt",
+	Short: "Encrypt sessions, memory logs and agent state",
+	Long: `Encrypt the sessions, memory logs and agent state in the data directory with
+the key from FRIDAY_DATA_KEY, encryption.key_file or encryption.key_command.
+Files already encrypted are left alone. Run it while no other Friday process
+is running.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if sessionStoreKind(cfg.Session.Store) == "sqlite" {
+			fmt.Fprintln(os.Stderr, "the sqlite session store does not support encryption")
+			os.Exit(1)
+		}
+		c := dataCipherOrExit()
+
+		n, err := convertDataFiles(dataPaths(), c, c)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to encrypt data: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Encrypted %d file(s)\n", n)
+		if !cfg.Encryption.Enabled {
+			fmt.Printf("Set encryption.enabled in %s so new data is encrypted too\n", cfg.Path())
+		}
+	},
+}
+
+// dataDecryptCmd represents the data decrypt command
+var dataDecryptCmd = &cobra.Command{
+	Use:   "decrypt",
+	Short: "Decrypt sessions, memory logs and agent state",
+	Long: `Decrypt the sessions, memory logs and agent state in the data directory back
+to plain files. Run it while no other Friday process is running.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		c := dataCipherOrExit()
+
+		n, err := convertDataFiles(dataPaths(), c, nil)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to decrypt data: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Decrypted %d file(s)\n", n)
+		if cfg.Encryption.Enabled {
+			fmt.Printf("Unset encryption.enabled in %s, or new data is encrypted again\n", cfg.Path())
+		}
+	},
+}
+
+// dataKeyCmd represents the data key command
+var dataKeyCmd = &cobra.Command{
+	Use:   "key",
+	Short: "Generate an encryption key",
+	Long: `Print a new random encryption key. Keep it in FRIDAY_DATA_KEY, the
+encryption.key_file or the OS keyring read by encryption.key_command.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		key, err := crypt.GenerateKey()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to generate key: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(key)
+	},
+}
+
+// dataCipherOrExit loads the configured key, whether or not encryption is
+// enabled yet.
+func dataCipherOrExit() *crypt.Cipher {
+	key, err := setup.DataKey(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load encryption key: %v\n", err)
+		os.Exit(1)
+	}
+	c, err := crypt.New(key)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid encryption key: %v\n", err)
+		os.Exit(1)
+	}
+	return c
+}
+
+// dataPaths lists the directories whose files are encrypted.
+func dataPaths() []string {
+	return []string{cfg.SessionsPath(), cfg.MemoryPath(), cfg.StatePath()}
+}
+
+// convertDataFiles converts every regular file under roots with
+// crypt.Convert and returns how many changed. Missing roots are skipped.
+func convertDataFiles(roots []string, from, to *crypt.Cipher) (int, error) {
+	converted := 0
+	for _, root := range roots {
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if path == root && os.IsNotExist(err) {
+					return filepath.SkipDir
+				}
+				return err
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			changed, err := crypt.Convert(path, from, to)
+			if err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			if changed {
+				converted++
+			}
+			return nil
+		})
+		if err != nil {
+			return converted, err
+		}
+	}
+	return converted, nil
+}
+
+func init() {
+	rootCmd.AddCommand(dataCmd)
+	dataCmd.AddCommand(dataEncryptCmd)
+	dataCmd.AddCommand(dataDecryptCmd)
+	dataCmd.AddCommand(dataKeyCmd)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/basenana/friday/utils/crypt"
+)
+
+func TestConvertDataFiles(t *testing.T) {
+	dir := t.TempDir()
+	sessionsDir := filepath.Join(dir, "sessions", "s1")
+	memoryDir := filepath.Join(dir, "memory")
+	for _, d := range []string{sessionsDir, memoryDir} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files := map[string]string{
+		filepath.Join(sessionsDir, "meta.json"):     `{"id":"s1"}`,
+		filepath.Join(sessionsDir, "history.jsonl"): "",
+		filepath.Join(memoryDir, "MEMORY.md"):       "# Memory\n",
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	roots := []string{filepath.Join(dir, "sessions"), memoryDir, filepath.Join(dir, "states")}
+
+	c, err := crypt.New(bytes.Repeat([]byte{3}, crypt.KeySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := convertDataFiles(roots, c, c); err != nil || n != len(files) {
+		t.Fatalf("encrypt = %d, %v; want %d", n, err, len(files))
+	}
+	if n, err := convertDataFiles(roots, c, c); err != nil || n != 0 {
+		t.Fatalf("encrypting again = %d, %v; want 0", n, err)
+	}
+	if n, err := convertDataFiles(roots, c, nil); err != nil || n != len(files) {
+		t.Fatalf("decrypt = %d, %v; want %d", n, err, len(files))
+	}
+	for path, content := range files {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(raw) != content {
+			t.Errorf("%s = %q, want %q", path, raw, content)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/basenana/friday/actor"
+	"github.com/basenana/friday/config"
+	"github.com/basenana/friday/core/tools"
+	"github.com/basenana/friday/mcp"
+	"github.com/basenana/friday/setup"
+	"github.com/basenana/friday/skills"
+)
+
+var (
+	mcpServeTransport  string
+	mcpServeListen     string
+	mcpServeAuthToken  string
+	mcpServeAllowHosts []string
+)
+
+// mcpCmd represents the mcp command
+var mcpCmd = &cobra.Command{
+	Use:   "mcp",
+	Short: "Inspect configured MCP servers or serve Friday over MCP",
+	Long:  `Inspect the MCP servers configured under mcp_servers, or publish Friday itself as an MCP server.`,
+}
+
+// mcpListCmd represents the mcp list command
+var mcpListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List configured MCP servers",
+	Long:  `List all MCP servers from the config with their transport and endpoint or command.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(cfg.MCPServers) == 0 {
+			fmt.Println("No MCP servers configured")
+			fmt.Println("\nAdd servers under mcp_servers in your config file.")
+			return
+		}
+
+		fmt.Println("MCP servers:")
+		for _, server := range cfg.MCPServers {
+			status := "enabled"
+			if !server.IsEnabled() {
+				status = "disabled"
+			}
+			transport := server.TransportName()
+			fmt.Printf("  %s (%s, %s)\n", server.Name, transport, status)
+			if transport == "stdio" {
+				fmt.Printf("    %s\n", strings.Join(append([]string{server.Command}, server.Args...), " "))
+			} else {
+				fmt.Printf("    %s\n", server.Endpoint)
+			}
+		}
+	},
+}
+
+// mcpTestCmd represents the mcp test command
+var mcpTestCmd = &cobra.Command{
+	Use:   "test <name>",
+	Short: "Connect to an MCP server and list its tools",
+	Long:  `Connect to a configured MCP server, run the initialize handshake and print the namespaced tools it exposes.`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		serverCfg, ok := findMCPServer(cfg, args[0])
+		if !ok {
+			fmt.Fprintf(os.Stderr, "MCP server not found: %s\n", args[0])
+			os.Exit(1)
+		}
+
+		start := time.Now()
+		server, serverTools, err := setup.ConnectMCPServer(context.Background(), serverCfg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to connect: %v\n", err)
+			os.Exit(1)
+		}
+		defer server.Close()
+
+		fmt.Printf("Connected to %s in %s\n", serverCfg.Name, time.Since(start).Round(time.Millisecond))
+		if len(serverTools) == 0 {
+			fmt.Println("No tools exposed")
+			return
+		}
+		fmt.Printf("Tools (%d):\n", len(serverTools))
+		for _, t := range serverTools {
+			fmt.Printf("  %s\n", t.Name)
+			if t.Description != "" {
+				fmt.Printf("    %s\n", t.Description)
+			}
+		}
+	},
+}
+
+// mcpServeCmd represents the mcp serve command
+var mcpServeCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve Friday's tools and agent over MCP",
+	Long: `Publish Friday as an MCP server so editors and other agents can use it.
+
+The server exposes:
+  - the sandboxed builtin tools (fs_*, bash, background tasks) and skill tools,
+    subject to the same sandbox permissions as the agent itself
+  - a chat tool that runs the full agent in a session
+
+Tools imported from configured mcp_servers are not re-exported.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// An empty toolset keeps the configured mcp_servers out of the
+		// published tools without connecting them.
+		agentCtx, err := setup.NewAgent(sessMgr, cfg, setup.WithTemporary(true), setup.WithMCP(&setup.MCPToolset{}))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to create agent: %v\n", err)
+			os.Exit(1)
+		}
+		defer agentCtx.Close()
+
+		registry := actor.NewRegistry(sessMgr, cfg, actor.DefaultRegistryConfig())
+		defer registry.ShutdownAll()
+
+		published := append([]*tools.Tool{}, agentCtx.Tools...)
+		published = append(published, skills.NewSkillTools(agentCtx.Skills)...)
+		published = append(published, mcp.NewChatTool(registry.Chat))
+		publisher := mcp.NewPublisher("friday", "1.0.0", published)
+
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+
+		switch mcpServeTransport {
+		case "stdio":
+			err = publisher.ServeStdio(ctx, os.Stdin, os.Stdout)
+			if errors.Is(err, context.Canceled) {
+				err = nil
+			}
+		case "http":
+			err = serveMCPHTTP(ctx, publisher)
+		default:
+			err = fmt.Errorf("unknown transport: %s (expected stdio or http)", mcpServeTransport)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "server error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func serveMCPHTTP(ctx context.Context, publisher *mcp.Publisher) error {
+	token := mcpServeAuthToken
+	if token == "" {
+		key := make([]byte, 24)
+		if _, err := rand.Read(key); err != nil {
+			return fmt.Errorf("failed to generate auth token: %w", err)
+		}
+		token = hex.EncodeToString(key)
+		fmt.Fprintf(os.Stderr, "Auth token: %s\n", token)
+	}
+	hosts := append([]string{}, mcpServeAllowHosts...)
+	if host, _, err := net.SplitHostPort(mcpServeListen); err == nil {
+		if ip := net.ParseIP(host); host != "" && (ip == nil || !ip.IsUnspecified()) {
+			hosts = append(hosts, host)
+		}
+	}
+	handler, err := publisher.HTTPHandler(token, hosts...)
+	if err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("tcp", mcpServeListen)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", mcpServeListen, err)
+	}
+	httpServer := &http.Server{Handler: handler}
+
+	go func() {
+		<-ctx.Done()
+		fmt.Fprintln(os.Stderr, "\nshutting down...")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := httpServer.Shutdown(shutdownCtx); err != nil {
+			fmt.Fprintf(os.Stderr, "shutdown error: %v\n", err)
+		}
+	}()
+
+	fmt.Fprintf(os.Stderr, "MCP server listening on http://%s/mcp (%d tools)\n", mcpServeListen, len(publisher.Tools()))
+	if err = httpServer.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func findMCPServer(cfg *config.Config, name string) (config.MCPServerConfig, bool) {
+	for _, server := range cfg.MCPServers {
+		if server.Name == name {
+			return server, true
+		}
+	}
+	return config.MCPServerConfig{}, false
+}
+
+func init() {
+	rootCmd.AddCommand(mcpCmd)
+	mcpCmd.AddCommand(mcpListCmd)
+	mcpCmd.AddCommand(mcpTestCmd)
+
+	mcpServeCmd.Flags().StringVar(&mcpServeTransport, "transport", "stdio", "transport to serve: stdio or http")
+	mcpServeCmd.Flags().StringVar(&mcpServeListen, "listen", "127.0.0.1:8998", "address to listen on for the http transport")
+	mcpServeCmd.Flags().StringVar(&mcpServeAuthToken, "auth-token", "", "Bearer token for the http transport (empty = generate and print one)")
+	mcpServeCmd.Flags().StringSliceVar(&mcpServeAllowHosts, "allow-host", nil, "host names the http transport may be reached at besides loopback and --listen")
+	mcpCmd.AddCommand(mcpServeCmd)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/basenana/friday/core/providers/ollama"
+)
+
+var modelsHost string
+
+// modelsCmd represents the models command
+var modelsCmd = &cobra.Command{
+	Use:   "models",
+	Short: "Manage local Ollama models",
+	Long: `Manage the models of a local Ollama server.
+
+The server is the base_url of the first configured model with provider
+"ollama", unless --host is given, and defaults to ` + ollama.DefaultHost + `.`,
+}
+
+// modelsListCmd represents the models list command
+var modelsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List local models",
+	Long:  `List the models installed on the Ollama server. Configured models are marked with '*'.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		models, err := ollama.ListModels(cmd.Context(), ollamaHost())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to list models: %v\n", err)
+			os.Exit(1)
+		}
+		if len(models) == 0 {
+			fmt.Println("No models installed")
+			fmt.Println("\nUse follow command to download one")
+			fmt.Println("\n  friday models pull <name>")
+			return
+		}
+
+		configured := make(map[string]bool)
+		for _, m := range cfg.ChatModels() {
+			if strings.EqualFold(m.Provider, "ollama") {
+				configured[m.Model] = true
+			}
+		}
+
+		fmt.Println("Models:")
+		for _, m := range models {
+			marker := " "
+			if configured[m.Name] || configured[strings.TrimSuffix(m.Name, ":latest")] {
+				marker = "*"
+			}
+			fmt.Printf("  %s  %-32s %8s %-8s %s\n",
+				marker, m.Name, formatModelSize(m.Size), m.Details.ParameterSize, m.ModifiedAt.Format("2006-01-02 15:04"))
+		}
+	},
+}
+
+// modelsPullCmd represents the models pull command
+var modelsPullCmd = &cobra.Command{
+	Use:   "pull <name>",
+	Short: "Download a model",
+	Long:  `Download a model from the Ollama library, e.g. "qwen3:8b".`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		var last string
+		err := ollama.PullModel(ctx, ollamaHost(), args[0], func(p ollama.PullProgress) {
+			if p.Total > 0 {
+				fmt.Printf("\r%s %3d%% (%s / %s)", p.Status, p.Completed*100/p.Total, formatModelSize(p.Completed), formatModelSize(p.Total))
+				last = p.Status
+				return
+			}
+			if last != "" {
+				fmt.Println()
+			}
+			fmt.Println(p.Status)
+			last = ""
+		})
+		if last != "" {
+			fmt.Println()
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to pull model: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// ollamaHost returns --host, or the base_url of the first configured
+// Ollama model.
+func ollamaHost() string {
+	if modelsHost != "" {
+		return modelsHost
+	}
+	for _, m := range cfg.ChatModels() {
+		if strings.EqualFold(m.Provider, "ollama") && m.BaseURL != "" {
+			return m.BaseURL
+		}
+	}
+	return ollama.DefaultHost
+}
+
+func formatModelSize(n int64) string {
+	const unit = 1000
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "kMGTPE"[exp])
+}
+
+func init() {
+	modelsCmd.PersistentFlags().StringVar(&modelsHost, "host", "", "Ollama server URL")
+	modelsCmd.AddCommand(modelsListCmd)
+	modelsCmd.AddCommand(modelsPullCmd)
+	rootCmd.AddCommand(modelsCmd)
+}
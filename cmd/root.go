@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -12,6 +13,8 @@ import (
 	"github.com/basenana/friday/config"
 	"github.com/basenana/friday/sessions"
 	"github.com/basenana/friday/sessions/file"
+	"github.com/basenana/friday/sessions/sqlite"
+	"github.com/basenana/friday/setup"
 	"github.com/basenana/friday/utils/logger"
 )
 
@@ -62,6 +65,50 @@ func currentFilePath(cfg *config.Config) string {
 	return filepath.Join(cfg.DataDirPath(), "current")
 }
 
+// sessionStoreKind normalizes a session store kind, empty meaning "file".
+func sessionStoreKind(kind string) string {
+	kind = strings.ToLower(strings.TrimSpace(kind))
+	if kind == "" {
+		return "file"
+	}
+	return kind
+}
+
+// newSessionStore returns the session store of the given kind: "file" or
+// "sqlite".
+func newSessionStore(kind string) (sessions.Store, error) {
+	switch sessionStoreKind(kind) {
+	case "file":
+		dataCipher, err := setup.DataCipher(cfg)
+		if err != nil {
+			return nil, err
+		}
+		store := file.NewFileSessionStore(cfg.SessionsPath())
+		store.SetCipher(dataCipher)
+		return store, nil
+	case "sqlite":
+		if cfg.Encryption.Enabled {
+			return nil, fmt.Errorf("the sqlite session store does not support encryption")
+		}
+		return sqlite.NewSQLiteSessionStore(cfg.SessionsDBPath()), nil
+	default:
+		return nil, fmt.Errorf("unknown session store %q: want file or sqlite", kind)
+	}
+}
+
+// loadConfig loads the config file into cfg, applying the --workspace flag.
+func loadConfig() error {
+	var err error
+	cfg, err = config.Load(cfgFile)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if workspaceDir != "" {
+		cfg.Workspace = workspaceDir
+	}
+	return nil
+}
+
 var rootCmd = &cobra.Command{
 	Use:   "friday",
 	Short: "A Unix-philosophy AI Agent for your terminal",
@@ -69,25 +116,28 @@ var rootCmd = &cobra.Command{
 
 Text in, text out. Pipe-friendly. No GUI, no cloud dependency.`,
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-		var err error
-		cfg, err = config.Load(cfgFile)
-		if err != nil {
-			return fmt.Errorf("failed to load config: %w", err)
-		}
-		if workspaceDir != "" {
-			cfg.Workspace = workspaceDir
+		if err := loadConfig(); err != nil {
+			return err
 		}
 
 		// Get TTY name for session isolation
 		currentTTY = getTTYName()
 
 		// Initialize session manager with tty-aware current file
-		store := file.NewFileSessionStore(cfg.SessionsPath())
+		store, err := newSessionStore(cfg.Session.Store)
+		if err != nil {
+			return err
+		}
 		sessMgr = sessions.NewManager(store, currentFilePath(cfg), currentTTY)
 
 		return nil
 	},
 	PersistentPostRun: func(cmd *cobra.Command, args []string) {
+		if sessMgr != nil {
+			if closer, ok := sessMgr.GetStore().(io.Closer); ok {
+				closer.Close()
+			}
+		}
 		logger.Sync()
 		logger.Close()
 	},
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/basenana/friday/sandbox"
+	"github.com/basenana/friday/setup"
+)
+
+var sandboxPolicyFile string
+
+// sandboxCmd represents the sandbox command
+var sandboxCmd = &cobra.Command{
+	Use:   "sandbox",
+	Short: "Inspect sandbox permissions",
+	Long:  `Inspect the effective sandbox permissions: the user config merged with the repository policy file.`,
+}
+
+// sandboxCheckCmd represents the sandbox check command
+var sandboxCheckCmd = &cobra.Command{
+	Use:   "check \"<command>\"",
+	Short: "Show whether a command would be allowed",
+	Long: `Check a command against the effective sandbox permissions and print the
+decision with the rule that produced it.
+
+The repository policy is read from .friday/sandbox.yaml (or .yml/.json) in the
+current directory or its parents up to the repository root, unless --policy is
+given. Quote the command, or put it after --, when it contains flags.
+Exit status is 0 for allow, 1 for deny and 2 for ask.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		command := strings.Join(args, " ")
+
+		sandboxCfg, policyPath, err := effectiveSandboxConfig()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to load sandbox policy: %v\n", err)
+			os.Exit(1)
+		}
+
+		decision, reason, err := sandbox.NewPermission(sandboxCfg).CheckWithReason(command)
+		fmt.Printf("Command:  %s\n", command)
+		fmt.Printf("Decision: %s\n", decision)
+		fmt.Printf("Reason:   %s\n", reason)
+		if err != nil {
+			fmt.Printf("Error:    %v\n", err)
+		}
+		if policyPath != "" {
+			fmt.Printf("Policy:   %s\n", policyPath)
+		}
+		if missing := sandboxCfg.UnenforcedLimits(); len(missing) > 0 {
+			fmt.Printf("Limits:   %s not enforced (no cgroup v2 available)\n", strings.Join(missing, ", "))
+		}
+
+		switch decision {
+		case sandbox.Deny:
+			os.Exit(1)
+		case sandbox.Ask:
+			os.Exit(2)
+		}
+	},
+}
+
+var (
+	sandboxBridgeListen string
+	sandboxBridgeSocket string
+)
+
+// sandboxBridgeCmd runs inside network-isolated sandboxes and connects them
+// to the filtering proxy; it is not meant to be run by hand.
+var sandboxBridgeCmd = &cobra.Command{
+	Use:    "bridge --listen <addr> --socket <path> -- <command...>",
+	Short:  "Bridge a sandbox to the network proxy",
+	Hidden: true,
+	Args:   cobra.MinimumNArgs(1),
+	// Skip loading the user config; the bridge only needs its flags.
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {},
+	Run: func(cmd *cobra.Command, args []string) {
+		code, err := sandbox.RunBridge(cmd.Context(), sandboxBridgeListen, sandboxBridgeSocket, args)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "friday sandbox bridge: %v\n", err)
+		}
+		os.Exit(code)
+	},
+}
+
+// effectiveSandboxConfig merges --policy, or else the repository policy
+// file, over the user's sandbox config.
+func effectiveSandboxConfig() (*sandbox.Config, string, error) {
+	if sandboxPolicyFile == "" {
+		workdir, _ := os.Getwd()
+		return setup.SandboxConfig(cfg, workdir)
+	}
+
+	policy, err := sandbox.LoadPolicy(sandboxPolicyFile)
+	if err != nil {
+		return nil, sandboxPolicyFile, err
+	}
+	userCfg := cfg.Sandbox
+	if userCfg == nil {
+		userCfg = sandbox.DefaultConfig()
+	}
+	return userCfg.Merge(policy), sandboxPolicyFile, nil
+}
+
+func init() {
+	sandboxCheckCmd.Flags().StringVar(&sandboxPolicyFile, "policy", "", "policy file to merge instead of the repository one")
+	sandboxCmd.AddCommand(sandboxCheckCmd)
+
+	sandboxBridgeCmd.Flags().StringVar(&sandboxBridgeListen, "listen", "", "loopback address to listen on inside the sandbox")
+	sandboxBridgeCmd.Flags().StringVar(&sandboxBridgeSocket, "socket", "", "unix socket of the network proxy")
+	sandboxCmd.AddCommand(sandboxBridgeCmd)
+	if exe, err := os.Executable(); err == nil {
+		sandbox.BridgeCommand = []string{exe, "sandbox", "bridge"}
+	}
+	rootCmd.AddCommand(sandboxCmd)
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 
@@ -10,7 +11,7 @@ import (
 
 	"github.com/basenana/friday/core/types"
 	"github.com/basenana/friday/sessions"
-	"github.com/basenana/friday/sessions/file"
+	"github.com/basenana/friday/sessions/export"
 	"github.com/basenana/friday/setup"
 )
 
@@ -175,6 +176,9 @@ var sessionShowCmd = &cobra.Command{
 
 		fmt.Printf("Session: %s\n", sessionID)
 		fmt.Printf("Created: %s\n", meta.CreatedAt.Format("2006-01-02 15:04:05"))
+		if meta.ParentID != "" {
+			fmt.Printf("Forked from: %s at [%d]\n", meta.ParentID, meta.BranchPoint)
+		}
 
 		// Count visible messages (exclude agent internal messages)
 		visibleCount := 0
@@ -186,12 +190,11 @@ var sessionShowCmd = &cobra.Command{
 		fmt.Printf("Messages: %d\n", visibleCount)
 		fmt.Println("")
 
-		idx := 0
-		for _, msg := range messages {
+		// Indices are positions in the history, as used by search and fork.
+		for idx, msg := range messages {
 			if msg.Role == types.RoleAgent {
 				continue // skip agent internal messages
 			}
-			idx++
 			timeStr := ""
 			if !msg.Time.IsZero() {
 				timeStr = msg.Time.Format("15:04:05")
@@ -335,6 +338,337 @@ var sessionArchivedCmd = &cobra.Command{
 	},
 }
 
+var (
+	sessionCostBy    string
+	sessionCostSince string
+)
+
+// sessionCostCmd represents the session cost command
+var sessionCostCmd = &cobra.Command{
+	Use:   "cost",
+	Short: "Report model spend",
+	Long: `Report the tokens and cost of model calls across all sessions,
+including archived ones, grouped by day, model or session.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		store := sessMgr.GetStore()
+		metas, err := store.List()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to list sessions: %v\n", err)
+			os.Exit(1)
+		}
+
+		summaries, err := sessions.SummarizeUsage(metas, sessionCostBy, sessionCostSince)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		if len(summaries) == 0 {
+			fmt.Println("No usage recorded")
+			return
+		}
+
+		var total sessions.UsageSummary
+		fmt.Printf("  %-32s %6s %12s %12s %12s %10s\n", strings.ToUpper(sessionCostBy), "CALLS", "PROMPT", "CACHED", "COMPLETION", "COST")
+		for _, s := range summaries {
+			printUsageSummary(s.Key, s)
+			total.Calls += s.Calls
+			total.PromptTokens += s.PromptTokens
+			total.CachedPromptTokens += s.CachedPromptTokens
+			total.CompletionTokens += s.CompletionTokens
+			total.Cost += s.Cost
+		}
+		printUsageSummary("total", total)
+	},
+}
+
+func printUsageSummary(key string, s sessions.UsageSummary) {
+	fmt.Printf("  %-32s %6d %12d %12d %12d %10s\n",
+		key, s.Calls, s.PromptTokens, s.CachedPromptTokens, s.CompletionTokens, fmt.Sprintf("$%.4f", s.Cost))
+}
+
+var (
+	sessionSearchRole    string
+	sessionSearchSince   string
+	sessionSearchUntil   string
+	sessionSearchLimit   int
+	sessionSearchReindex bool
+)
+
+// sessionSearchCmd represents the session search command
+var sessionSearchCmd = &cobra.Command{
+	Use:   "search <query>",
+	Short: "Search all session histories",
+	Long: `Find messages containing every word of the query across all sessions,
+archived ones included. Each result shows the session ID and message index,
+newest first.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		searcher, ok := sessMgr.GetStore().(sessions.Searcher)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "session store does not support search\n")
+			os.Exit(1)
+		}
+		if sessionSearchReindex {
+			indexed, err := searcher.Reindex()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "failed to rebuild index: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("Indexed %d messages\n", indexed)
+		}
+
+		q, err := sessions.NewSearchQuery(strings.Join(args, " "), sessionSearchRole, sessionSearchSince, sessionSearchUntil)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		q.Limit = sessionSearchLimit
+
+		hits, err := searcher.Search(q)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to search sessions: %v\n", err)
+			os.Exit(1)
+		}
+		if len(hits) == 0 {
+			fmt.Println("No messages found")
+			return
+		}
+		fmt.Print(sessions.FormatHits(hits))
+	},
+}
+
+var (
+	sessionForkAt      int
+	sessionRewindTurns int
+)
+
+// sessionForkCmd represents the session fork command
+var sessionForkCmd = &cobra.Command{
+	Use:   "fork <id> [--at <index>]",
+	Short: "Branch a session into a new one",
+	Long: `Create a session that starts with the messages of another one before
+message <index>, as numbered by "sessions show", and switch to it. A negative
+index counts from the end; without --at the whole history is copied. The
+original session is left untouched.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		store := sessMgr.GetStore()
+		prefix := args[0]
+
+		metas, err := store.List()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to list sessions: %v\n", err)
+			os.Exit(1)
+		}
+
+		sessionID, found := findSessionByPrefix(metas, prefix)
+		if !found {
+			fmt.Printf("Session not found: %s\n", prefix)
+			os.Exit(1)
+		}
+
+		at := sessionForkAt
+		if !cmd.Flags().Changed("at") {
+			meta, err := store.GetMeta(sessionID)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "failed to get meta: %v\n", err)
+				os.Exit(1)
+			}
+			at = meta.MessageCount
+		}
+
+		forkID, err := sessMgr.Fork(sessionID, at)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to fork session: %v\n", err)
+			os.Exit(1)
+		}
+		if err := sessMgr.SetCurrentID(forkID); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to set current session: %v\n", err)
+			os.Exit(1)
+		}
+
+		meta, _ := store.GetMeta(forkID)
+		fmt.Printf("Forked %s into %s with %d messages\n", sessionID, forkID, meta.MessageCount)
+		fmt.Printf("Switched to session: %s\n", forkID)
+	},
+}
+
+// sessionRewindCmd represents the session rewind command
+var sessionRewindCmd = &cobra.Command{
+	Use:   "rewind <id> [--turns <n>]",
+	Short: "Drop the last turns of a session",
+	Long: `Drop the last user messages of a session together with the replies and
+tool calls that followed them. The dropped messages are kept in the store's
+backup of replaced histories.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		store := sessMgr.GetStore()
+		prefix := args[0]
+
+		metas, err := store.List()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to list sessions: %v\n", err)
+			os.Exit(1)
+		}
+
+		sessionID, found := findSessionByPrefix(metas, prefix)
+		if !found {
+			fmt.Printf("Session not found: %s\n", prefix)
+			os.Exit(1)
+		}
+
+		left, err := sessMgr.Rewind(sessionID, sessionRewindTurns)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to rewind session: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Rewound %s by %d turns, %d messages left\n", sessionID, sessionRewindTurns, left)
+	},
+}
+
+var (
+	sessionExportFormat        string
+	sessionExportOutput        string
+	sessionExportRedactTools   bool
+	sessionExportRedactSecrets bool
+)
+
+// sessionExportCmd represents the session export command
+var sessionExportCmd = &cobra.Command{
+	Use:   "export <id>",
+	Short: "Export a session transcript",
+	Long: `Write a session as Markdown or HTML to share, as the messages array of an
+OpenAI Chat Completions request, or as JSONL that "sessions import" reads back
+with tool calls, reasoning, images and session memory intact. Tool outputs and
+secrets such as API keys can be redacted first.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		store := sessMgr.GetStore()
+		prefix := args[0]
+
+		metas, err := store.List()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to list sessions: %v\n", err)
+			os.Exit(1)
+		}
+
+		sessionID, found := findSessionByPrefix(metas, prefix)
+		if !found {
+			fmt.Printf("Session not found: %s\n", prefix)
+			os.Exit(1)
+		}
+
+		transcript, err := export.Load(store, sessionID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to load session: %v\n", err)
+			os.Exit(1)
+		}
+		export.Redact(transcript, export.RedactOptions{
+			ToolOutputs: sessionExportRedactTools,
+			Secrets:     sessionExportRedactSecrets,
+		})
+
+		out := io.Writer(os.Stdout)
+		if sessionExportOutput != "" && sessionExportOutput != "-" {
+			f, err := os.Create(sessionExportOutput)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "failed to create output: %v\n", err)
+				os.Exit(1)
+			}
+			defer f.Close()
+			out = f
+		}
+		if err := export.Write(out, transcript, sessionExportFormat); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to export session: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// sessionImportCmd represents the session import command
+var sessionImportCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Import a session exported as JSONL",
+	Long: `Import a session written by "sessions export --format jsonl". The session
+keeps its ID unless one with that ID already exists, in which case it gets a
+new one. Its spend and fork lineage are not imported. Use - to read from stdin.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		store := sessMgr.GetStore()
+		importer, ok := store.(sessions.Importer)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "session store %T cannot import sessions\n", store)
+			os.Exit(1)
+		}
+
+		in := io.Reader(os.Stdin)
+		if args[0] != "-" {
+			f, err := os.Open(args[0])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "failed to open export: %v\n", err)
+				os.Exit(1)
+			}
+			defer f.Close()
+			in = f
+		}
+		transcript, err := export.ReadJSONL(in)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to read export: %v\n", err)
+			os.Exit(1)
+		}
+
+		// The spend and the parent belong to the exporter's sessions.
+		transcript.Meta.Cost, transcript.Meta.Usage = 0, nil
+		transcript.Meta.ParentID, transcript.Meta.BranchPoint = "", 0
+
+		if exists, err := sessMgr.Exists(transcript.Meta.ID); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to look up session: %v\n", err)
+			os.Exit(1)
+		} else if exists {
+			transcript.Meta.ID = types.NewID()
+		}
+		if err := importer.ImportSession(transcript.Meta, transcript.Messages, transcript.Memory); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to import session: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Imported session %s with %d messages\n", transcript.Meta.ID, len(transcript.Messages))
+	},
+}
+
+var sessionMigrateTo string
+
+// sessionMigrateCmd represents the session migrate command
+var sessionMigrateCmd = &cobra.Command{
+	Use:   "migrate --to <file|sqlite>",
+	Short: "Copy sessions to another store",
+	Long: `Copy all sessions, including archived ones, from the configured session
+store to another one. Sessions the target already has are skipped and the
+source is left untouched; set session.store in the config to switch over.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		to := sessionStoreKind(sessionMigrateTo)
+		if to == sessionStoreKind(cfg.Session.Store) {
+			fmt.Fprintf(os.Stderr, "sessions are already stored in %s\n", to)
+			os.Exit(1)
+		}
+		dst, err := newSessionStore(to)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		if closer, ok := dst.(io.Closer); ok {
+			defer closer.Close()
+		}
+
+		copied, err := sessions.Migrate(sessMgr.GetStore(), dst)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to migrate sessions after %d: %v\n", copied, err)
+			os.Exit(1)
+		}
+		fmt.Printf("Migrated %d sessions to %s\n", copied, to)
+		fmt.Printf("Set session.store to %q in %s to use them\n", to, cfg.Path())
+	},
+}
+
 // sessionDeleteCmd represents the session delete command
 var sessionDeleteCmd = &cobra.Command{
 	Use:   "delete <id>",
@@ -399,8 +733,7 @@ var sessionCompactCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
-		fileStore := file.NewFileSessionStore(cfg.SessionsPath())
-		sess, err := fileStore.Load(sessionID, client)
+		sess, err := store.Load(sessionID, client)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "failed to load session: %v\n", err)
 			os.Exit(1)
@@ -497,4 +830,27 @@ func init() {
 	sessionCmd.AddCommand(sessionArchivedCmd)
 	sessionCmd.AddCommand(sessionDeleteCmd)
 	sessionCmd.AddCommand(sessionCompactCmd)
+	sessionCmd.AddCommand(sessionCostCmd)
+	sessionCmd.AddCommand(sessionMigrateCmd)
+	sessionCmd.AddCommand(sessionSearchCmd)
+	sessionCmd.AddCommand(sessionForkCmd)
+	sessionCmd.AddCommand(sessionRewindCmd)
+	sessionCmd.AddCommand(sessionExportCmd)
+	sessionCmd.AddCommand(sessionImportCmd)
+
+	sessionCostCmd.Flags().StringVar(&sessionCostBy, "by", sessions.UsageByDay, "group by day, model or session")
+	sessionCostCmd.Flags().StringVar(&sessionCostSince, "since", "", "only count usage from this date on (YYYY-MM-DD)")
+	sessionMigrateCmd.Flags().StringVar(&sessionMigrateTo, "to", "", "target store: file or sqlite")
+	sessionMigrateCmd.MarkFlagRequired("to")
+	sessionSearchCmd.Flags().StringVar(&sessionSearchRole, "role", "", "only match messages of this role: user, assistant, tool or system")
+	sessionSearchCmd.Flags().StringVar(&sessionSearchSince, "since", "", "only match messages from this date on (YYYY-MM-DD)")
+	sessionSearchCmd.Flags().StringVar(&sessionSearchUntil, "until", "", "only match messages up to this date, inclusive (YYYY-MM-DD)")
+	sessionSearchCmd.Flags().IntVarP(&sessionSearchLimit, "limit", "n", 20, "maximum number of results")
+	sessionSearchCmd.Flags().BoolVar(&sessionSearchReindex, "reindex", false, "rebuild the search index from all sessions first")
+	sessionForkCmd.Flags().IntVar(&sessionForkAt, "at", 0, "keep the messages before this index; negative counts from the end")
+	sessionRewindCmd.Flags().IntVar(&sessionRewindTurns, "turns", 1, "number of user turns to drop")
+	sessionExportCmd.Flags().StringVarP(&sessionExportFormat, "format", "f", export.FormatMarkdown, "output format: "+strings.Join(export.Formats, ", "))
+	sessionExportCmd.Flags().StringVarP(&sessionExportOutput, "output", "o", "", "write to this file instead of stdout")
+	sessionExportCmd.Flags().BoolVar(&sessionExportRedactTools, "redact-tool-output", false, "replace the content of tool results")
+	sessionExportCmd.Flags().BoolVar(&sessionExportRedactSecrets, "redact-secrets", false, "replace API keys, tokens, passwords and private keys")
 }
@@ -46,3 +46,20 @@ func (p ToolPolicy) Apply(all []*tools.Tool) []*tools.Tool {
 	}
 	return out
 }
+
+// WithExternal returns a copy of p adjusted for externally provided tools
+// such as MCP server tools. Whitelisted policies gain the readOnly names in
+// Allow; blacklisted policies gain the mutating names in Deny. An empty
+// policy already keeps every tool and is returned unchanged.
+func (p ToolPolicy) WithExternal(readOnly, mutating []string) ToolPolicy {
+	switch {
+	case len(p.Allow) > 0:
+		allow := append(append([]string{}, p.Allow...), readOnly...)
+		return ToolPolicy{Allow: allow, Deny: p.Deny}
+	case len(p.Deny) > 0:
+		deny := append(append([]string{}, p.Deny...), mutating...)
+		return ToolPolicy{Deny: deny}
+	default:
+		return p
+	}
+}
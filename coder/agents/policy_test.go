@@ -71,3 +71,33 @@ func TestToolPolicy_AllowUnknownNamesIgnored(t *testing.T) {
 		t.Fatalf("unknown allow names should be ignored: got %v", toolNames(got))
 	}
 }
+
+func TestToolPolicy_WithExternal(t *testing.T) {
+	all := makeTools("fs_read", "fs_list", "bash", "docs__search", "github__create_issue")
+	readOnly := []string{"docs__search"}
+	mutating := []string{"github__create_issue"}
+
+	allow := ToolPolicy{Allow: []string{"fs_read", "fs_list"}}.WithExternal(readOnly, mutating)
+	got := toolNames(allow.Apply(all))
+	want := []string{"fs_read", "fs_list", "docs__search"}
+	if len(got) != len(want) {
+		t.Fatalf("allow policy: got %v want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("allow policy: got %v want %v", got, want)
+		}
+	}
+
+	deny := ToolPolicy{Deny: []string{"bash"}}.WithExternal(readOnly, mutating)
+	got = toolNames(deny.Apply(all))
+	want = []string{"fs_read", "fs_list", "docs__search"}
+	if len(got) != len(want) {
+		t.Fatalf("deny policy: got %v want %v", got, want)
+	}
+
+	empty := ToolPolicy{}.WithExternal(readOnly, mutating)
+	if len(empty.Apply(all)) != len(all) {
+		t.Fatalf("empty policy should keep every tool")
+	}
+}
@@ -0,0 +1,81 @@
+package commands
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// --- /fork ---
+
+type forkCmd struct{}
+
+func (forkCmd) Name() string      { return "fork" }
+func (forkCmd) Aliases() []string { return []string{"branch"} }
+func (forkCmd) Description() string {
+	return "Branch the session into a new one: /fork [index], keeping the messages before index"
+}
+func (forkCmd) Execute(ctx *Context) (*Result, error) {
+	if ctx.SessMgr == nil || ctx.SessionID == "" {
+		return &Result{Message: "no active session"}, nil
+	}
+	if ctx.Busy {
+		return &Result{Message: "a task is running; wait or cancel before forking"}, nil
+	}
+	meta, err := ctx.SessMgr.GetStore().GetMeta(ctx.SessionID)
+	if err != nil {
+		return &Result{Message: "load session failed: " + err.Error()}, nil
+	}
+	at := meta.MessageCount
+	if len(ctx.Args) > 0 {
+		if at, err = strconv.Atoi(ctx.Args[0]); err != nil {
+			return &Result{Message: "usage: /fork [index]"}, nil
+		}
+	}
+
+	forkID, err := ctx.SessMgr.Fork(ctx.SessionID, at)
+	if err != nil {
+		return &Result{Message: "fork failed: " + err.Error()}, nil
+	}
+	if err := ctx.SessMgr.SetCurrentID(forkID); err != nil {
+		return &Result{Message: "set current session failed: " + err.Error()}, nil
+	}
+	return &Result{
+		SwitchSession: forkID,
+		Message:       fmt.Sprintf("forked %s into %s", shortID(ctx.SessionID), shortID(forkID)),
+	}, nil
+}
+
+// --- /rewind ---
+
+type rewindCmd struct{}
+
+func (rewindCmd) Name() string      { return "rewind" }
+func (rewindCmd) Aliases() []string { return []string{"undo"} }
+func (rewindCmd) Description() string {
+	return "Drop the last user turns and their replies: /rewind [turns]"
+}
+func (rewindCmd) Execute(ctx *Context) (*Result, error) {
+	if ctx.SessMgr == nil || ctx.SessionID == "" {
+		return &Result{Message: "no active session"}, nil
+	}
+	if ctx.Busy {
+		return &Result{Message: "a task is running; wait or cancel before rewinding"}, nil
+	}
+	turns := 1
+	if len(ctx.Args) > 0 {
+		n, err := strconv.Atoi(ctx.Args[0])
+		if err != nil || n < 1 {
+			return &Result{Message: "usage: /rewind [turns]"}, nil
+		}
+		turns = n
+	}
+
+	left, err := ctx.SessMgr.Rewind(ctx.SessionID, turns)
+	if err != nil {
+		return &Result{Message: "rewind failed: " + err.Error()}, nil
+	}
+	return &Result{
+		ReloadSession: true,
+		Message:       fmt.Sprintf("rewound %d turns, %d messages left", turns, left),
+	}, nil
+}
@@ -0,0 +1,52 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/basenana/friday/core/types"
+)
+
+func TestForkAndRewindCmds(t *testing.T) {
+	mgr := newSessionManagerForTest(t)
+	sess, id, err := mgr.CreateIsolated()
+	if err != nil {
+		t.Fatalf("CreateIsolated failed: %v", err)
+	}
+	sess.AppendMessage(
+		&types.Message{Role: types.RoleUser, Content: "first"},
+		&types.Message{Role: types.RoleAssistant, Content: "one"},
+		&types.Message{Role: types.RoleUser, Content: "second"},
+		&types.Message{Role: types.RoleAssistant, Content: "two"},
+	)
+
+	result, err := rewindCmd{}.Execute(&Context{SessMgr: mgr, SessionID: id, Busy: true})
+	if err != nil || result.ReloadSession {
+		t.Fatalf("rewind while busy = %+v, %v; want a refusal", result, err)
+	}
+
+	result, err = forkCmd{}.Execute(&Context{SessMgr: mgr, SessionID: id, Args: []string{"2"}})
+	if err != nil {
+		t.Fatalf("Execute returned error: %v", err)
+	}
+	if result.SwitchSession == "" || result.SwitchSession == id {
+		t.Fatalf("SwitchSession = %q, want the fork", result.SwitchSession)
+	}
+	if msgs, _ := mgr.GetStore().LoadMessages(result.SwitchSession); len(msgs) != 2 {
+		t.Fatalf("fork has %d messages, want 2", len(msgs))
+	}
+	if current, _ := mgr.GetCurrentID(); current != result.SwitchSession {
+		t.Fatalf("current session = %q, want the fork", current)
+	}
+
+	result, err = rewindCmd{}.Execute(&Context{SessMgr: mgr, SessionID: id})
+	if err != nil {
+		t.Fatalf("Execute returned error: %v", err)
+	}
+	if !result.ReloadSession || !strings.Contains(result.Message, "2 messages left") {
+		t.Fatalf("result = %+v, want a reload with 2 messages left", result)
+	}
+	if msgs, _ := mgr.GetStore().LoadMessages(id); len(msgs) != 2 || msgs[1].Content != "one" {
+		t.Fatalf("unexpected history after rewind %+v", msgs)
+	}
+}
@@ -0,0 +1,99 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/basenana/friday/sandbox"
+)
+
+const changesUsage = "usage: /changes [list] | diff [paths] | apply [--force] [paths] | discard [paths]"
+
+// --- /changes ---
+
+type changesCmd struct{}
+
+func (changesCmd) Name() string      { return "changes" }
+func (changesCmd) Aliases() []string { return nil }
+func (changesCmd) Description() string {
+	return "Review sandbox overlay changes: /changes list | diff | apply | discard"
+}
+func (changesCmd) Execute(ctx *Context) (*Result, error) {
+	if ctx.Config == nil {
+		return &Result{Message: "config unavailable"}, nil
+	}
+	workdir, _ := os.Getwd()
+	overlay, err := sandbox.LoadOverlay(ctx.Config.OverlaysPath(), workdir)
+	if os.IsNotExist(err) {
+		return &Result{Message: "no overlay for this directory; enable sandbox.overlay to create one"}, nil
+	}
+	if err != nil {
+		return &Result{Message: "load overlay failed: " + err.Error()}, nil
+	}
+
+	sub, args := "", []string(nil)
+	if len(ctx.Args) > 0 {
+		sub, args = ctx.Args[0], ctx.Args[1:]
+	}
+	switch sub {
+	case "", "list":
+		changes, err := overlay.Changes()
+		if err != nil {
+			return &Result{Message: "list changes failed: " + err.Error()}, nil
+		}
+		if len(changes) == 0 {
+			return &Result{Message: "no changes"}, nil
+		}
+		return &Result{Message: "Overlay changes ('!' conflicts with the real tree):\n" + formatChanges(changes)}, nil
+
+	case "diff":
+		diff, err := overlay.Diff(args...)
+		if err != nil {
+			return &Result{Message: "diff failed: " + err.Error()}, nil
+		}
+		if diff == "" {
+			return &Result{Message: "no changes"}, nil
+		}
+		return &Result{Message: "```diff\n" + diff + "```"}, nil
+
+	case "apply":
+		force := false
+		var paths []string
+		for _, arg := range args {
+			if arg == "--force" {
+				force = true
+				continue
+			}
+			paths = append(paths, arg)
+		}
+		applied, err := overlay.Apply(force, paths...)
+		msg := fmt.Sprintf("applied %d change(s)", len(applied))
+		if len(applied) > 0 {
+			msg += ":\n" + formatChanges(applied)
+		}
+		if err != nil {
+			msg += "\napply failed: " + err.Error()
+		}
+		return &Result{Message: msg}, nil
+
+	case "discard":
+		discarded, err := overlay.Discard(args...)
+		msg := fmt.Sprintf("discarded %d change(s)", len(discarded))
+		if err != nil {
+			msg += "\ndiscard failed: " + err.Error()
+		}
+		return &Result{Message: msg}, nil
+
+	default:
+		return &Result{Message: "unknown subcommand: " + sub + "\n" + changesUsage}, nil
+	}
+}
+
+func formatChanges(changes []sandbox.Change) string {
+	lines := make([]string, 0, len(changes))
+	for _, c := range changes {
+		lines = append(lines, "  "+c.String())
+	}
+	return strings.Join(lines, "\n")
+}
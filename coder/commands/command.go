@@ -18,6 +18,9 @@ type Result struct {
 	ClearMessages bool
 	// SwitchSession switches the TUI to the named session ID.
 	SwitchSession string
+	// ReloadSession rebinds the current session so its actor picks up a
+	// history the command rewrote.
+	ReloadSession bool
 	// Quit exits the TUI.
 	Quit bool
 
@@ -27,6 +30,10 @@ type Result struct {
 	RunAgent string
 	// AgentInput is the input text passed to the RunAgent.
 	AgentInput string
+
+	// UserMessage is sent to the main actor as if the user had typed it
+	// (e.g. a rendered MCP prompt).
+	UserMessage string
 }
 
 // Context carries the dependencies a command may need at execution time.
@@ -39,6 +46,8 @@ type Context struct {
 	SessMgr   *sessions.Manager
 	ActorReg  *actor.Registry
 	Config    *config.Config
+	// Busy reports whether the session's actor is running a task.
+	Busy bool
 }
 
 // Command is a single slash command.
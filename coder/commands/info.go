@@ -3,7 +3,11 @@ package commands
 import (
 	"fmt"
 	"strings"
+	"time"
 
+	"github.com/basenana/friday/config"
+	"github.com/basenana/friday/core/logger"
+	"github.com/basenana/friday/core/providers/fallback"
 	"github.com/basenana/friday/core/session"
 )
 
@@ -13,14 +17,21 @@ type costCmd struct{}
 
 func (costCmd) Name() string        { return "cost" }
 func (costCmd) Aliases() []string   { return nil }
-func (costCmd) Description() string { return "Show token usage for the current session" }
+func (costCmd) Description() string { return "Show token usage and spend for the current session" }
 func (costCmd) Execute(ctx *Context) (*Result, error) {
 	sess := currentSession(ctx)
 	if sess == nil {
 		return &Result{Message: "no active session"}, nil
 	}
 	tokens := sess.Context.TokenCheckpoint.PromptTokens
-	msg := fmt.Sprintf("Session tokens (prompt): %d\nMessages: %d", tokens, len(sess.History))
+	msg := fmt.Sprintf("Session tokens (prompt): %d\nMessages: %d\nCost: $%.4f", tokens, len(sess.History), sess.Cost())
+	if budget := sess.CostBudget(); budget > 0 {
+		msg += fmt.Sprintf(" of $%.2f budget", budget)
+	}
+	if cache := sess.CacheStats(); cache.Requests > 0 {
+		msg += fmt.Sprintf("\nPrompt cache: %.0f%% hit (%d of %d prompt tokens, last call %.0f%%)",
+			cache.HitRate()*100, cache.CachedTokens, cache.PromptTokens, cache.LastHitRate*100)
+	}
 	return &Result{Message: msg}, nil
 }
 
@@ -78,13 +89,35 @@ func (modelCmd) Execute(ctx *Context) (*Result, error) {
 	}
 	if len(ctx.Args) == 0 {
 		pm := ctx.Config.PrimaryModel()
-		return &Result{Message: fmt.Sprintf("Current model: %s (provider: %s)", pm.Model, pm.Provider)}, nil
+		msg := fmt.Sprintf("Current model: %s (provider: %s)", pm.Model, pm.Provider)
+		if health := modelHealth(ctx.Config); health != "" {
+			msg += "\n" + health
+		}
+		return &Result{Message: msg}, nil
 	}
 	// Setting a model at runtime requires rewriting config and rebuilding the
 	// provider client — out of scope for the first cut. Surface clearly.
 	return &Result{Message: "switching models at runtime is not yet supported; edit your config file"}, nil
 }
 
+// modelHealth describes the fallback models' health saved by earlier calls.
+func modelHealth(cfg *config.Config) string {
+	stats, err := fallback.ReadStats(cfg.ModelHealthPath())
+	if err != nil || len(stats) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("Model health:")
+	for _, s := range stats {
+		fmt.Fprintf(&b, "\n  %-24s %-9s calls %d, errors %d (%.0f%%), avg %s",
+			s.Name, s.State, s.Calls, s.Failures, s.ErrorRate()*100, s.AvgLatency().Round(time.Millisecond))
+		if s.State != fallback.StateClosed && s.LastError != "" {
+			fmt.Fprintf(&b, ", last error: %s", logger.FirstLine(s.LastError))
+		}
+	}
+	return b.String()
+}
+
 // --- /session ---
 
 type sessionCmd struct{}
@@ -159,7 +192,8 @@ func shortID(id string) string {
 	return id
 }
 
-// RegisterInfoCommands registers /cost, /context, /compact, /model, /session.
+// RegisterInfoCommands registers /cost, /context, /compact, /model, /session,
+// /fork, /rewind, /changes.
 func RegisterInfoCommands(reg *Registry) {
 	if reg == nil {
 		return
@@ -169,4 +203,7 @@ func RegisterInfoCommands(reg *Registry) {
 	reg.Register(compactCmd{})
 	reg.Register(modelCmd{})
 	reg.Register(sessionCmd{})
+	reg.Register(forkCmd{})
+	reg.Register(rewindCmd{})
+	reg.Register(changesCmd{})
 }
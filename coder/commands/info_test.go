@@ -1,10 +1,16 @@
 package commands
 
 import (
+	"context"
+	"errors"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/basenana/friday/config"
+	"github.com/basenana/friday/core/providers"
+	"github.com/basenana/friday/core/providers/fallback"
 	"github.com/basenana/friday/sessions"
 	sessionfile "github.com/basenana/friday/sessions/file"
 )
@@ -42,3 +48,39 @@ func TestSessionCmdUseMissingDoesNotCreateSession(t *testing.T) {
 		t.Fatal("missing session should not have been created")
 	}
 }
+
+func TestModelCmdShowsSavedModelHealth(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.DataDir = t.TempDir()
+
+	result, err := modelCmd{}.Execute(&Context{Config: cfg})
+	if err != nil {
+		t.Fatalf("Execute returned error: %v", err)
+	}
+	if strings.Contains(result.Message, "Model health") {
+		t.Fatalf("message = %q, want no health without saved state", result.Message)
+	}
+
+	broken := fallbackErrClient{}
+	fc := fallback.NewFallbackClient([]fallback.ModelEntry{{Client: broken, Name: "gpt-broken"}},
+		fallback.WithMaxTotalRetries(1), fallback.WithCircuitBreaker(1, time.Hour), fallback.WithStatePath(cfg.ModelHealthPath()))
+	if _, err := fc.CompletionNonStreaming(context.Background(), providers.NewPromptRequest("hi")); err == nil {
+		t.Fatal("expected error from broken model")
+	}
+
+	result, err = modelCmd{}.Execute(&Context{Config: cfg})
+	if err != nil {
+		t.Fatalf("Execute returned error: %v", err)
+	}
+	for _, want := range []string{"Model health:", "gpt-broken", "open", "errors 1 (100%)", "last error: provider down"} {
+		if !strings.Contains(result.Message, want) {
+			t.Fatalf("message = %q, want %q", result.Message, want)
+		}
+	}
+}
+
+type fallbackErrClient struct{ providers.Client }
+
+func (fallbackErrClient) CompletionNonStreaming(context.Context, providers.Request) (string, error) {
+	return "", errors.New("provider down")
+}
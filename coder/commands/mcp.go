@@ -0,0 +1,141 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	mcpgo "github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/basenana/friday/mcp"
+)
+
+// --- /<server>:<prompt> ---
+
+// mcpPromptCmd renders an MCP prompt and sends the result to the main agent
+// as a user message.
+type mcpPromptCmd struct {
+	server *mcp.Server
+	prompt mcpgo.Prompt
+}
+
+func (c mcpPromptCmd) Name() string {
+	return strings.ToLower(c.server.Name + ":" + c.prompt.Name)
+}
+func (c mcpPromptCmd) Aliases() []string { return nil }
+func (c mcpPromptCmd) Description() string {
+	if c.prompt.Description != "" {
+		return c.prompt.Description
+	}
+	return "MCP prompt from " + c.server.Name
+}
+
+func (c mcpPromptCmd) Execute(ctx *Context) (*Result, error) {
+	args, err := promptArguments(c.prompt.Arguments, ctx.Args)
+	if err != nil {
+		return &Result{Message: fmt.Sprintf("%s\nusage: %s", err, c.usage())}, nil
+	}
+	runCtx := ctx.Ctx
+	if runCtx == nil {
+		runCtx = context.Background()
+	}
+	text, err := c.server.GetPrompt(runCtx, c.prompt.Name, args)
+	if err != nil {
+		return nil, fmt.Errorf("get prompt %s: %w", c.Name(), err)
+	}
+	if strings.TrimSpace(text) == "" {
+		return &Result{Message: "prompt " + c.Name() + " is empty"}, nil
+	}
+	return &Result{UserMessage: text}, nil
+}
+
+func (c mcpPromptCmd) usage() string {
+	parts := []string{"/" + c.Name()}
+	for _, arg := range c.prompt.Arguments {
+		if arg.Required {
+			parts = append(parts, "<"+arg.Name+">")
+		} else {
+			parts = append(parts, "["+arg.Name+"]")
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// promptArguments maps command tokens onto the prompt's declared arguments.
+// Tokens of the form name=value set that argument; the remaining tokens fill
+// the unset arguments in declaration order, the last one taking the rest of
+// the line.
+func promptArguments(defs []mcpgo.PromptArgument, tokens []string) (map[string]string, error) {
+	args := make(map[string]string)
+	declared := make(map[string]bool, len(defs))
+	for _, def := range defs {
+		declared[def.Name] = true
+	}
+
+	var positional []string
+	for _, tok := range tokens {
+		if name, value, ok := strings.Cut(tok, "="); ok && declared[name] {
+			args[name] = value
+			continue
+		}
+		positional = append(positional, tok)
+	}
+
+	var unset []string
+	for _, def := range defs {
+		if _, ok := args[def.Name]; !ok {
+			unset = append(unset, def.Name)
+		}
+	}
+	for i, name := range unset {
+		if len(positional) == 0 {
+			break
+		}
+		if i == len(unset)-1 {
+			args[name] = strings.Join(positional, " ")
+			positional = nil
+			break
+		}
+		args[name] = positional[0]
+		positional = positional[1:]
+	}
+	if len(positional) > 0 {
+		return nil, fmt.Errorf("too many arguments")
+	}
+
+	for _, def := range defs {
+		if _, ok := args[def.Name]; def.Required && !ok {
+			return nil, fmt.Errorf("missing argument: %s", def.Name)
+		}
+	}
+	return args, nil
+}
+
+// RegisterMCPPromptCommands registers a /<server>:<prompt> command for every
+// prompt offered by servers. Commands never shadow an existing name. Servers
+// whose prompts cannot be listed are skipped and reported in the returned error.
+func RegisterMCPPromptCommands(ctx context.Context, reg *Registry, servers []*mcp.Server) error {
+	if reg == nil {
+		return nil
+	}
+	var errs []error
+	for _, server := range servers {
+		if !server.HasPrompts() {
+			continue
+		}
+		prompts, err := server.ListPrompts(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("mcp server %s: list prompts: %w", server.Name, err))
+			continue
+		}
+		for _, prompt := range prompts {
+			cmd := mcpPromptCmd{server: server, prompt: prompt}
+			if _, exists := reg.Lookup(cmd.Name()); exists {
+				continue
+			}
+			reg.Register(cmd)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,54 @@
+package commands
+
+import (
+	"testing"
+
+	mcpgo "github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestPromptArguments(t *testing.T) {
+	defs := []mcpgo.PromptArgument{
+		{Name: "file", Required: true},
+		{Name: "focus"},
+	}
+
+	cases := []struct {
+		name    string
+		tokens  []string
+		want    map[string]string
+		wantErr bool
+	}{
+		{name: "positional", tokens: []string{"main.go", "error", "handling"}, want: map[string]string{"file": "main.go", "focus": "error handling"}},
+		{name: "named", tokens: []string{"focus=tests", "main.go"}, want: map[string]string{"file": "main.go", "focus": "tests"}},
+		{name: "optional omitted", tokens: []string{"main.go"}, want: map[string]string{"file": "main.go"}},
+		{name: "missing required", tokens: nil, wantErr: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := promptArguments(defs, tc.tokens)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("promptArguments() = %v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("promptArguments() error = %v", err)
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("promptArguments() = %v, want %v", got, tc.want)
+			}
+			for k, v := range tc.want {
+				if got[k] != v {
+					t.Errorf("arg %s = %q, want %q", k, got[k], v)
+				}
+			}
+		})
+	}
+}
+
+func TestPromptArgumentsTooMany(t *testing.T) {
+	if _, err := promptArguments(nil, []string{"extra"}); err == nil {
+		t.Error("promptArguments() with no declared args should reject extra tokens")
+	}
+}
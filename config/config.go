@@ -1,12 +1,14 @@
 package config
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
+	"unicode"
 
 	"gopkg.in/yaml.v3"
 )
@@ -28,9 +30,11 @@ func Load(configPath string) (*Config, error) {
 		} else if _, err := os.Stat(yamlPath); err == nil {
 			configPath = yamlPath
 		} else {
+			cfg.path = jsonPath
 			return cfg, nil // use default
 		}
 	}
+	cfg.path = configPath
 
 	data, err := os.ReadFile(configPath)
 	if err != nil {
@@ -64,6 +68,25 @@ func (c *Config) expandEnv() {
 	c.DataDir = expandEnvStr(c.DataDir)
 	c.Workspace = expandEnvStr(c.Workspace)
 	expandModelEnv(&c.ImageModel)
+	c.Encryption.KeyFile = expandEnvStr(c.Encryption.KeyFile)
+	for i := range c.MCPServers {
+		expandMCPServerEnv(&c.MCPServers[i])
+	}
+}
+
+func expandMCPServerEnv(m *MCPServerConfig) {
+	m.Endpoint = expandEnvStr(m.Endpoint)
+	for k, v := range m.Headers {
+		m.Headers[k] = expandEnvStr(v)
+	}
+	m.Command = expandEnvStr(m.Command)
+	for i := range m.Args {
+		m.Args[i] = expandEnvStr(m.Args[i])
+	}
+	for k, v := range m.Env {
+		m.Env[k] = expandEnvStr(v)
+	}
+	m.Cwd = expandEnvStr(m.Cwd)
 }
 
 func expandModelEnv(m *ModelConfig) {
@@ -86,6 +109,351 @@ func expandEnvStr(s string) string {
 	})
 }
 
+// Path returns the config file this Config was loaded from, or the file a
+// default config would be saved to. It is empty for configs not built by Load.
+func (c *Config) Path() string {
+	return c.path
+}
+
+// SaveAllowRules adds patterns to sandbox.permissions.allow in the config
+// file and leaves the rest of the file as it was. A file without an allow
+// list gets the whole in-memory list, since a list in the file replaces the
+// default one. The file is created when missing.
+func (c *Config) SaveAllowRules(patterns ...string) error {
+	if c.path == "" {
+		return fmt.Errorf("config has no file path")
+	}
+	data, err := os.ReadFile(c.path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	full := patterns
+	if c.Sandbox != nil {
+		full = c.Sandbox.Permissions.Allow
+	}
+
+	if strings.HasSuffix(c.path, ".json") {
+		data, err = addJSONAllowRules(data, patterns, full)
+	} else {
+		data, err = addYAMLAllowRules(data, patterns, full)
+	}
+	if err != nil {
+		return fmt.Errorf("update %s: %w", c.path, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(c.path, data, 0644)
+}
+
+// allowPath is the key path of the allow list in the config file.
+var allowPath = []string{"sandbox", "permissions", "allow"}
+
+// addJSONAllowRules splices the rules into the text of data so key order and
+// formatting are kept.
+func addJSONAllowRules(data []byte, patterns, full []string) ([]byte, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		text, err := jsonText(allowTree(allowPath, full), "", true)
+		if err != nil {
+			return nil, err
+		}
+		return []byte(text + "\n"), nil
+	}
+
+	start, end := 0, len(bytes.TrimRightFunc(data, unicode.IsSpace))
+	for i, key := range allowPath {
+		vs, ve, found, err := jsonMember(data, start, end, key)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", pathName(allowPath[:i]), err)
+		}
+		if !found {
+			return appendJSON(data, end, key, allowTree(allowPath[i+1:], full))
+		}
+		start, end = vs, ve
+	}
+
+	var existing []string
+	if err := json.Unmarshal(data[start:end], &existing); err != nil {
+		return nil, fmt.Errorf("%s is not a list of strings", pathName(allowPath))
+	}
+	missing := missingRules(existing, patterns)
+	items := make([]any, len(missing))
+	for i, p := range missing {
+		items[i] = p
+	}
+	return appendJSON(data, end, "", items...)
+}
+
+// jsonMember returns the span of the value of key in the JSON object held in
+// data[start:end].
+func jsonMember(data []byte, start, end int, key string) (int, int, bool, error) {
+	dec := json.NewDecoder(bytes.NewReader(data[start:end]))
+	if tok, err := dec.Token(); err != nil {
+		return 0, 0, false, err
+	} else if tok != json.Delim('{') {
+		return 0, 0, false, fmt.Errorf("not an object")
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return 0, 0, false, err
+		}
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return 0, 0, false, err
+		}
+		if tok == key {
+			off := start + int(dec.InputOffset())
+			return off - len(raw), off, true, nil
+		}
+	}
+	return 0, 0, false, nil
+}
+
+// appendJSON adds values to the object or array whose closing bracket is
+// data[end-1], following the layout of its existing entries. With a key it
+// adds a member holding each value.
+func appendJSON(data []byte, end int, key string, values ...any) ([]byte, error) {
+	if len(values) == 0 {
+		return data, nil
+	}
+	closing := end - 1
+	last := closing - 1
+	for last > 0 && unicode.IsSpace(rune(data[last])) {
+		last--
+	}
+	empty := data[last] == '{' || data[last] == '['
+	multiline := bytes.IndexByte(data[last:closing], '\n') >= 0
+
+	var indent, sep string
+	switch {
+	case multiline && empty:
+		indent = lineIndent(data, closing) + "  "
+		sep = ",\n" + indent
+	case multiline:
+		indent = lineIndent(data, last)
+		sep = ",\n" + indent
+	default:
+		sep = ", "
+	}
+
+	var b strings.Builder
+	for i, v := range values {
+		switch {
+		case i > 0 || !empty:
+			b.WriteString(sep)
+		case multiline:
+			b.WriteString("\n" + indent)
+		}
+		if key != "" {
+			k, _ := jsonText(key, "", false)
+			b.WriteString(k + ": ")
+		}
+		text, err := jsonText(v, indent, multiline)
+		if err != nil {
+			return nil, err
+		}
+		b.WriteString(text)
+	}
+
+	out := make([]byte, 0, len(data)+b.Len())
+	out = append(out, data[:last+1]...)
+	out = append(out, b.String()...)
+	return append(out, data[last+1:]...), nil
+}
+
+// jsonText renders v without escaping HTML characters, indented when
+// multiline is set; prefix starts every line but the first.
+func jsonText(v any, prefix string, multiline bool) (string, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if multiline {
+		enc.SetIndent(prefix, "  ")
+	}
+	if err := enc.Encode(v); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+// lineIndent returns the leading whitespace of the line holding data[pos].
+func lineIndent(data []byte, pos int) string {
+	start := bytes.LastIndexByte(data[:pos], '\n') + 1
+	end := start
+	for end < len(data) && (data[end] == ' ' || data[end] == '\t') {
+		end++
+	}
+	return string(data[start:end])
+}
+
+// addYAMLAllowRules inserts the rules as new lines when the sections around
+// them are in block style. Other layouts are re-encoded from the node tree,
+// which keeps comments and key order but not the formatting.
+func addYAMLAllowRules(data []byte, patterns, full []string) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Kind == 0 || len(doc.Content) == 0 {
+		return insertLines(data, -1, yamlLines(allowPath, full, "")), nil
+	}
+
+	node := doc.Content[0]
+	for i, key := range allowPath {
+		if node.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("%s is not a mapping", pathName(allowPath[:i]))
+		}
+		value := yamlValue(node, key)
+		switch {
+		case value == nil && i == 0:
+			return insertLines(data, -1, yamlLines(allowPath, full, "")), nil
+		case value == nil && node.Style&yaml.FlowStyle == 0 && len(node.Content) > 0:
+			first := node.Content[0]
+			indent := strings.Repeat(" ", first.Column-1)
+			return insertLines(data, first.Line-1, yamlLines(allowPath[i:], full, indent)), nil
+		case value == nil:
+			node.Content = append(node.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Value: key}, allowNode(allowPath[i+1:], full))
+			return encodeYAML(&doc)
+		case value.Kind == yaml.ScalarNode && value.Tag == "!!null":
+			*value = *allowNode(allowPath[i+1:], full)
+			return encodeYAML(&doc)
+		}
+		node = value
+	}
+	if node.Kind != yaml.SequenceNode {
+		return nil, fmt.Errorf("%s is not a list", pathName(allowPath))
+	}
+
+	existing := make([]string, len(node.Content))
+	for i, item := range node.Content {
+		existing[i] = item.Value
+	}
+	missing := missingRules(existing, patterns)
+	if len(missing) == 0 {
+		return data, nil
+	}
+	if n := len(node.Content); n > 0 && node.Style&yaml.FlowStyle == 0 {
+		last := node.Content[n-1]
+		if last.Kind == yaml.ScalarNode && last.Style&(yaml.LiteralStyle|yaml.FoldedStyle) == 0 {
+			// New items reuse the "  - " prefix of the last one.
+			line := strings.SplitAfter(string(data), "\n")[last.Line-1]
+			prefix := line[:last.Column-1]
+			lines := make([]string, len(missing))
+			for i, p := range missing {
+				lines[i] = prefix + yamlScalar(p)
+			}
+			return insertLines(data, last.Line, lines), nil
+		}
+	}
+	for _, p := range missing {
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: p})
+	}
+	return encodeYAML(&doc)
+}
+
+func yamlValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// yamlLines renders the nested keys holding the allow list as block YAML.
+func yamlLines(keys, allow []string, indent string) []string {
+	var lines []string
+	for i, key := range keys {
+		lines = append(lines, indent+strings.Repeat("  ", i)+key+":")
+	}
+	for _, p := range allow {
+		lines = append(lines, indent+strings.Repeat("  ", len(keys))+"- "+yamlScalar(p))
+	}
+	return lines
+}
+
+func yamlScalar(s string) string {
+	out, _ := yaml.Marshal(s)
+	return strings.TrimSuffix(string(out), "\n")
+}
+
+// insertLines inserts lines after the first n lines of data, or at its end
+// when n is negative.
+func insertLines(data []byte, n int, lines []string) []byte {
+	all := strings.SplitAfter(string(data), "\n")
+	if n < 0 || n > len(all) {
+		n = len(all)
+	}
+	if n > 0 && all[n-1] != "" && !strings.HasSuffix(all[n-1], "\n") {
+		all[n-1] += "\n"
+	}
+	text := strings.Join(all[:n], "") + strings.Join(lines, "\n") + "\n" + strings.Join(all[n:], "")
+	return []byte(text)
+}
+
+func encodeYAML(doc *yaml.Node) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// allowTree nests allow under keys for encoding.
+func allowTree(keys, allow []string) any {
+	if len(keys) == 0 {
+		if allow == nil {
+			return []string{}
+		}
+		return allow
+	}
+	return map[string]any{keys[0]: allowTree(keys[1:], allow)}
+}
+
+func allowNode(keys, allow []string) *yaml.Node {
+	if len(keys) == 0 {
+		seq := &yaml.Node{Kind: yaml.SequenceNode}
+		for _, p := range allow {
+			seq.Content = append(seq.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: p})
+		}
+		return seq
+	}
+	return &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+		{Kind: yaml.ScalarNode, Value: keys[0]}, allowNode(keys[1:], allow),
+	}}
+}
+
+// missingRules returns the patterns not in existing, without repeats.
+func missingRules(existing, patterns []string) []string {
+	seen := make(map[string]bool, len(existing))
+	for _, p := range existing {
+		seen[p] = true
+	}
+	var missing []string
+	for _, p := range patterns {
+		if !seen[p] {
+			seen[p] = true
+			missing = append(missing, p)
+		}
+	}
+	return missing
+}
+
+func pathName(keys []string) string {
+	if len(keys) == 0 {
+		return "top level"
+	}
+	return strings.Join(keys, ".")
+}
+
 func (c *Config) ResolvePath(path string) string {
 	if strings.HasPrefix(path, "~/") {
 		home, err := os.UserHomeDir()
@@ -109,6 +477,10 @@ func (c *Config) SessionsPath() string {
 	return filepath.Join(c.DataDirPath(), "sessions")
 }
 
+func (c *Config) SessionsDBPath() string {
+	return filepath.Join(c.DataDirPath(), "sessions.db")
+}
+
 func (c *Config) MemoryPath() string {
 	return filepath.Join(c.DataDirPath(), "memory")
 }
@@ -125,6 +497,18 @@ func (c *Config) ProposalsPath() string {
 	return filepath.Join(c.DataDirPath(), "proposals")
 }
 
+func (c *Config) OverlaysPath() string {
+	return filepath.Join(c.DataDirPath(), "overlays")
+}
+
+func (c *Config) ModelHealthPath() string {
+	return filepath.Join(c.DataDirPath(), "model_health.json")
+}
+
+func (c *Config) RateLimitPath() string {
+	return filepath.Join(c.DataDirPath(), "ratelimit")
+}
+
 func LogPath() string {
 	return filepath.Join("/tmp", fmt.Sprintf("friday-%s.log", time.Now().Format(time.DateOnly)))
 }
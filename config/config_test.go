@@ -0,0 +1,255 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadMCPServers(t *testing.T) {
+	t.Setenv("FRIDAY_MCP_TOKEN", "secret")
+	t.Setenv("FRIDAY_MCP_HOST", "mcp.example.com")
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "friday.yaml")
+	data := []byte(`
+mcp_servers:
+  - name: github
+    transport: sse
+    endpoint: https://$FRIDAY_MCP_HOST/mcp
+    headers:
+      Authorization: Bearer $FRIDAY_MCP_TOKEN
+  - name: docs
+    endpoint: http://127.0.0.1:9000/mcp
+    enabled: false
+    read_only: true
+`)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(cfg.MCPServers) != 2 {
+		t.Fatalf("expected 2 mcp servers, got %d", len(cfg.MCPServers))
+	}
+
+	github := cfg.MCPServers[0]
+	if github.Endpoint != "https://mcp.example.com/mcp" {
+		t.Fatalf("endpoint not expanded, got %q", github.Endpoint)
+	}
+	if github.Headers["Authorization"] != "Bearer secret" {
+		t.Fatalf("header not expanded, got %q", github.Headers["Authorization"])
+	}
+	if !github.IsEnabled() {
+		t.Fatalf("server without enabled field should default to enabled")
+	}
+
+	docs := cfg.MCPServers[1]
+	if docs.IsEnabled() {
+		t.Fatalf("expected docs server to be disabled")
+	}
+	if !docs.ReadOnly {
+		t.Fatalf("expected docs server to be read-only")
+	}
+}
+
+func TestMCPServerTransportName(t *testing.T) {
+	tests := []struct {
+		cfg  MCPServerConfig
+		want string
+	}{
+		{cfg: MCPServerConfig{Endpoint: "http://localhost/mcp"}, want: "http"},
+		{cfg: MCPServerConfig{Command: "npx"}, want: "stdio"},
+		{cfg: MCPServerConfig{Transport: "SSE", Endpoint: "http://localhost/sse"}, want: "sse"},
+	}
+	for _, tt := range tests {
+		if got := tt.cfg.TransportName(); got != tt.want {
+			t.Errorf("TransportName() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestSaveAllowRulesYAMLKeepsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "friday.yaml")
+	data := `# my model
+model:
+  provider: anthropic
+  key: $ANTHROPIC_KEY
+
+sandbox:
+  enabled: true
+  permissions:
+    # commands I trust
+    allow:
+      - ls
+      - "git status"
+    deny: [rm]
+`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Path() != path {
+		t.Fatalf("Path() = %q, want %q", cfg.Path(), path)
+	}
+	cfg.Sandbox.AllowCommand("terraform plan")
+	if err = cfg.SaveAllowRules("terraform plan", "ls"); err != nil {
+		t.Fatalf("SaveAllowRules() error = %v", err)
+	}
+
+	saved, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	want := strings.Replace(data, `      - "git status"
+`, `      - "git status"
+      - terraform plan
+`, 1)
+	if string(saved) != want {
+		t.Errorf("saved config =\n%s\nwant\n%s", saved, want)
+	}
+}
+
+func TestSaveAllowRulesYAMLAddsSection(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "friday.yaml")
+	data := "# my model\nmodel:\n  provider: anthropic\n"
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	defaults := len(cfg.Sandbox.Permissions.Allow)
+	cfg.Sandbox.AllowCommand("terraform plan")
+	if err = cfg.SaveAllowRules("terraform plan"); err != nil {
+		t.Fatalf("SaveAllowRules() error = %v", err)
+	}
+
+	saved, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if !strings.HasPrefix(string(saved), data+"sandbox:\n  permissions:\n    allow:\n") {
+		t.Errorf("saved config =\n%s", saved)
+	}
+	if strings.Contains(string(saved), "filesystem") {
+		t.Errorf("saved config holds more than the allow list:\n%s", saved)
+	}
+
+	// The default allow list survives, since the file's list replaces it.
+	reloaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() after save error = %v", err)
+	}
+	allow := reloaded.Sandbox.Permissions.Allow
+	if len(allow) != defaults+1 || allow[len(allow)-1] != "terraform plan" {
+		t.Errorf("reloaded allow list = %v, want the defaults and terraform plan", allow)
+	}
+}
+
+func TestSaveAllowRulesJSONKeepsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	data := `{
+  "sandbox": {
+    "permissions": {
+      "allow": [
+        "ls",
+        "git status"
+      ]
+    }
+  },
+  "model": {"provider": "anthropic"}
+}
+`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	cfg.Sandbox.AllowCommand("make && make test")
+	if err := cfg.SaveAllowRules("make && make test"); err != nil {
+		t.Fatalf("SaveAllowRules() error = %v", err)
+	}
+
+	saved, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	want := strings.Replace(data, `"git status"
+`, `"git status",
+        "make && make test"
+`, 1)
+	if string(saved) != want {
+		t.Errorf("saved config =\n%s\nwant\n%s", saved, want)
+	}
+}
+
+func TestSaveAllowRulesJSONAddsSection(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	data := "{\n  \"model\": {\n    \"provider\": \"anthropic\"\n  }\n}\n"
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	cfg.Sandbox.Permissions.Allow = []string{"ls"}
+	cfg.Sandbox.AllowCommand("terraform plan")
+	if err := cfg.SaveAllowRules("terraform plan"); err != nil {
+		t.Fatalf("SaveAllowRules() error = %v", err)
+	}
+
+	saved, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	want := `{
+  "model": {
+    "provider": "anthropic"
+  },
+  "sandbox": {
+    "permissions": {
+      "allow": [
+        "ls",
+        "terraform plan"
+      ]
+    }
+  }
+}
+`
+	if string(saved) != want {
+		t.Errorf("saved config =\n%s\nwant\n%s", saved, want)
+	}
+}
+
+func TestSaveAllowRulesJSONCreatesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "config.json")
+	cfg := DefaultConfig()
+	cfg.path = path
+	cfg.Sandbox.AllowCommand("terraform plan")
+	if err := cfg.SaveAllowRules("terraform plan"); err != nil {
+		t.Fatalf("SaveAllowRules() error = %v", err)
+	}
+
+	reloaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	allow := reloaded.Sandbox.Permissions.Allow
+	if len(allow) == 0 || allow[len(allow)-1] != "terraform plan" {
+		t.Errorf("reloaded allow list = %v, want terraform plan appended", allow)
+	}
+}
@@ -131,4 +131,28 @@ func (m *ModelConfig) overlay(src ModelConfig) {
 	if strings.TrimSpace(src.Proxy) != "" {
 		m.Proxy = src.Proxy
 	}
+	if strings.TrimSpace(src.KeepAlive) != "" {
+		m.KeepAlive = src.KeepAlive
+	}
+	if strings.TrimSpace(src.API) != "" {
+		m.API = src.API
+	}
+	if src.ChainResponses {
+		m.ChainResponses = true
+	}
+	if src.Pricing != nil {
+		m.Pricing = src.Pricing
+	}
+	if strings.TrimSpace(src.Tier) != "" {
+		m.Tier = src.Tier
+	}
+	if src.MaxTools != 0 {
+		m.MaxTools = src.MaxTools
+	}
+	if strings.TrimSpace(src.Tokenizer) != "" {
+		m.Tokenizer = src.Tokenizer
+	}
+	if strings.TrimSpace(src.Cache) != "" {
+		m.Cache = src.Cache
+	}
 }
@@ -1,18 +1,29 @@
 package config
 
-import "github.com/basenana/friday/sandbox"
+import (
+	"strings"
+
+	"github.com/basenana/friday/sandbox"
+)
 
 type Config struct {
-	Model      ModelConfig     `yaml:"model" json:"model"`
-	Models     []ModelConfig   `yaml:"models" json:"models"`
-	ImageModel ModelConfig     `yaml:"image_model" json:"image_model"`
+	Model      ModelConfig            `yaml:"model" json:"model"`
+	Models     []ModelConfig          `yaml:"models" json:"models"`
+	ImageModel ModelConfig            `yaml:"image_model" json:"image_model"`
 	Agents     map[string]ModelConfig `yaml:"agents" json:"agents"`
-	DataDir    string          `yaml:"data_dir" json:"data_dir"`
-	Workspace  string          `yaml:"workspace" json:"workspace"`
-	Memory     MemoryConfig    `yaml:"memory" json:"memory"`
-	Session    SessionConfig   `yaml:"session" json:"session"`
-	Log        LogConfig       `yaml:"log" json:"log"`
-	Sandbox    *sandbox.Config `yaml:"sandbox" json:"sandbox"`
+	DataDir    string                 `yaml:"data_dir" json:"data_dir"`
+	Workspace  string                 `yaml:"workspace" json:"workspace"`
+	Memory     MemoryConfig           `yaml:"memory" json:"memory"`
+	Session    SessionConfig          `yaml:"session" json:"session"`
+	Router     RouterConfig           `yaml:"router" json:"router"`
+	RateLimit  RateLimitConfig        `yaml:"rate_limit" json:"rate_limit"`
+	Log        LogConfig              `yaml:"log" json:"log"`
+	Sandbox    *sandbox.Config        `yaml:"sandbox" json:"sandbox"`
+	MCPServers []MCPServerConfig      `yaml:"mcp_servers" json:"mcp_servers"`
+	Encryption EncryptionConfig       `yaml:"encryption" json:"encryption"`
+
+	// path is the file Load read, or the default location when none existed.
+	path string
 }
 
 type LogConfig struct {
@@ -20,7 +31,7 @@ type LogConfig struct {
 }
 
 type ModelConfig struct {
-	Provider      string  `yaml:"provider" json:"provider"` // "openai" or "anthropic"
+	Provider      string  `yaml:"provider" json:"provider"` // "openai", "anthropic", "gemini" or "ollama"
 	BaseURL       string  `yaml:"base_url" json:"base_url"`
 	Key           string  `yaml:"key" json:"key"`
 	Input         string  `yaml:"input" json:"input"` // "text" "image"
@@ -30,6 +41,69 @@ type ModelConfig struct {
 	Temperature   float64 `yaml:"temperature" json:"temperature"`
 	QPM           int64   `yaml:"qpm" json:"qpm"`
 	Proxy         string  `yaml:"proxy" json:"proxy"`
+	// KeepAlive is how long Ollama keeps the model loaded, e.g. "30m"
+	KeepAlive string `yaml:"keep_alive" json:"keep_alive"`
+	// API is the OpenAI wire protocol: "chat_completions" (default) or "responses"
+	API string `yaml:"api" json:"api"`
+	// ChainResponses stores OpenAI responses and continues from the previous one
+	ChainResponses bool `yaml:"chain_responses" json:"chain_responses"`
+	// Pricing overrides the built-in price list for this model
+	Pricing *ModelPricing `yaml:"pricing" json:"pricing,omitempty"`
+	// Tier marks the model "cheap" or "smart" for the router
+	Tier string `yaml:"tier" json:"tier"`
+	// MaxTools keeps the router from giving the model more tools; 0 means no limit
+	MaxTools int `yaml:"max_tools" json:"max_tools"`
+	// Tokenizer counts the model's tokens: "cl100k_base", "o200k_base", "claude"
+	// or "estimate"; empty picks one from the provider and model name
+	Tokenizer string `yaml:"tokenizer" json:"tokenizer"`
+	// Cache is how an OpenAI-compatible server caches prompts:
+	// "prompt_cache_key" (default), "prefix" for llama.cpp or vLLM, or "none"
+	Cache string `yaml:"cache" json:"cache"`
+}
+
+// ModelPricing is what a model charges, in USD per million tokens. CachedInput
+// and Reasoning default to Input and Output.
+type ModelPricing struct {
+	Input       float64 `yaml:"input" json:"input"`
+	CachedInput float64 `yaml:"cached_input" json:"cached_input"`
+	Output      float64 `yaml:"output" json:"output"`
+	Reasoning   float64 `yaml:"reasoning" json:"reasoning"`
+}
+
+// MCPServerConfig describes an MCP server whose tools are exposed to the agent.
+// Endpoint and Headers apply to the "http" (streamable HTTP) and "sse"
+// (legacy HTTP+SSE) transports; Command, Args, Env and Cwd to "stdio".
+// Transport defaults to "stdio" when Command is set and to "http" otherwise.
+type MCPServerConfig struct {
+	Name      string            `yaml:"name" json:"name"`
+	Transport string            `yaml:"transport" json:"transport"` // "http", "sse" or "stdio"
+	Endpoint  string            `yaml:"endpoint" json:"endpoint"`
+	Headers   map[string]string `yaml:"headers" json:"headers"`
+	Command   string            `yaml:"command" json:"command"`
+	Args      []string          `yaml:"args" json:"args"`
+	Env       map[string]string `yaml:"env" json:"env"`
+	Cwd       string            `yaml:"cwd" json:"cwd"`
+	// Enabled defaults to true when omitted.
+	Enabled *bool `yaml:"enabled" json:"enabled"`
+	// ReadOnly marks the server's tools as side-effect free so read-only
+	// subagents (explorer, planner, reviewer, advisor) may use them too.
+	ReadOnly bool `yaml:"read_only" json:"read_only"`
+}
+
+// IsEnabled reports whether the server should be connected.
+func (m MCPServerConfig) IsEnabled() bool {
+	return m.Enabled == nil || *m.Enabled
+}
+
+// TransportName returns the effective transport.
+func (m MCPServerConfig) TransportName() string {
+	if t := strings.ToLower(strings.TrimSpace(m.Transport)); t != "" {
+		return t
+	}
+	if strings.TrimSpace(m.Command) != "" {
+		return "stdio"
+	}
+	return "http"
 }
 
 type MemoryConfig struct {
@@ -37,8 +111,38 @@ type MemoryConfig struct {
 	Days    int  `yaml:"days" json:"days"`
 }
 
+// RouterConfig routes each turn to one of the configured models instead of
+// falling back between them on errors.
+type RouterConfig struct {
+	Enabled bool `yaml:"enabled" json:"enabled"`
+	// SimpleTokens is the estimated prompt size up to which a turn goes to a cheap model
+	SimpleTokens int64 `yaml:"simple_tokens" json:"simple_tokens"`
+}
+
+// RateLimitConfig shares the QPM of each provider account between every
+// Friday process using the same data dir, instead of pacing each process alone.
+type RateLimitConfig struct {
+	Shared bool `yaml:"shared" json:"shared"`
+}
+
 type SessionConfig struct {
 	DefaultAgent string `yaml:"default_agent" json:"default_agent"`
+	// Budget stops the agent once a session has spent this much, in USD. Zero means no limit.
+	Budget float64 `yaml:"budget" json:"budget"`
+	// Store is where sessions are kept: "file" (default), a directory per
+	// session, or "sqlite", one database for all of them
+	Store string `yaml:"store" json:"store"`
+}
+
+// EncryptionConfig encrypts sessions, memory logs and agent state at rest.
+// The key is read from the FRIDAY_DATA_KEY environment variable, then
+// KeyFile, then KeyCommand.
+type EncryptionConfig struct {
+	Enabled bool   `yaml:"enabled" json:"enabled"`
+	KeyFile string `yaml:"key_file" json:"key_file"`
+	// KeyCommand prints the key, e.g. a lookup in the OS keyring:
+	// ["secret-tool", "lookup", "service", "friday"]
+	KeyCommand []string `yaml:"key_command" json:"key_command"`
 }
 
 func DefaultConfig() *Config {
@@ -98,6 +98,11 @@ func (a *react) reactLoop(ctx context.Context, sess *session.Session, resp *api.
 	}()
 
 	for {
+		if err = sess.CheckBudget(); err != nil {
+			a.logger.Warnw("session budget exceeded", "session", sess.ID, "error", err)
+			resp.Fail(err)
+			return
+		}
 		select {
 		case <-ctx.Done():
 			return
@@ -271,10 +276,13 @@ WaitMessage:
 		}
 	}
 
+	usage := stream.Tokens()
+	sess.RecordUsage(usage)
+
 	a.logger.Infow("message finish",
-		"fuzzyTokens", sess.Tokens(), "promptTokens", stream.Tokens().PromptTokens,
-		"cachedPromptTokens", stream.Tokens().CachedPromptTokens,
-		"completionTokens", stream.Tokens().CompletionTokens, "budget", budget, "session", sess.ID)
+		"fuzzyTokens", sess.Tokens(), "promptTokens", usage.PromptTokens,
+		"cachedPromptTokens", usage.CachedPromptTokens,
+		"completionTokens", usage.CompletionTokens, "cost", usage.Cost, "budget", budget, "session", sess.ID)
 	span.SetAttributes(
 		tracing.Int("prompt_tokens", stream.Tokens().PromptTokens),
 		tracing.Int("completion_tokens", stream.Tokens().CompletionTokens),
@@ -285,9 +293,13 @@ WaitMessage:
 	sess.PublishEvent(types.Event{
 		Type: types.EventModelFinish,
 		Data: map[string]string{
-			"prompt_tokens":     strconv.FormatInt(stream.Tokens().PromptTokens, 10),
-			"completion_tokens": strconv.FormatInt(stream.Tokens().CompletionTokens, 10),
-			"tool_calls":        strconv.Itoa(len(toolUse)),
+			"prompt_tokens":        strconv.FormatInt(usage.PromptTokens, 10),
+			"cached_prompt_tokens": strconv.FormatInt(usage.CachedPromptTokens, 10),
+			"completion_tokens":    strconv.FormatInt(usage.CompletionTokens, 10),
+			"reasoning_tokens":     strconv.FormatInt(usage.ReasoningTokens, 10),
+			"model":                usage.Model,
+			"cost":                 strconv.FormatFloat(usage.Cost, 'f', -1, 64),
+			"tool_calls":           strconv.Itoa(len(toolUse)),
 		},
 	})
 	// Providers that don't return PromptTokens will fall back to
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/basenana/friday/core/api"
+	"github.com/basenana/friday/core/pricing"
 	"github.com/basenana/friday/core/providers"
 	"github.com/basenana/friday/core/session"
 	"github.com/basenana/friday/core/tools"
@@ -222,6 +223,43 @@ func TestReactCalibratesMessageTokensFromAPI(t *testing.T) {
 	}
 }
 
+func TestReact_StopsWhenBudgetExceeded(t *testing.T) {
+	llm := &calibratingFakeLLM{
+		completions: [][]providers.Delta{
+			{{ToolUse: []providers.ToolCall{{ID: "call-1", Name: "noop", Arguments: `{}`}}}},
+			{{Content: "Done."}},
+		},
+		promptTokens:     100,
+		completionTokens: 10,
+	}
+	// $1 per thousand prompt tokens: the first call costs $0.10.
+	metered := pricing.Meter(llm, "test-model", pricing.Price{Input: 1000})
+	tool := tools.NewTool("noop",
+		tools.WithToolHandler(func(ctx context.Context, request *tools.Request) (*tools.Result, error) {
+			return tools.NewToolResultText("ok"), nil
+		}),
+	)
+
+	sess := session.New("sess-budget", metered, session.WithCostBudget(0.05))
+	resp := New(metered, Option{SystemPrompt: "system", MaxLoopTimes: 4}).Chat(context.Background(), &api.Request{
+		Session:     sess,
+		UserMessage: "Run the tool.",
+		Tools:       []*tools.Tool{tool},
+	})
+
+	_, err := api.ReadAllContent(context.Background(), resp)
+	var budgetErr *session.BudgetExceededError
+	if !errors.As(err, &budgetErr) {
+		t.Fatalf("ReadAllContent() error = %v, want BudgetExceededError", err)
+	}
+	if llm.calls != 1 {
+		t.Fatalf("expected the loop to stop after 1 model call, got %d", llm.calls)
+	}
+	if got := sess.Cost(); got < 0.0999 || got > 0.1001 {
+		t.Fatalf("session cost = %v, want 0.10", got)
+	}
+}
+
 type calibratingFakeLLM struct {
 	mu               sync.Mutex
 	completions      [][]providers.Delta
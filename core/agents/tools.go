@@ -124,5 +124,6 @@ func newLLMRequest(systemMessage string, sess *session.Session, toolList []*tool
 
 	req := providers.NewRequest(systemMessage, sess.GetHistory()...)
 	req.SetToolDefines(toolDef)
+	req.SetTokenizer(sess.Tokenizer())
 	return req
 }
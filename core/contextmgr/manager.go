@@ -19,8 +19,16 @@ const (
 	defaultHardThresholdRatio     float64 = 0.85
 	defaultMaxToolResultChars     int     = 600
 	defaultSessionMemoryThreshold int64   = 15_000
+	defaultCacheHitRate           float64 = 0.5
 	projectionTailGroups          int     = 4
 	tokensSkipCompact             int64   = -1
+
+	// microCompactRatio is the share of the full projection a micro
+	// compaction must shrink it to; cachedMicroCompactRatio applies instead
+	// while the prompt prefix is read from the provider's cache, since
+	// rewriting it gives up the cache.
+	microCompactRatio       float64 = 0.8
+	cachedMicroCompactRatio float64 = 0.6
 )
 
 type Config struct {
@@ -29,6 +37,11 @@ type Config struct {
 	HardThresholdRatio     float64
 	MaxToolResultChars     int
 	SessionMemoryThreshold int64
+	// CacheHitRate is the prompt cache hit rate of the latest call from which
+	// micro compaction preserves the cached prefix: it needs larger savings
+	// to rewrite it and keeps a frozen projection for longer. Negative
+	// disables this.
+	CacheHitRate float64
 
 	SessionMemoryStore SessionMemoryStore
 }
@@ -55,6 +68,9 @@ func New(llm providers.Client, cfg Config) *Manager {
 	if cfg.SessionMemoryThreshold == 0 {
 		cfg.SessionMemoryThreshold = defaultSessionMemoryThreshold
 	}
+	if cfg.CacheHitRate == 0 {
+		cfg.CacheHitRate = defaultCacheHitRate
+	}
 
 	return &Manager{
 		llm:    llm,
@@ -72,7 +88,7 @@ func (m *Manager) BeforeModel(ctx stdctx.Context, sess *session.Session, req pro
 	projected := history
 	projectedTokens := sess.Tokens()
 	if sessLen := sess.HistoryLen(); len(history) > sessLen {
-		projectedTokens += session.EstimateHistoryTokens(history[sessLen:])
+		projectedTokens += sess.CountTokens(history[sessLen:])
 	}
 
 	if req.PromptCacheKey() == "" && projectedTokens > defaultSessionMemoryThreshold {
@@ -91,9 +107,13 @@ func (m *Manager) BeforeModel(ctx stdctx.Context, sess *session.Session, req pro
 		}
 	}
 
+	cache := sess.CacheStats()
 	m.logger.Infow("starting context projection",
 		"session", sess.ID,
 		"history_messages", len(history),
+		"cache_strategy", providers.CacheStrategyOf(m.llm),
+		"cache_hit_rate", cache.HitRate(),
+		"last_cache_hit_rate", cache.LastHitRate,
 		"session_memory_messages", len(st.SessionMemory),
 		"last_synced_at", st.LastSyncedAt,
 		"projected_tokens", projectedTokens,
@@ -152,13 +172,18 @@ func (m *Manager) BeforeModel(ctx stdctx.Context, sess *session.Session, req pro
 }
 
 func (m *Manager) applyMicroCompact(sess *session.Session, st *session.ContextState, history []types.Message, fullTokens int64) ([]types.Message, int64, bool) {
-	micro, savedTokens := m.buildMicroProjected(sess.ID, st, history)
+	cached := m.prefixCached(sess)
+	micro, savedTokens := m.buildMicroProjected(sess.ID, st, history, cached)
 	if savedTokens < 0 {
 		return micro, fullTokens, true // already micro compact
 	}
 
+	ratio := microCompactRatio
+	if cached {
+		ratio = cachedMicroCompactRatio
+	}
 	microTokens := fullTokens - savedTokens
-	if fullTokens > 0 && float64(microTokens)/float64(fullTokens) < 0.8 {
+	if fullTokens > 0 && float64(microTokens)/float64(fullTokens) < ratio {
 		m.logger.Infow("[COMPACT] microcompact applied",
 			"session", sess.ID,
 			"projected_tokens", microTokens,
@@ -171,12 +196,27 @@ func (m *Manager) applyMicroCompact(sess *session.Session, st *session.ContextSt
 		"session", sess.ID,
 		"full_tokens", fullTokens,
 		"micro_tokens", microTokens,
+		"prefix_cached", cached,
 	)
 	return history, fullTokens, false
 }
 
-func (m *Manager) buildMicroProjected(sessionID string, st *session.ContextState, history []types.Message) ([]types.Message, int64) {
-	if projected, ok := frozenMicroCompactProjection(st, history); ok {
+// prefixCached reports whether the provider served most of the latest
+// prompt of sess from its cache, making the prompt prefix worth preserving.
+func (m *Manager) prefixCached(sess *session.Session) bool {
+	if m.cfg.CacheHitRate < 0 || providers.CacheStrategyOf(m.llm) == providers.CacheNone {
+		return false
+	}
+	cache := sess.CacheStats()
+	return cache.Requests > 0 && cache.LastHitRate >= m.cfg.CacheHitRate
+}
+
+func (m *Manager) buildMicroProjected(sessionID string, st *session.ContextState, history []types.Message, cached bool) ([]types.Message, int64) {
+	limit := defaultSessionMemoryThreshold * 2
+	if cached {
+		limit *= 2
+	}
+	if projected, ok := frozenMicroCompactProjection(st, history, limit); ok {
 		m.logger.Infow("using frozen microcompact",
 			"session", sessionID,
 			"projected_messages", len(projected),
@@ -420,7 +460,7 @@ func countTokens(sess *session.Session, history []types.Message) int64 {
 		return sess.CountTokens(history)
 	}
 
-	return session.EstimateHistoryTokens(history)
+	return session.EstimateHistoryTokens(nil, history)
 }
 
 func maxInt64(a, b int64) int64 {
@@ -492,7 +532,9 @@ func containsString(items []string, target string) bool {
 	return false
 }
 
-func frozenMicroCompactProjection(st *session.ContextState, history []types.Message) ([]types.Message, bool) {
+// frozenMicroCompactProjection reuses the pruned prefix of an earlier micro
+// compaction while at most limit tokens follow it.
+func frozenMicroCompactProjection(st *session.ContextState, history []types.Message, limit int64) ([]types.Message, bool) {
 	if st == nil || st.MicroCompactSourceMessages == 0 || len(st.MicroCompactPrefix) == 0 {
 		return nil, false
 	}
@@ -502,7 +544,7 @@ func frozenMicroCompactProjection(st *session.ContextState, history []types.Mess
 	}
 
 	afterMessage := cloneMessages(history[st.MicroCompactSourceMessages:])
-	if countTokens(nil, afterMessage) > defaultSessionMemoryThreshold*2 {
+	if countTokens(nil, afterMessage) > limit {
 		st.ResetMicroCompact()
 		return nil, false
 	}
@@ -323,6 +323,50 @@ func TestBeforeModelMicroCompactFreezesBoundaryOnceSelected(t *testing.T) {
 	}
 }
 
+func TestBeforeModelMicroCompactPreservesCachedPrefix(t *testing.T) {
+	toolResult := strings.Repeat("tool output ", 40)
+	newSession := func(id string) *session.Session {
+		return session.New(id, nil, session.WithHistory(
+			types.Message{Role: types.RoleUser, Content: "Investigate core/session/compact.go."},
+			types.Message{Role: types.RoleTool, ToolResult: &types.ToolResult{CallID: "call-1", Content: toolResult}},
+			types.Message{Role: types.RoleAssistant, Content: strings.Repeat("The file compacts the history. ", 20)},
+			types.Message{Role: types.RoleUser, Content: "Now summarize the changes."},
+			types.Message{Role: types.RoleAssistant, Content: "I'm preparing the summary."},
+			types.Message{Role: types.RoleUser, Content: "Give me the final answer."},
+			types.Message{Role: types.RoleAssistant, Content: "I'm drafting the final response."},
+			types.Message{Role: types.RoleUser, Content: "Double check the result."},
+			types.Message{Role: types.RoleAssistant, Content: "I'm reviewing the result."},
+			types.Message{Role: types.RoleUser, Content: "Deliver it."},
+			types.Message{Role: types.RoleAssistant, Content: "Delivering the response."},
+		))
+	}
+	mgr := New(&cachingCompletionClient{}, Config{
+		ContextWindow:      1200,
+		SoftThresholdRatio: 0.20,
+		HardThresholdRatio: 0.90,
+		MaxToolResultChars: 80,
+	})
+
+	uncached := newSession("sess-uncached")
+	req := providers.NewRequest("", uncached.GetHistory()...)
+	if err := mgr.BeforeModel(stdctx.Background(), uncached, req); err != nil {
+		t.Fatalf("BeforeModel failed: %v", err)
+	}
+	if historyContainsToolResult(req.History(), toolResult) {
+		t.Fatalf("expected tool result to be pruned without a cached prefix")
+	}
+
+	cached := newSession("sess-cached")
+	cached.RecordUsage(providers.Tokens{PromptTokens: 1000, CachedPromptTokens: 900})
+	req = providers.NewRequest("", cached.GetHistory()...)
+	if err := mgr.BeforeModel(stdctx.Background(), cached, req); err != nil {
+		t.Fatalf("BeforeModel failed: %v", err)
+	}
+	if !historyContainsToolResult(req.History(), toolResult) {
+		t.Fatalf("expected cached prefix to be kept for a marginal micro compaction")
+	}
+}
+
 func TestBeforeModelHardCompactRewritesHistory(t *testing.T) {
 	writer := &mockMessageWriter{}
 	sess := session.New("sess-3", nil,
@@ -682,6 +726,14 @@ func (s *simpleCompletionClient) StructuredPredict(_ stdctx.Context, _ providers
 	return errors.New("structured predict unavailable")
 }
 
+type cachingCompletionClient struct {
+	simpleCompletionClient
+}
+
+func (c *cachingCompletionClient) CacheStrategy() providers.CacheStrategy {
+	return providers.CacheKey
+}
+
 type failingCompactClient struct {
 	completionCalls int
 }
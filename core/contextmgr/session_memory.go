@@ -250,13 +250,13 @@ func (m *Manager) maybeStartAsyncSessionMemory(sess *session.Session, st *sessio
 	}
 
 	// Compute tokens of new (unsynced) messages
-	var newTokens int64
+	var newMsgs []types.Message
 	for _, msg := range history {
 		if msg.Time.After(st.LastSyncedAt) {
-			newTokens += msg.FuzzyTokens()
+			newMsgs = append(newMsgs, msg)
 		}
 	}
-	if newTokens < m.cfg.SessionMemoryThreshold {
+	if newTokens := countTokens(sess, newMsgs); newTokens < m.cfg.SessionMemoryThreshold {
 		return
 	}
 	// Atomic CAS to prevent concurrent generation
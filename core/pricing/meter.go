@@ -0,0 +1,55 @@
+package pricing
+
+import (
+	"context"
+
+	"github.com/basenana/friday/core/providers"
+)
+
+// Meter wraps client so the Tokens of its streamed responses name model and
+// carry their cost at price. Non-streaming calls report no usage and are
+// passed through.
+func Meter(client providers.Client, model string, price Price) providers.Client {
+	return &meter{Client: client, model: model, price: price}
+}
+
+type meter struct {
+	providers.Client
+	model string
+	price Price
+}
+
+func (m *meter) Completion(ctx context.Context, request providers.Request) providers.Response {
+	return &meteredResponse{Response: m.Client.Completion(ctx, request), meter: m}
+}
+
+// ContextWindow forwards the wrapped client's context window, if it reports one.
+func (m *meter) ContextWindow() int64 {
+	if cw, ok := m.Client.(providers.ContextWindowProvider); ok {
+		return cw.ContextWindow()
+	}
+	return 0
+}
+
+// CacheStrategy forwards the wrapped client's cache strategy.
+func (m *meter) CacheStrategy() providers.CacheStrategy {
+	return providers.CacheStrategyOf(m.Client)
+}
+
+type meteredResponse struct {
+	providers.Response
+	meter *meter
+}
+
+func (r *meteredResponse) Tokens() providers.Tokens {
+	t := r.Response.Tokens()
+	t.Model = r.meter.model
+	t.Cost = r.meter.price.Cost(t)
+	return t
+}
+
+var (
+	_ providers.Client                = (*meter)(nil)
+	_ providers.ContextWindowProvider = (*meter)(nil)
+	_ providers.PromptCacher          = (*meter)(nil)
+)
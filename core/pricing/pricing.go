@@ -0,0 +1,139 @@
+// Package pricing prices model usage and attaches the cost to each response.
+package pricing
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/basenana/friday/core/providers"
+)
+
+// Price is what a model charges, in USD per million tokens. CachedInput and
+// Reasoning fall back to Input and Output when zero.
+type Price struct {
+	Input       float64 `json:"input"`
+	CachedInput float64 `json:"cached_input,omitempty"`
+	Output      float64 `json:"output"`
+	Reasoning   float64 `json:"reasoning,omitempty"`
+}
+
+// IsZero reports whether the price is unknown or free.
+func (p Price) IsZero() bool {
+	return p == Price{}
+}
+
+// Cost returns the cost of t in USD.
+func (p Price) Cost(t providers.Tokens) float64 {
+	cachedPrice := p.CachedInput
+	if cachedPrice == 0 {
+		cachedPrice = p.Input
+	}
+	reasoningPrice := p.Reasoning
+	if reasoningPrice == 0 {
+		reasoningPrice = p.Output
+	}
+
+	cached := min(t.CachedPromptTokens, t.PromptTokens)
+	reasoning := min(t.ReasoningTokens, t.CompletionTokens)
+	total := float64(t.PromptTokens-cached)*p.Input +
+		float64(cached)*cachedPrice +
+		float64(t.CompletionTokens-reasoning)*p.Output +
+		float64(reasoning)*reasoningPrice
+	return total / 1e6
+}
+
+// Registry maps model names to prices. A name matches its own entry, or
+// else the longest entry it extends with a "-", ":" or "@" suffix, so
+// "gpt-4o-2024-08-06" is priced as "gpt-4o". A provider prefix such as
+// "openai/" is ignored.
+type Registry struct {
+	mu     sync.RWMutex
+	prices map[string]Price
+}
+
+// NewRegistry returns a registry holding prices.
+func NewRegistry(prices map[string]Price) *Registry {
+	r := &Registry{prices: make(map[string]Price, len(prices))}
+	for name, p := range prices {
+		r.Set(name, p)
+	}
+	return r
+}
+
+// Set sets the price of a model.
+func (r *Registry) Set(model string, p Price) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.prices[strings.ToLower(model)] = p
+}
+
+// Lookup returns the price of a model.
+func (r *Registry) Lookup(model string) (Price, bool) {
+	name := strings.ToLower(strings.TrimSpace(model))
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if p, ok := r.prices[name]; ok {
+		return p, true
+	}
+	var (
+		best  string
+		price Price
+	)
+	for prefix, p := range r.prices {
+		if len(prefix) <= len(best) || len(name) <= len(prefix) || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		if strings.ContainsRune("-:@", rune(name[len(prefix)])) {
+			best, price = prefix, p
+		}
+	}
+	return price, best != ""
+}
+
+var defaultRegistry = NewRegistry(map[string]Price{
+	// OpenAI
+	"gpt-4o":       {Input: 2.50, CachedInput: 1.25, Output: 10},
+	"gpt-4o-mini":  {Input: 0.15, CachedInput: 0.075, Output: 0.60},
+	"gpt-4.1":      {Input: 2, CachedInput: 0.50, Output: 8},
+	"gpt-4.1-mini": {Input: 0.40, CachedInput: 0.10, Output: 1.60},
+	"gpt-4.1-nano": {Input: 0.10, CachedInput: 0.025, Output: 0.40},
+	"gpt-5":        {Input: 1.25, CachedInput: 0.125, Output: 10},
+	"gpt-5-mini":   {Input: 0.25, CachedInput: 0.025, Output: 2},
+	"gpt-5-nano":   {Input: 0.05, CachedInput: 0.005, Output: 0.40},
+	"o3":           {Input: 2, CachedInput: 0.50, Output: 8},
+	"o3-mini":      {Input: 1.10, CachedInput: 0.55, Output: 4.40},
+	"o4-mini":      {Input: 1.10, CachedInput: 0.275, Output: 4.40},
+
+	// Anthropic
+	"claude-opus-4":     {Input: 15, CachedInput: 1.50, Output: 75},
+	"claude-opus-4-5":   {Input: 5, CachedInput: 0.50, Output: 25},
+	"claude-sonnet-4":   {Input: 3, CachedInput: 0.30, Output: 15},
+	"claude-3-7-sonnet": {Input: 3, CachedInput: 0.30, Output: 15},
+	"claude-3-5-sonnet": {Input: 3, CachedInput: 0.30, Output: 15},
+	"claude-haiku-4-5":  {Input: 1, CachedInput: 0.10, Output: 5},
+	"claude-3-5-haiku":  {Input: 0.80, CachedInput: 0.08, Output: 4},
+
+	// Google
+	"gemini-2.5-pro":        {Input: 1.25, CachedInput: 0.31, Output: 10},
+	"gemini-2.5-flash":      {Input: 0.30, CachedInput: 0.075, Output: 2.50},
+	"gemini-2.5-flash-lite": {Input: 0.10, CachedInput: 0.025, Output: 0.40},
+	"gemini-2.0-flash":      {Input: 0.10, CachedInput: 0.025, Output: 0.40},
+
+	// DeepSeek
+	"deepseek-chat":     {Input: 0.27, CachedInput: 0.07, Output: 1.10},
+	"deepseek-reasoner": {Input: 0.55, CachedInput: 0.14, Output: 2.19},
+})
+
+// Default returns the registry of built-in list prices.
+func Default() *Registry {
+	return defaultRegistry
+}
+
+// Lookup returns the built-in price of a model.
+func Lookup(model string) (Price, bool) {
+	return defaultRegistry.Lookup(model)
+}
@@ -0,0 +1,80 @@
+package pricing
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/basenana/friday/core/providers"
+)
+
+func TestPriceCost(t *testing.T) {
+	price := Price{Input: 2, CachedInput: 0.5, Output: 8, Reasoning: 10}
+	tokens := providers.Tokens{
+		PromptTokens:       1_000_000,
+		CachedPromptTokens: 400_000,
+		CompletionTokens:   200_000,
+		ReasoningTokens:    50_000,
+	}
+	// 0.6M*2 + 0.4M*0.5 + 0.15M*8 + 0.05M*10
+	if got, want := price.Cost(tokens), 1.2+0.2+1.2+0.5; math.Abs(got-want) > 1e-9 {
+		t.Fatalf("Cost() = %v, want %v", got, want)
+	}
+
+	fallback := Price{Input: 2, Output: 8}
+	if got, want := fallback.Cost(tokens), 2.0+1.6; math.Abs(got-want) > 1e-9 {
+		t.Fatalf("Cost() without cached/reasoning prices = %v, want %v", got, want)
+	}
+}
+
+func TestRegistryLookup(t *testing.T) {
+	r := NewRegistry(map[string]Price{
+		"gpt-4o":      {Input: 2.5},
+		"gpt-4o-mini": {Input: 0.15},
+		"o3":          {Input: 2},
+	})
+
+	cases := []struct {
+		model string
+		want  float64
+		found bool
+	}{
+		{"gpt-4o", 2.5, true},
+		{"GPT-4o-2024-08-06", 2.5, true},
+		{"gpt-4o-mini-2024-07-18", 0.15, true},
+		{"openai/gpt-4o-mini", 0.15, true},
+		{"o3-pro", 2, true},
+		{"o3mini", 0, false},
+		{"llama3", 0, false},
+	}
+	for _, c := range cases {
+		p, ok := r.Lookup(c.model)
+		if ok != c.found || p.Input != c.want {
+			t.Errorf("Lookup(%q) = %v, %v; want %v, %v", c.model, p.Input, ok, c.want, c.found)
+		}
+	}
+}
+
+type tokenClient struct{ providers.Client }
+
+func (tokenClient) Completion(context.Context, providers.Request) providers.Response {
+	resp := providers.NewCommonResponse()
+	go func() {
+		defer close(resp.Stream)
+		defer close(resp.Err)
+		resp.Token = providers.Tokens{PromptTokens: 1000, CompletionTokens: 100}
+	}()
+	return resp
+}
+
+func TestMeterSetsModelAndCost(t *testing.T) {
+	resp := Meter(tokenClient{}, "gpt-test", Price{Input: 1, Output: 10}).Completion(context.Background(), providers.NewPromptRequest("hi"))
+	for range resp.Message() {
+	}
+	<-resp.Error()
+
+	got := resp.Tokens()
+	if got.Model != "gpt-test" || math.Abs(got.Cost-0.002) > 1e-12 {
+		t.Fatalf("Tokens() = %+v, want model gpt-test and cost 0.002", got)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -15,11 +16,11 @@ import (
 	"github.com/basenana/friday/core/logger"
 	"github.com/basenana/friday/core/providers"
 	"github.com/basenana/friday/core/providers/common"
+	"github.com/basenana/friday/core/providers/ratelimit"
 	"github.com/basenana/friday/core/session"
 	"github.com/basenana/friday/core/tracing"
 	"github.com/basenana/friday/core/types"
 	"github.com/invopop/jsonschema"
-	"golang.org/x/time/rate"
 )
 
 type Model struct {
@@ -31,12 +32,15 @@ type Model struct {
 	Proxy              string
 	ContextWindow      int64
 	InsecureSkipVerify bool
+	// Limiter paces the calls instead of a limiter of QPM private to this
+	// client, e.g. one shared with other processes.
+	Limiter ratelimit.Limiter
 }
 
 type client struct {
 	anthropic  anthropic.Client
 	model      Model
-	apiLimiter *rate.Limiter
+	apiLimiter ratelimit.Limiter
 	logger     logger.Logger
 }
 
@@ -44,6 +48,12 @@ func (c *client) ContextWindow() int64 {
 	return c.model.ContextWindow
 }
 
+// CacheStrategy is CacheBreakpoints: requests with a PromptCacheKey mark the
+// system prompt, the tools and the recent history for caching.
+func (c *client) CacheStrategy() providers.CacheStrategy {
+	return providers.CacheBreakpoints
+}
+
 func (c *client) Completion(ctx context.Context, request providers.Request) providers.Response {
 	c.logger.Infow("llm processing...")
 	ctx, span := tracing.Start(ctx, "llm.anthropic.completion",
@@ -97,8 +107,9 @@ func (c *client) Completion(ctx context.Context, request providers.Request) prov
 
 		if err = stream.Err(); err != nil {
 			if isRateLimitError(err) {
-				time.Sleep(time.Second * 10)
-				c.logger.Warn("rate limited, trying again")
+				wait := retryAfter(err)
+				c.apiLimiter.Pause(wait)
+				c.logger.Warnw("rate limited, trying again", "wait", wait)
 				goto Retry
 			}
 			c.logger.Errorw("completion stream error", "err", err)
@@ -142,8 +153,9 @@ Retry:
 	message, err := c.anthropic.Messages.New(ctx, *params)
 	if err != nil {
 		if isRateLimitError(err) {
-			time.Sleep(time.Second * 10)
-			c.logger.Warn("rate limited, trying again")
+			wait := retryAfter(err)
+			c.apiLimiter.Pause(wait)
+			c.logger.Warnw("rate limited, trying again", "wait", wait)
 			goto Retry
 		}
 		c.logger.Errorw("completion error", "err", err)
@@ -462,11 +474,15 @@ func newClient(host, apiKey string, model Model) *client {
 	if model.QPM == 0 {
 		model.QPM = 20
 	}
+	limiter := model.Limiter
+	if limiter == nil {
+		limiter = ratelimit.New(model.QPM)
+	}
 
 	return &client{
 		anthropic:  anthropicClient,
 		model:      model,
-		apiLimiter: rate.NewLimiter(rate.Limit(float64(model.QPM)/60), int(model.QPM/2)),
+		apiLimiter: limiter,
 		logger:     logger.New("anthropics"),
 	}
 }
@@ -622,6 +638,21 @@ func isRateLimitError(err error) bool {
 	return strings.Contains(err.Error(), "rate_limit") || strings.Contains(err.Error(), "429")
 }
 
+// defaultRetryAfter is how long to wait after a rate limit error that does
+// not say.
+const defaultRetryAfter = 10 * time.Second
+
+// retryAfter returns how long a rate limit error asks to wait.
+func retryAfter(err error) time.Duration {
+	var apiErr *anthropic.Error
+	if errors.As(err, &apiErr) && apiErr.Response != nil {
+		if d := ratelimit.RetryAfter(apiErr.Response.Header); d > 0 {
+			return d
+		}
+	}
+	return defaultRetryAfter
+}
+
 func normalizeAnthropicToolMessages(messages []anthropic.MessageParam) []anthropic.MessageParam {
 	if len(messages) == 0 {
 		return messages
@@ -2,8 +2,11 @@ package anthropics
 
 import (
 	"encoding/json"
+	"fmt"
+	"net/http"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/basenana/friday/core/providers"
@@ -649,3 +652,16 @@ func TestMessageCreateParamsConvertsUnresolvedFinalToolUseToText(t *testing.T) {
 		t.Fatalf("expected fallback text for unresolved final tool_use, got %#v", assistant.Content[1])
 	}
 }
+
+func TestRetryAfterReadsRateLimitHeaders(t *testing.T) {
+	err := fmt.Errorf("completion: %w", &anthropic.Error{Response: &http.Response{Header: http.Header{"Retry-After": {"3"}}}})
+	if got := retryAfter(err); got != 3*time.Second {
+		t.Fatalf("retryAfter() = %v, want 3s", got)
+	}
+	if got := retryAfter(&anthropic.Error{Response: &http.Response{Header: http.Header{}}}); got != defaultRetryAfter {
+		t.Fatalf("retryAfter() without header = %v, want %v", got, defaultRetryAfter)
+	}
+	if got := retryAfter(fmt.Errorf("boom")); got != defaultRetryAfter {
+		t.Fatalf("retryAfter() of a plain error = %v, want %v", got, defaultRetryAfter)
+	}
+}
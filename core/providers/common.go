@@ -1,6 +1,9 @@
 package providers
 
-import "github.com/basenana/friday/core/types"
+import (
+	"github.com/basenana/friday/core/tokenizer"
+	"github.com/basenana/friday/core/types"
+)
 
 type CommonResponse struct {
 	Stream chan Delta
@@ -21,6 +24,8 @@ type commonRequest struct {
 	tools          []ToolDefine
 	history        []types.Message
 	promptCacheKey string
+	modelHint      string
+	tokenizer      tokenizer.Tokenizer
 }
 
 func NewRequest(systemMessage string, history ...types.Message) Request {
@@ -67,6 +72,14 @@ func (s *commonRequest) PromptCacheKey() string {
 	return s.promptCacheKey
 }
 
+func (s *commonRequest) ModelHint() string {
+	return s.modelHint
+}
+
+func (s *commonRequest) Tokenizer() tokenizer.Tokenizer {
+	return s.tokenizer
+}
+
 func (s *commonRequest) SetHistory(history []types.Message) {
 	s.history = history
 }
@@ -98,6 +111,14 @@ func (s *commonRequest) SetPromptCacheKey(key string) {
 	s.promptCacheKey = key
 }
 
+func (s *commonRequest) SetModelHint(hint string) {
+	s.modelHint = hint
+}
+
+func (s *commonRequest) SetTokenizer(tok tokenizer.Tokenizer) {
+	s.tokenizer = tok
+}
+
 func (s *commonRequest) AppendHistory(messages ...types.Message) {
 	s.history = append(s.history, messages...)
 }
@@ -6,6 +6,9 @@ import (
 	"log/slog"
 )
 
+// errEmptyResponse is recorded against a model that streamed nothing.
+var errEmptyResponse = errors.New("empty response")
+
 // shouldFallbackOnError returns true for any model error except context cancellation.
 func shouldFallbackOnError(err error) bool {
 	if err == nil {
@@ -20,10 +20,12 @@ type ModelEntry struct {
 
 // FallbackClient implements providers.Client. On retriable error from model N,
 // it advances to model N+1 (circular wrap-around). It retries up to
-// maxTotalRetries across all models before giving up.
+// maxTotalRetries across all models before giving up. Models whose circuit
+// breaker is open are skipped while any other model is available.
 type FallbackClient struct {
 	models          []ModelEntry
 	maxTotalRetries int
+	health          *health
 	logger          logger.Logger
 }
 
@@ -41,6 +43,7 @@ func NewFallbackClient(entries []ModelEntry, opts ...FallbackOption) *FallbackCl
 	fc := &FallbackClient{
 		models:          entries,
 		maxTotalRetries: cfg.maxTotalRetries,
+		health:          newHealth(cfg.failureThreshold, cfg.cooldown, cfg.statePath),
 		logger:          logger.New("fallback"),
 	}
 
@@ -69,11 +72,9 @@ func (fc *FallbackClient) Completion(ctx context.Context, req providers.Request)
 
 		var (
 			attempt    int
-			modelIndex int
+			modelIndex = fc.pick(0)
 			lastErr    error
-			promptTok  int64
-			complTok   int64
-			cachedTok  int64
+			usage      providers.Tokens
 		)
 
 		for {
@@ -89,6 +90,7 @@ func (fc *FallbackClient) Completion(ctx context.Context, req providers.Request)
 				tracing.IntVal("fallback.attempt", attempt),
 			)
 
+			startAt := time.Now()
 			modelResp := entry.Client.Completion(ctx, req)
 
 			// Pipe response, watching for errors.
@@ -104,19 +106,20 @@ func (fc *FallbackClient) Completion(ctx context.Context, req providers.Request)
 				case err, ok := <-modelResp.Error():
 					if !ok {
 						// Stream completed successfully (error channel closed with nil).
-						t := modelResp.Tokens()
-						promptTok += t.PromptTokens
-						complTok += t.CompletionTokens
-						cachedTok += t.CachedPromptTokens
+						addTokens(&usage, modelResp.Tokens())
 						break PipeLoop
 					}
 
-					if shouldFallbackOnError(err) && !emitted {
+					retriable := shouldFallbackOnError(err)
+					if retriable {
+						fc.health.failure(entry.Name, err)
+					}
+					if retriable && !emitted {
 						lastErr = err
 						fc.logger.Warnw("model error, falling back",
 							"model", entry.Name, "attempt", attempt, "error", err)
 						attempt++
-						modelIndex = (modelIndex + 1) % len(fc.models)
+						modelIndex = fc.pick(modelIndex + 1)
 						fallbackRequested = true
 						backoff := time.Second * time.Duration(attempt)
 						if waitErr := common.WaitBackoff(ctx, backoff); waitErr != nil {
@@ -133,10 +136,7 @@ func (fc *FallbackClient) Completion(ctx context.Context, req providers.Request)
 				case delta, ok := <-modelResp.Message():
 					if !ok {
 						// Stream finished normally.
-						t := modelResp.Tokens()
-						promptTok += t.PromptTokens
-						complTok += t.CompletionTokens
-						cachedTok += t.CachedPromptTokens
+						addTokens(&usage, modelResp.Tokens())
 						break PipeLoop
 					}
 					emitted = true
@@ -146,12 +146,9 @@ func (fc *FallbackClient) Completion(ctx context.Context, req providers.Request)
 
 			// If we got here with no error and emitted deltas, we're done.
 			if emitted && !fallbackRequested {
-				resp.Token = providers.Tokens{
-					PromptTokens:       promptTok,
-					CompletionTokens:   complTok,
-					CachedPromptTokens: cachedTok,
-					TotalTokens:        promptTok + complTok,
-				}
+				fc.health.success(entry.Name, time.Since(startAt))
+				usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+				resp.Token = usage
 				return
 			}
 
@@ -160,15 +157,29 @@ func (fc *FallbackClient) Completion(ctx context.Context, req providers.Request)
 			}
 
 			// If lastErr is nil and no deltas, model returned empty — try next.
+			fc.health.failure(entry.Name, errEmptyResponse)
 			lastErr = nil
 			attempt++
-			modelIndex = (modelIndex + 1) % len(fc.models)
+			modelIndex = fc.pick(modelIndex + 1)
 		}
 	}()
 
 	return resp
 }
 
+// addTokens adds the usage of one attempt to the total; the model is the
+// one that answered last.
+func addTokens(total *providers.Tokens, t providers.Tokens) {
+	total.PromptTokens += t.PromptTokens
+	total.CompletionTokens += t.CompletionTokens
+	total.CachedPromptTokens += t.CachedPromptTokens
+	total.ReasoningTokens += t.ReasoningTokens
+	total.Cost += t.Cost
+	if t.Model != "" {
+		total.Model = t.Model
+	}
+}
+
 // CompletionNonStreaming tries each model with circular retry for non-streaming completion.
 func (fc *FallbackClient) CompletionNonStreaming(ctx context.Context, req providers.Request) (string, error) {
 	if len(fc.models) == 0 {
@@ -177,7 +188,7 @@ func (fc *FallbackClient) CompletionNonStreaming(ctx context.Context, req provid
 
 	var (
 		attempt    int
-		modelIndex int
+		modelIndex = fc.pick(0)
 		lastErr    error
 	)
 
@@ -188,14 +199,16 @@ func (fc *FallbackClient) CompletionNonStreaming(ctx context.Context, req provid
 
 		entry := fc.models[modelIndex]
 
+		startAt := time.Now()
 		result, err := entry.Client.CompletionNonStreaming(ctx, req)
 		if err != nil {
 			if shouldFallbackOnError(err) {
+				fc.health.failure(entry.Name, err)
 				lastErr = err
 				fc.logger.Warnw("non-streaming model error, falling back",
 					"model", entry.Name, "attempt", attempt, "error", err)
 				attempt++
-				modelIndex = (modelIndex + 1) % len(fc.models)
+				modelIndex = fc.pick(modelIndex + 1)
 				backoff := time.Second * time.Duration(attempt)
 				if waitErr := common.WaitBackoff(ctx, backoff); waitErr != nil {
 					return "", waitErr
@@ -205,6 +218,7 @@ func (fc *FallbackClient) CompletionNonStreaming(ctx context.Context, req provid
 			return "", err
 		}
 
+		fc.health.success(entry.Name, time.Since(startAt))
 		return result, nil
 	}
 }
@@ -217,7 +231,7 @@ func (fc *FallbackClient) StructuredPredict(ctx context.Context, req providers.R
 
 	var (
 		attempt    int
-		modelIndex int
+		modelIndex = fc.pick(0)
 		lastErr    error
 	)
 
@@ -228,14 +242,16 @@ func (fc *FallbackClient) StructuredPredict(ctx context.Context, req providers.R
 
 		entry := fc.models[modelIndex]
 
+		startAt := time.Now()
 		err := entry.Client.StructuredPredict(ctx, req, model)
 		if err != nil {
 			if shouldFallbackOnError(err) {
+				fc.health.failure(entry.Name, err)
 				lastErr = err
 				fc.logger.Warnw("structured predict model error, falling back",
 					"model", entry.Name, "attempt", attempt, "error", err)
 				attempt++
-				modelIndex = (modelIndex + 1) % len(fc.models)
+				modelIndex = fc.pick(modelIndex + 1)
 				backoff := time.Second * time.Duration(attempt)
 				if waitErr := common.WaitBackoff(ctx, backoff); waitErr != nil {
 					return waitErr
@@ -245,10 +261,36 @@ func (fc *FallbackClient) StructuredPredict(ctx context.Context, req providers.R
 			return err
 		}
 
+		fc.health.success(entry.Name, time.Since(startAt))
 		return nil
 	}
 }
 
+// pick returns the index of the first model from start on, wrapping around,
+// whose breaker lets a call through. When every breaker is open the model at
+// start is tried anyway rather than failing without a call.
+func (fc *FallbackClient) pick(start int) int {
+	n := len(fc.models)
+	start %= n
+	for i := range n {
+		idx := (start + i) % n
+		if fc.health.allow(fc.models[idx].Name) {
+			return idx
+		}
+	}
+	fc.logger.Warnw("all model breakers open, trying anyway", "model", fc.models[start].Name)
+	return start
+}
+
+// Stats returns the health of every model, in fallback order.
+func (fc *FallbackClient) Stats() []EntryStats {
+	names := make([]string, len(fc.models))
+	for i, e := range fc.models {
+		names[i] = e.Name
+	}
+	return fc.health.stats(names)
+}
+
 // ContextWindow returns the minimum context window across all models.
 func (fc *FallbackClient) ContextWindow() int64 {
 	var min int64 = -1
@@ -266,6 +308,21 @@ func (fc *FallbackClient) ContextWindow() int64 {
 	return min
 }
 
+// CacheStrategy returns the cache strategy of the model the next request
+// goes to: the first one whose breaker lets calls through, or the primary
+// model when every breaker is open.
+func (fc *FallbackClient) CacheStrategy() providers.CacheStrategy {
+	if len(fc.models) == 0 {
+		return providers.CacheNone
+	}
+	for _, entry := range fc.models {
+		if !fc.health.skipped(entry.Name) {
+			return providers.CacheStrategyOf(entry.Client)
+		}
+	}
+	return providers.CacheStrategyOf(fc.models[0].Client)
+}
+
 func fallbackExhaustedError(attempt int, lastErr error) error {
 	if lastErr == nil {
 		return fmt.Errorf("fallback exhausted after %d attempts", attempt)
@@ -273,8 +330,10 @@ func fallbackExhaustedError(attempt int, lastErr error) error {
 	return fmt.Errorf("fallback exhausted after %d attempts, last error: %w", attempt, lastErr)
 }
 
-// Ensure FallbackClient implements providers.Client and providers.ContextWindowProvider.
+// Ensure FallbackClient implements providers.Client, providers.ContextWindowProvider
+// and providers.PromptCacher.
 var (
 	_ providers.Client                = (*FallbackClient)(nil)
 	_ providers.ContextWindowProvider = (*FallbackClient)(nil)
+	_ providers.PromptCacher          = (*FallbackClient)(nil)
 )
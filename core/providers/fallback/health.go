@@ -0,0 +1,374 @@
+package fallback
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/basenana/friday/core/logger"
+)
+
+// BreakerState is the circuit breaker state of a model entry.
+type BreakerState string
+
+const (
+	// StateClosed lets calls through.
+	StateClosed BreakerState = "closed"
+	// StateOpen skips the entry until its cooldown has passed.
+	StateOpen BreakerState = "open"
+	// StateHalfOpen lets one probe call through to decide whether to close again.
+	StateHalfOpen BreakerState = "half-open"
+)
+
+// EntryStats is the health of one model entry.
+type EntryStats struct {
+	Name                string        `json:"name"`
+	State               BreakerState  `json:"state"`
+	Calls               int64         `json:"calls"`
+	Failures            int64         `json:"failures"`
+	ConsecutiveFailures int           `json:"consecutive_failures"`
+	TotalLatency        time.Duration `json:"total_latency"`
+	LastLatency         time.Duration `json:"last_latency"`
+	LastError           string        `json:"last_error,omitempty"`
+	LastFailure         time.Time     `json:"last_failure,omitzero"`
+	OpenedAt            time.Time     `json:"opened_at,omitzero"`
+	ProbeAt             time.Time     `json:"probe_at,omitzero"`
+}
+
+// AvgLatency is the mean duration of the successful calls.
+func (s EntryStats) AvgLatency() time.Duration {
+	if ok := s.Calls - s.Failures; ok > 0 {
+		return s.TotalLatency / time.Duration(ok)
+	}
+	return 0
+}
+
+// ErrorRate is the share of calls that failed.
+func (s EntryStats) ErrorRate() float64 {
+	if s.Calls == 0 {
+		return 0
+	}
+	return float64(s.Failures) / float64(s.Calls)
+}
+
+// statsSaveInterval is how often call counts and latencies alone are saved;
+// breaker changes are saved at once.
+const statsSaveInterval = 10 * time.Second
+
+// health tracks the EntryStats of every entry and decides which may be called.
+// An entry's breaker opens after threshold consecutive failures; once cooldown
+// has passed, one probe call is let through, closing the breaker on success
+// and reopening it on failure. When path is set the stats are loaded from and
+// saved to it, so short-lived processes share what earlier ones learned.
+// Saves merge this process's changes into the file under a lock, so
+// concurrent processes never drop each other's updates.
+type health struct {
+	threshold int
+	cooldown  time.Duration
+	path      string
+	now       func() time.Time
+	logger    logger.Logger
+
+	mu       sync.Mutex
+	entries  map[string]*EntryStats
+	pending  map[string]*pendingStats
+	lastSave time.Time
+	flushing *time.Timer
+}
+
+// pendingStats are the changes to an entry not saved yet.
+type pendingStats struct {
+	calls    int64
+	failures int64
+	latency  time.Duration
+	// breaker is set when the breaker fields changed; they then replace
+	// the saved ones.
+	breaker bool
+}
+
+func newHealth(threshold int, cooldown time.Duration, path string) *health {
+	h := &health{
+		threshold: threshold,
+		cooldown:  cooldown,
+		path:      path,
+		now:       time.Now,
+		logger:    logger.New("fallback"),
+		entries:   make(map[string]*EntryStats),
+		pending:   make(map[string]*pendingStats),
+	}
+	if path != "" {
+		stats, err := readStats(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			h.logger.Warnw("load model health failed", "path", path, "error", err)
+		}
+		for name, s := range stats {
+			h.entries[name] = s
+		}
+	}
+	return h
+}
+
+func (h *health) entry(name string) *EntryStats {
+	s, ok := h.entries[name]
+	if !ok {
+		s = &EntryStats{Name: name, State: StateClosed}
+		h.entries[name] = s
+	}
+	return s
+}
+
+func (h *health) pendingFor(name string) *pendingStats {
+	p, ok := h.pending[name]
+	if !ok {
+		p = &pendingStats{}
+		h.pending[name] = p
+	}
+	return p
+}
+
+// allow reports whether name may be called now, starting a probe when its
+// breaker has cooled down.
+func (h *health) allow(name string) bool {
+	if h.threshold <= 0 {
+		return true
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s := h.entry(name)
+	if s.State == StateClosed {
+		return true
+	}
+	now := h.now()
+	if now.Sub(s.OpenedAt) < h.cooldown {
+		return false
+	}
+	// One probe at a time; a probe that never reported back (e.g. its
+	// process exited) expires after another cooldown.
+	if s.State == StateHalfOpen && now.Sub(s.ProbeAt) < h.cooldown {
+		return false
+	}
+	s.State = StateHalfOpen
+	s.ProbeAt = now
+	h.logger.Infow("probing model", "model", name)
+	h.pendingFor(name).breaker = true
+	h.saveLocked(true)
+	return true
+}
+
+// skipped reports whether allow would refuse name now, without starting a
+// probe.
+func (h *health) skipped(name string) bool {
+	if h.threshold <= 0 {
+		return false
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s, ok := h.entries[name]
+	if !ok || s.State == StateClosed {
+		return false
+	}
+	now := h.now()
+	if now.Sub(s.OpenedAt) < h.cooldown {
+		return true
+	}
+	return s.State == StateHalfOpen && now.Sub(s.ProbeAt) < h.cooldown
+}
+
+func (h *health) success(name string, latency time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s := h.entry(name)
+	p := h.pendingFor(name)
+	changed := s.State != StateClosed || s.ConsecutiveFailures != 0
+	if s.State != StateClosed {
+		h.logger.Infow("model recovered, closing breaker", "model", name)
+	}
+	s.Calls++
+	s.TotalLatency += latency
+	s.LastLatency = latency
+	s.ConsecutiveFailures = 0
+	s.State = StateClosed
+	s.OpenedAt = time.Time{}
+	s.ProbeAt = time.Time{}
+	p.calls++
+	p.latency += latency
+	p.breaker = p.breaker || changed
+	h.saveLocked(changed)
+}
+
+func (h *health) failure(name string, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := h.now()
+	s := h.entry(name)
+	s.Calls++
+	s.Failures++
+	s.ConsecutiveFailures++
+	s.LastFailure = now
+	if err != nil {
+		s.LastError = err.Error()
+	}
+	if h.threshold > 0 && (s.State == StateHalfOpen || s.ConsecutiveFailures >= h.threshold) {
+		if s.State != StateOpen {
+			h.logger.Warnw("opening breaker", "model", name, "failures", s.ConsecutiveFailures, "cooldown", h.cooldown)
+		}
+		s.State = StateOpen
+		s.OpenedAt = now
+		s.ProbeAt = time.Time{}
+	}
+	p := h.pendingFor(name)
+	p.calls++
+	p.failures++
+	p.breaker = true
+	h.saveLocked(true)
+}
+
+// stats returns the stats of names, reporting open breakers whose cooldown
+// has passed as half-open.
+func (h *health) stats(names []string) []EntryStats {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	result := make([]EntryStats, 0, len(names))
+	for _, name := range names {
+		s := *h.entry(name)
+		if s.State == StateOpen && h.now().Sub(s.OpenedAt) >= h.cooldown {
+			s.State = StateHalfOpen
+		}
+		result = append(result, s)
+	}
+	return result
+}
+
+// saveLocked merges the pending changes into the file at path. Unless force
+// is set, only call counts and latencies changed, and they were saved less
+// than statsSaveInterval ago, the save is put off until the interval ends.
+func (h *health) saveLocked(force bool) {
+	if h.path == "" || len(h.pending) == 0 {
+		return
+	}
+	now := h.now()
+	if wait := statsSaveInterval - now.Sub(h.lastSave); !force && wait > 0 {
+		if h.flushing == nil {
+			h.flushing = time.AfterFunc(wait, h.flush)
+		}
+		return
+	}
+	if h.flushing != nil {
+		h.flushing.Stop()
+		h.flushing = nil
+	}
+	if err := h.mergeLocked(); err != nil {
+		h.logger.Warnw("save model health failed", "path", h.path, "error", err)
+		return
+	}
+	h.lastSave = now
+}
+
+// flush saves the changes put off by saveLocked.
+func (h *health) flush() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.saveLocked(true)
+}
+
+// mergeLocked reads the file under an exclusive lock, adds the pending call
+// counts to it, replaces the breaker fields this process changed and writes
+// it back. The merged stats also become the in-memory ones, so this process
+// sees what others saved meanwhile.
+func (h *health) mergeLocked() error {
+	if err := os.MkdirAll(filepath.Dir(h.path), 0755); err != nil {
+		return err
+	}
+	lock, err := os.OpenFile(h.path+".lock", os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer lock.Close()
+	if err := lockFile(lock); err != nil {
+		return err
+	}
+	defer unlockFile(lock)
+
+	saved, err := readStats(h.path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			// A corrupt file is replaced by this process's view.
+			h.logger.Warnw("load model health failed", "path", h.path, "error", err)
+		}
+		saved = make(map[string]*EntryStats)
+	}
+	for name, p := range h.pending {
+		mine := h.entry(name)
+		s, ok := saved[name]
+		if !ok {
+			s = &EntryStats{Name: name, State: StateClosed}
+			saved[name] = s
+		}
+		s.Calls += p.calls
+		s.Failures += p.failures
+		s.TotalLatency += p.latency
+		if p.calls > p.failures {
+			s.LastLatency = mine.LastLatency
+		}
+		if p.breaker {
+			s.State = mine.State
+			s.ConsecutiveFailures = mine.ConsecutiveFailures
+			s.LastError = mine.LastError
+			s.LastFailure = mine.LastFailure
+			s.OpenedAt = mine.OpenedAt
+			s.ProbeAt = mine.ProbeAt
+		}
+	}
+
+	data, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := h.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, h.path); err != nil {
+		return err
+	}
+	h.entries = saved
+	h.pending = make(map[string]*pendingStats)
+	return nil
+}
+
+func readStats(path string) (map[string]*EntryStats, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	stats := make(map[string]*EntryStats)
+	if err := json.Unmarshal(data, &stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// ReadStats returns the model health saved at path by a FallbackClient
+// created WithStatePath, sorted by name.
+func ReadStats(path string) ([]EntryStats, error) {
+	stats, err := readStats(path)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]EntryStats, 0, len(stats))
+	for name, s := range stats {
+		s.Name = name
+		result = append(result, *s)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
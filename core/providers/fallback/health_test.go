@@ -0,0 +1,170 @@
+package fallback
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/basenana/friday/core/providers"
+)
+
+func TestHealth_BreakerOpensAndProbes(t *testing.T) {
+	now := time.Unix(1000, 0)
+	h := newHealth(2, time.Minute, "")
+	h.now = func() time.Time { return now }
+
+	h.failure("m", errors.New("boom"))
+	if !h.allow("m") {
+		t.Fatal("breaker should stay closed below the threshold")
+	}
+	h.failure("m", errors.New("boom"))
+	if h.allow("m") {
+		t.Fatal("breaker should open at the threshold")
+	}
+
+	now = now.Add(time.Minute)
+	if !h.allow("m") {
+		t.Fatal("breaker should let a probe through after the cooldown")
+	}
+	if h.allow("m") {
+		t.Fatal("only one probe should be let through")
+	}
+	h.failure("m", errors.New("still down"))
+	if h.allow("m") {
+		t.Fatal("a failed probe should reopen the breaker")
+	}
+
+	now = now.Add(time.Minute)
+	if !h.allow("m") {
+		t.Fatal("breaker should probe again after another cooldown")
+	}
+	h.success("m", 2*time.Second)
+	stats := h.stats([]string{"m"})[0]
+	if stats.State != StateClosed || stats.Calls != 4 || stats.Failures != 3 || stats.ConsecutiveFailures != 0 {
+		t.Fatalf("unexpected stats after recovery: %+v", stats)
+	}
+	if stats.AvgLatency() != 2*time.Second || stats.ErrorRate() != 0.75 || stats.LastError != "still down" {
+		t.Fatalf("unexpected metrics: avg=%v rate=%v last=%q", stats.AvgLatency(), stats.ErrorRate(), stats.LastError)
+	}
+}
+
+func TestFallback_SkipsOpenBreakerAcrossClients(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "health.json")
+	broken := &fakeClient{name: "broken", nonStreamErrs: []error{errors.New("down")}}
+	ok := &fakeClient{name: "ok"}
+	entries := []ModelEntry{{broken, "broken"}, {ok, "ok"}}
+
+	fc := NewFallbackClient(entries, WithCircuitBreaker(1, time.Hour), WithStatePath(path))
+	if _, err := fc.CompletionNonStreaming(context.Background(), providers.NewRequest("sys")); err != nil {
+		t.Fatalf("expected fallback success, got %v", err)
+	}
+	if got := fc.Stats()[0]; got.State != StateOpen || got.Failures != 1 {
+		t.Fatalf("expected open breaker for broken model, got %+v", got)
+	}
+
+	// The success of "ok" only changed its stats, so its save is put off.
+	fc.health.flush()
+
+	// A new client, as in the next short-lived process, skips the broken model.
+	fc = NewFallbackClient(entries, WithCircuitBreaker(1, time.Hour), WithStatePath(path))
+	if _, err := fc.CompletionNonStreaming(context.Background(), providers.NewRequest("sys")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if broken.callCount() != 1 {
+		t.Fatalf("expected broken model to be skipped, got %d calls", broken.callCount())
+	}
+
+	stats, err := ReadStats(path)
+	if err != nil {
+		t.Fatalf("ReadStats() error = %v", err)
+	}
+	if len(stats) != 2 || stats[0].Name != "broken" || stats[1].Name != "ok" || stats[1].Calls != 2 {
+		t.Fatalf("unexpected saved stats: %+v", stats)
+	}
+}
+
+func TestHealth_MergesConcurrentSaves(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "health.json")
+	a := newHealth(2, time.Hour, path)
+	b := newHealth(2, time.Hour, path)
+
+	a.failure("m", errors.New("a"))
+	b.failure("m", errors.New("b"))
+	b.failure("other", errors.New("b"))
+	a.failure("m", errors.New("a"))
+
+	stats, err := ReadStats(path)
+	if err != nil {
+		t.Fatalf("ReadStats() error = %v", err)
+	}
+	if len(stats) != 2 || stats[0].Name != "m" || stats[0].Calls != 3 || stats[0].Failures != 3 || stats[1].Name != "other" {
+		t.Fatalf("expected the saves of both processes, got %+v", stats)
+	}
+}
+
+func TestHealth_PutsOffStatsOnlySaves(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "health.json")
+	now := time.Unix(1000, 0)
+	h := newHealth(2, time.Hour, path)
+	h.now = func() time.Time { return now }
+
+	h.success("m", time.Second)
+	h.success("m", time.Second)
+	if stats, _ := ReadStats(path); len(stats) != 1 || stats[0].Calls != 1 {
+		t.Fatalf("expected only the first success saved, got %+v", stats)
+	}
+	h.failure("m", errors.New("boom"))
+	if stats, _ := ReadStats(path); len(stats) != 1 || stats[0].Calls != 3 || stats[0].Failures != 1 {
+		t.Fatalf("expected a breaker change to save the pending stats, got %+v", stats)
+	}
+
+	now = now.Add(statsSaveInterval)
+	h.success("m", time.Second)
+	if stats, _ := ReadStats(path); stats[0].Calls != 4 || stats[0].ConsecutiveFailures != 0 {
+		t.Fatalf("expected the recovery saved, got %+v", stats)
+	}
+}
+
+func TestFallback_TriesOpenModelWhenAllBreakersOpen(t *testing.T) {
+	only := &fakeClient{name: "only", streamContent: []string{"back"}}
+	fc := NewFallbackClient([]ModelEntry{{only, "only"}}, WithCircuitBreaker(1, time.Hour))
+	fc.health.failure("only", errors.New("down"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	content, err := collect(t, ctx, fc.Completion(ctx, providers.NewRequest("sys")))
+	if err != nil || content != "back" {
+		t.Fatalf("expected the only model to be tried, got %q, %v", content, err)
+	}
+	if got := fc.Stats()[0].State; got != StateClosed {
+		t.Fatalf("expected breaker closed after success, got %s", got)
+	}
+}
+
+// cachingClient is a fakeClient reporting a cache strategy.
+type cachingClient struct {
+	*fakeClient
+	strategy providers.CacheStrategy
+}
+
+func (c *cachingClient) CacheStrategy() providers.CacheStrategy { return c.strategy }
+
+func TestFallback_CacheStrategyFollowsBreaker(t *testing.T) {
+	primary := &cachingClient{&fakeClient{name: "primary"}, providers.CacheBreakpoints}
+	backup := &cachingClient{&fakeClient{name: "backup"}, providers.CachePrefix}
+	fc := NewFallbackClient([]ModelEntry{{primary, "primary"}, {backup, "backup"}}, WithCircuitBreaker(1, time.Hour))
+
+	if got := fc.CacheStrategy(); got != providers.CacheBreakpoints {
+		t.Fatalf("CacheStrategy() = %s, want the primary's", got)
+	}
+	fc.health.failure("primary", errors.New("down"))
+	if got := fc.CacheStrategy(); got != providers.CachePrefix {
+		t.Fatalf("CacheStrategy() = %s, want the backup's while the primary's breaker is open", got)
+	}
+	fc.health.failure("backup", errors.New("down"))
+	if got := fc.CacheStrategy(); got != providers.CacheBreakpoints {
+		t.Fatalf("CacheStrategy() = %s, want the primary's when every breaker is open", got)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package fallback
+
+import "os"
+
+// Without flock saves are only serialized within this process.
+func lockFile(f *os.File) error { return nil }
+
+func unlockFile(f *os.File) {}
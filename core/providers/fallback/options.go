@@ -1,13 +1,20 @@
 package fallback
 
+import "time"
+
 // fallbackConfig holds the resolved options for a FallbackClient.
 type fallbackConfig struct {
-	maxTotalRetries int
+	maxTotalRetries  int
+	failureThreshold int
+	cooldown         time.Duration
+	statePath        string
 }
 
 func defaultConfig() fallbackConfig {
 	return fallbackConfig{
-		maxTotalRetries: 0, // 0 means auto-calculate as len(models) * 3
+		maxTotalRetries:  0, // 0 means auto-calculate as len(models) * 3
+		failureThreshold: 3,
+		cooldown:         time.Minute,
 	}
 }
 
@@ -20,3 +27,22 @@ func WithMaxTotalRetries(n int) FallbackOption {
 		cfg.maxTotalRetries = n
 	}
 }
+
+// WithCircuitBreaker skips a model for cooldown after threshold consecutive
+// failures, then lets one probe call decide whether it is back. A threshold
+// of zero or less disables the breaker.
+func WithCircuitBreaker(threshold int, cooldown time.Duration) FallbackOption {
+	return func(cfg *fallbackConfig) {
+		cfg.failureThreshold = threshold
+		cfg.cooldown = cooldown
+	}
+}
+
+// WithStatePath keeps the breaker state and model stats in a file, so
+// processes share what earlier ones learned about failing models.
+// Concurrent processes merge their updates into it under a file lock.
+func WithStatePath(path string) FallbackOption {
+	return func(cfg *fallbackConfig) {
+		cfg.statePath = path
+	}
+}
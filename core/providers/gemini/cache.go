@@ -0,0 +1,187 @@
+package gemini
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/basenana/friday/core/providers"
+)
+
+const (
+	// cacheTTL is how long a cached prefix is kept by Gemini.
+	cacheTTL = 10 * time.Minute
+	// cacheMinTokens is the smallest prefix worth caching; Gemini refuses
+	// caches under 1024 to 4096 tokens, depending on the model.
+	cacheMinTokens = 4096
+	// cacheRefreshTokens is how much history may follow a cached prefix
+	// before the longer prefix is cached instead.
+	cacheRefreshTokens = 16 * 1024
+)
+
+// CacheStrategy is CacheContext: requests with a PromptCacheKey store their
+// prefix as cachedContents and later requests of the key only send the
+// contents following it.
+func (c *client) CacheStrategy() providers.CacheStrategy {
+	return providers.CacheContext
+}
+
+// cachedPrefix is a prompt prefix stored as cachedContents.
+type cachedPrefix struct {
+	name      string
+	hash      string
+	contents  int
+	expiresAt time.Time
+}
+
+// contextCaches holds the cached prefix of each prompt cache key.
+type contextCaches struct {
+	mu      sync.Mutex
+	entries map[string]*cachedPrefix
+}
+
+func newContextCaches() *contextCaches {
+	return &contextCaches{entries: make(map[string]*cachedPrefix)}
+}
+
+func (cc *contextCaches) get(key string) *cachedPrefix {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	return cc.entries[key]
+}
+
+// put stores entry for key and returns the one it replaced.
+func (cc *contextCaches) put(key string, entry *cachedPrefix) *cachedPrefix {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	old := cc.entries[key]
+	cc.entries[key] = entry
+	return old
+}
+
+func (cc *contextCaches) forget(key string) {
+	if cc == nil {
+		return
+	}
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	delete(cc.entries, key)
+}
+
+// withCachedPrefix returns body continuing the cached prefix of key: an
+// unchanged cached prefix is reused, and a long enough prefix is cached when
+// there is none or too much history follows it. Everything but the last
+// content is cached, since the last one grows when tool results merge into it.
+func (c *client) withCachedPrefix(ctx context.Context, key string, body *generateContentRequest) *generateContentRequest {
+	if key == "" || c.caches == nil || len(body.Contents) < 2 {
+		return body
+	}
+	n := len(body.Contents) - 1
+	now := time.Now()
+
+	entry := c.caches.get(key)
+	if entry != nil && !entry.matches(body, n, now) {
+		entry = nil
+	}
+	if entry == nil || estimateTokens(body.Contents[entry.contents:n]) >= cacheRefreshTokens {
+		if estimateTokens(prefixOf(body, n)) >= cacheMinTokens {
+			created, err := c.createCache(ctx, body, n)
+			if err != nil {
+				c.logger.Warnw("cache prompt prefix failed", "err", err)
+			} else {
+				if old := c.caches.put(key, created); old != nil {
+					go c.deleteCache(old.name)
+				}
+				entry = created
+			}
+		}
+	}
+	if entry == nil {
+		return body
+	}
+	return &generateContentRequest{
+		CachedContent:    entry.name,
+		Contents:         body.Contents[entry.contents:],
+		GenerationConfig: body.GenerationConfig,
+	}
+}
+
+// matches reports whether the entry is still alive and caches the first
+// contents of body along with its system instruction and tools.
+func (e *cachedPrefix) matches(body *generateContentRequest, n int, now time.Time) bool {
+	if e.contents > n || now.Add(time.Minute).After(e.expiresAt) {
+		return false
+	}
+	return hashPrefix(prefixOf(body, e.contents)) == e.hash
+}
+
+func (c *client) createCache(ctx context.Context, body *generateContentRequest, n int) (*cachedPrefix, error) {
+	prefix := prefixOf(body, n)
+	req := &cachedContent{
+		Model:             "models/" + c.model.Name,
+		SystemInstruction: prefix.SystemInstruction,
+		Contents:          prefix.Contents,
+		Tools:             prefix.Tools,
+		TTL:               fmt.Sprintf("%ds", int(cacheTTL.Seconds())),
+	}
+	httpResp, err := c.send(ctx, http.MethodPost, strings.TrimSuffix(c.host, "/")+"/cachedContents", req)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+
+	created := &cachedContent{}
+	if err := json.NewDecoder(httpResp.Body).Decode(created); err != nil {
+		return nil, fmt.Errorf("decode cached content: %w", err)
+	}
+	if created.Name == "" {
+		return nil, fmt.Errorf("cached content has no name")
+	}
+	expiresAt := created.ExpireTime
+	if expiresAt.IsZero() {
+		expiresAt = time.Now().Add(cacheTTL)
+	}
+	c.logger.Infow("cached prompt prefix", "name", created.Name, "contents", n, "expires_at", expiresAt)
+	return &cachedPrefix{name: created.Name, hash: hashPrefix(prefix), contents: n, expiresAt: expiresAt}, nil
+}
+
+// deleteCache drops a replaced prefix rather than paying for its storage
+// until it expires.
+func (c *client) deleteCache(name string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	httpResp, err := c.send(ctx, http.MethodDelete, strings.TrimSuffix(c.host, "/")+"/"+name, nil)
+	if err != nil {
+		c.logger.Warnw("delete cached prompt prefix failed", "name", name, "err", err)
+		return
+	}
+	httpResp.Body.Close()
+}
+
+// prefixOf returns the part of body a cache of its first n contents holds.
+func prefixOf(body *generateContentRequest, n int) *cachedContent {
+	return &cachedContent{
+		SystemInstruction: body.SystemInstruction,
+		Contents:          body.Contents[:n],
+		Tools:             body.Tools,
+	}
+}
+
+func hashPrefix(prefix *cachedContent) string {
+	data, _ := json.Marshal(prefix)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// estimateTokens guesses the tokens of v from its JSON, at about four bytes
+// a token.
+func estimateTokens(v any) int64 {
+	data, _ := json.Marshal(v)
+	return int64(len(data) / 4)
+}
@@ -0,0 +1,652 @@
+package gemini
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/basenana/friday/core/logger"
+	"github.com/basenana/friday/core/providers"
+	"github.com/basenana/friday/core/providers/common"
+	"github.com/basenana/friday/core/providers/ratelimit"
+	"github.com/basenana/friday/core/session"
+	"github.com/basenana/friday/core/tracing"
+	"github.com/basenana/friday/core/types"
+	"github.com/invopop/jsonschema"
+)
+
+const (
+	roleUser  = "user"
+	roleModel = "model"
+)
+
+type Model struct {
+	Name               string
+	Temperature        *float64
+	MaxTokens          *int64
+	QPM                int64
+	Proxy              string
+	ContextWindow      int64
+	InsecureSkipVerify bool
+	// Limiter paces the calls instead of a limiter of QPM private to this
+	// client, e.g. one shared with other processes.
+	Limiter ratelimit.Limiter
+}
+
+type client struct {
+	host       string
+	apiKey     string
+	http       *http.Client
+	model      Model
+	apiLimiter ratelimit.Limiter
+	caches     *contextCaches
+	logger     logger.Logger
+}
+
+func (c *client) ContextWindow() int64 {
+	return c.model.ContextWindow
+}
+
+func (c *client) Completion(ctx context.Context, request providers.Request) providers.Response {
+	c.logger.Infow("llm processing...")
+	ctx, span := tracing.Start(ctx, "llm.gemini.completion",
+		tracing.WithAttributes(tracing.String("model", c.model.Name)),
+	)
+	resp := newResponse(request)
+	go func() {
+		defer span.End()
+		defer resp.close()
+		var (
+			body      = c.generateContentRequest(request)
+			startAt   = time.Now()
+			skipCache bool
+			err       error
+		)
+
+		defer func() {
+			span.SetAttributes(
+				tracing.Int("prompt_tokens", resp.Token.PromptTokens),
+				tracing.Int("completion_tokens", resp.Token.CompletionTokens),
+			)
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(tracing.StatusError, err.Error())
+			} else {
+				span.SetStatus(tracing.StatusOK, "")
+			}
+			sec := time.Since(startAt).Seconds()
+			if sec < 1 {
+				sec = 1
+			}
+			tps := float64(resp.Token.CompletionTokens) / sec
+			c.logger.Infow("completion-with-streaming finish", "elapsed", time.Since(startAt).String(), "tps", fmt.Sprintf("%.2f", tps))
+		}()
+
+	Retry:
+		if err = c.apiLimiter.Wait(ctx); err != nil {
+			c.logger.Errorw("new completion stream error", "err", err)
+			resp.fail(err)
+			return
+		}
+		if time.Since(startAt).Seconds() > 1 {
+			c.logger.Infow("client-side llm api throttled", "wait", time.Since(startAt).String())
+		}
+
+		sent := body
+		if !skipCache {
+			sent = c.withCachedPrefix(ctx, request.PromptCacheKey(), body)
+		}
+		err = c.stream(ctx, sent, resp.handleChunk)
+		if err != nil {
+			if isRateLimitError(err) && !resp.started {
+				c.apiLimiter.Pause(10 * time.Second)
+				c.logger.Warn("rate limited, trying again")
+				goto Retry
+			}
+			if sent.CachedContent != "" && !resp.started {
+				c.logger.Warnw("cached prompt prefix failed, sending the full prompt", "err", err)
+				c.caches.forget(request.PromptCacheKey())
+				skipCache = true
+				goto Retry
+			}
+			c.logger.Errorw("completion stream error", "err", err)
+			resp.fail(err)
+			return
+		}
+
+		// Fallback: if API didn't return token counts, estimate using FuzzyTokens
+		resp.applyTokenFallback(request.Messages())
+	}()
+	return resp
+}
+
+func (c *client) CompletionNonStreaming(ctx context.Context, request providers.Request) (_ string, retErr error) {
+	ctx, span := tracing.Start(ctx, "llm.gemini.completion_sync",
+		tracing.WithAttributes(tracing.String("model", c.model.Name)),
+	)
+	defer span.End()
+	defer func() { tracing.DeferStatus(span, &retErr) }()
+
+	c.logger.Infow("llm processing...")
+	var (
+		body      = c.generateContentRequest(request)
+		startAt   = time.Now()
+		skipCache bool
+		err       error
+	)
+
+	defer func() {
+		c.logger.Infow("completion-non-streaming finish", "elapsed", time.Since(startAt).String())
+	}()
+
+Retry:
+	if err = c.apiLimiter.Wait(ctx); err != nil {
+		c.logger.Errorw("new completion error", "err", err)
+		return "", err
+	}
+	if time.Since(startAt).Seconds() > 1 {
+		c.logger.Infow("client-side llm api throttled", "wait", time.Since(startAt).String())
+	}
+
+	sent := body
+	if !skipCache {
+		sent = c.withCachedPrefix(ctx, request.PromptCacheKey(), body)
+	}
+	result, err := c.generate(ctx, sent)
+	if err != nil {
+		if isRateLimitError(err) {
+			c.apiLimiter.Pause(10 * time.Second)
+			c.logger.Warn("rate limited, trying again")
+			goto Retry
+		}
+		if sent.CachedContent != "" {
+			c.logger.Warnw("cached prompt prefix failed, sending the full prompt", "err", err)
+			c.caches.forget(request.PromptCacheKey())
+			skipCache = true
+			goto Retry
+		}
+		c.logger.Errorw("completion error", "err", err)
+		return "", err
+	}
+	if err = blockedError(result); err != nil {
+		return "", err
+	}
+	if len(result.Candidates) == 0 {
+		return "", fmt.Errorf("no completion content returned")
+	}
+
+	var text strings.Builder
+	for _, p := range result.Candidates[0].Content.Parts {
+		if !p.Thought {
+			text.WriteString(p.Text)
+		}
+	}
+	if text.Len() == 0 {
+		return "", fmt.Errorf("no text content returned")
+	}
+	return text.String(), nil
+}
+
+func (c *client) StructuredPredict(ctx context.Context, request providers.Request, model any) (retErr error) {
+	ctx, span := tracing.Start(ctx, "llm.gemini.structured_predict",
+		tracing.WithAttributes(tracing.String("model", c.model.Name)),
+	)
+	defer span.End()
+	defer func() { tracing.DeferStatus(span, &retErr) }()
+
+	messages := request.Messages()
+	if len(messages) == 0 || messages[0].Content == "" {
+		return fmt.Errorf("user request is empty")
+	}
+	prompt := DEFAULT_STRUCTURED_PREDICT_PROMPT
+	prompt = strings.ReplaceAll(prompt, "{insert_user_request_here}", messages[0].Content)
+	schemaRaw, _ := json.Marshal(jsonschema.Reflect(model))
+	prompt = strings.ReplaceAll(prompt, "{insert_json_schema_here}", string(schemaRaw))
+
+	return common.StructuredPredictWithFallback(
+		ctx,
+		providers.NewPromptRequest(prompt),
+		model,
+		c.CompletionNonStreaming,
+		c.Completion,
+		c.logger,
+	)
+}
+
+// stream posts body to streamGenerateContent and passes each server-sent
+// chunk to handle.
+func (c *client) stream(ctx context.Context, body *generateContentRequest, handle func(*generateContentResponse) error) error {
+	httpResp, err := c.post(ctx, "streamGenerateContent?alt=sse", body)
+	if err != nil {
+		return err
+	}
+	defer httpResp.Body.Close()
+
+	scanner := bufio.NewScanner(httpResp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "" || data == "[DONE]" {
+			continue
+		}
+		chunk := &generateContentResponse{}
+		if err := json.Unmarshal([]byte(data), chunk); err != nil {
+			return fmt.Errorf("decode stream chunk: %w", err)
+		}
+		if err := handle(chunk); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func (c *client) generate(ctx context.Context, body *generateContentRequest) (*generateContentResponse, error) {
+	httpResp, err := c.post(ctx, "generateContent", body)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+
+	result := &generateContentResponse{}
+	if err := json.NewDecoder(httpResp.Body).Decode(result); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	return result, nil
+}
+
+// post sends body to the model's method and returns the response when its
+// status is 200.
+func (c *client) post(ctx context.Context, method string, body *generateContentRequest) (*http.Response, error) {
+	endpoint := fmt.Sprintf("%s/models/%s:%s", strings.TrimSuffix(c.host, "/"), url.PathEscape(c.model.Name), method)
+	return c.send(ctx, http.MethodPost, endpoint, body)
+}
+
+// send makes an API call with body as JSON, if any, and returns the
+// response when its status is 200.
+func (c *client) send(ctx context.Context, method, endpoint string, body any) (*http.Response, error) {
+	var payload io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		payload = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, payload)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-goog-api-key", c.apiKey)
+
+	httpResp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if httpResp.StatusCode != http.StatusOK {
+		defer httpResp.Body.Close()
+		data, _ := io.ReadAll(io.LimitReader(httpResp.Body, 64*1024))
+		var apiErr apiError
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Error.Message != "" {
+			return nil, fmt.Errorf("gemini api error %d %s: %s", httpResp.StatusCode, apiErr.Error.Status, apiErr.Error.Message)
+		}
+		return nil, fmt.Errorf("gemini api error %d: %s", httpResp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	return httpResp, nil
+}
+
+func (c *client) generateContentRequest(request providers.Request) *generateContentRequest {
+	body := &generateContentRequest{}
+
+	genCfg := &generationConfig{Temperature: c.model.Temperature}
+	if c.model.MaxTokens != nil {
+		genCfg.MaxOutputTokens = *c.model.MaxTokens
+	}
+	if supportsThinking(c.model.Name) {
+		genCfg.ThinkingConfig = &thinkingConfig{IncludeThoughts: true}
+	}
+	body.GenerationConfig = genCfg
+
+	messages := common.RepairToolHistory(request.Messages())
+
+	// Gemini pairs function responses with calls by name, so a result can
+	// only be sent as one when its call made it into the history.
+	answered := make(map[string]struct{})
+	for _, msg := range messages {
+		if msg.Role == types.RoleTool && msg.ToolResult != nil {
+			answered[msg.ToolResult.CallID] = struct{}{}
+		}
+	}
+	callNames := make(map[string]string)
+	invalidCallNames := make(map[string]string)
+
+	var systemParts []part
+	for _, msg := range messages {
+		switch msg.Role {
+		case types.RoleSystem:
+			if msg.Content != "" {
+				systemParts = append(systemParts, part{Text: msg.Content})
+			}
+
+		case types.RoleUser:
+			var parts []part
+			if msg.Content != "" {
+				parts = append(parts, part{Text: msg.Content})
+			}
+			if p, ok := imagePart(msg.Image); ok {
+				parts = append(parts, p)
+			}
+			body.Contents = appendContent(body.Contents, roleUser, parts...)
+
+		case types.RoleAssistant:
+			var parts []part
+			if msg.Content != "" {
+				parts = append(parts, part{Text: msg.Content})
+			}
+			for _, tc := range msg.ToolCalls {
+				_, ok := answered[tc.ID]
+				if fc, valid := buildHistoricalFunctionCall(tc); valid && ok {
+					parts = append(parts, part{FunctionCall: fc})
+					callNames[tc.ID] = tc.Name
+					continue
+				}
+				if tc.ID != "" {
+					invalidCallNames[tc.ID] = tc.Name
+				}
+				parts = append(parts, part{Text: formatInvalidHistoricalToolCall(tc)})
+			}
+			attachThoughtSignature(parts, msg.ReasoningSignature)
+			body.Contents = appendContent(body.Contents, roleModel, parts...)
+
+		case types.RoleTool:
+			if msg.ToolResult == nil {
+				continue
+			}
+			name, ok := callNames[msg.ToolResult.CallID]
+			if !ok {
+				body.Contents = appendContent(body.Contents, roleUser,
+					part{Text: formatOrphanedHistoricalToolResult(msg.ToolResult, invalidCallNames[msg.ToolResult.CallID])})
+				continue
+			}
+			body.Contents = appendContent(body.Contents, roleUser, part{FunctionResponse: &functionResponse{
+				Name:     name,
+				Response: map[string]any{"output": msg.ToolResult.Content},
+			}})
+		}
+	}
+
+	if len(systemParts) > 0 {
+		body.SystemInstruction = &content{Parts: systemParts}
+	}
+
+	// Gemini requires at least one content. Prompt-only requests built via
+	// NewRequest(prompt) would otherwise be sent as a bare system instruction.
+	if len(body.Contents) == 0 && body.SystemInstruction != nil {
+		body.Contents = []content{{Role: roleUser, Parts: body.SystemInstruction.Parts}}
+		body.SystemInstruction = nil
+	}
+
+	var decls []functionDeclaration
+	for _, t := range request.ToolDefines() {
+		decls = append(decls, functionDeclaration{
+			Name:                 t.GetName(),
+			Description:          t.GetDescription(),
+			ParametersJSONSchema: t.GetParameters(),
+		})
+	}
+	if len(decls) > 0 {
+		body.Tools = []tool{{FunctionDeclarations: decls}}
+	}
+	return body
+}
+
+// appendContent adds parts to the conversation, merging them into the last
+// content when it has the same role, since Gemini expects turns to
+// alternate and all responses to a set of calls in one turn.
+func appendContent(contents []content, role string, parts ...part) []content {
+	if len(parts) == 0 {
+		return contents
+	}
+	if n := len(contents); n > 0 && contents[n-1].Role == role {
+		contents[n-1].Parts = append(contents[n-1].Parts, parts...)
+		return contents
+	}
+	return append(contents, content{Role: role, Parts: parts})
+}
+
+// attachThoughtSignature returns the model's thought signature with the
+// part it came on: the first function call, or else the last part.
+func attachThoughtSignature(parts []part, signature string) {
+	if signature == "" || len(parts) == 0 {
+		return
+	}
+	for i := range parts {
+		if parts[i].FunctionCall != nil {
+			parts[i].ThoughtSignature = signature
+			return
+		}
+	}
+	parts[len(parts)-1].ThoughtSignature = signature
+}
+
+func imagePart(image *types.ImageContent) (part, bool) {
+	if image == nil {
+		return part{}, false
+	}
+	switch image.Type {
+	case types.ImageTypeBase64:
+		return part{InlineData: &blob{MimeType: image.MediaType, Data: image.Data}}, true
+	case types.ImageTypeURL:
+		mimeType := image.MediaType
+		if mimeType == "" {
+			if u, err := url.Parse(image.URL); err == nil {
+				mimeType = mime.TypeByExtension(path.Ext(u.Path))
+			}
+		}
+		return part{FileData: &fileData{MimeType: mimeType, FileURI: image.URL}}, true
+	}
+	return part{}, false
+}
+
+func buildHistoricalFunctionCall(tc types.ToolCall) (*functionCall, bool) {
+	if tc.ID == "" || tc.Name == "" {
+		return nil, false
+	}
+	args := strings.TrimSpace(tc.Arguments)
+	if args == "" {
+		args = "{}"
+	}
+	var obj map[string]any
+	if json.Unmarshal([]byte(args), &obj) != nil {
+		return nil, false
+	}
+	return &functionCall{Name: tc.Name, Args: json.RawMessage(args)}, true
+}
+
+func formatInvalidHistoricalToolCall(tc types.ToolCall) string {
+	name := tc.Name
+	if name == "" {
+		name = "unknown_tool"
+	}
+	args := strings.TrimSpace(tc.Arguments)
+	if args == "" {
+		return fmt.Sprintf("[historical invalid tool call omitted: %s]", name)
+	}
+	return fmt.Sprintf("[historical invalid tool call omitted: %s(%s)]", name, args)
+}
+
+func formatOrphanedHistoricalToolResult(result *types.ToolResult, toolName string) string {
+	if result == nil {
+		return "[historical tool result omitted]"
+	}
+	if toolName == "" {
+		toolName = "unknown_tool"
+	}
+	content := strings.TrimSpace(result.Content)
+	if content == "" {
+		return fmt.Sprintf("[historical tool result omitted for invalid tool call: %s]", toolName)
+	}
+	return fmt.Sprintf("[historical tool result for invalid tool call %s] %s", toolName, content)
+}
+
+// supportsThinking reports whether the model can return thought summaries;
+// older generations reject the thinking config.
+func supportsThinking(model string) bool {
+	name := strings.TrimPrefix(strings.ToLower(model), "models/")
+	return !strings.HasPrefix(name, "gemini-1.") && !strings.HasPrefix(name, "gemini-2.0")
+}
+
+func blockedError(resp *generateContentResponse) error {
+	if resp.PromptFeedback != nil && resp.PromptFeedback.BlockReason != "" {
+		return fmt.Errorf("prompt blocked by gemini: %s", resp.PromptFeedback.BlockReason)
+	}
+	return nil
+}
+
+func New(host, apiKey string, model Model) providers.Client {
+	return newClient(host, apiKey, model)
+}
+
+func newClient(host, apiKey string, model Model) *client {
+	tp := &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: model.InsecureSkipVerify}}
+	if model.Proxy == "" {
+		tp.Proxy = http.ProxyFromEnvironment
+	} else {
+		proxyUrl, err := url.Parse(model.Proxy)
+		if err == nil {
+			tp.Proxy = http.ProxyURL(proxyUrl)
+		}
+	}
+	cli := &http.Client{
+		Transport: tp,
+		Timeout:   time.Hour,
+	}
+
+	if model.QPM == 0 {
+		model.QPM = 20
+	}
+	limiter := model.Limiter
+	if limiter == nil {
+		limiter = ratelimit.New(model.QPM)
+	}
+
+	return &client{
+		host:       host,
+		apiKey:     apiKey,
+		http:       cli,
+		model:      model,
+		apiLimiter: limiter,
+		caches:     newContextCaches(),
+		logger:     logger.New("gemini"),
+	}
+}
+
+var _ providers.Client = (*client)(nil)
+var _ providers.ContextWindowProvider = (*client)(nil)
+var _ providers.PromptCacher = (*client)(nil)
+
+type response struct {
+	*providers.CommonResponse
+
+	request providers.Request
+
+	// started is set once a chunk arrived; a request is only retried before
+	// anything was streamed.
+	started bool
+	// signature is the first thought signature; later ones are dropped since
+	// a message carries a single signature.
+	signature string
+
+	// accumulatedContent tracks the content for token fallback calculation
+	accumulatedContent string
+}
+
+func (r *response) handleChunk(chunk *generateContentResponse) error {
+	r.started = true
+	if u := chunk.UsageMetadata; u != nil {
+		// Usage is cumulative; the last chunk carries the totals.
+		r.Token.PromptTokens = u.PromptTokenCount
+		r.Token.CachedPromptTokens = u.CachedContentTokenCount
+		r.Token.CompletionTokens = u.CandidatesTokenCount + u.ThoughtsTokenCount
+		r.Token.ReasoningTokens = u.ThoughtsTokenCount
+		r.Token.TotalTokens = u.TotalTokenCount
+	}
+	if err := blockedError(chunk); err != nil {
+		return err
+	}
+	if len(chunk.Candidates) == 0 {
+		return nil
+	}
+
+	for _, p := range chunk.Candidates[0].Content.Parts {
+		if p.ThoughtSignature != "" && r.signature == "" {
+			r.signature = p.ThoughtSignature
+			r.Stream <- providers.Delta{ReasoningSignature: p.ThoughtSignature}
+		}
+		switch {
+		case p.FunctionCall != nil:
+			args := string(p.FunctionCall.Args)
+			if args == "" || args == "null" {
+				args = "{}"
+			}
+			id := p.FunctionCall.ID
+			if id == "" {
+				id = "call_" + types.NewID()
+			}
+			r.Stream <- providers.Delta{ToolUse: []providers.ToolCall{{
+				ID:        id,
+				Name:      p.FunctionCall.Name,
+				Arguments: args,
+			}}}
+		case p.Thought:
+			if p.Text != "" {
+				r.Stream <- providers.Delta{Reasoning: p.Text}
+			}
+		case p.Text != "":
+			r.accumulatedContent += p.Text
+			r.Stream <- providers.Delta{Content: p.Text}
+		}
+	}
+	return nil
+}
+
+// applyTokenFallback fills in token counts using FuzzyTokens if API didn't return them
+func (r *response) applyTokenFallback(requestMessages []types.Message) {
+	overhead := session.EstimateRequestOverhead(r.request)
+	r.Token.PromptTokens, r.Token.CompletionTokens, r.Token.TotalTokens =
+		common.ApplyTokenFallback(r.Token.PromptTokens, r.Token.CompletionTokens, r.accumulatedContent, requestMessages, overhead)
+}
+
+func (r *response) fail(err error) { r.Err <- err }
+
+func (r *response) close() {
+	close(r.Stream)
+	close(r.Err)
+}
+
+func newResponse(req providers.Request) *response {
+	return &response{CommonResponse: providers.NewCommonResponse(), request: req}
+}
+
+var _ providers.Response = (*response)(nil)
+
+func isRateLimitError(err error) bool {
+	if err == nil {
+		return false
+	}
+	return strings.Contains(err.Error(), "RESOURCE_EXHAUSTED") || strings.Contains(err.Error(), "429")
+}
@@ -0,0 +1,312 @@
+package gemini
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/basenana/friday/core/providers"
+	"github.com/basenana/friday/core/types"
+)
+
+func TestNewClientInsecureSkipVerify(t *testing.T) {
+	secure := newClient("https://generativelanguage.googleapis.com/v1beta", "key", Model{Name: "gemini-test", InsecureSkipVerify: false})
+	if secure == nil {
+		t.Fatal("expected client to be initialized")
+	}
+
+	insecure := newClient("https://generativelanguage.googleapis.com/v1beta", "key", Model{Name: "gemini-test", InsecureSkipVerify: true})
+	if insecure == nil {
+		t.Fatal("expected insecure client to be initialized")
+	}
+}
+
+func TestGenerateContentRequestBuildsToolTurns(t *testing.T) {
+	cli := &client{model: Model{Name: "gemini-2.5-pro"}}
+	req := providers.NewRequest("You are helpful.",
+		types.Message{Role: types.RoleUser, Content: "Read main.go"},
+		types.Message{
+			Role:               types.RoleAssistant,
+			Content:            "Reading both files.",
+			ReasoningSignature: "sig-1",
+			ToolCalls: []types.ToolCall{
+				{ID: "call-1", Name: "fs_read", Arguments: `{"path":"main.go"}`},
+				{ID: "call-2", Name: "fs_read", Arguments: `{"path":"go.mod"}`},
+			},
+		},
+		types.Message{Role: types.RoleTool, ToolResult: &types.ToolResult{CallID: "call-1", Content: "package main"}},
+		types.Message{Role: types.RoleTool, ToolResult: &types.ToolResult{CallID: "call-2", Content: "module x"}},
+	)
+	req.SetToolDefines([]providers.ToolDefine{
+		providers.NewToolDefine("fs_read", "Read a file", map[string]any{
+			"type":       "object",
+			"properties": map[string]any{"path": map[string]any{"type": "string"}},
+		}),
+	})
+
+	body := cli.generateContentRequest(req)
+
+	if body.SystemInstruction == nil || !strings.Contains(body.SystemInstruction.Parts[0].Text, "You are helpful.") {
+		t.Fatalf("system instruction = %+v", body.SystemInstruction)
+	}
+	if len(body.Contents) != 3 {
+		t.Fatalf("expected user, model and function response turns, got %d: %+v", len(body.Contents), body.Contents)
+	}
+
+	model := body.Contents[1]
+	if model.Role != roleModel || len(model.Parts) != 3 {
+		t.Fatalf("model turn = %+v", model)
+	}
+	if model.Parts[1].FunctionCall == nil || model.Parts[1].FunctionCall.Name != "fs_read" {
+		t.Fatalf("expected function call part, got %+v", model.Parts[1])
+	}
+	if model.Parts[1].ThoughtSignature != "sig-1" || model.Parts[2].ThoughtSignature != "" {
+		t.Fatalf("thought signature should be on the first function call only: %+v", model.Parts)
+	}
+
+	responses := body.Contents[2]
+	if responses.Role != roleUser || len(responses.Parts) != 2 {
+		t.Fatalf("function responses should share one user turn: %+v", responses)
+	}
+	if fr := responses.Parts[1].FunctionResponse; fr == nil || fr.Name != "fs_read" || fr.Response["output"] != "module x" {
+		t.Fatalf("function response = %+v", responses.Parts[1].FunctionResponse)
+	}
+
+	if len(body.Tools) != 1 || body.Tools[0].FunctionDeclarations[0].Name != "fs_read" {
+		t.Fatalf("tools = %+v", body.Tools)
+	}
+	if body.GenerationConfig.ThinkingConfig == nil || !body.GenerationConfig.ThinkingConfig.IncludeThoughts {
+		t.Fatal("expected thoughts to be requested for a thinking model")
+	}
+}
+
+func TestGenerateContentRequestDowngradesUnansweredToolCallToText(t *testing.T) {
+	cli := &client{model: Model{Name: "gemini-2.0-flash"}}
+	req := providers.NewRequest("",
+		types.Message{Role: types.RoleUser, Content: "hi"},
+		types.Message{Role: types.RoleAssistant, ToolCalls: []types.ToolCall{{ID: "call-1", Name: "bash", Arguments: `{"command":"ls"}`}}},
+		types.Message{Role: types.RoleTool, ToolResult: &types.ToolResult{CallID: "call-9", Content: "stray"}},
+	)
+
+	body := cli.generateContentRequest(req)
+
+	if len(body.Contents) != 3 {
+		t.Fatalf("contents = %+v", body.Contents)
+	}
+	if got := body.Contents[1].Parts[0]; got.FunctionCall != nil || !strings.Contains(got.Text, "historical invalid tool call omitted: bash") {
+		t.Fatalf("unanswered call should become text, got %+v", got)
+	}
+	if got := body.Contents[2].Parts[0]; got.FunctionResponse != nil || !strings.Contains(got.Text, "stray") {
+		t.Fatalf("orphaned result should become text, got %+v", got)
+	}
+	if body.GenerationConfig.ThinkingConfig != nil {
+		t.Fatal("gemini-2.0 models should not request thoughts")
+	}
+}
+
+func TestGenerateContentRequestImagesAndPromptOnly(t *testing.T) {
+	cli := &client{model: Model{Name: "gemini-2.5-flash"}}
+
+	req := providers.NewRequest("",
+		types.Message{Role: types.RoleUser, Content: "What is this?", Image: &types.ImageContent{Type: types.ImageTypeBase64, MediaType: "image/png", Data: "aGVsbG8="}},
+		types.Message{Role: types.RoleUser, Image: &types.ImageContent{Type: types.ImageTypeURL, URL: "https://example.com/cat.jpg"}},
+	)
+	body := cli.generateContentRequest(req)
+	if len(body.Contents) != 1 || len(body.Contents[0].Parts) != 3 {
+		t.Fatalf("consecutive user messages should merge: %+v", body.Contents)
+	}
+	if p := body.Contents[0].Parts[1]; p.InlineData == nil || p.InlineData.MimeType != "image/png" {
+		t.Fatalf("inline image = %+v", p)
+	}
+	if p := body.Contents[0].Parts[2]; p.FileData == nil || p.FileData.MimeType != "image/jpeg" {
+		t.Fatalf("url image = %+v", p)
+	}
+
+	body = cli.generateContentRequest(providers.NewRequest("Summarize the weather."))
+	if body.SystemInstruction != nil || len(body.Contents) != 1 || body.Contents[0].Role != roleUser {
+		t.Fatalf("prompt-only request should be sent as a user turn: %+v", body)
+	}
+}
+
+func TestResponseHandleChunk(t *testing.T) {
+	resp := newResponse(providers.NewPromptRequest("hi"))
+	resp.CommonResponse.Stream = make(chan providers.Delta, 10)
+
+	chunks := []string{
+		`{"candidates":[{"content":{"role":"model","parts":[{"text":"Let me look.","thought":true}]}}]}`,
+		`{"candidates":[{"content":{"role":"model","parts":[{"text":"Checking"},{"functionCall":{"name":"bash","args":{"command":"ls"}},"thoughtSignature":"sig-a"}]}}]}`,
+		`{"candidates":[{"content":{"role":"model","parts":[{"functionCall":{"name":"fs_read","args":{"path":"a"}},"thoughtSignature":"sig-b"}]},"finishReason":"STOP"}],
+		  "usageMetadata":{"promptTokenCount":100,"candidatesTokenCount":20,"thoughtsTokenCount":5,"cachedContentTokenCount":40,"totalTokenCount":125}}`,
+	}
+	for _, raw := range chunks {
+		chunk := &generateContentResponse{}
+		if err := json.Unmarshal([]byte(raw), chunk); err != nil {
+			t.Fatal(err)
+		}
+		if err := resp.handleChunk(chunk); err != nil {
+			t.Fatalf("handleChunk: %v", err)
+		}
+	}
+	close(resp.CommonResponse.Stream)
+
+	var (
+		reasoning, content, signature string
+		calls                         []providers.ToolCall
+	)
+	for d := range resp.Message() {
+		reasoning += d.Reasoning
+		content += d.Content
+		if d.ReasoningSignature != "" {
+			signature = d.ReasoningSignature
+		}
+		calls = append(calls, d.ToolUse...)
+	}
+	if reasoning != "Let me look." || content != "Checking" {
+		t.Fatalf("reasoning = %q, content = %q", reasoning, content)
+	}
+	if signature != "sig-a" {
+		t.Fatalf("signature = %q, want the first one", signature)
+	}
+	if len(calls) != 2 || calls[0].Name != "bash" || calls[0].Arguments != `{"command":"ls"}` || calls[0].ID == "" || calls[0].ID == calls[1].ID {
+		t.Fatalf("tool calls = %+v", calls)
+	}
+	want := providers.Tokens{PromptTokens: 100, CachedPromptTokens: 40, CompletionTokens: 25, ReasoningTokens: 5, TotalTokens: 125}
+	if resp.Tokens() != want {
+		t.Fatalf("tokens = %+v, want %+v", resp.Tokens(), want)
+	}
+}
+
+func TestCompletionStreamsFromServer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("x-goog-api-key") != "secret" {
+			http.Error(w, `{"error":{"code":401,"message":"bad key","status":"UNAUTHENTICATED"}}`, http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/models/gemini-2.5-flash:streamGenerateContent" || r.URL.Query().Get("alt") != "sse" {
+			http.Error(w, "unexpected path "+r.URL.String(), http.StatusNotFound)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		if !strings.Contains(string(body), `"text":"hello"`) {
+			http.Error(w, "unexpected body "+string(body), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"candidates\":[{\"content\":{\"role\":\"model\",\"parts\":[{\"text\":\"Hi \"}]}}]}\n\n")
+		fmt.Fprint(w, "data: {\"candidates\":[{\"content\":{\"role\":\"model\",\"parts\":[{\"text\":\"there\"}]},\"finishReason\":\"STOP\"}],\"usageMetadata\":{\"promptTokenCount\":3,\"candidatesTokenCount\":2,\"totalTokenCount\":5}}\n\n")
+	}))
+	defer server.Close()
+
+	cli := newClient(server.URL, "secret", Model{Name: "gemini-2.5-flash"})
+	resp := cli.Completion(context.Background(), providers.NewPromptRequest("hello"))
+
+	var content string
+	for d := range resp.Message() {
+		content += d.Content
+	}
+	if err := <-resp.Error(); err != nil {
+		t.Fatalf("completion error: %v", err)
+	}
+	if content != "Hi there" {
+		t.Fatalf("content = %q", content)
+	}
+	if resp.Tokens().TotalTokens != 5 {
+		t.Fatalf("tokens = %+v", resp.Tokens())
+	}
+
+	bad := newClient(server.URL, "wrong", Model{Name: "gemini-2.5-flash"})
+	if _, err := bad.CompletionNonStreaming(context.Background(), providers.NewPromptRequest("hello")); err == nil || !strings.Contains(err.Error(), "UNAUTHENTICATED") {
+		t.Fatalf("expected API error, got %v", err)
+	}
+}
+
+func TestCompletionNonStreamingCachesPromptPrefix(t *testing.T) {
+	var (
+		created   int
+		generated []generateContentRequest
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/cachedContents":
+			var req cachedContent
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			if req.Model != "models/gemini-2.5-flash" || req.SystemInstruction == nil || len(req.Tools) != 1 || req.TTL != "600s" {
+				http.Error(w, "unexpected cache request", http.StatusBadRequest)
+				return
+			}
+			created++
+			fmt.Fprintf(w, `{"name":"cachedContents/c%d","expireTime":%q}`, created, time.Now().Add(time.Hour).Format(time.RFC3339))
+		case r.URL.Path == "/models/gemini-2.5-flash:generateContent":
+			var req generateContentRequest
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			generated = append(generated, req)
+			if req.CachedContent == "cachedContents/gone" {
+				http.Error(w, `{"error":{"code":403,"message":"CachedContent not found","status":"PERMISSION_DENIED"}}`, http.StatusForbidden)
+				return
+			}
+			fmt.Fprint(w, `{"candidates":[{"content":{"role":"model","parts":[{"text":"ok"}]}}],"usageMetadata":{"promptTokenCount":5000,"cachedContentTokenCount":4800,"totalTokenCount":5001}}`)
+		default:
+			http.Error(w, "unexpected path "+r.URL.String(), http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	cli := newClient(server.URL, "key", Model{Name: "gemini-2.5-flash"})
+	long := strings.Repeat("context ", 4000)
+	history := []types.Message{
+		{Role: types.RoleUser, Content: long},
+		{Role: types.RoleAssistant, Content: "noted"},
+		{Role: types.RoleUser, Content: "first question"},
+	}
+	newRequest := func(history ...types.Message) providers.Request {
+		req := providers.NewRequest("You are helpful.", history...)
+		req.SetToolDefines([]providers.ToolDefine{providers.NewToolDefine("bash", "Run a command", map[string]any{"type": "object"})})
+		req.SetPromptCacheKey("session:1")
+		return req
+	}
+
+	if _, err := cli.CompletionNonStreaming(context.Background(), newRequest(history...)); err != nil {
+		t.Fatalf("first completion error: %v", err)
+	}
+	history = append(history,
+		types.Message{Role: types.RoleAssistant, Content: "answer"},
+		types.Message{Role: types.RoleUser, Content: "second question"},
+	)
+	if _, err := cli.CompletionNonStreaming(context.Background(), newRequest(history...)); err != nil {
+		t.Fatalf("second completion error: %v", err)
+	}
+
+	if created != 1 || len(generated) != 2 {
+		t.Fatalf("created %d caches for %d requests, want 1 for 2", created, len(generated))
+	}
+	for i, req := range generated {
+		if req.CachedContent != "cachedContents/c1" || req.SystemInstruction != nil || len(req.Tools) != 0 {
+			t.Fatalf("request %d = %+v, want the cached prefix without system instruction and tools", i, req)
+		}
+	}
+	if got := len(generated[1].Contents); got != 3 {
+		t.Fatalf("second request sent %d contents, want the 3 after the cached prefix", got)
+	}
+
+	// A prefix the server lost is dropped and the full prompt sent instead.
+	cli.caches.put("session:1", &cachedPrefix{name: "cachedContents/gone", hash: generatedHash(cli, newRequest(history...), 2), contents: 2, expiresAt: time.Now().Add(time.Hour)})
+	if _, err := cli.CompletionNonStreaming(context.Background(), newRequest(history...)); err != nil {
+		t.Fatalf("completion after a lost cache error: %v", err)
+	}
+	if last := generated[len(generated)-1]; last.CachedContent != "" || last.SystemInstruction == nil {
+		t.Fatalf("retry = %+v, want the full prompt", last)
+	}
+	if cli.caches.get("session:1") != nil {
+		t.Fatal("expected the lost cache to be forgotten")
+	}
+}
+
+func generatedHash(cli *client, req providers.Request, n int) string {
+	return hashPrefix(prefixOf(cli.generateContentRequest(req), n))
+}
@@ -19,6 +19,7 @@ package providers
 import (
 	"context"
 
+	"github.com/basenana/friday/core/tokenizer"
 	"github.com/basenana/friday/core/types"
 )
 
@@ -32,6 +33,42 @@ type ContextWindowProvider interface {
 	ContextWindow() int64
 }
 
+// CacheStrategy is how a client lets the provider reuse the prompt prefix a
+// request shares with the earlier requests of a session.
+type CacheStrategy string
+
+const (
+	// CacheNone means the client does nothing to have prompts cached.
+	CacheNone CacheStrategy = "none"
+	// CacheBreakpoints marks explicit cache breakpoints in the prompt (Anthropic).
+	CacheBreakpoints CacheStrategy = "breakpoints"
+	// CacheKey sends the PromptCacheKey so requests of a session are routed
+	// to servers holding their prefix (OpenAI).
+	CacheKey CacheStrategy = "prompt_cache_key"
+	// CacheContext stores the prefix as cached content that later requests
+	// reference instead of resending it (Gemini).
+	CacheContext CacheStrategy = "context"
+	// CachePrefix relies on the server reusing the longest byte-identical
+	// prefix, so the client keeps its prompt layout stable (llama.cpp, vLLM, Ollama).
+	CachePrefix CacheStrategy = "prefix"
+)
+
+// PromptCacher is implemented by clients that report their CacheStrategy.
+// Clients wrapping another client should report the strategy of the client
+// they call.
+type PromptCacher interface {
+	CacheStrategy() CacheStrategy
+}
+
+// CacheStrategyOf returns the strategy of client, or CacheNone when it does
+// not report one.
+func CacheStrategyOf(client Client) CacheStrategy {
+	if c, ok := client.(PromptCacher); ok {
+		return c.CacheStrategy()
+	}
+	return CacheNone
+}
+
 type Embedding interface {
 	Vectorization(ctx context.Context, content string) ([]float64, error)
 }
@@ -42,16 +79,29 @@ type Request interface {
 	ToolDefines() []ToolDefine
 	SystemPrompt() string
 	PromptCacheKey() string
+	ModelHint() string
+	// Tokenizer counts the tokens of the request; nil means the rune-count
+	// heuristic.
+	Tokenizer() tokenizer.Tokenizer
 
 	SetHistory([]types.Message)
 	SetToolDefines([]ToolDefine)
 	SetSystemPrompt(string)
 	SetPromptCacheKey(string)
+	SetModelHint(string)
+	SetTokenizer(tokenizer.Tokenizer)
 	AppendHistory(...types.Message)
 	AppendToolDefines(...ToolDefine)
 	AppendSystemPrompt(...string)
 }
 
+// Model hints a hook can set on a request for a router to pick a cheaper or
+// a more capable model.
+const (
+	ModelHintCheap = "cheap"
+	ModelHintSmart = "smart"
+)
+
 type Response interface {
 	Message() <-chan Delta
 	Error() <-chan error
@@ -83,7 +133,14 @@ type Tokens struct {
 	CompletionTokens   int64
 	PromptTokens       int64
 	CachedPromptTokens int64
-	TotalTokens        int64
+	// ReasoningTokens is the part of CompletionTokens spent on reasoning,
+	// for providers that report it.
+	ReasoningTokens int64
+	TotalTokens     int64
+
+	// Model and Cost (in USD) are set by pricing.Meter.
+	Model string
+	Cost  float64
 }
 
 type Apply struct {
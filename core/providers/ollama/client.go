@@ -0,0 +1,517 @@
+package ollama
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/basenana/friday/core/logger"
+	"github.com/basenana/friday/core/providers"
+	"github.com/basenana/friday/core/providers/common"
+	"github.com/basenana/friday/core/providers/ratelimit"
+	"github.com/basenana/friday/core/session"
+	"github.com/basenana/friday/core/tracing"
+	"github.com/basenana/friday/core/types"
+	"github.com/invopop/jsonschema"
+)
+
+const (
+	// DefaultHost is where a local Ollama server listens
+	DefaultHost = "http://localhost:11434"
+
+	// maxImageSize limits images fetched from URLs, which Ollama only
+	// accepts inline
+	maxImageSize = 20 * 1024 * 1024
+)
+
+type Model struct {
+	Name        string
+	Temperature *float64
+	MaxTokens   int64
+	// KeepAlive is how long the server keeps the model loaded after a
+	// request, e.g. "30m"; empty uses the server default
+	KeepAlive string
+	// QPM throttles requests; zero means unlimited, as the server is local
+	QPM   int64
+	Proxy string
+	// ContextWindow is sent as num_ctx; zero detects it from the model
+	ContextWindow      int64
+	InsecureSkipVerify bool
+	// Limiter paces the calls instead of a limiter of QPM private to this
+	// client, e.g. one shared with other processes.
+	Limiter ratelimit.Limiter
+}
+
+type client struct {
+	host       string
+	http       *http.Client
+	model      Model
+	apiLimiter ratelimit.Limiter
+	logger     logger.Logger
+
+	contextOnce   sync.Once
+	contextWindow int64
+}
+
+// CacheStrategy is CachePrefix: Ollama keeps the KV cache of the last
+// prompt of a loaded model and reuses the prefix a new prompt shares with it.
+func (c *client) CacheStrategy() providers.CacheStrategy {
+	return providers.CachePrefix
+}
+
+// ContextWindow returns the configured context window, or else the one the
+// model was built with, as reported by the server.
+func (c *client) ContextWindow() int64 {
+	if c.model.ContextWindow > 0 {
+		return c.model.ContextWindow
+	}
+	c.contextOnce.Do(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		window, err := c.detectContextWindow(ctx)
+		if err != nil {
+			c.logger.Warnw("detect context window failed", "model", c.model.Name, "err", err)
+			return
+		}
+		c.contextWindow = window
+	})
+	return c.contextWindow
+}
+
+func (c *client) Completion(ctx context.Context, request providers.Request) providers.Response {
+	return c.completion(ctx, request, nil)
+}
+
+func (c *client) completion(ctx context.Context, request providers.Request, format json.RawMessage) providers.Response {
+	c.logger.Infow("llm processing...")
+	ctx, span := tracing.Start(ctx, "llm.ollama.completion",
+		tracing.WithAttributes(tracing.String("model", c.model.Name)),
+	)
+	resp := newResponse(request)
+	go func() {
+		defer span.End()
+		defer resp.close()
+		var (
+			body    = c.chatRequest(ctx, request, true, format)
+			startAt = time.Now()
+			err     error
+		)
+
+		defer func() {
+			span.SetAttributes(
+				tracing.Int("prompt_tokens", resp.Token.PromptTokens),
+				tracing.Int("completion_tokens", resp.Token.CompletionTokens),
+			)
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(tracing.StatusError, err.Error())
+			} else {
+				span.SetStatus(tracing.StatusOK, "")
+			}
+			sec := time.Since(startAt).Seconds()
+			if sec < 1 {
+				sec = 1
+			}
+			tps := float64(resp.Token.CompletionTokens) / sec
+			c.logger.Infow("completion-with-streaming finish", "elapsed", time.Since(startAt).String(), "tps", fmt.Sprintf("%.2f", tps))
+		}()
+
+		if err = c.apiLimiter.Wait(ctx); err != nil {
+			c.logger.Errorw("new completion stream error", "err", err)
+			resp.fail(err)
+			return
+		}
+		if time.Since(startAt).Seconds() > 1 {
+			c.logger.Infow("client-side llm api throttled", "wait", time.Since(startAt).String())
+		}
+
+		if err = c.stream(ctx, body, resp.handleChunk); err != nil {
+			c.logger.Errorw("completion stream error", "err", err)
+			resp.fail(err)
+			return
+		}
+
+		// Fallback: if API didn't return token counts, estimate using FuzzyTokens
+		resp.applyTokenFallback(request.Messages())
+	}()
+	return resp
+}
+
+func (c *client) CompletionNonStreaming(ctx context.Context, request providers.Request) (string, error) {
+	return c.completionNonStreaming(ctx, request, nil)
+}
+
+func (c *client) completionNonStreaming(ctx context.Context, request providers.Request, format json.RawMessage) (_ string, retErr error) {
+	ctx, span := tracing.Start(ctx, "llm.ollama.completion_sync",
+		tracing.WithAttributes(tracing.String("model", c.model.Name)),
+	)
+	defer span.End()
+	defer func() { tracing.DeferStatus(span, &retErr) }()
+
+	c.logger.Infow("llm processing...")
+	var (
+		body    = c.chatRequest(ctx, request, false, format)
+		startAt = time.Now()
+	)
+
+	defer func() {
+		c.logger.Infow("completion-non-streaming finish", "elapsed", time.Since(startAt).String())
+	}()
+
+	if err := c.apiLimiter.Wait(ctx); err != nil {
+		c.logger.Errorw("new completion error", "err", err)
+		return "", err
+	}
+	if time.Since(startAt).Seconds() > 1 {
+		c.logger.Infow("client-side llm api throttled", "wait", time.Since(startAt).String())
+	}
+
+	httpResp, err := c.post(ctx, "/api/chat", body)
+	if err != nil {
+		c.logger.Errorw("completion error", "err", err)
+		return "", err
+	}
+	defer httpResp.Body.Close()
+
+	var result chatResponse
+	if err := json.NewDecoder(httpResp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("decode response: %w", err)
+	}
+	if result.Error != "" {
+		return "", fmt.Errorf("ollama error: %s", result.Error)
+	}
+	if result.Message.Content == "" {
+		return "", fmt.Errorf("no text content returned")
+	}
+	return result.Message.Content, nil
+}
+
+// StructuredPredict constrains the output with the model's JSON schema as
+// Ollama's format, on top of the schema prompt the other providers use.
+func (c *client) StructuredPredict(ctx context.Context, request providers.Request, model any) (retErr error) {
+	ctx, span := tracing.Start(ctx, "llm.ollama.structured_predict",
+		tracing.WithAttributes(tracing.String("model", c.model.Name)),
+	)
+	defer span.End()
+	defer func() { tracing.DeferStatus(span, &retErr) }()
+
+	messages := request.Messages()
+	if len(messages) == 0 || messages[0].Content == "" {
+		return fmt.Errorf("user request is empty")
+	}
+	// Ollama turns the schema into a grammar, which does not follow $ref.
+	reflector := &jsonschema.Reflector{DoNotReference: true}
+	schemaRaw, _ := json.Marshal(reflector.Reflect(model))
+	prompt := DEFAULT_STRUCTURED_PREDICT_PROMPT
+	prompt = strings.ReplaceAll(prompt, "{insert_user_request_here}", messages[0].Content)
+	prompt = strings.ReplaceAll(prompt, "{insert_json_schema_here}", string(schemaRaw))
+
+	return common.StructuredPredictWithFallback(
+		ctx,
+		providers.NewPromptRequest(prompt),
+		model,
+		func(ctx context.Context, req providers.Request) (string, error) {
+			return c.completionNonStreaming(ctx, req, schemaRaw)
+		},
+		func(ctx context.Context, req providers.Request) providers.Response {
+			return c.completion(ctx, req, schemaRaw)
+		},
+		c.logger,
+	)
+}
+
+// stream posts body to /api/chat and passes each streamed chunk to handle.
+func (c *client) stream(ctx context.Context, body *chatRequest, handle func(*chatResponse) error) error {
+	httpResp, err := c.post(ctx, "/api/chat", body)
+	if err != nil {
+		return err
+	}
+	defer httpResp.Body.Close()
+
+	scanner := bufio.NewScanner(httpResp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		chunk := &chatResponse{}
+		if err := json.Unmarshal([]byte(line), chunk); err != nil {
+			return fmt.Errorf("decode stream chunk: %w", err)
+		}
+		if chunk.Error != "" {
+			return fmt.Errorf("ollama error: %s", chunk.Error)
+		}
+		if err := handle(chunk); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func (c *client) post(ctx context.Context, path string, body any) (*http.Response, error) {
+	return post(ctx, c.http, c.host, path, body)
+}
+
+func (c *client) chatRequest(ctx context.Context, request providers.Request, stream bool, format json.RawMessage) *chatRequest {
+	body := &chatRequest{
+		Model:     c.model.Name,
+		Stream:    stream,
+		Format:    format,
+		KeepAlive: c.model.KeepAlive,
+		Options: &options{
+			Temperature: c.model.Temperature,
+			NumCtx:      c.ContextWindow(),
+			NumPredict:  c.model.MaxTokens,
+		},
+	}
+
+	messages := common.RepairToolHistory(request.Messages())
+
+	// Ollama matches tool results to calls by name; only results whose call
+	// is in the history can be sent as tool messages.
+	answered := make(map[string]struct{})
+	for _, msg := range messages {
+		if msg.Role == types.RoleTool && msg.ToolResult != nil {
+			answered[msg.ToolResult.CallID] = struct{}{}
+		}
+	}
+	callNames := make(map[string]string)
+	invalidCallNames := make(map[string]string)
+
+	for _, msg := range messages {
+		switch msg.Role {
+		case types.RoleSystem:
+			body.Messages = append(body.Messages, message{Role: "system", Content: msg.Content})
+
+		case types.RoleUser:
+			m := message{Role: "user", Content: msg.Content}
+			if image, err := c.imageData(ctx, msg.Image); err != nil {
+				c.logger.Warnw("skip image", "err", err)
+			} else if image != "" {
+				m.Images = []string{image}
+			}
+			body.Messages = append(body.Messages, m)
+
+		case types.RoleAssistant:
+			m := message{Role: "assistant", Content: msg.Content, Thinking: msg.Reasoning}
+			for _, tc := range msg.ToolCalls {
+				_, ok := answered[tc.ID]
+				if args, valid := common.ParseToolUseArguments(tc.Arguments); valid && ok && tc.Name != "" {
+					raw, _ := json.Marshal(args)
+					m.ToolCalls = append(m.ToolCalls, toolCall{Function: toolCallFunction{Name: tc.Name, Arguments: raw}})
+					callNames[tc.ID] = tc.Name
+					continue
+				}
+				if tc.ID != "" {
+					invalidCallNames[tc.ID] = tc.Name
+				}
+				m.Content = joinText(m.Content, formatInvalidHistoricalToolCall(tc))
+			}
+			body.Messages = append(body.Messages, m)
+
+		case types.RoleTool:
+			if msg.ToolResult == nil {
+				continue
+			}
+			name, ok := callNames[msg.ToolResult.CallID]
+			if !ok {
+				body.Messages = append(body.Messages, message{
+					Role:    "user",
+					Content: formatOrphanedHistoricalToolResult(msg.ToolResult, invalidCallNames[msg.ToolResult.CallID]),
+				})
+				continue
+			}
+			body.Messages = append(body.Messages, message{Role: "tool", Content: msg.ToolResult.Content, ToolName: name})
+		}
+	}
+
+	for _, t := range request.ToolDefines() {
+		body.Tools = append(body.Tools, tool{
+			Type: "function",
+			Function: toolFunction{
+				Name:        t.GetName(),
+				Description: t.GetDescription(),
+				Parameters:  t.GetParameters(),
+			},
+		})
+	}
+	return body
+}
+
+// imageData returns an image as base64, fetching URL images since Ollama
+// only accepts inline data.
+func (c *client) imageData(ctx context.Context, image *types.ImageContent) (string, error) {
+	if image == nil {
+		return "", nil
+	}
+	switch image.Type {
+	case types.ImageTypeBase64:
+		return image.Data, nil
+	case types.ImageTypeURL:
+		if strings.HasPrefix(image.URL, "data:") {
+			if _, data, ok := strings.Cut(image.URL, ";base64,"); ok {
+				return data, nil
+			}
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, image.URL, nil)
+		if err != nil {
+			return "", err
+		}
+		resp, err := c.http.Do(req)
+		if err != nil {
+			return "", fmt.Errorf("fetch image: %w", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return "", fmt.Errorf("fetch image: %s", resp.Status)
+		}
+		data, err := io.ReadAll(io.LimitReader(resp.Body, maxImageSize+1))
+		if err != nil {
+			return "", fmt.Errorf("fetch image: %w", err)
+		}
+		if len(data) > maxImageSize {
+			return "", fmt.Errorf("fetch image: larger than %d bytes", maxImageSize)
+		}
+		return base64.StdEncoding.EncodeToString(data), nil
+	}
+	return "", nil
+}
+
+func joinText(a, b string) string {
+	if a == "" {
+		return b
+	}
+	return a + "\n" + b
+}
+
+func formatInvalidHistoricalToolCall(tc types.ToolCall) string {
+	name := tc.Name
+	if name == "" {
+		name = "unknown_tool"
+	}
+	args := strings.TrimSpace(tc.Arguments)
+	if args == "" {
+		return fmt.Sprintf("[historical invalid tool call omitted: %s]", name)
+	}
+	return fmt.Sprintf("[historical invalid tool call omitted: %s(%s)]", name, args)
+}
+
+func formatOrphanedHistoricalToolResult(result *types.ToolResult, toolName string) string {
+	if result == nil {
+		return "[historical tool result omitted]"
+	}
+	if toolName == "" {
+		toolName = "unknown_tool"
+	}
+	content := strings.TrimSpace(result.Content)
+	if content == "" {
+		return fmt.Sprintf("[historical tool result omitted for invalid tool call: %s]", toolName)
+	}
+	return fmt.Sprintf("[historical tool result for invalid tool call %s] %s", toolName, content)
+}
+
+func New(host string, model Model) providers.Client {
+	return newClient(host, model)
+}
+
+func newClient(host string, model Model) *client {
+	tp := &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: model.InsecureSkipVerify}}
+	if model.Proxy == "" {
+		tp.Proxy = http.ProxyFromEnvironment
+	} else {
+		proxyUrl, err := url.Parse(model.Proxy)
+		if err == nil {
+			tp.Proxy = http.ProxyURL(proxyUrl)
+		}
+	}
+	cli := &http.Client{
+		Transport: tp,
+		Timeout:   time.Hour,
+	}
+
+	limiter := model.Limiter
+	if limiter == nil {
+		limiter = ratelimit.New(model.QPM)
+	}
+
+	return &client{
+		host:       normalizeHost(host),
+		http:       cli,
+		model:      model,
+		apiLimiter: limiter,
+		logger:     logger.New("ollama"),
+	}
+}
+
+var _ providers.Client = (*client)(nil)
+var _ providers.ContextWindowProvider = (*client)(nil)
+
+type response struct {
+	*providers.CommonResponse
+
+	request providers.Request
+
+	// accumulatedContent tracks the content for token fallback calculation
+	accumulatedContent string
+}
+
+func (r *response) handleChunk(chunk *chatResponse) error {
+	if chunk.Message.Thinking != "" {
+		r.Stream <- providers.Delta{Reasoning: chunk.Message.Thinking}
+	}
+	if chunk.Message.Content != "" {
+		r.accumulatedContent += chunk.Message.Content
+		r.Stream <- providers.Delta{Content: chunk.Message.Content}
+	}
+	if len(chunk.Message.ToolCalls) > 0 {
+		calls := make([]providers.ToolCall, 0, len(chunk.Message.ToolCalls))
+		for _, tc := range chunk.Message.ToolCalls {
+			args := string(tc.Function.Arguments)
+			if args == "" || args == "null" {
+				args = "{}"
+			}
+			calls = append(calls, providers.ToolCall{
+				ID:        "call_" + types.NewID(),
+				Name:      tc.Function.Name,
+				Arguments: args,
+			})
+		}
+		r.Stream <- providers.Delta{ToolUse: calls}
+	}
+	if chunk.Done {
+		r.Token.PromptTokens = chunk.PromptEvalCount
+		r.Token.CompletionTokens = chunk.EvalCount
+		r.Token.TotalTokens = chunk.PromptEvalCount + chunk.EvalCount
+	}
+	return nil
+}
+
+// applyTokenFallback fills in token counts using FuzzyTokens if API didn't return them
+func (r *response) applyTokenFallback(requestMessages []types.Message) {
+	overhead := session.EstimateRequestOverhead(r.request)
+	r.Token.PromptTokens, r.Token.CompletionTokens, r.Token.TotalTokens =
+		common.ApplyTokenFallback(r.Token.PromptTokens, r.Token.CompletionTokens, r.accumulatedContent, requestMessages, overhead)
+}
+
+func (r *response) fail(err error) { r.Err <- err }
+
+func (r *response) close() {
+	close(r.Stream)
+	close(r.Err)
+}
+
+func newResponse(req providers.Request) *response {
+	return &response{CommonResponse: providers.NewCommonResponse(), request: req}
+}
+
+var _ providers.Response = (*response)(nil)
@@ -0,0 +1,199 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/basenana/friday/core/providers"
+	"github.com/basenana/friday/core/types"
+)
+
+// fakeServer serves /api/show and records /api/chat requests, answering them
+// with the given NDJSON lines.
+func fakeServer(t *testing.T, chatLines ...string) (*httptest.Server, *[]chatRequest) {
+	t.Helper()
+	var requests []chatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/show":
+			fmt.Fprint(w, `{"parameters":"stop \"<|im_end|>\"","model_info":{"general.architecture":"qwen3","qwen3.context_length":40960}}`)
+		case "/api/chat":
+			var req chatRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			requests = append(requests, req)
+			for _, line := range chatLines {
+				fmt.Fprintln(w, line)
+			}
+		case "/api/tags":
+			fmt.Fprint(w, `{"models":[{"name":"qwen3:8b","size":5200000000,"details":{"parameter_size":"8.2B"}}]}`)
+		case "/api/pull":
+			fmt.Fprintln(w, `{"status":"pulling manifest"}`)
+			fmt.Fprintln(w, `{"status":"pulling abc","digest":"sha256:abc","total":100,"completed":50}`)
+			fmt.Fprintln(w, `{"status":"success"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestChatRequestBuildsToolTurns(t *testing.T) {
+	cli := newClient(DefaultHost, Model{Name: "qwen3:8b", ContextWindow: 8192, KeepAlive: "30m"})
+	req := providers.NewRequest("You are helpful.",
+		types.Message{Role: types.RoleUser, Content: "Look", Image: &types.ImageContent{Type: types.ImageTypeBase64, MediaType: "image/png", Data: "aGVsbG8="}},
+		types.Message{
+			Role:      types.RoleAssistant,
+			Reasoning: "need to run ls",
+			ToolCalls: []types.ToolCall{
+				{ID: "call-1", Name: "bash", Arguments: `{"command":"ls"}`},
+				{ID: "call-2", Name: "bash", Arguments: `not json`},
+			},
+		},
+		types.Message{Role: types.RoleTool, ToolResult: &types.ToolResult{CallID: "call-1", Content: "main.go"}},
+		types.Message{Role: types.RoleTool, ToolResult: &types.ToolResult{CallID: "call-2", Content: "error"}},
+	)
+	req.SetToolDefines([]providers.ToolDefine{providers.NewToolDefine("bash", "Run a command", map[string]any{"type": "object"})})
+
+	body := cli.chatRequest(context.Background(), req, true, nil)
+
+	if body.Options.NumCtx != 8192 || body.KeepAlive != "30m" {
+		t.Fatalf("options = %+v, keep_alive = %q", body.Options, body.KeepAlive)
+	}
+	if len(body.Messages) != 5 {
+		t.Fatalf("messages = %+v", body.Messages)
+	}
+	if m := body.Messages[1]; m.Role != "user" || len(m.Images) != 1 || m.Images[0] != "aGVsbG8=" {
+		t.Fatalf("user message = %+v", m)
+	}
+	assistant := body.Messages[2]
+	if assistant.Thinking != "need to run ls" || len(assistant.ToolCalls) != 1 || string(assistant.ToolCalls[0].Function.Arguments) != `{"command":"ls"}` {
+		t.Fatalf("assistant message = %+v", assistant)
+	}
+	if !strings.Contains(assistant.Content, "historical invalid tool call omitted: bash(not json)") {
+		t.Fatalf("invalid call should be kept as text: %q", assistant.Content)
+	}
+	if m := body.Messages[3]; m.Role != "tool" || m.ToolName != "bash" || m.Content != "main.go" {
+		t.Fatalf("tool message = %+v", m)
+	}
+	if m := body.Messages[4]; m.Role != "user" || !strings.Contains(m.Content, "invalid tool call bash") {
+		t.Fatalf("orphaned result = %+v", m)
+	}
+	if len(body.Tools) != 1 || body.Tools[0].Type != "function" {
+		t.Fatalf("tools = %+v", body.Tools)
+	}
+}
+
+func TestCompletionStreamsContentThinkingAndToolCalls(t *testing.T) {
+	server, requests := fakeServer(t,
+		`{"message":{"role":"assistant","content":"","thinking":"Hmm."},"done":false}`,
+		`{"message":{"role":"assistant","content":"Running it."},"done":false}`,
+		`{"message":{"role":"assistant","content":"","tool_calls":[{"function":{"name":"bash","arguments":{"command":"ls"}}}]},"done":false}`,
+		`{"message":{"role":"assistant","content":""},"done":true,"done_reason":"stop","prompt_eval_count":42,"eval_count":7}`,
+	)
+	cli := newClient(server.URL, Model{Name: "qwen3:8b"})
+
+	resp := cli.Completion(context.Background(), providers.NewPromptRequest("list files"))
+	var (
+		reasoning, content string
+		calls              []providers.ToolCall
+	)
+	for d := range resp.Message() {
+		reasoning += d.Reasoning
+		content += d.Content
+		calls = append(calls, d.ToolUse...)
+	}
+	if err := <-resp.Error(); err != nil {
+		t.Fatalf("completion error: %v", err)
+	}
+	if reasoning != "Hmm." || content != "Running it." {
+		t.Fatalf("reasoning = %q, content = %q", reasoning, content)
+	}
+	if len(calls) != 1 || calls[0].Name != "bash" || calls[0].Arguments != `{"command":"ls"}` || calls[0].ID == "" {
+		t.Fatalf("tool calls = %+v", calls)
+	}
+	if want := (providers.Tokens{PromptTokens: 42, CompletionTokens: 7, TotalTokens: 49}); resp.Tokens() != want {
+		t.Fatalf("tokens = %+v, want %+v", resp.Tokens(), want)
+	}
+
+	// The context window is detected from the model and sent as num_ctx.
+	if cli.ContextWindow() != 40960 {
+		t.Fatalf("ContextWindow() = %d, want 40960", cli.ContextWindow())
+	}
+	if len(*requests) != 1 || !(*requests)[0].Stream || (*requests)[0].Options.NumCtx != 40960 {
+		t.Fatalf("chat requests = %+v", *requests)
+	}
+}
+
+func TestStructuredPredictSendsSchemaFormat(t *testing.T) {
+	server, requests := fakeServer(t, `{"message":{"role":"assistant","content":"{\"name\":\"friday\",\"count\":2}"},"done":true}`)
+	cli := newClient(server.URL+"/v1", Model{Name: "qwen3:8b", ContextWindow: 4096})
+
+	var out struct {
+		Name  string `json:"name"`
+		Count int    `json:"count"`
+	}
+	if err := cli.StructuredPredict(context.Background(), providers.NewPromptRequest("give me a name"), &out); err != nil {
+		t.Fatalf("StructuredPredict: %v", err)
+	}
+	if out.Name != "friday" || out.Count != 2 {
+		t.Fatalf("out = %+v", out)
+	}
+
+	req := (*requests)[0]
+	if req.Stream {
+		t.Fatal("structured predict should try a non-streaming request first")
+	}
+	var schema map[string]any
+	if err := json.Unmarshal(req.Format, &schema); err != nil {
+		t.Fatalf("format is not a schema: %s", req.Format)
+	}
+	if _, ok := schema["properties"].(map[string]any)["count"]; !ok || strings.Contains(string(req.Format), "$ref") {
+		t.Fatalf("format = %s", req.Format)
+	}
+}
+
+func TestContextLengthPrefersModelfileNumCtx(t *testing.T) {
+	show := showResponse{
+		Parameters: "num_ctx                        16384\ntemperature 0.7",
+		ModelInfo:  map[string]any{"llama.context_length": float64(131072)},
+	}
+	if got := contextLength(show); got != 16384 {
+		t.Fatalf("contextLength = %d, want 16384", got)
+	}
+	show.Parameters = ""
+	if got := contextLength(show); got != 131072 {
+		t.Fatalf("contextLength = %d, want 131072", got)
+	}
+}
+
+func TestListAndPullModels(t *testing.T) {
+	server, _ := fakeServer(t)
+
+	models, err := ListModels(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("ListModels: %v", err)
+	}
+	if len(models) != 1 || models[0].Name != "qwen3:8b" || models[0].Details.ParameterSize != "8.2B" {
+		t.Fatalf("models = %+v", models)
+	}
+
+	var statuses []string
+	err = PullModel(context.Background(), server.URL, "qwen3:8b", func(p PullProgress) {
+		statuses = append(statuses, p.Status)
+	})
+	if err != nil {
+		t.Fatalf("PullModel: %v", err)
+	}
+	if len(statuses) != 3 || statuses[2] != "success" {
+		t.Fatalf("statuses = %v", statuses)
+	}
+}
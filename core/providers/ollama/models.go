@@ -0,0 +1,151 @@
+package ollama
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ListModels returns the models installed on the Ollama server at host.
+func ListModels(ctx context.Context, host string) ([]LocalModel, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, normalizeHost(host)+"/api/tags", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if err := checkStatus(resp); err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Models []LocalModel `json:"models"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("decode model list: %w", err)
+	}
+	return result.Models, nil
+}
+
+// PullModel downloads a model to the Ollama server at host, reporting each
+// progress update to progress, which may be nil.
+func PullModel(ctx context.Context, host, name string, progress func(PullProgress)) error {
+	resp, err := post(ctx, http.DefaultClient, normalizeHost(host), "/api/pull", map[string]any{"model": name, "stream": true})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var p PullProgress
+		if err := json.Unmarshal([]byte(line), &p); err != nil {
+			return fmt.Errorf("decode pull progress: %w", err)
+		}
+		if p.Error != "" {
+			return fmt.Errorf("pull %s: %s", name, p.Error)
+		}
+		if progress != nil {
+			progress(p)
+		}
+	}
+	return scanner.Err()
+}
+
+// detectContextWindow reads the model's context length: num_ctx from its
+// Modelfile when set, or else the length it was trained with.
+func (c *client) detectContextWindow(ctx context.Context) (int64, error) {
+	resp, err := c.post(ctx, "/api/show", map[string]any{"model": c.model.Name})
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	var show showResponse
+	if err := json.NewDecoder(resp.Body).Decode(&show); err != nil {
+		return 0, fmt.Errorf("decode model info: %w", err)
+	}
+	return contextLength(show), nil
+}
+
+func contextLength(show showResponse) int64 {
+	for _, line := range strings.Split(show.Parameters, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "num_ctx" {
+			if n, err := strconv.ParseInt(fields[1], 10, 64); err == nil && n > 0 {
+				return n
+			}
+		}
+	}
+	for key, value := range show.ModelInfo {
+		if !strings.HasSuffix(key, ".context_length") {
+			continue
+		}
+		if n, ok := value.(float64); ok && n > 0 {
+			return int64(n)
+		}
+	}
+	return 0
+}
+
+// post sends body as JSON to path and returns the response when its status
+// is 200.
+func post(ctx context.Context, cli *http.Client, host, path string, body any) (*http.Response, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, host+path, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := cli.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkStatus(resp); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return resp, nil
+}
+
+func checkStatus(resp *http.Response) error {
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	var apiErr struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(data, &apiErr) == nil && apiErr.Error != "" {
+		return fmt.Errorf("ollama error %d: %s", resp.StatusCode, apiErr.Error)
+	}
+	return fmt.Errorf("ollama error %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+}
+
+// normalizeHost accepts the OpenAI-compatible base URL ("…/v1") used with
+// the openai provider and an empty host for the local default.
+func normalizeHost(host string) string {
+	host = strings.TrimSuffix(strings.TrimSpace(host), "/")
+	host = strings.TrimSuffix(host, "/v1")
+	if host == "" {
+		return DefaultHost
+	}
+	return host
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/basenana/friday/core/logger"
 	"github.com/basenana/friday/core/providers"
 	"github.com/basenana/friday/core/providers/common"
+	"github.com/basenana/friday/core/providers/ratelimit"
 	"github.com/basenana/friday/core/session"
 	"github.com/basenana/friday/core/tracing"
 	"github.com/basenana/friday/core/types"
@@ -22,13 +23,12 @@ import (
 	"github.com/openai/openai-go/option"
 	"github.com/openai/openai-go/packages/param"
 	"github.com/openai/openai-go/shared"
-	"golang.org/x/time/rate"
 )
 
 type client struct {
 	openai     openai.Client
 	model      Model
-	apiLimiter *rate.Limiter
+	apiLimiter ratelimit.Limiter
 	logger     logger.Logger
 }
 
@@ -36,6 +36,26 @@ func (c *client) ContextWindow() int64 {
 	return c.model.ContextWindow
 }
 
+// CacheStrategy returns the configured strategy, CacheKey by default as the
+// OpenAI API routes requests by prompt_cache_key. Compatible servers that
+// cache differently configure their own.
+func (c *client) CacheStrategy() providers.CacheStrategy {
+	if c.model.Cache == "" {
+		return providers.CacheKey
+	}
+	return c.model.Cache
+}
+
+// promptCacheKey returns the key to send with request. Only CacheKey sends
+// one; prefix caches match on the prompt itself, which the sorted tools and
+// the system prompt leading the messages keep stable across turns.
+func (c *client) promptCacheKey(request providers.Request) string {
+	if c.CacheStrategy() != providers.CacheKey {
+		return ""
+	}
+	return request.PromptCacheKey()
+}
+
 func (c *client) Completion(ctx context.Context, request providers.Request) providers.Response {
 	c.logger.Infow("llm processing...")
 	ctx, span := tracing.Start(ctx, "llm.openai.completion",
@@ -96,8 +116,9 @@ func (c *client) Completion(ctx context.Context, request providers.Request) prov
 
 		if err = stream.Err(); err != nil {
 			if isTooManyError(err) {
-				time.Sleep(time.Second * 10)
-				c.logger.Warn("too many requests try again")
+				wait := retryAfter(err)
+				c.apiLimiter.Pause(wait)
+				c.logger.Warnw("too many requests, try again", "wait", wait)
 				goto Retry
 			}
 			c.logger.Errorw("completion stream error", "err", err)
@@ -144,8 +165,9 @@ Retry:
 		}...)
 	if err != nil {
 		if isTooManyError(err) {
-			time.Sleep(time.Second * 10)
-			c.logger.Warn("too many requests try again")
+			wait := retryAfter(err)
+			c.apiLimiter.Pause(wait)
+			c.logger.Warnw("too many requests, try again", "wait", wait)
 			goto Retry
 		}
 		c.logger.Errorw("completion error", "err", err)
@@ -208,7 +230,7 @@ func (c *client) chatCompletionNewParams(request providers.Request) *openai.Chat
 	if c.model.PresencePenalty != nil {
 		p.PresencePenalty = param.NewOpt(*c.model.PresencePenalty)
 	}
-	if key := request.PromptCacheKey(); key != "" {
+	if key := c.promptCacheKey(request); key != "" {
 		p.PromptCacheKey = param.NewOpt(key)
 	}
 
@@ -293,6 +315,9 @@ func (c *client) chatCompletionNewParams(request providers.Request) *openai.Chat
 }
 
 func New(host, apiKey string, model Model) providers.Client {
+	if model.API == APIResponses {
+		return &responsesClient{client: newClient(host, apiKey, model), chains: newResponseChains()}
+	}
 	return newClient(host, apiKey, model)
 }
 
@@ -321,11 +346,15 @@ func newClient(host, apiKey string, model Model) *client {
 	if model.QPM == 0 {
 		model.QPM = 20
 	}
+	limiter := model.Limiter
+	if limiter == nil {
+		limiter = ratelimit.New(model.QPM)
+	}
 
 	return &client{
 		openai:     oc,
 		model:      model,
-		apiLimiter: rate.NewLimiter(rate.Limit(float64(model.QPM)/60), int(model.QPM/2)),
+		apiLimiter: limiter,
 		logger:     logger.New("openai"),
 	}
 }
@@ -428,6 +457,7 @@ func (r *response) updateUsage(chunk openai.CompletionUsage) {
 	r.Token.CompletionTokens += chunk.CompletionTokens
 	r.Token.PromptTokens += chunk.PromptTokens
 	r.Token.CachedPromptTokens += chunk.PromptTokensDetails.CachedTokens
+	r.Token.ReasoningTokens += chunk.CompletionTokensDetails.ReasoningTokens
 	r.Token.TotalTokens += chunk.TotalTokens
 }
 
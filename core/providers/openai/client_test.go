@@ -2,8 +2,11 @@ package openai
 
 import (
 	"encoding/json"
+	"fmt"
+	"net/http"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/basenana/friday/core/providers"
 	openaisdk "github.com/openai/openai-go"
@@ -371,3 +374,16 @@ func TestCompatibleChatCompletionNewParamsNormalizesToolHistory(t *testing.T) {
 		t.Fatalf("expected downgraded omitted tool call in assistant content, got %#v", assistant["content"])
 	}
 }
+
+func TestRetryAfterReadsRateLimitHeaders(t *testing.T) {
+	err := fmt.Errorf("completion: %w", &openaisdk.Error{Response: &http.Response{Header: http.Header{"Retry-After": {"3"}}}})
+	if got := retryAfter(err); got != 3*time.Second {
+		t.Fatalf("retryAfter() = %v, want 3s", got)
+	}
+	if got := retryAfter(&openaisdk.Error{Response: &http.Response{Header: http.Header{}}}); got != defaultRetryAfter {
+		t.Fatalf("retryAfter() without header = %v, want %v", got, defaultRetryAfter)
+	}
+	if got := retryAfter(fmt.Errorf("boom")); got != defaultRetryAfter {
+		t.Fatalf("retryAfter() of a plain error = %v, want %v", got, defaultRetryAfter)
+	}
+}
@@ -63,8 +63,9 @@ func (c *compatibleClient) Completion(ctx context.Context, request providers.Req
 
 		if err = stream.Err(); err != nil {
 			if isTooManyError(err) {
-				time.Sleep(time.Second * 10)
-				c.logger.Warn("too many requests try again")
+				wait := retryAfter(err)
+				c.apiLimiter.Pause(wait)
+				c.logger.Warnw("too many requests, try again", "wait", wait)
 				goto Retry
 			}
 			c.logger.Errorw("completion stream error", "err", err)
@@ -92,7 +93,7 @@ func (c *compatibleClient) chatCompletionNewParams(request providers.Request) *o
 	if c.model.PresencePenalty != nil {
 		p.PresencePenalty = param.NewOpt(*c.model.PresencePenalty)
 	}
-	if key := request.PromptCacheKey(); key != "" {
+	if key := c.client.promptCacheKey(request); key != "" {
 		p.PromptCacheKey = param.NewOpt(key)
 	}
 
@@ -3,10 +3,14 @@ package openai
 import (
 	"encoding/json"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/basenana/friday/core/providers"
+	"github.com/basenana/friday/core/providers/ratelimit"
+	"github.com/openai/openai-go"
 )
 
 type xmlParser struct {
@@ -240,6 +244,20 @@ func isTooManyError(err error) bool {
 	return strings.Contains(err.Error(), "429 Too Many Requests")
 }
 
+// defaultRetryAfter is how long to wait after a 429 that does not say.
+const defaultRetryAfter = 10 * time.Second
+
+// retryAfter returns how long a 429 error asks to wait.
+func retryAfter(err error) time.Duration {
+	var apiErr *openai.Error
+	if errors.As(err, &apiErr) && apiErr.Response != nil {
+		if d := ratelimit.RetryAfter(apiErr.Response.Header); d > 0 {
+			return d
+		}
+	}
+	return defaultRetryAfter
+}
+
 type compatibleResponse struct {
 	*providers.CommonResponse
 	buf *xmlParser
@@ -0,0 +1,566 @@
+package openai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/basenana/friday/core/providers"
+	"github.com/basenana/friday/core/providers/common"
+	"github.com/basenana/friday/core/session"
+	"github.com/basenana/friday/core/tracing"
+	"github.com/basenana/friday/core/types"
+	"github.com/invopop/jsonschema"
+	"github.com/openai/openai-go/packages/param"
+	"github.com/openai/openai-go/responses"
+	"github.com/openai/openai-go/shared"
+)
+
+// maxResponseChains bounds how many response ids responsesClient remembers
+// for previous_response_id chaining.
+const maxResponseChains = 64
+
+// responsesClient talks to the OpenAI Responses API.
+//
+// Reasoning items are replayed across turns through
+// types.Message.ReasoningSignature, which holds them JSON encoded, the same
+// way Anthropic thinking signatures are. With Model.ChainResponses a request
+// that extends an earlier one only sends the new messages along with
+// previous_response_id, and falls back to the full history when the server
+// no longer knows the response.
+type responsesClient struct {
+	*client
+	chains *responseChains
+}
+
+func (c *responsesClient) Completion(ctx context.Context, request providers.Request) providers.Response {
+	c.logger.Infow("llm processing...")
+	ctx, span := tracing.Start(ctx, "llm.openai.responses",
+		tracing.WithAttributes(tracing.String("model", string(c.model.Name))),
+	)
+	resp := newResponsesResponse(request)
+	go func() {
+		defer span.End()
+		defer resp.close()
+		var (
+			p, key  = c.responseNewParams(request, c.model.ChainResponses)
+			startAt = time.Now()
+			err     error
+		)
+
+		defer func() {
+			span.SetAttributes(
+				tracing.Int("prompt_tokens", resp.Token.PromptTokens),
+				tracing.Int("completion_tokens", resp.Token.CompletionTokens),
+				tracing.Int("cached_prompt_tokens", resp.Token.CachedPromptTokens),
+			)
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(tracing.StatusError, err.Error())
+			} else {
+				span.SetStatus(tracing.StatusOK, "")
+			}
+			sec := time.Since(startAt).Seconds()
+			if sec < 1 {
+				sec = 1
+			}
+			tps := float64(resp.Token.CompletionTokens) / sec
+			c.logger.Infow("completion-with-streaming finish", "elapsed", time.Since(startAt).String(), "tps", fmt.Sprintf("%.2f", tps))
+		}()
+
+	Retry:
+		if err = c.apiLimiter.Wait(ctx); err != nil {
+			c.logger.Errorw("new completion stream error", "err", err)
+			resp.fail(err)
+			return
+		}
+		if time.Since(startAt).Seconds() > 1 {
+			c.logger.Infow("client-side llm api throttled", "wait", time.Since(startAt).String())
+		}
+
+		stream := c.openai.Responses.NewStreaming(ctx, *p)
+		for stream.Next() {
+			resp.nextEvent(stream.Current())
+		}
+		if err = stream.Err(); err == nil {
+			err = resp.err
+		}
+
+		if err != nil {
+			if !resp.started && isTooManyError(err) {
+				wait := retryAfter(err)
+				c.apiLimiter.Pause(wait)
+				c.logger.Warnw("too many requests, try again", "wait", wait)
+				goto Retry
+			}
+			if !resp.started && p.PreviousResponseID.Valid() && isPreviousResponseError(err) {
+				c.logger.Warnw("previous response unavailable, resending full history", "response", p.PreviousResponseID.Value)
+				c.chains.forget(p.PreviousResponseID.Value)
+				p, key = c.responseNewParams(request, false)
+				goto Retry
+			}
+			c.logger.Errorw("completion stream error", "err", err)
+			resp.fail(err)
+			return
+		}
+
+		if c.model.ChainResponses && resp.responseID != "" {
+			c.chains.put(key, resp.responseID)
+		}
+		resp.flushReasoningItems()
+
+		// Fallback: if API didn't return token counts, estimate using FuzzyTokens
+		resp.applyTokenFallback(request.Messages())
+	}()
+	return resp
+}
+
+func (c *responsesClient) CompletionNonStreaming(ctx context.Context, request providers.Request) (_ string, retErr error) {
+	ctx, span := tracing.Start(ctx, "llm.openai.responses_sync",
+		tracing.WithAttributes(tracing.String("model", string(c.model.Name))),
+	)
+	defer span.End()
+	defer func() { tracing.DeferStatus(span, &retErr) }()
+
+	c.logger.Infow("llm processing...")
+	var (
+		p, _    = c.responseNewParams(request, false)
+		startAt = time.Now()
+		err     error
+	)
+
+	defer func() {
+		c.logger.Infow("completion-non-streaming finish", "elapsed", time.Since(startAt).String())
+	}()
+
+Retry:
+	if err = c.apiLimiter.Wait(ctx); err != nil {
+		c.logger.Errorw("new completion error", "err", err)
+		return "", err
+	}
+	if time.Since(startAt).Seconds() > 1 {
+		c.logger.Infow("client-side llm api throttled", "wait", time.Since(startAt).String())
+	}
+
+	response, err := c.openai.Responses.New(ctx, *p)
+	if err != nil {
+		if isTooManyError(err) {
+			wait := retryAfter(err)
+			c.apiLimiter.Pause(wait)
+			c.logger.Warnw("too many requests, try again", "wait", wait)
+			goto Retry
+		}
+		c.logger.Errorw("completion error", "err", err)
+		return "", err
+	}
+	if response.Error.Message != "" {
+		return "", fmt.Errorf("response failed: %s", response.Error.Message)
+	}
+
+	var content strings.Builder
+	for _, item := range response.Output {
+		if item.Type != "message" {
+			continue
+		}
+		for _, part := range item.Content {
+			if part.Type == "output_text" {
+				content.WriteString(part.Text)
+			}
+		}
+	}
+	return content.String(), nil
+}
+
+func (c *responsesClient) StructuredPredict(ctx context.Context, request providers.Request, model any) (retErr error) {
+	ctx, span := tracing.Start(ctx, "llm.openai.structured_predict",
+		tracing.WithAttributes(tracing.String("model", string(c.model.Name))),
+	)
+	defer span.End()
+	defer func() { tracing.DeferStatus(span, &retErr) }()
+
+	messages := request.Messages()
+	if len(messages) == 0 || messages[0].Content == "" {
+		return fmt.Errorf("user request is empty")
+	}
+	prompt := DEFAULT_STRUCTURED_PREDICT_PROMPT
+	prompt = strings.ReplaceAll(prompt, "{insert_user_request_here}", messages[0].Content)
+	schemaRaw, _ := json.Marshal(jsonschema.Reflect(model))
+	prompt = strings.ReplaceAll(prompt, "{insert_json_schema_here}", string(schemaRaw))
+
+	return common.StructuredPredictWithFallback(
+		ctx,
+		providers.NewPromptRequest(prompt),
+		model,
+		c.CompletionNonStreaming,
+		c.Completion,
+		c.logger,
+	)
+}
+
+// responseNewParams builds the request and returns the chain key that
+// identifies its input. With chain set, messages already covered by an
+// earlier response are replaced by its previous_response_id.
+func (c *responsesClient) responseNewParams(request providers.Request, chain bool) (*responses.ResponseNewParams, string) {
+	p := responses.ResponseNewParams{
+		Model: shared.ResponsesModel(c.model.Name),
+		Store: param.NewOpt(c.model.ChainResponses),
+	}
+
+	reasoning := isReasoningModel(c.model.Name)
+	if c.model.Temperature != nil && !reasoning {
+		p.Temperature = param.NewOpt(*c.model.Temperature)
+	}
+	if c.model.MaxTokens > 0 {
+		p.MaxOutputTokens = param.NewOpt(c.model.MaxTokens)
+	}
+	if key := c.client.promptCacheKey(request); key != "" {
+		p.PromptCacheKey = param.NewOpt(key)
+	}
+	if reasoning {
+		p.Reasoning = shared.ReasoningParam{Summary: shared.ReasoningSummaryAuto}
+		if !c.model.ChainResponses {
+			// Unstored responses can only be continued from their encrypted reasoning.
+			p.Include = []responses.ResponseIncludable{responses.ResponseIncludableReasoningEncryptedContent}
+		}
+	}
+
+	messages := normalizeOpenAIToolMessages(common.RepairToolHistory(request.Messages()))
+	var instructions []string
+	for len(messages) > 0 && messages[0].Role == types.RoleSystem {
+		instructions = append(instructions, messages[0].Content)
+		messages = messages[1:]
+	}
+	if len(instructions) > 0 {
+		p.Instructions = param.NewOpt(strings.Join(instructions, "\n\n"))
+	}
+
+	digests := historyDigests(messages)
+	var key string
+	if len(digests) > 0 {
+		key = digests[len(digests)-1]
+	}
+
+	start := 0
+	if chain {
+		if id, next := c.chains.resume(messages, digests); id != "" {
+			p.PreviousResponseID = param.NewOpt(id)
+			start = next
+		}
+	}
+	p.Input = responses.ResponseNewParamsInputUnion{OfInputItemList: responseInputItems(messages[start:])}
+
+	for _, t := range sortedToolDefines(request.ToolDefines()) {
+		p.Tools = append(p.Tools, responses.ToolUnionParam{
+			OfFunction: &responses.FunctionToolParam{
+				Name:        t.GetName(),
+				Strict:      param.NewOpt(c.model.StrictMode),
+				Description: param.NewOpt(t.GetDescription()),
+				Parameters:  t.GetParameters(),
+			},
+		})
+	}
+
+	return &p, key
+}
+
+func responseInputItems(messages []types.Message) responses.ResponseInputParam {
+	var (
+		items     = responses.ResponseInputParam{}
+		reasoning = make(map[string]struct{})
+	)
+	for _, msg := range messages {
+		switch msg.Role {
+		case types.RoleSystem:
+			items = append(items, responses.ResponseInputItemParamOfMessage(msg.Content, responses.EasyInputMessageRoleSystem))
+
+		case types.RoleUser:
+			if msg.Image == nil {
+				items = append(items, responses.ResponseInputItemParamOfMessage(msg.Content, responses.EasyInputMessageRoleUser))
+				continue
+			}
+			var content responses.ResponseInputMessageContentListParam
+			if msg.Content != "" {
+				content = append(content, responses.ResponseInputContentUnionParam{
+					OfInputText: &responses.ResponseInputTextParam{Text: msg.Content},
+				})
+			}
+			imageURL := msg.Image.URL
+			if msg.Image.Type == types.ImageTypeBase64 {
+				imageURL = fmt.Sprintf("data:%s;base64,%s", msg.Image.MediaType, msg.Image.Data)
+			}
+			content = append(content, responses.ResponseInputContentUnionParam{
+				OfInputImage: &responses.ResponseInputImageParam{
+					ImageURL: param.NewOpt(imageURL),
+					Detail:   responses.ResponseInputImageDetailAuto,
+				},
+			})
+			items = append(items, responses.ResponseInputItemParamOfMessage(content, responses.EasyInputMessageRoleUser))
+
+		case types.RoleAgent:
+			items = append(items, responses.ResponseInputItemParamOfMessage(msg.Content, responses.EasyInputMessageRoleUser))
+
+		case types.RoleAssistant:
+			// A reasoning item must be followed by the output it produced, and
+			// tool calls split across messages share the same items.
+			if msg.Content != "" || len(msg.ToolCalls) > 0 {
+				for _, item := range decodeReasoningItems(msg.ReasoningSignature) {
+					if _, ok := reasoning[item.ID]; ok {
+						continue
+					}
+					reasoning[item.ID] = struct{}{}
+					rp := responses.ResponseInputItemParamOfReasoning(item.ID, []responses.ResponseReasoningItemSummaryParam{})
+					if item.EncryptedContent != "" {
+						rp.OfReasoning.EncryptedContent = param.NewOpt(item.EncryptedContent)
+					}
+					items = append(items, rp)
+				}
+			}
+			if msg.Content != "" {
+				items = append(items, responses.ResponseInputItemParamOfMessage(msg.Content, responses.EasyInputMessageRoleAssistant))
+			}
+			for _, tc := range msg.ToolCalls {
+				items = append(items, responses.ResponseInputItemParamOfFunctionCall(tc.Arguments, tc.ID, tc.Name))
+			}
+
+		case types.RoleTool:
+			if msg.ToolResult != nil {
+				items = append(items, responses.ResponseInputItemParamOfFunctionCallOutput(msg.ToolResult.CallID, msg.ToolResult.Content))
+			}
+		}
+	}
+	return items
+}
+
+// reasoningItem is the part of a Responses API reasoning item that has to be
+// sent back to continue the conversation.
+type reasoningItem struct {
+	ID               string `json:"id"`
+	EncryptedContent string `json:"encrypted_content,omitempty"`
+}
+
+// decodeReasoningItems ignores signatures written by other providers or by
+// the Chat Completions API.
+func decodeReasoningItems(signature string) []reasoningItem {
+	if !strings.HasPrefix(signature, "[") {
+		return nil
+	}
+	var items []reasoningItem
+	if json.Unmarshal([]byte(signature), &items) != nil {
+		return nil
+	}
+	valid := items[:0]
+	for _, item := range items {
+		if strings.HasPrefix(item.ID, "rs_") {
+			valid = append(valid, item)
+		}
+	}
+	return valid
+}
+
+func isReasoningModel(name string) bool {
+	name = strings.ToLower(name)
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	for _, prefix := range []string{"o1", "o3", "o4", "gpt-5", "codex"} {
+		if strings.HasPrefix(name, prefix) {
+			return !strings.HasPrefix(name, "gpt-5-chat")
+		}
+	}
+	return false
+}
+
+func isPreviousResponseError(err error) bool {
+	return strings.Contains(err.Error(), "previous_response")
+}
+
+// historyDigests returns, for each message, a digest of the history up to and
+// including it. Token counts and timestamps are left out as they are
+// rewritten after the message was sent.
+func historyDigests(messages []types.Message) []string {
+	var (
+		digests = make([]string, len(messages))
+		prev    []byte
+	)
+	for i, msg := range messages {
+		data, _ := json.Marshal(struct {
+			Role               types.MessageRole   `json:"role"`
+			Content            string              `json:"content,omitempty"`
+			ReasoningSignature string              `json:"reasoning_signature,omitempty"`
+			Image              *types.ImageContent `json:"image,omitempty"`
+			ToolCalls          []types.ToolCall    `json:"tool_calls,omitempty"`
+			ToolResult         *types.ToolResult   `json:"tool_result,omitempty"`
+		}{msg.Role, msg.Content, msg.ReasoningSignature, msg.Image, msg.ToolCalls, msg.ToolResult})
+		h := sha256.New()
+		h.Write(prev)
+		h.Write(data)
+		prev = h.Sum(nil)
+		digests[i] = hex.EncodeToString(prev)
+	}
+	return digests
+}
+
+// responseChains maps the digest of a request's input to the id of the
+// response it got.
+type responseChains struct {
+	mu    sync.Mutex
+	ids   map[string]string
+	order []string
+}
+
+func newResponseChains() *responseChains {
+	return &responseChains{ids: make(map[string]string)}
+}
+
+// resume finds the latest response whose input is a prefix of messages and
+// which is followed by its assistant output. It returns the response id and
+// the index of the first message the response does not cover.
+func (r *responseChains) resume(messages []types.Message, digests []string) (string, int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := len(digests) - 2; i >= 0; i-- {
+		id, ok := r.ids[digests[i]]
+		if !ok {
+			continue
+		}
+		next := i + 1
+		for next < len(messages) && messages[next].Role == types.RoleAssistant {
+			next++
+		}
+		if next == i+1 || next == len(messages) {
+			return "", 0
+		}
+		return id, next
+	}
+	return "", 0
+}
+
+func (r *responseChains) put(key, id string) {
+	if key == "" {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.ids[key]; !ok {
+		r.order = append(r.order, key)
+	}
+	r.ids[key] = id
+	for len(r.order) > maxResponseChains {
+		delete(r.ids, r.order[0])
+		r.order = r.order[1:]
+	}
+}
+
+func (r *responseChains) forget(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for key, v := range r.ids {
+		if v == id {
+			delete(r.ids, key)
+		}
+	}
+}
+
+type responsesResponse struct {
+	*providers.CommonResponse
+
+	request    providers.Request
+	responseID string
+	reasoning  []reasoningItem
+	started    bool
+	err        error
+
+	// accumulatedContent tracks the content for token fallback calculation
+	accumulatedContent string
+}
+
+func (r *responsesResponse) nextEvent(event responses.ResponseStreamEventUnion) {
+	switch event.Type {
+	case "response.output_text.delta":
+		r.started = true
+		r.accumulatedContent += event.Delta.OfString
+		r.Stream <- providers.Delta{Content: event.Delta.OfString}
+
+	case "response.reasoning_summary_part.added":
+		if event.SummaryIndex > 0 {
+			r.Stream <- providers.Delta{Reasoning: "\n\n"}
+		}
+
+	case "response.reasoning_summary_text.delta":
+		r.started = true
+		r.Stream <- providers.Delta{Reasoning: event.Delta.OfString}
+
+	case "response.output_item.done":
+		switch event.Item.Type {
+		case "function_call":
+			r.started = true
+			r.Stream <- providers.Delta{ToolUse: []providers.ToolCall{{
+				ID:        event.Item.CallID,
+				Name:      event.Item.Name,
+				Arguments: event.Item.Arguments,
+			}}}
+		case "reasoning":
+			r.reasoning = append(r.reasoning, reasoningItem{ID: event.Item.ID, EncryptedContent: event.Item.EncryptedContent})
+		}
+
+	case "response.completed", "response.incomplete":
+		r.responseID = event.Response.ID
+		r.updateUsage(event.Response.Usage)
+
+	case "response.failed":
+		r.updateUsage(event.Response.Usage)
+		r.err = fmt.Errorf("response failed: %s", event.Response.Error.Message)
+
+	case "error":
+		r.err = fmt.Errorf("response error %s: %s", event.Code, event.Message)
+	}
+}
+
+// flushReasoningItems emits all reasoning items of the response as one
+// signature, since only the last signature of a turn is kept.
+func (r *responsesResponse) flushReasoningItems() {
+	if len(r.reasoning) == 0 {
+		return
+	}
+	data, err := json.Marshal(r.reasoning)
+	if err != nil {
+		return
+	}
+	r.Stream <- providers.Delta{ReasoningSignature: string(data)}
+}
+
+func (r *responsesResponse) updateUsage(usage responses.ResponseUsage) {
+	r.Token.PromptTokens = usage.InputTokens
+	r.Token.CachedPromptTokens = usage.InputTokensDetails.CachedTokens
+	r.Token.CompletionTokens = usage.OutputTokens
+	r.Token.ReasoningTokens = usage.OutputTokensDetails.ReasoningTokens
+	r.Token.TotalTokens = usage.TotalTokens
+}
+
+// applyTokenFallback fills in token counts using FuzzyTokens if API didn't return them
+func (r *responsesResponse) applyTokenFallback(requestMessages []types.Message) {
+	overhead := session.EstimateRequestOverhead(r.request)
+	r.Token.PromptTokens, r.Token.CompletionTokens, r.Token.TotalTokens =
+		common.ApplyTokenFallback(r.Token.PromptTokens, r.Token.CompletionTokens, r.accumulatedContent, requestMessages, overhead)
+}
+
+func (r *responsesResponse) fail(err error) { r.Err <- err }
+
+func (r *responsesResponse) close() {
+	close(r.Stream)
+	close(r.Err)
+}
+
+func newResponsesResponse(req providers.Request) *responsesResponse {
+	return &responsesResponse{CommonResponse: providers.NewCommonResponse(), request: req}
+}
+
+var (
+	_ providers.Client   = (*responsesClient)(nil)
+	_ providers.Response = (*responsesResponse)(nil)
+)
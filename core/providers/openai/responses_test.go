@@ -0,0 +1,220 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/basenana/friday/core/providers"
+	"github.com/basenana/friday/core/types"
+)
+
+// fakeResponsesServer records the bodies posted to /responses and streams
+// the events returned by reply for each of them.
+func fakeResponsesServer(t *testing.T, reply func(n int, body map[string]any) (int, []string)) (*httptest.Server, *[]map[string]any) {
+	t.Helper()
+	var bodies []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/responses" {
+			http.NotFound(w, r)
+			return
+		}
+		data, _ := io.ReadAll(r.Body)
+		var body map[string]any
+		if err := json.Unmarshal(data, &body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		bodies = append(bodies, body)
+
+		status, events := reply(len(bodies), body)
+		if status != http.StatusOK {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			fmt.Fprint(w, events[0])
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range events {
+			fmt.Fprintf(w, "data: %s\n\n", event)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &bodies
+}
+
+func completedEvent(id string) string {
+	return `{"type":"response.completed","response":{"id":"` + id + `","usage":{"input_tokens":120,"input_tokens_details":{"cached_tokens":96},"output_tokens":15,"output_tokens_details":{"reasoning_tokens":8},"total_tokens":135}}}`
+}
+
+func drainResponse(t *testing.T, resp providers.Response) (content, reasoning, signature string, calls []providers.ToolCall) {
+	t.Helper()
+	for d := range resp.Message() {
+		content += d.Content
+		reasoning += d.Reasoning
+		if d.ReasoningSignature != "" {
+			signature = d.ReasoningSignature
+		}
+		calls = append(calls, d.ToolUse...)
+	}
+	if err := <-resp.Error(); err != nil {
+		t.Fatalf("completion error: %v", err)
+	}
+	return
+}
+
+func TestResponsesCompletionStreamsReasoningToolCallsAndCachedTokens(t *testing.T) {
+	server, bodies := fakeResponsesServer(t, func(int, map[string]any) (int, []string) {
+		return http.StatusOK, []string{
+			`{"type":"response.reasoning_summary_part.added","summary_index":0}`,
+			`{"type":"response.reasoning_summary_text.delta","delta":"Listing first."}`,
+			`{"type":"response.output_item.done","item":{"type":"reasoning","id":"rs_1","summary":[],"encrypted_content":"enc-1"}}`,
+			`{"type":"response.output_text.delta","delta":"Let me look."}`,
+			`{"type":"response.output_item.done","item":{"type":"function_call","id":"fc_1","call_id":"call_1","name":"bash","arguments":"{\"command\":\"ls\"}"}}`,
+			completedEvent("resp_1"),
+		}
+	})
+	cli := New(server.URL, "key", Model{Name: "gpt-5", API: APIResponses})
+
+	req := providers.NewRequest("You are helpful.", types.Message{Role: types.RoleUser, Content: "list files"})
+	req.SetToolDefines([]providers.ToolDefine{providers.NewToolDefine("bash", "Run a command", map[string]any{"type": "object"})})
+	content, reasoning, signature, calls := drainResponse(t, cli.Completion(context.Background(), req))
+
+	if content != "Let me look." || reasoning != "Listing first." {
+		t.Fatalf("content = %q, reasoning = %q", content, reasoning)
+	}
+	if len(calls) != 1 || calls[0].ID != "call_1" || calls[0].Name != "bash" || calls[0].Arguments != `{"command":"ls"}` {
+		t.Fatalf("tool calls = %+v", calls)
+	}
+	if signature != `[{"id":"rs_1","encrypted_content":"enc-1"}]` {
+		t.Fatalf("signature = %q", signature)
+	}
+
+	body := (*bodies)[0]
+	if body["instructions"] != "You are helpful.\n\n" || body["store"] != false {
+		t.Fatalf("body = %v", body)
+	}
+	if include, _ := body["include"].([]any); len(include) != 1 || include[0] != "reasoning.encrypted_content" {
+		t.Fatalf("include = %v", body["include"])
+	}
+	if _, ok := body["temperature"]; ok {
+		t.Fatal("reasoning models should not be sent a temperature")
+	}
+}
+
+func TestResponsesCompletionReportsCachedPromptTokens(t *testing.T) {
+	server, _ := fakeResponsesServer(t, func(int, map[string]any) (int, []string) {
+		return http.StatusOK, []string{`{"type":"response.output_text.delta","delta":"hi"}`, completedEvent("resp_1")}
+	})
+	cli := New(server.URL, "key", Model{Name: "gpt-4.1", API: APIResponses})
+
+	resp := cli.Completion(context.Background(), providers.NewPromptRequest("hello"))
+	drainResponse(t, resp)
+	if want := (providers.Tokens{PromptTokens: 120, CachedPromptTokens: 96, CompletionTokens: 15, ReasoningTokens: 8, TotalTokens: 135}); resp.Tokens() != want {
+		t.Fatalf("tokens = %+v, want %+v", resp.Tokens(), want)
+	}
+}
+
+func TestResponseInputItemsReplaysReasoningOnce(t *testing.T) {
+	signature := `[{"id":"rs_1","encrypted_content":"enc-1"}]`
+	items := responseInputItems([]types.Message{
+		{Role: types.RoleUser, Content: "Look", Image: &types.ImageContent{Type: types.ImageTypeBase64, MediaType: "image/png", Data: "aGVsbG8="}},
+		{Role: types.RoleAssistant, ReasoningSignature: signature, ToolCalls: []types.ToolCall{{ID: "call_1", Name: "bash", Arguments: `{}`}}},
+		{Role: types.RoleTool, ToolResult: &types.ToolResult{CallID: "call_1", Content: "a"}},
+		{Role: types.RoleAssistant, ReasoningSignature: signature, ToolCalls: []types.ToolCall{{ID: "call_2", Name: "bash", Arguments: `{}`}}},
+		{Role: types.RoleTool, ToolResult: &types.ToolResult{CallID: "call_2", Content: "b"}},
+		{Role: types.RoleAssistant, Content: "Done.", ReasoningSignature: "anthropic-signature"},
+	})
+
+	raw, err := json.Marshal(items)
+	if err != nil {
+		t.Fatalf("marshal input: %v", err)
+	}
+	var decoded []map[string]any
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		t.Fatalf("unmarshal input: %v", err)
+	}
+
+	var kinds []string
+	for _, item := range decoded {
+		typ, _ := item["type"].(string)
+		if typ == "" || typ == "message" {
+			typ = fmt.Sprint(item["role"])
+		}
+		kinds = append(kinds, typ)
+	}
+	want := "user,reasoning,function_call,function_call_output,function_call,function_call_output,assistant"
+	if got := strings.Join(kinds, ","); got != want {
+		t.Fatalf("items = %s, want %s", got, want)
+	}
+	if decoded[1]["encrypted_content"] != "enc-1" || decoded[1]["id"] != "rs_1" {
+		t.Fatalf("reasoning item = %v", decoded[1])
+	}
+	if !strings.Contains(string(raw), `"image_url":"data:image/png;base64,aGVsbG8="`) {
+		t.Fatalf("image missing: %s", raw)
+	}
+}
+
+func TestResponsesChainingSendsOnlyNewMessages(t *testing.T) {
+	server, bodies := fakeResponsesServer(t, func(n int, body map[string]any) (int, []string) {
+		if n == 3 {
+			return http.StatusNotFound, []string{`{"error":{"message":"Previous response with id 'resp_2' not found.","type":"invalid_request_error","param":"previous_response_id"}}`}
+		}
+		return http.StatusOK, []string{`{"type":"response.output_text.delta","delta":"ok"}`, completedEvent(fmt.Sprintf("resp_%d", n))}
+	})
+	cli := New(server.URL, "key", Model{Name: "gpt-4.1", API: APIResponses, ChainResponses: true})
+
+	history := []types.Message{{Role: types.RoleUser, Content: "first"}}
+	drainResponse(t, cli.Completion(context.Background(), providers.NewRequest("sys", history...)))
+
+	history = append(history,
+		types.Message{Role: types.RoleAssistant, Content: "ok", Tokens: 3},
+		types.Message{Role: types.RoleUser, Content: "second"},
+	)
+	drainResponse(t, cli.Completion(context.Background(), providers.NewRequest("sys", history...)))
+
+	second := (*bodies)[1]
+	if second["previous_response_id"] != "resp_1" || second["store"] != true {
+		t.Fatalf("second request = %v", second)
+	}
+	if input := second["input"].([]any); len(input) != 1 || input[0].(map[string]any)["content"] != "second" {
+		t.Fatalf("second input = %v", second["input"])
+	}
+
+	// The server forgot resp_2: the full history is sent instead.
+	history = append(history,
+		types.Message{Role: types.RoleAssistant, Content: "ok"},
+		types.Message{Role: types.RoleUser, Content: "third"},
+	)
+	drainResponse(t, cli.Completion(context.Background(), providers.NewRequest("sys", history...)))
+
+	if len(*bodies) != 4 {
+		t.Fatalf("requests = %d, want 4", len(*bodies))
+	}
+	if (*bodies)[2]["previous_response_id"] != "resp_2" {
+		t.Fatalf("third request = %v", (*bodies)[2])
+	}
+	retry := (*bodies)[3]
+	if _, ok := retry["previous_response_id"]; ok || len(retry["input"].([]any)) != 5 {
+		t.Fatalf("retry request = %v", retry)
+	}
+}
+
+func TestIsReasoningModel(t *testing.T) {
+	for name, want := range map[string]bool{
+		"gpt-5":             true,
+		"o4-mini":           true,
+		"openai/o3":         true,
+		"gpt-5-chat-latest": false,
+		"gpt-4.1":           false,
+	} {
+		if got := isReasoningModel(name); got != want {
+			t.Errorf("isReasoningModel(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
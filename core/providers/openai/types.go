@@ -2,6 +2,9 @@ package openai
 
 import (
 	"encoding/xml"
+
+	"github.com/basenana/friday/core/providers"
+	"github.com/basenana/friday/core/providers/ratelimit"
 )
 
 type Model struct {
@@ -15,8 +18,29 @@ type Model struct {
 	Proxy              string
 	ContextWindow      int64
 	InsecureSkipVerify bool
+	// Limiter paces the calls instead of a limiter of QPM private to this
+	// client, e.g. one shared with other processes.
+	Limiter ratelimit.Limiter
+
+	// API selects the wire protocol: APIChatCompletions (the default) or
+	// APIResponses.
+	API string
+	// ChainResponses lets the Responses API store responses and continue
+	// from the previous one with previous_response_id instead of resending
+	// the whole history.
+	ChainResponses bool
+	// Cache is how prompts get cached: providers.CacheKey (the default)
+	// sends prompt_cache_key, providers.CachePrefix suits servers with
+	// automatic prefix caching such as llama.cpp and vLLM, and
+	// providers.CacheNone sends nothing.
+	Cache providers.CacheStrategy
 }
 
+const (
+	APIChatCompletions = "chat_completions"
+	APIResponses       = "responses"
+)
+
 type ToolUse struct {
 	XMLName   xml.Name `xml:"tool_use" json:"-"`
 	ID        string   `xml:"id" json:"id"`
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package ratelimit
+
+import "os"
+
+// Without flock the bucket is only serialized within this process.
+func lockFile(f *os.File) error { return nil }
+
+func unlockFile(f *os.File) {}
@@ -0,0 +1,99 @@
+// Package ratelimit paces the calls of provider clients, optionally sharing
+// one token bucket between every process that calls the same account.
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Limiter paces the calls of a provider client.
+type Limiter interface {
+	// Wait blocks until a call may be made.
+	Wait(ctx context.Context) error
+	// Pause holds back every call for d, e.g. after the API answered 429.
+	Pause(d time.Duration)
+}
+
+// New returns an in-process Limiter allowing qpm calls per minute, with
+// bursts of half that. A qpm of zero or less means no limit.
+func New(qpm int64) Limiter {
+	l := &limiter{rate: rate.NewLimiter(rate.Inf, 0)}
+	if qpm > 0 {
+		l.rate = rate.NewLimiter(rate.Limit(float64(qpm)/60), burst(qpm))
+	}
+	return l
+}
+
+func burst(qpm int64) int {
+	return max(1, int(qpm/2))
+}
+
+type limiter struct {
+	rate *rate.Limiter
+
+	mu          sync.Mutex
+	pausedUntil time.Time
+}
+
+func (l *limiter) Wait(ctx context.Context) error {
+	l.mu.Lock()
+	pause := time.Until(l.pausedUntil)
+	l.mu.Unlock()
+	if pause > 0 {
+		if err := sleep(ctx, pause); err != nil {
+			return err
+		}
+	}
+	return l.rate.Wait(ctx)
+}
+
+func (l *limiter) Pause(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if until := time.Now().Add(d); until.After(l.pausedUntil) {
+		l.pausedUntil = until
+	}
+}
+
+// RetryAfter returns how long a response asks to wait before retrying, from
+// its retry-after-ms or Retry-After header, or zero when it does not say.
+func RetryAfter(header http.Header) time.Duration {
+	if header == nil {
+		return 0
+	}
+	if ms, err := strconv.ParseFloat(strings.TrimSpace(header.Get("retry-after-ms")), 64); err == nil && ms > 0 {
+		return time.Duration(ms * float64(time.Millisecond))
+	}
+	value := strings.TrimSpace(header.Get("Retry-After"))
+	if value == "" {
+		return 0
+	}
+	if sec, err := strconv.ParseFloat(value, 64); err == nil {
+		if sec <= 0 {
+			return 0
+		}
+		return time.Duration(sec * float64(time.Second))
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(0, time.Until(at))
+	}
+	return 0
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,88 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		name   string
+		header http.Header
+		want   time.Duration
+	}{
+		{name: "none", header: http.Header{}, want: 0},
+		{name: "seconds", header: http.Header{"Retry-After": {"3"}}, want: 3 * time.Second},
+		{name: "milliseconds first", header: http.Header{"Retry-After": {"3"}, "Retry-After-Ms": {"250"}}, want: 250 * time.Millisecond},
+		{name: "negative", header: http.Header{"Retry-After": {"-1"}}, want: 0},
+		{name: "garbage", header: http.Header{"Retry-After": {"soon"}}, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RetryAfter(tt.header); got != tt.want {
+				t.Fatalf("RetryAfter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	at := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	got := RetryAfter(http.Header{"Retry-After": {at}})
+	if got <= 50*time.Second || got > time.Minute {
+		t.Fatalf("RetryAfter(http date) = %v, want about a minute", got)
+	}
+}
+
+func TestNew_PauseDelaysWait(t *testing.T) {
+	l := New(0)
+	l.Pause(50 * time.Millisecond)
+
+	start := time.Now()
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Fatalf("Wait() returned after %v, want the pause honored", elapsed)
+	}
+}
+
+func TestShared_SameKeySharesBucket(t *testing.T) {
+	dir := t.TempDir()
+	if Shared(dir, "openai|key", 60) != Shared(dir, "openai|key", 60) {
+		t.Fatal("Shared() returned different limiters for the same key")
+	}
+
+	// Another process sees the same file through its own limiter.
+	other := &sharedLimiter{path: Shared(dir, "openai|key", 60).(*sharedLimiter).path, qpm: 60}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	for i := 0; i < burst(60); i++ {
+		if err := Shared(dir, "openai|key", 60).Wait(ctx); err != nil {
+			t.Fatalf("Wait() #%d error = %v", i, err)
+		}
+	}
+	if err := other.Wait(ctx); err == nil {
+		t.Fatal("Wait() after the burst was spent elsewhere returned immediately")
+	}
+
+	// A different key has a bucket of its own.
+	if err := Shared(dir, "anthropic|key", 60).Wait(context.Background()); err != nil {
+		t.Fatalf("Wait() on another key error = %v", err)
+	}
+}
+
+func TestShared_PauseIsShared(t *testing.T) {
+	dir := t.TempDir()
+	l := Shared(dir, "gemini|key", 0)
+	other := &sharedLimiter{path: l.(*sharedLimiter).path}
+
+	l.Pause(time.Minute)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := other.Wait(ctx); err == nil {
+		t.Fatal("Wait() returned during a pause set by another limiter")
+	}
+}
@@ -0,0 +1,146 @@
+package ratelimit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+var (
+	sharedMu sync.Mutex
+	shared   = make(map[string]*sharedLimiter)
+)
+
+// Shared returns the Limiter of key kept in dir, which every client and
+// process using the same dir and key draws from, at qpm calls per minute.
+// Keys name an account, e.g. provider and API key; they are hashed before
+// use. A qpm of zero or less means no limit, but pauses are still shared.
+func Shared(dir, key string, qpm int64) Limiter {
+	sum := sha256.Sum256([]byte(key))
+	path := filepath.Join(dir, hex.EncodeToString(sum[:8])+".json")
+
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+	if l, ok := shared[path]; ok {
+		return l
+	}
+	l := &sharedLimiter{path: path, qpm: qpm}
+	shared[path] = l
+	return l
+}
+
+// bucket is the token bucket saved in a shared limiter's file.
+type bucket struct {
+	Tokens      float64   `json:"tokens"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	PausedUntil time.Time `json:"paused_until,omitzero"`
+}
+
+// sharedLimiter is a token bucket in a file, updated under an exclusive file
+// lock so concurrent processes take turns.
+type sharedLimiter struct {
+	path string
+	qpm  int64
+
+	// mu serializes the goroutines of this process; the file lock does
+	// the same across processes.
+	mu sync.Mutex
+}
+
+func (l *sharedLimiter) Wait(ctx context.Context) error {
+	for {
+		var wait time.Duration
+		err := l.update(func(b *bucket, now time.Time) {
+			l.refill(b, now)
+			switch {
+			case now.Before(b.PausedUntil):
+				wait = b.PausedUntil.Sub(now)
+			case l.qpm <= 0:
+			case b.Tokens >= 1:
+				b.Tokens--
+			default:
+				wait = time.Duration((1 - b.Tokens) / l.perSecond() * float64(time.Second))
+			}
+		})
+		if err != nil {
+			return fmt.Errorf("shared rate limit: %w", err)
+		}
+		if wait <= 0 {
+			return nil
+		}
+		if err := sleep(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+func (l *sharedLimiter) Pause(d time.Duration) {
+	_ = l.update(func(b *bucket, now time.Time) {
+		l.refill(b, now)
+		if until := now.Add(d); until.After(b.PausedUntil) {
+			b.PausedUntil = until
+		}
+	})
+}
+
+func (l *sharedLimiter) perSecond() float64 {
+	return float64(l.qpm) / 60
+}
+
+// refill adds the tokens earned since the bucket was last updated.
+func (l *sharedLimiter) refill(b *bucket, now time.Time) {
+	capacity := float64(burst(l.qpm))
+	if b.UpdatedAt.IsZero() {
+		b.Tokens = capacity
+	} else if elapsed := now.Sub(b.UpdatedAt); elapsed > 0 {
+		b.Tokens = min(capacity, b.Tokens+elapsed.Seconds()*l.perSecond())
+	}
+	b.UpdatedAt = now
+}
+
+// update applies fn to the bucket in the file while holding its lock.
+func (l *sharedLimiter) update(fn func(b *bucket, now time.Time)) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(l.path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := lockFile(f); err != nil {
+		return err
+	}
+	defer unlockFile(f)
+
+	var b bucket
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return err
+	}
+	if len(data) > 0 {
+		// A corrupt bucket is replaced by a full one.
+		_ = json.Unmarshal(data, &b)
+	}
+
+	fn(&b, time.Now())
+
+	if data, err = json.Marshal(b); err != nil {
+		return err
+	}
+	if err := f.Truncate(0); err != nil {
+		return err
+	}
+	_, err = f.WriteAt(data, 0)
+	return err
+}
@@ -0,0 +1,164 @@
+package replay
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/basenana/friday/core/providers"
+	"github.com/basenana/friday/core/types"
+)
+
+// The kinds of call a fixture is recorded for.
+const (
+	kindCompletion   = "completion"
+	kindNonStreaming = "non_streaming"
+	kindStructured   = "structured"
+)
+
+// fixture is the content of one fixture file: the request it answers and
+// every response recorded for it, in order.
+type fixture struct {
+	Request   requestKey  `json:"request"`
+	Responses []recording `json:"responses"`
+}
+
+// requestKey is what a request is matched by. It leaves out what changes
+// between runs of the same conversation, such as token counts, timestamps
+// and prompt cache keys.
+type requestKey struct {
+	Kind     string       `json:"kind"`
+	Schema   string       `json:"schema,omitempty"`
+	System   string       `json:"system,omitempty"`
+	Messages []keyMessage `json:"messages"`
+	Tools    []keyTool    `json:"tools,omitempty"`
+}
+
+type keyMessage struct {
+	Role               types.MessageRole   `json:"role"`
+	Content            string              `json:"content,omitempty"`
+	Reasoning          string              `json:"reasoning,omitempty"`
+	ReasoningSignature string              `json:"reasoning_signature,omitempty"`
+	RedactedThinking   string              `json:"redacted_thinking,omitempty"`
+	Image              *types.ImageContent `json:"image,omitempty"`
+	ToolCalls          []types.ToolCall    `json:"tool_calls,omitempty"`
+	ToolResult         *types.ToolResult   `json:"tool_result,omitempty"`
+}
+
+type keyTool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Parameters  map[string]any `json:"parameters,omitempty"`
+}
+
+// recording is one recorded answer. Only the fields of its kind are set.
+type recording struct {
+	Deltas  []delta          `json:"deltas,omitempty"`
+	Tokens  providers.Tokens `json:"tokens"`
+	Content string           `json:"content,omitempty"`
+	Result  json.RawMessage  `json:"result,omitempty"`
+	Error   string           `json:"error,omitempty"`
+}
+
+type delta struct {
+	Content            string               `json:"content,omitempty"`
+	Reasoning          string               `json:"reasoning,omitempty"`
+	ReasoningSignature string               `json:"reasoning_signature,omitempty"`
+	RedactedThinking   string               `json:"redacted_thinking,omitempty"`
+	ToolUse            []providers.ToolCall `json:"tool_use,omitempty"`
+}
+
+func newDelta(d providers.Delta) delta {
+	return delta{
+		Content:            d.Content,
+		Reasoning:          d.Reasoning,
+		ReasoningSignature: d.ReasoningSignature,
+		RedactedThinking:   d.RedactedThinking,
+		ToolUse:            d.ToolUse,
+	}
+}
+
+func (d delta) providerDelta() providers.Delta {
+	return providers.Delta{
+		Content:            d.Content,
+		Reasoning:          d.Reasoning,
+		ReasoningSignature: d.ReasoningSignature,
+		RedactedThinking:   d.RedactedThinking,
+		ToolUse:            d.ToolUse,
+	}
+}
+
+// newRequestKey builds the key of request, passing every text through
+// normalize.
+func newRequestKey(kind string, request providers.Request, normalize func(string) string) requestKey {
+	key := requestKey{Kind: kind, System: normalize(request.SystemPrompt())}
+	for _, msg := range request.History() {
+		km := keyMessage{
+			Role:               msg.Role,
+			Content:            normalize(msg.Content),
+			Reasoning:          normalize(msg.Reasoning),
+			ReasoningSignature: msg.ReasoningSignature,
+			RedactedThinking:   msg.RedactedThinking,
+			Image:              msg.Image,
+		}
+		for _, tc := range msg.ToolCalls {
+			tc.Arguments = normalize(tc.Arguments)
+			km.ToolCalls = append(km.ToolCalls, tc)
+		}
+		if msg.ToolResult != nil {
+			result := *msg.ToolResult
+			result.Content = normalize(result.Content)
+			km.ToolResult = &result
+		}
+		key.Messages = append(key.Messages, km)
+	}
+	for _, t := range request.ToolDefines() {
+		key.Tools = append(key.Tools, keyTool{
+			Name:        t.GetName(),
+			Description: normalize(t.GetDescription()),
+			Parameters:  t.GetParameters(),
+		})
+	}
+	return key
+}
+
+// hash names the fixture file of the key.
+func (k requestKey) hash() string {
+	data, _ := json.Marshal(k)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+func fixturePath(dir, hash string) string {
+	return filepath.Join(dir, hash+".json")
+}
+
+func readFixture(path string) (*fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f fixture
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("decode fixture %s: %w", path, err)
+	}
+	return &f, nil
+}
+
+func writeFixture(path string, f *fixture) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,273 @@
+// Package replay records the answers of a providers.Client to fixture files
+// and serves them back offline, so tests that drive a model can run
+// deterministically without one.
+//
+// Each request is matched by a hash of its system prompt, history, tool
+// definitions and call kind; the fixture file is named after the hash. A
+// request made several times while recording keeps every answer, and they
+// are served back in the same order, the last one repeating.
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/basenana/friday/core/logger"
+	"github.com/basenana/friday/core/providers"
+)
+
+// Mode selects whether a Client records or replays.
+type Mode string
+
+const (
+	ModeRecord Mode = "record"
+	ModeReplay Mode = "replay"
+)
+
+const (
+	// EnvMode holds the Mode FromEnv wraps clients in; unset means live.
+	EnvMode = "FRIDAY_PROVIDER_MODE"
+	// EnvFixtures holds the fixture directory, DefaultFixtures when unset.
+	EnvFixtures = "FRIDAY_PROVIDER_FIXTURES"
+
+	DefaultFixtures = "testdata/replay"
+)
+
+// ErrNoFixture is returned in replay mode for a request that was never recorded.
+var ErrNoFixture = errors.New("no recorded response")
+
+// Client wraps a providers.Client. In ModeRecord it passes every call through
+// and writes the answer to the fixture directory; in ModeReplay it answers
+// from the fixture directory only, and the wrapped client may be nil.
+type Client struct {
+	inner     providers.Client
+	mode      Mode
+	dir       string
+	normalize func(string) string
+	logger    logger.Logger
+
+	mu       sync.Mutex
+	served   map[string]int
+	recorded map[string]*fixture
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithNormalizer rewrites every text of a request before it is hashed, e.g.
+// to replace temporary directories that differ between runs.
+func WithNormalizer(normalize func(string) string) Option {
+	return func(c *Client) {
+		c.normalize = normalize
+	}
+}
+
+// WithNamespace keeps the fixtures in a subdirectory of the fixture
+// directory, for clients of different models that may see the same requests.
+func WithNamespace(name string) Option {
+	return func(c *Client) {
+		c.dir = filepath.Join(c.dir, name)
+	}
+}
+
+// New wraps inner in a Client that records to or replays from dir.
+func New(inner providers.Client, mode Mode, dir string, opts ...Option) *Client {
+	c := &Client{
+		inner:     inner,
+		mode:      mode,
+		dir:       dir,
+		normalize: func(s string) string { return s },
+		logger:    logger.New("replay"),
+		served:    make(map[string]int),
+		recorded:  make(map[string]*fixture),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// FromEnv wraps inner as configured by FRIDAY_PROVIDER_MODE and
+// FRIDAY_PROVIDER_FIXTURES, and returns inner unchanged when no mode is set.
+func FromEnv(inner providers.Client, opts ...Option) (providers.Client, error) {
+	mode := Mode(strings.ToLower(strings.TrimSpace(os.Getenv(EnvMode))))
+	switch mode {
+	case "":
+		return inner, nil
+	case ModeRecord, ModeReplay:
+	default:
+		return nil, fmt.Errorf("invalid %s %q: want %q or %q", EnvMode, mode, ModeRecord, ModeReplay)
+	}
+	dir := os.Getenv(EnvFixtures)
+	if dir == "" {
+		dir = DefaultFixtures
+	}
+	return New(inner, mode, dir, opts...), nil
+}
+
+func (c *Client) Completion(ctx context.Context, request providers.Request) providers.Response {
+	key := newRequestKey(kindCompletion, request, c.normalize)
+	resp := providers.NewCommonResponse()
+
+	if c.mode == ModeReplay {
+		rec, err := c.replay(key)
+		go func() {
+			defer close(resp.Stream)
+			defer close(resp.Err)
+			if err != nil {
+				resp.Err <- err
+				return
+			}
+			for _, d := range rec.Deltas {
+				select {
+				case <-ctx.Done():
+					resp.Err <- ctx.Err()
+					return
+				case resp.Stream <- d.providerDelta():
+				}
+			}
+			resp.Token = rec.Tokens
+			if rec.Error != "" {
+				resp.Err <- errors.New(rec.Error)
+			}
+		}()
+		return resp
+	}
+
+	inner := c.inner.Completion(ctx, request)
+	go func() {
+		defer close(resp.Stream)
+		defer close(resp.Err)
+		var rec recording
+		for d := range inner.Message() {
+			rec.Deltas = append(rec.Deltas, newDelta(d))
+			resp.Stream <- d
+		}
+		err := <-inner.Error()
+		rec.Tokens = inner.Tokens()
+		resp.Token = rec.Tokens
+		if err != nil {
+			rec.Error = err.Error()
+		}
+		c.record(key, rec)
+		if err != nil {
+			resp.Err <- err
+		}
+	}()
+	return resp
+}
+
+func (c *Client) CompletionNonStreaming(ctx context.Context, request providers.Request) (string, error) {
+	key := newRequestKey(kindNonStreaming, request, c.normalize)
+	if c.mode == ModeReplay {
+		rec, err := c.replay(key)
+		if err != nil {
+			return "", err
+		}
+		if rec.Error != "" {
+			return rec.Content, errors.New(rec.Error)
+		}
+		return rec.Content, nil
+	}
+
+	content, err := c.inner.CompletionNonStreaming(ctx, request)
+	rec := recording{Content: content}
+	if err != nil {
+		rec.Error = err.Error()
+	}
+	c.record(key, rec)
+	return content, err
+}
+
+func (c *Client) StructuredPredict(ctx context.Context, request providers.Request, model any) error {
+	key := newRequestKey(kindStructured, request, c.normalize)
+	key.Schema = fmt.Sprintf("%T", model)
+	if c.mode == ModeReplay {
+		rec, err := c.replay(key)
+		if err != nil {
+			return err
+		}
+		if rec.Error != "" {
+			return errors.New(rec.Error)
+		}
+		return json.Unmarshal(rec.Result, model)
+	}
+
+	err := c.inner.StructuredPredict(ctx, request, model)
+	var rec recording
+	if err != nil {
+		rec.Error = err.Error()
+	} else if rec.Result, err = json.Marshal(model); err != nil {
+		return fmt.Errorf("record structured result: %w", err)
+	}
+	c.record(key, rec)
+	return err
+}
+
+// ContextWindow forwards the wrapped client's context window, if it reports one.
+func (c *Client) ContextWindow() int64 {
+	if cw, ok := c.inner.(providers.ContextWindowProvider); ok {
+		return cw.ContextWindow()
+	}
+	return 0
+}
+
+// CacheStrategy forwards the wrapped client's cache strategy.
+func (c *Client) CacheStrategy() providers.CacheStrategy {
+	return providers.CacheStrategyOf(c.inner)
+}
+
+// replay returns the next recorded answer to key.
+func (c *Client) replay(key requestKey) (recording, error) {
+	hash := key.hash()
+	f, err := readFixture(fixturePath(c.dir, hash))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return recording{}, fmt.Errorf("replay %s request %s from %s: %w", key.Kind, hash, c.dir, ErrNoFixture)
+		}
+		return recording{}, err
+	}
+	if len(f.Responses) == 0 {
+		return recording{}, fmt.Errorf("replay %s request %s from %s: %w", key.Kind, hash, c.dir, ErrNoFixture)
+	}
+
+	c.mu.Lock()
+	n := c.served[hash]
+	c.served[hash] = n + 1
+	c.mu.Unlock()
+
+	if n >= len(f.Responses) {
+		n = len(f.Responses) - 1
+	}
+	return f.Responses[n], nil
+}
+
+// record appends rec to the fixture of key. A fixture left by an earlier
+// run is replaced on the first answer of this one.
+func (c *Client) record(key requestKey, rec recording) {
+	hash := key.hash()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	f, ok := c.recorded[hash]
+	if !ok {
+		f = &fixture{Request: key}
+		c.recorded[hash] = f
+	}
+	f.Responses = append(f.Responses, rec)
+	if err := writeFixture(fixturePath(c.dir, hash), f); err != nil {
+		c.logger.Errorw("write fixture failed", "request", hash, "err", err)
+	}
+}
+
+var (
+	_ providers.Client                = (*Client)(nil)
+	_ providers.ContextWindowProvider = (*Client)(nil)
+	_ providers.PromptCacher          = (*Client)(nil)
+)
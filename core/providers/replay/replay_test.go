@@ -0,0 +1,183 @@
+package replay
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/basenana/friday/core/providers"
+	"github.com/basenana/friday/core/types"
+)
+
+// fakeClient answers every call with the next of its replies.
+type fakeClient struct {
+	calls   int
+	replies []string
+}
+
+func (f *fakeClient) next() string {
+	reply := f.replies[f.calls%len(f.replies)]
+	f.calls++
+	return reply
+}
+
+func (f *fakeClient) Completion(_ context.Context, _ providers.Request) providers.Response {
+	resp := providers.NewCommonResponse()
+	reply := f.next()
+	go func() {
+		defer close(resp.Stream)
+		defer close(resp.Err)
+		resp.Stream <- providers.Delta{Reasoning: "thinking", ReasoningSignature: "sig"}
+		resp.Stream <- providers.Delta{Content: reply}
+		resp.Stream <- providers.Delta{ToolUse: []providers.ToolCall{{ID: "call-1", Name: "bash", Arguments: `{"command":"ls"}`}}}
+		resp.Token = providers.Tokens{PromptTokens: 10, CompletionTokens: 3, TotalTokens: 13}
+	}()
+	return resp
+}
+
+func (f *fakeClient) CompletionNonStreaming(_ context.Context, _ providers.Request) (string, error) {
+	reply := f.next()
+	if reply == "fail" {
+		return "", errors.New("model unavailable")
+	}
+	return reply, nil
+}
+
+func (f *fakeClient) StructuredPredict(_ context.Context, _ providers.Request, model any) error {
+	model.(*answer).Value = f.next()
+	return nil
+}
+
+type answer struct {
+	Value string `json:"value"`
+}
+
+func collect(t *testing.T, resp providers.Response) ([]providers.Delta, error) {
+	t.Helper()
+	var deltas []providers.Delta
+	for d := range resp.Message() {
+		deltas = append(deltas, d)
+	}
+	return deltas, <-resp.Error()
+}
+
+func TestRecordThenReplayCompletion(t *testing.T) {
+	dir := t.TempDir()
+	request := func(now string) providers.Request {
+		req := providers.NewRequest("You are helpful. Now: "+now, types.Message{Role: types.RoleUser, Content: "list files", Tokens: 42})
+		req.SetToolDefines([]providers.ToolDefine{providers.NewToolDefine("bash", "Run a command", map[string]any{"type": "object"})})
+		return req
+	}
+	normalizer := WithNormalizer(func(s string) string {
+		if i := strings.Index(s, "Now: "); i >= 0 {
+			return s[:i]
+		}
+		return s
+	})
+
+	recorder := New(&fakeClient{replies: []string{"one", "two"}}, ModeRecord, dir, normalizer)
+	for range 2 {
+		if _, err := collect(t, recorder.Completion(context.Background(), request(time.Now().String()))); err != nil {
+			t.Fatalf("record: %v", err)
+		}
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(files) != 1 {
+		t.Fatalf("fixtures = %v, want one file", files)
+	}
+
+	player := New(nil, ModeReplay, dir, normalizer)
+	for _, want := range []string{"one", "two", "two"} {
+		resp := player.Completion(context.Background(), request("later"))
+		deltas, err := collect(t, resp)
+		if err != nil {
+			t.Fatalf("replay: %v", err)
+		}
+		if len(deltas) != 3 || deltas[0].ReasoningSignature != "sig" || deltas[1].Content != want || deltas[2].ToolUse[0].Arguments != `{"command":"ls"}` {
+			t.Fatalf("deltas = %+v, want content %q", deltas, want)
+		}
+		if resp.Tokens() != (providers.Tokens{PromptTokens: 10, CompletionTokens: 3, TotalTokens: 13}) {
+			t.Fatalf("tokens = %+v", resp.Tokens())
+		}
+	}
+}
+
+func TestReplayMissingFixture(t *testing.T) {
+	player := New(nil, ModeReplay, t.TempDir())
+
+	_, err := collect(t, player.Completion(context.Background(), providers.NewPromptRequest("hello")))
+	if !errors.Is(err, ErrNoFixture) {
+		t.Fatalf("err = %v, want ErrNoFixture", err)
+	}
+}
+
+func TestRecordReplacesFixturesOfEarlierRuns(t *testing.T) {
+	dir := t.TempDir()
+	req := providers.NewPromptRequest("hello")
+
+	for _, reply := range []string{"old", "new"} {
+		recorder := New(&fakeClient{replies: []string{reply}}, ModeRecord, dir)
+		if _, err := recorder.CompletionNonStreaming(context.Background(), req); err != nil {
+			t.Fatalf("record: %v", err)
+		}
+	}
+
+	got, err := New(nil, ModeReplay, dir).CompletionNonStreaming(context.Background(), req)
+	if err != nil || got != "new" {
+		t.Fatalf("replay = %q, %v; want new", got, err)
+	}
+}
+
+func TestReplayErrorsAndStructuredResults(t *testing.T) {
+	dir := t.TempDir()
+	failing := providers.NewPromptRequest("fail please")
+	structured := providers.NewPromptRequest("give me a value")
+
+	recorder := New(&fakeClient{replies: []string{"fail", "forty-two"}}, ModeRecord, dir)
+	if _, err := recorder.CompletionNonStreaming(context.Background(), failing); err == nil {
+		t.Fatal("expected recorded error")
+	}
+	if err := recorder.StructuredPredict(context.Background(), structured, &answer{}); err != nil {
+		t.Fatalf("record structured: %v", err)
+	}
+
+	player := New(nil, ModeReplay, dir)
+	if _, err := player.CompletionNonStreaming(context.Background(), failing); err == nil || err.Error() != "model unavailable" {
+		t.Fatalf("replayed err = %v", err)
+	}
+	var out answer
+	if err := player.StructuredPredict(context.Background(), structured, &out); err != nil || out.Value != "forty-two" {
+		t.Fatalf("structured = %+v, %v", out, err)
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	inner := &fakeClient{replies: []string{"x"}}
+
+	t.Setenv(EnvMode, "")
+	if c, err := FromEnv(inner); err != nil || c != providers.Client(inner) {
+		t.Fatalf("FromEnv without mode = %v, %v", c, err)
+	}
+
+	t.Setenv(EnvMode, "replay")
+	t.Setenv(EnvFixtures, "fixtures")
+	c, err := FromEnv(inner)
+	if err != nil {
+		t.Fatalf("FromEnv: %v", err)
+	}
+	if rc, ok := c.(*Client); !ok || rc.mode != ModeReplay || rc.dir != "fixtures" {
+		t.Fatalf("client = %+v", c)
+	}
+
+	t.Setenv(EnvMode, "live")
+	if _, err := FromEnv(inner); err == nil {
+		t.Fatal("expected error for unknown mode")
+	}
+	if _, err := os.Stat("fixtures"); err == nil {
+		t.Fatal("FromEnv should not create the fixture directory")
+	}
+}
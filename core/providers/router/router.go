@@ -0,0 +1,234 @@
+// Package router picks one of several models for each request: cheap models
+// take simple turns, and requests that need a larger context window, image
+// input or more tools go to the models that support them.
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/basenana/friday/core/logger"
+	"github.com/basenana/friday/core/providers"
+	"github.com/basenana/friday/core/tokenizer"
+	"github.com/basenana/friday/core/tracing"
+	"github.com/basenana/friday/core/types"
+)
+
+// Route is a model the router can pick.
+type Route struct {
+	Client providers.Client
+	Name   string
+
+	// ContextWindow is the model's context window in tokens; zero asks the
+	// client, and a model that reports none takes any request.
+	ContextWindow int64
+	// MaxTokens is reserved for the completion within the context window.
+	MaxTokens int64
+	// Images reports whether the model accepts image input.
+	Images bool
+	// MaxTools is the most tools the model is given; zero means no limit.
+	MaxTools int
+	// Tier is providers.ModelHintCheap, providers.ModelHintSmart or empty
+	// for a general model.
+	Tier string
+}
+
+// Router implements providers.Client by sending each request to one route.
+// A route is eligible when the estimated prompt fits its context window, it
+// accepts the images in the history and it allows the number of tools. Among
+// the eligible routes, in configured order, the router prefers the tier
+// hinted on the request; without a hint, turns under the simple-turn
+// threshold prefer cheap models and other turns avoid them.
+type Router struct {
+	routes       []Route
+	simpleTokens int64
+	logger       logger.Logger
+}
+
+// Option configures a Router.
+type Option func(*Router)
+
+// WithSimpleTokens sets the estimated prompt size up to which an unhinted
+// turn goes to a cheap model. Zero sends unhinted turns to cheap models only
+// when no other model is eligible.
+func WithSimpleTokens(tokens int64) Option {
+	return func(r *Router) {
+		r.simpleTokens = tokens
+	}
+}
+
+// New creates a Router over routes, the first being the default.
+func New(routes []Route, opts ...Option) *Router {
+	r := &Router{routes: routes, logger: logger.New("router")}
+	for i := range r.routes {
+		if r.routes[i].ContextWindow == 0 {
+			if cw, ok := r.routes[i].Client.(providers.ContextWindowProvider); ok {
+				r.routes[i].ContextWindow = cw.ContextWindow()
+			}
+		}
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Route returns the route for request.
+func (r *Router) Route(request providers.Request) Route {
+	d := demandOf(request)
+
+	var eligible []Route
+	for _, route := range r.routes {
+		if route.accepts(d) {
+			eligible = append(eligible, route)
+		}
+	}
+	if len(eligible) == 0 {
+		return r.largest(d)
+	}
+
+	wanted := request.ModelHint()
+	if wanted == "" && r.simpleTokens > 0 && d.tokens <= r.simpleTokens {
+		wanted = providers.ModelHintCheap
+	}
+	if wanted != "" {
+		for _, route := range eligible {
+			if route.Tier == wanted {
+				return route
+			}
+		}
+	}
+	if wanted != providers.ModelHintCheap {
+		for _, route := range eligible {
+			if route.Tier != providers.ModelHintCheap {
+				return route
+			}
+		}
+	}
+	return eligible[0]
+}
+
+// largest returns the route with the largest context window among those
+// accepting the images of d, for a request no route is known to fit.
+func (r *Router) largest(d demand) Route {
+	best := -1
+	for i, route := range r.routes {
+		if d.images && !route.Images {
+			continue
+		}
+		if best < 0 || route.ContextWindow > r.routes[best].ContextWindow {
+			best = i
+		}
+	}
+	if best < 0 {
+		best = 0
+	}
+	return r.routes[best]
+}
+
+func (r *Router) pick(ctx context.Context, request providers.Request) (Route, error) {
+	if len(r.routes) == 0 {
+		return Route{}, fmt.Errorf("router has no models configured")
+	}
+	route := r.Route(request)
+	tracing.SpanFromContext(ctx).SetAttributes(tracing.String("router.model", route.Name))
+	r.logger.Infow("route request", "model", route.Name, "tier", route.Tier, "hint", request.ModelHint())
+	return route, nil
+}
+
+func (r *Router) Completion(ctx context.Context, request providers.Request) providers.Response {
+	route, err := r.pick(ctx, request)
+	if err != nil {
+		resp := providers.NewCommonResponse()
+		resp.Err <- err
+		close(resp.Err)
+		close(resp.Stream)
+		return resp
+	}
+	return route.Client.Completion(ctx, request)
+}
+
+func (r *Router) CompletionNonStreaming(ctx context.Context, request providers.Request) (string, error) {
+	route, err := r.pick(ctx, request)
+	if err != nil {
+		return "", err
+	}
+	return route.Client.CompletionNonStreaming(ctx, request)
+}
+
+func (r *Router) StructuredPredict(ctx context.Context, request providers.Request, model any) error {
+	route, err := r.pick(ctx, request)
+	if err != nil {
+		return err
+	}
+	return route.Client.StructuredPredict(ctx, request, model)
+}
+
+// ContextWindow returns the largest context window of the routes, since
+// longer prompts are routed to the models that fit them.
+func (r *Router) ContextWindow() int64 {
+	var max int64
+	for _, route := range r.routes {
+		if route.ContextWindow > max {
+			max = route.ContextWindow
+		}
+	}
+	if max == 0 {
+		return 128 * 1000 // default 128K
+	}
+	return max
+}
+
+// CacheStrategy returns the cache strategy of the default route.
+func (r *Router) CacheStrategy() providers.CacheStrategy {
+	if len(r.routes) == 0 {
+		return providers.CacheNone
+	}
+	return providers.CacheStrategyOf(r.routes[0].Client)
+}
+
+// demand is what a request needs from a model.
+type demand struct {
+	tokens int64
+	images bool
+	tools  int
+}
+
+// demandOf counts the tokens of request with its tokenizer.
+func demandOf(request providers.Request) demand {
+	tok := request.Tokenizer()
+	d := demand{tools: len(request.ToolDefines())}
+	d.tokens = tokenizer.CountMessage(tok, types.Message{Content: request.SystemPrompt()})
+	for _, msg := range request.History() {
+		if msg.Tokens != 0 {
+			d.tokens += msg.Tokens
+		} else {
+			d.tokens += tokenizer.CountMessage(tok, msg)
+		}
+		if msg.Image != nil {
+			d.images = true
+		}
+	}
+	for _, tool := range request.ToolDefines() {
+		params, _ := json.Marshal(tool.GetParameters())
+		d.tokens += tokenizer.CountMessage(tok, types.Message{Content: tool.GetName() + tool.GetDescription() + string(params)})
+	}
+	return d
+}
+
+func (route Route) accepts(d demand) bool {
+	if d.images && !route.Images {
+		return false
+	}
+	if route.MaxTools > 0 && d.tools > route.MaxTools {
+		return false
+	}
+	return route.ContextWindow <= 0 || d.tokens+route.MaxTokens <= route.ContextWindow
+}
+
+var (
+	_ providers.Client                = (*Router)(nil)
+	_ providers.ContextWindowProvider = (*Router)(nil)
+	_ providers.PromptCacher          = (*Router)(nil)
+)
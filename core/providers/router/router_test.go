@@ -0,0 +1,139 @@
+package router
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/basenana/friday/core/providers"
+	"github.com/basenana/friday/core/types"
+)
+
+// nameClient answers every completion with its name.
+type nameClient struct {
+	name   string
+	window int64
+}
+
+func (c nameClient) Completion(context.Context, providers.Request) providers.Response {
+	resp := providers.NewCommonResponse()
+	go func() {
+		defer close(resp.Stream)
+		defer close(resp.Err)
+		resp.Stream <- providers.Delta{Content: c.name}
+	}()
+	return resp
+}
+
+func (c nameClient) CompletionNonStreaming(context.Context, providers.Request) (string, error) {
+	return c.name, nil
+}
+
+func (c nameClient) StructuredPredict(context.Context, providers.Request, any) error {
+	return nil
+}
+
+func (c nameClient) ContextWindow() int64 { return c.window }
+
+func testRouter(opts ...Option) *Router {
+	return New([]Route{
+		{Client: nameClient{name: "general"}, Name: "general", ContextWindow: 10_000},
+		{Client: nameClient{name: "mini"}, Name: "mini", ContextWindow: 10_000, Tier: providers.ModelHintCheap, MaxTools: 2},
+		{Client: nameClient{name: "large", window: 1_000_000}, Name: "large", Tier: providers.ModelHintSmart, Images: true},
+	}, opts...)
+}
+
+func TestRoute(t *testing.T) {
+	tools := func(n int) []providers.ToolDefine {
+		var defs []providers.ToolDefine
+		for i := range n {
+			defs = append(defs, providers.NewToolDefine(strings.Repeat("t", i+1), "", nil))
+		}
+		return defs
+	}
+
+	cases := []struct {
+		name    string
+		history []types.Message
+		tools   int
+		hint    string
+		want    string
+	}{
+		{name: "simple turn goes cheap", history: []types.Message{{Role: types.RoleUser, Content: "hi"}}, want: "mini"},
+		{name: "too many tools for cheap", history: []types.Message{{Role: types.RoleUser, Content: "hi"}}, tools: 3, want: "general"},
+		{name: "long turn avoids cheap", history: []types.Message{{Role: types.RoleUser, Content: "hi", Tokens: 3000}}, want: "general"},
+		{name: "beyond window goes large", history: []types.Message{{Role: types.RoleUser, Content: "hi", Tokens: 50_000}}, want: "large"},
+		{name: "images go to image model", history: []types.Message{{Role: types.RoleUser, Content: "look", Image: &types.ImageContent{Type: types.ImageTypeURL, URL: "http://x/a.png"}}}, want: "large"},
+		{name: "smart hint", history: []types.Message{{Role: types.RoleUser, Content: "hi"}}, hint: providers.ModelHintSmart, want: "large"},
+		{name: "cheap hint on long turn", history: []types.Message{{Role: types.RoleUser, Content: "hi", Tokens: 3000}}, hint: providers.ModelHintCheap, want: "mini"},
+	}
+
+	r := testRouter(WithSimpleTokens(2000))
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			req := providers.NewRequest("system", c.history...)
+			req.SetToolDefines(tools(c.tools))
+			req.SetModelHint(c.hint)
+			if got := r.Route(req).Name; got != c.want {
+				t.Fatalf("Route() = %s, want %s", got, c.want)
+			}
+		})
+	}
+}
+
+func TestRouteWithoutSimpleThresholdPrefersNonCheap(t *testing.T) {
+	r := testRouter()
+	if got := r.Route(providers.NewPromptRequest("hi")).Name; got != "general" {
+		t.Fatalf("Route() = %s, want general", got)
+	}
+}
+
+func TestRouteFallsBackToLargestWindow(t *testing.T) {
+	r := New([]Route{
+		{Client: nameClient{name: "a"}, Name: "a", ContextWindow: 1000},
+		{Client: nameClient{name: "b"}, Name: "b", ContextWindow: 2000},
+	})
+	req := providers.NewRequest("", types.Message{Role: types.RoleUser, Content: "hi", Tokens: 5000})
+	if got := r.Route(req).Name; got != "b" {
+		t.Fatalf("Route() = %s, want b", got)
+	}
+}
+
+func TestRouterCompletionAndContextWindow(t *testing.T) {
+	r := testRouter(WithSimpleTokens(2000))
+
+	resp := r.Completion(context.Background(), providers.NewPromptRequest("hi"))
+	var content string
+	for d := range resp.Message() {
+		content += d.Content
+	}
+	if err := <-resp.Error(); err != nil || content != "mini" {
+		t.Fatalf("Completion() = %q, %v; want mini", content, err)
+	}
+	if got := r.ContextWindow(); got != 1_000_000 {
+		t.Fatalf("ContextWindow() = %d, want the largest window", got)
+	}
+
+	if _, err := New(nil).CompletionNonStreaming(context.Background(), providers.NewPromptRequest("hi")); err == nil {
+		t.Fatal("expected error without routes")
+	}
+}
+
+// wordTokenizer counts one token per word.
+type wordTokenizer struct{}
+
+func (wordTokenizer) Name() string            { return "words" }
+func (wordTokenizer) Count(text string) int64 { return int64(len(strings.Fields(text))) }
+
+func TestRouteCountsWithRequestTokenizer(t *testing.T) {
+	r := testRouter(WithSimpleTokens(100))
+	// 50 words are 125 tokens by the rune-count heuristic.
+	req := providers.NewRequest("", types.Message{Role: types.RoleUser, Content: strings.Repeat("word ", 50)})
+	if got := r.Route(req).Name; got != "general" {
+		t.Fatalf("Route() = %s, want general with the heuristic", got)
+	}
+	req.SetTokenizer(wordTokenizer{})
+	if got := r.Route(req).Name; got != "mini" {
+		t.Fatalf("Route() = %s, want mini with the request tokenizer", got)
+	}
+}
@@ -41,6 +41,40 @@ type ContextState struct {
 	// When PromptTokens > 0, the total context size can be calculated as:
 	//   PromptTokens + estimated tokens for messages added since Index
 	TokenCheckpoint TokenCheckpoint
+
+	// Cache tallies how much of this session's prompts the provider served
+	// from its prompt cache. Forks start their own tally.
+	Cache CacheStats
+}
+
+// CacheStats counts the prompt tokens of a session's model calls and how
+// many of them were read from the provider's prompt cache.
+type CacheStats struct {
+	Requests     int64
+	PromptTokens int64
+	CachedTokens int64
+	// LastHitRate is the hit rate of the latest call.
+	LastHitRate float64
+}
+
+// Record adds the prompt tokens of one call.
+func (c *CacheStats) Record(promptTokens, cachedTokens int64) {
+	if promptTokens <= 0 {
+		return
+	}
+	cachedTokens = min(max(cachedTokens, 0), promptTokens)
+	c.Requests++
+	c.PromptTokens += promptTokens
+	c.CachedTokens += cachedTokens
+	c.LastHitRate = float64(cachedTokens) / float64(promptTokens)
+}
+
+// HitRate is the share of prompt tokens read from the cache.
+func (c CacheStats) HitRate() float64 {
+	if c.PromptTokens == 0 {
+		return 0
+	}
+	return float64(c.CachedTokens) / float64(c.PromptTokens)
 }
 
 // TokenCheckpoint records the exact token usage from the last LLM response,
@@ -9,6 +9,7 @@ import (
 	"github.com/basenana/friday/core/logger"
 	"github.com/basenana/friday/core/providers"
 	"github.com/basenana/friday/core/state"
+	"github.com/basenana/friday/core/tokenizer"
 	"github.com/basenana/friday/core/types"
 )
 
@@ -37,6 +38,9 @@ type Session struct {
 	Temporary bool
 
 	compactThreshold int64
+	tokenizer        tokenizer.Tokenizer
+	cost             float64
+	costBudget       float64
 
 	hooks     []Hook
 	llm       providers.Client
@@ -86,15 +90,27 @@ func (s *Session) Fork() *Session {
 		CreatedAt:        time.Now(),
 		Temporary:        s.Temporary,
 		compactThreshold: s.compactThreshold,
+		tokenizer:        s.tokenizer,
 		hooks:            s.hooks,
 		llm:              s.llm,
 	}
+	fork.Context.Cache = CacheStats{}
 	s.Children = append(s.Children, fork)
 	s.mu.Unlock()
 
 	return fork
 }
 
+// TruncateHistory returns a copy of the first n messages of history. A tool
+// call whose results fall beyond n is dropped along with the results kept,
+// so the copy can be sent to a model as is.
+func TruncateHistory(history []types.Message, n int) []types.Message {
+	n = min(max(n, 0), len(history))
+	kept := make([]types.Message, n)
+	copy(kept, history[:n])
+	return trimOrphanedToolCalls(kept)
+}
+
 // trimOrphanedToolCalls removes the last assistant tool-call message if any of
 // its calls lack a corresponding tool result. Only the final tool-call message
 // is inspected — this is sufficient because orphaned calls only arise at the
@@ -170,18 +186,18 @@ func (s *Session) Tokens() int64 {
 		if len(newMsgs) == 0 {
 			return base
 		}
-		return base + estimatedTokensForMessages(newMsgs)
+		return base + estimatedTokensForMessages(s.tokenizer, newMsgs)
 	}
 
-	return EstimateHistoryTokens(history)
+	return EstimateHistoryTokens(s.tokenizer, history)
 }
 
-// estimatedTokensForMessages returns a fuzzy token estimate for a slice of messages.
+// estimatedTokensForMessages returns a token estimate for a slice of messages.
 // Used for incremental estimation of messages added after the last token checkpoint.
-func estimatedTokensForMessages(msgs []types.Message) int64 {
+func estimatedTokensForMessages(tok tokenizer.Tokenizer, msgs []types.Message) int64 {
 	var total int64
 	for _, msg := range msgs {
-		total += msg.EstimatedTokens()
+		total += tokenizer.CountMessage(tok, msg)
 	}
 	return total
 }
@@ -201,8 +217,15 @@ func (s *Session) HistoryLen() int {
 	return len(s.History)
 }
 
+// CountTokens estimates the prompt tokens of history with the session's tokenizer.
 func (s *Session) CountTokens(history []types.Message) int64 {
-	return EstimateHistoryTokens(history)
+	return EstimateHistoryTokens(s.tokenizer, history)
+}
+
+// Tokenizer returns the tokenizer set WithTokenizer, nil for the rune-count
+// heuristic.
+func (s *Session) Tokenizer() tokenizer.Tokenizer {
+	return s.tokenizer
 }
 
 func (s *Session) RegisterHook(handler Hook) {
@@ -281,6 +304,14 @@ func WithCompactThreshold(ct int64) Option {
 	}
 }
 
+// WithTokenizer counts tokens with tok instead of the rune-count heuristic.
+// Forked sessions inherit it.
+func WithTokenizer(tok tokenizer.Tokenizer) Option {
+	return func(s *Session) {
+		s.tokenizer = tok
+	}
+}
+
 func WithMessageWriter(w MessageWriter) Option {
 	return func(s *Session) {
 		s.writer = w
@@ -148,6 +148,32 @@ func TestTrimOrphanedToolCalls_OnlyOrphanNoUser(t *testing.T) {
 	}
 }
 
+func TestTruncateHistory_DropsCallWithCutResults(t *testing.T) {
+	history := []types.Message{
+		userMsg("go"),
+		toolCallMsg(tc("c1", "tool_a"), tc("c2", "tool_b")),
+		toolResultMsg("c1", "ok"),
+		toolResultMsg("c2", "ok"),
+		assistantMsg("done"),
+	}
+	got := TruncateHistory(history, 3)
+	if len(got) != 1 || got[0].Content != "go" {
+		t.Fatalf("expected only the user message, got %+v", got)
+	}
+	if got := TruncateHistory(history, 4); len(got) != 4 {
+		t.Fatalf("expected the complete tool call to be kept, got %d messages", len(got))
+	}
+	if got := TruncateHistory(history, 10); len(got) != len(history) {
+		t.Fatalf("expected the whole history, got %d messages", len(got))
+	}
+
+	got = TruncateHistory(history, 1)
+	got[0].Content = "changed"
+	if history[0].Content != "go" {
+		t.Fatal("TruncateHistory must not share messages with history")
+	}
+}
+
 // --- Fork integration tests ---
 
 func newTestSession(history ...types.Message) *Session {
@@ -5,45 +5,51 @@ import (
 	"strings"
 
 	"github.com/basenana/friday/core/providers"
+	"github.com/basenana/friday/core/tokenizer"
 	"github.com/basenana/friday/core/types"
 )
 
 // EstimateRequestOverhead returns the approximate prompt-token cost of data
-// carried outside session history, such as the system prompt and tool schemas.
+// carried outside session history, such as the system prompt and tool schemas,
+// counted with the request's tokenizer.
 func EstimateRequestOverhead(req providers.Request) int64 {
 	if req == nil {
 		return 0
 	}
 
+	tok := req.Tokenizer()
 	var total int64
 	if prompt := strings.TrimSpace(req.SystemPrompt()); prompt != "" {
-		total += types.Message{Role: types.RoleSystem, Content: prompt}.EstimatedTokens()
+		total += tokenizer.CountMessage(tok, types.Message{Role: types.RoleSystem, Content: prompt})
 	}
 
 	for _, tool := range req.ToolDefines() {
-		total += estimateToolTokens(tool)
+		total += estimateToolTokens(tok, tool)
 	}
 	return total
 }
 
-func estimateToolTokens(tool providers.ToolDefine) int64 {
+func estimateToolTokens(tok tokenizer.Tokenizer, tool providers.ToolDefine) int64 {
 	if tool == nil {
 		return 0
 	}
 
 	params, _ := json.Marshal(tool.GetParameters())
 	body := tool.GetName() + "\n" + tool.GetDescription() + "\n" + string(params)
-	return types.Message{Role: types.RoleSystem, Content: body}.EstimatedTokens()
+	return tokenizer.CountMessage(tok, types.Message{Role: types.RoleSystem, Content: body})
 }
 
-func EstimateHistoryTokens(history []types.Message) int64 {
+// EstimateHistoryTokens returns the prompt tokens of history, taking the
+// calibrated count of messages that have one and counting the others with
+// tok. A nil tok uses the rune-count heuristic.
+func EstimateHistoryTokens(tok tokenizer.Tokenizer, history []types.Message) int64 {
 	var total int64
 	for _, msg := range history {
 		if msg.Tokens != 0 {
 			total += msg.Tokens
 			continue
 		}
-		total += msg.EstimatedTokens()
+		total += tokenizer.CountMessage(tok, msg)
 	}
 	return total
 }
@@ -2,8 +2,11 @@ package session
 
 import (
 	"context"
+	"strings"
 	"testing"
 
+	"github.com/basenana/friday/core/providers"
+	"github.com/basenana/friday/core/tokenizer"
 	"github.com/basenana/friday/core/types"
 )
 
@@ -11,13 +14,31 @@ func TestEstimateHistoryTokens_UsesBackfilledTokens(t *testing.T) {
 	msg1 := types.Message{Role: types.RoleUser, Content: "hello", Tokens: 10}
 	msg2 := types.Message{Role: types.RoleUser, Content: "world"}
 
-	total := EstimateHistoryTokens([]types.Message{msg1, msg2})
+	total := EstimateHistoryTokens(nil, []types.Message{msg1, msg2})
 	expectedFromMsg2 := msg2.EstimatedTokens()
 	if total != 10+expectedFromMsg2 {
 		t.Fatalf("expected total=%d, got %d", 10+expectedFromMsg2, total)
 	}
 }
 
+// wordTokenizer counts one token per word.
+type wordTokenizer struct{}
+
+func (wordTokenizer) Name() string            { return "words" }
+func (wordTokenizer) Count(text string) int64 { return int64(len(strings.Fields(text))) }
+
+func TestEstimateRequestOverhead_UsesRequestTokenizer(t *testing.T) {
+	req := providers.NewRequest(strings.Repeat("word ", 40))
+	req.SetToolDefines([]providers.ToolDefine{providers.NewToolDefine("search", "find things", nil)})
+
+	heuristic := EstimateRequestOverhead(req)
+	req.SetTokenizer(wordTokenizer{})
+	// 40 prompt words, then "search", "find things" and the "null" parameters.
+	if got := EstimateRequestOverhead(req); got != 40+4 {
+		t.Fatalf("EstimateRequestOverhead() = %d, want 44 (heuristic gave %d)", got, heuristic)
+	}
+}
+
 func TestEstimatedTokens_UsesReasoningFields(t *testing.T) {
 	msg := types.Message{
 		Role:               types.RoleAssistant,
@@ -81,7 +102,7 @@ func TestTokens_FallsBackToEstimationWhenNoCheckpoint(t *testing.T) {
 	}
 
 	history := sess.GetHistory()
-	if tokens != EstimateHistoryTokens(history) {
+	if tokens != EstimateHistoryTokens(nil, history) {
 		t.Fatalf("expected fallback to match EstimateHistoryTokens")
 	}
 }
@@ -110,3 +131,23 @@ func TestCompactHistory_ResetsTokenCheckpoint(t *testing.T) {
 			ctxState.TokenCheckpoint.PromptTokens, ctxState.TokenCheckpoint.Index)
 	}
 }
+
+func TestSessionCountTokens_UsesTokenizer(t *testing.T) {
+	tok, err := tokenizer.Get(tokenizer.NameO200k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	history := []types.Message{{Role: types.RoleUser, Content: "你好，世界！今天天气很好。"}}
+
+	sess := New("s1", nil, WithTokenizer(tok))
+	want := tok.Count(history[0].Content)
+	if got := sess.CountTokens(history); got != want {
+		t.Fatalf("CountTokens() = %d, want %d", got, want)
+	}
+	if got := sess.Fork().CountTokens(history); got != want {
+		t.Fatalf("forked CountTokens() = %d, want %d", got, want)
+	}
+	if got := New("s2", nil).CountTokens(history); got != history[0].EstimatedTokens() {
+		t.Fatalf("CountTokens() without tokenizer = %d, want the estimate %d", got, history[0].EstimatedTokens())
+	}
+}
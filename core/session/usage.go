@@ -0,0 +1,98 @@
+package session
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/basenana/friday/core/providers"
+)
+
+// UsageWriter persists the token usage and cost of model calls. Message
+// writers can implement it to keep a session's spend across restarts.
+type UsageWriter interface {
+	RecordUsage(sessionID string, usage providers.Tokens, at time.Time) error
+}
+
+// BudgetExceededError is returned once a session has spent its cost budget.
+type BudgetExceededError struct {
+	Spent  float64
+	Budget float64
+}
+
+func (e *BudgetExceededError) Error() string {
+	return fmt.Sprintf("session budget exceeded: spent $%.4f of $%.2f", e.Spent, e.Budget)
+}
+
+// WithCost restores the cost a session had already spent.
+func WithCost(spent float64) Option {
+	return func(s *Session) {
+		s.cost = spent
+	}
+}
+
+// WithCostBudget limits what a session may spend, in USD. Zero means no limit.
+func WithCostBudget(limit float64) Option {
+	return func(s *Session) {
+		s.costBudget = limit
+	}
+}
+
+// RecordUsage adds the usage of one model call to the root session and
+// persists it when the writer is a UsageWriter. Forks spend from the
+// budget of their root, while the prompt cache hits are counted on the
+// session itself.
+func (s *Session) RecordUsage(usage providers.Tokens) {
+	st := s.EnsureContextState()
+	s.mu.Lock()
+	st.Cache.Record(usage.PromptTokens, usage.CachedPromptTokens)
+	s.mu.Unlock()
+
+	root := s.eventBusOwner()
+	root.mu.Lock()
+	root.cost += usage.Cost
+	writer := root.writer
+	root.mu.Unlock()
+
+	if s.Temporary || writer == nil {
+		return
+	}
+	if uw, ok := writer.(UsageWriter); ok {
+		uw.RecordUsage(root.ID, usage, time.Now())
+	}
+}
+
+// CacheStats returns the prompt cache hits of this session's model calls.
+func (s *Session) CacheStats() CacheStats {
+	st := s.EnsureContextState()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return st.Cache
+}
+
+// Cost returns what the root session has spent so far, in USD.
+func (s *Session) Cost() float64 {
+	root := s.eventBusOwner()
+	root.mu.RLock()
+	defer root.mu.RUnlock()
+	return root.cost
+}
+
+// CostBudget returns the spending limit of the root session; zero means none.
+func (s *Session) CostBudget() float64 {
+	root := s.eventBusOwner()
+	root.mu.RLock()
+	defer root.mu.RUnlock()
+	return root.costBudget
+}
+
+// CheckBudget returns a *BudgetExceededError once the root session has
+// spent its budget.
+func (s *Session) CheckBudget() error {
+	root := s.eventBusOwner()
+	root.mu.RLock()
+	defer root.mu.RUnlock()
+	if root.costBudget > 0 && root.cost >= root.costBudget {
+		return &BudgetExceededError{Spent: root.cost, Budget: root.costBudget}
+	}
+	return nil
+}
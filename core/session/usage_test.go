@@ -0,0 +1,92 @@
+package session
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/basenana/friday/core/providers"
+	"github.com/basenana/friday/core/types"
+)
+
+type usageRecorder struct {
+	ids   []string
+	usage []providers.Tokens
+}
+
+func (r *usageRecorder) AppendMessages(string, ...types.Message) error  { return nil }
+func (r *usageRecorder) ReplaceMessages(string, ...types.Message) error { return nil }
+
+func (r *usageRecorder) RecordUsage(sessionID string, usage providers.Tokens, _ time.Time) error {
+	r.ids = append(r.ids, sessionID)
+	r.usage = append(r.usage, usage)
+	return nil
+}
+
+func TestRecordUsage_AccumulatesOnRootAndPersists(t *testing.T) {
+	w := &usageRecorder{}
+	root := New("root", nil, WithMessageWriter(w), WithCost(0.5))
+	child := root.Fork()
+
+	root.RecordUsage(providers.Tokens{PromptTokens: 10, Cost: 0.25})
+	child.RecordUsage(providers.Tokens{PromptTokens: 20, Cost: 0.25})
+
+	if root.Cost() != 1 || child.Cost() != 1 {
+		t.Fatalf("cost = %v/%v, want 1", root.Cost(), child.Cost())
+	}
+	if len(w.ids) != 2 || w.ids[0] != "root" || w.ids[1] != "root" {
+		t.Fatalf("persisted usage for %v, want root twice", w.ids)
+	}
+}
+
+func TestRecordUsage_TemporaryIsNotPersisted(t *testing.T) {
+	w := &usageRecorder{}
+	sess := New("tmp", nil, WithMessageWriter(w), WithTemporary(true))
+	sess.RecordUsage(providers.Tokens{Cost: 0.1})
+
+	if len(w.usage) != 0 {
+		t.Fatalf("temporary session persisted usage %v", w.usage)
+	}
+	if sess.Cost() != 0.1 {
+		t.Fatalf("cost = %v, want 0.1", sess.Cost())
+	}
+}
+
+func TestCheckBudget(t *testing.T) {
+	unlimited := New("free", nil, WithCost(100))
+	if err := unlimited.CheckBudget(); err != nil {
+		t.Fatalf("CheckBudget() without budget = %v", err)
+	}
+
+	sess := New("capped", nil, WithCostBudget(1))
+	sess.RecordUsage(providers.Tokens{Cost: 0.6})
+	if err := sess.CheckBudget(); err != nil {
+		t.Fatalf("CheckBudget() under budget = %v", err)
+	}
+	sess.Fork().RecordUsage(providers.Tokens{Cost: 0.6})
+
+	var budgetErr *BudgetExceededError
+	if err := sess.CheckBudget(); !errors.As(err, &budgetErr) || budgetErr.Budget != 1 {
+		t.Fatalf("CheckBudget() = %v, want BudgetExceededError", err)
+	}
+}
+
+func TestRecordUsage_CountsCacheHitsPerSession(t *testing.T) {
+	root := New("root", nil)
+	root.RecordUsage(providers.Tokens{PromptTokens: 100, CachedPromptTokens: 20})
+	root.RecordUsage(providers.Tokens{PromptTokens: 100, CachedPromptTokens: 80})
+
+	stats := root.CacheStats()
+	if stats.Requests != 2 || stats.HitRate() != 0.5 || stats.LastHitRate != 0.8 {
+		t.Fatalf("cache stats = %+v (hit rate %v), want 2 requests at 0.5 and last 0.8", stats, stats.HitRate())
+	}
+
+	child := root.Fork()
+	if got := child.CacheStats(); got.Requests != 0 {
+		t.Fatalf("fork inherited cache stats %+v", got)
+	}
+	child.RecordUsage(providers.Tokens{PromptTokens: 100})
+	if got := root.CacheStats(); got.Requests != 2 {
+		t.Fatalf("fork usage counted on root cache stats %+v", got)
+	}
+}
@@ -0,0 +1,163 @@
+// Package tokenizer counts the tokens of text the way a model's tokenizer
+// would, so context budgets hold for code and CJK text as well as prose.
+package tokenizer
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+
+	"github.com/basenana/friday/core/types"
+	"github.com/tiktoken-go/tokenizer"
+)
+
+const (
+	// NameEstimate is the rune-count heuristic of types.Message.EstimatedTokens.
+	NameEstimate = "estimate"
+	// NameCl100k is the BPE vocabulary of GPT-4 and GPT-3.5.
+	NameCl100k = "cl100k_base"
+	// NameO200k is the BPE vocabulary of GPT-4o, GPT-4.1, GPT-5 and the o-series.
+	NameO200k = "o200k_base"
+	// NameClaude approximates the Anthropic tokenizer, whose vocabulary is not published.
+	NameClaude = "claude"
+)
+
+// claudeRatio scales cl100k counts to approximate Claude's, which splits the
+// same text into somewhat more tokens.
+const claudeRatio = 1.15
+
+// Tokenizer counts the tokens of text.
+type Tokenizer interface {
+	Name() string
+	Count(text string) int64
+}
+
+// Get returns the tokenizer called name. The BPE vocabularies are compiled
+// in, so no tokenizer needs network access; each is loaded on first use.
+func Get(name string) (Tokenizer, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case NameEstimate, "":
+		return Estimate, nil
+	case NameCl100k, "cl100k":
+		return cl100k, nil
+	case NameO200k, "o200k":
+		return o200k, nil
+	case NameClaude, "anthropic":
+		return claude, nil
+	default:
+		return nil, fmt.Errorf("unknown tokenizer: %s", name)
+	}
+}
+
+// ForModel returns the tokenizer matching a model of provider: o200k for
+// current OpenAI models, cl100k for GPT-4 and GPT-3.5, the Claude
+// approximation for Anthropic models, and cl100k for any other model, being
+// closer to most BPE vocabularies than the rune-count heuristic.
+func ForModel(provider, model string) Tokenizer {
+	provider = strings.ToLower(strings.TrimSpace(provider))
+	model = strings.ToLower(strings.TrimSpace(model))
+	if i := strings.LastIndex(model, "/"); i >= 0 {
+		model = model[i+1:]
+	}
+
+	switch {
+	case provider == "anthropic" || strings.HasPrefix(model, "claude"):
+		return claude
+	case hasAnyPrefix(model, "gpt-4o", "chatgpt-4o", "gpt-4.1", "gpt-4.5", "gpt-5", "gpt-oss", "o1", "o3", "o4"):
+		return o200k
+	case hasAnyPrefix(model, "gpt-4", "gpt-3.5", "gpt-35", "text-embedding"):
+		return cl100k
+	case provider == "openai" && strings.HasPrefix(model, "gpt-"):
+		return o200k
+	default:
+		return cl100k
+	}
+}
+
+func hasAnyPrefix(s string, prefixes ...string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// CountMessage returns the tokens of msg counted by t, the way
+// types.Message.EstimatedTokens does with the rune-count heuristic. A nil t
+// uses that heuristic.
+func CountMessage(t Tokenizer, msg types.Message) int64 {
+	if t == nil || t == Estimate {
+		return msg.EstimatedTokens()
+	}
+
+	total := t.Count(msg.Content) + t.Count(msg.Reasoning) + t.Count(msg.RedactedThinking)
+	// Signatures are opaque base64 blobs, which BPE splits at about 4 bytes a token.
+	total += int64(len(msg.ReasoningSignature) / 4)
+
+	if msg.Image != nil {
+		switch msg.Image.Type {
+		case types.ImageTypeURL:
+			total += t.Count(msg.Image.URL)
+		case types.ImageTypeBase64:
+			total += 1000
+		}
+	}
+	for _, tc := range msg.ToolCalls {
+		total += t.Count(tc.ID) + t.Count(tc.Name) + t.Count(tc.Arguments)
+	}
+	if msg.ToolResult != nil {
+		total += t.Count(msg.ToolResult.CallID) + t.Count(msg.ToolResult.Content)
+	}
+	return total
+}
+
+// Estimate is the rune-count heuristic: half a token per rune.
+var Estimate Tokenizer = estimate{}
+
+type estimate struct{}
+
+func (estimate) Name() string { return NameEstimate }
+
+func (estimate) Count(text string) int64 {
+	return int64(float64(len([]rune(text))) * 0.5)
+}
+
+var (
+	cl100k = &bpe{name: NameCl100k, encoding: tokenizer.Cl100kBase, ratio: 1}
+	o200k  = &bpe{name: NameO200k, encoding: tokenizer.O200kBase, ratio: 1}
+	claude = &bpe{name: NameClaude, encoding: tokenizer.Cl100kBase, ratio: claudeRatio}
+)
+
+// bpe counts with a tiktoken vocabulary, scaled by ratio.
+type bpe struct {
+	name     string
+	encoding tokenizer.Encoding
+	ratio    float64
+
+	once  sync.Once
+	codec tokenizer.Codec
+}
+
+func (b *bpe) Name() string { return b.name }
+
+func (b *bpe) Count(text string) int64 {
+	if text == "" {
+		return 0
+	}
+	b.once.Do(func() {
+		b.codec, _ = tokenizer.Get(b.encoding)
+	})
+	if b.codec == nil {
+		return Estimate.Count(text)
+	}
+	n, err := b.codec.Count(text)
+	if err != nil {
+		return Estimate.Count(text)
+	}
+	if b.ratio == 1 {
+		return int64(n)
+	}
+	return int64(math.Ceil(float64(n) * b.ratio))
+}
@@ -0,0 +1,80 @@
+package tokenizer
+
+import (
+	"testing"
+
+	"github.com/basenana/friday/core/types"
+)
+
+func TestCount(t *testing.T) {
+	const (
+		prose = "hello world"
+		cjk   = "你好，世界！今天天气很好。"
+		code  = "func main() {\n\tfmt.Println(\"hi\")\n}"
+	)
+	tests := []struct {
+		name  string
+		text  string
+		wants map[string]int64
+	}{
+		{name: "prose", text: prose, wants: map[string]int64{NameCl100k: 2, NameO200k: 2, NameClaude: 3, NameEstimate: 5}},
+		{name: "cjk", text: cjk, wants: map[string]int64{NameCl100k: 16, NameO200k: 9, NameClaude: 19, NameEstimate: 6}},
+		{name: "code", text: code, wants: map[string]int64{NameCl100k: 10, NameO200k: 10, NameClaude: 12, NameEstimate: 17}},
+	}
+	for _, tt := range tests {
+		for name, want := range tt.wants {
+			tok, err := Get(name)
+			if err != nil {
+				t.Fatalf("Get(%q) error = %v", name, err)
+			}
+			if got := tok.Count(tt.text); got != want {
+				t.Errorf("%s: %s.Count() = %d, want %d", tt.name, name, got, want)
+			}
+		}
+	}
+}
+
+func TestGetUnknown(t *testing.T) {
+	if _, err := Get("sentencepiece"); err == nil {
+		t.Fatal("expected an error for an unknown tokenizer")
+	}
+}
+
+func TestForModel(t *testing.T) {
+	tests := []struct {
+		provider, model, want string
+	}{
+		{"openai", "gpt-4o-mini", NameO200k},
+		{"openai", "gpt-4.1", NameO200k},
+		{"openai", "o3-mini", NameO200k},
+		{"openai", "gpt-4-turbo", NameCl100k},
+		{"openai", "openai/gpt-5", NameO200k},
+		{"anthropic", "claude-sonnet-4-5", NameClaude},
+		{"openai", "anthropic/claude-3-haiku", NameClaude},
+		{"gemini", "gemini-2.5-pro", NameCl100k},
+		{"ollama", "qwen3:8b", NameCl100k},
+	}
+	for _, tt := range tests {
+		if got := ForModel(tt.provider, tt.model).Name(); got != tt.want {
+			t.Errorf("ForModel(%q, %q) = %s, want %s", tt.provider, tt.model, got, tt.want)
+		}
+	}
+}
+
+func TestCountMessage(t *testing.T) {
+	msg := types.Message{
+		Role:       types.RoleAssistant,
+		Content:    "hello world",
+		ToolCalls:  []types.ToolCall{{ID: "call_1", Name: "bash", Arguments: `{"command":"ls"}`}},
+		ToolResult: &types.ToolResult{CallID: "call_1", Content: "hello world"},
+	}
+	if got, want := CountMessage(nil, msg), msg.EstimatedTokens(); got != want {
+		t.Fatalf("CountMessage(nil) = %d, want the estimate %d", got, want)
+	}
+
+	tok, _ := Get(NameCl100k)
+	want := tok.Count("hello world")*2 + tok.Count("call_1")*2 + tok.Count("bash") + tok.Count(`{"command":"ls"}`)
+	if got := CountMessage(tok, msg); got != want {
+		t.Fatalf("CountMessage(cl100k) = %d, want %d", got, want)
+	}
+}
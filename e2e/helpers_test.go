@@ -29,6 +29,7 @@ import (
 	"github.com/basenana/friday/core/agents"
 	"github.com/basenana/friday/core/api"
 	"github.com/basenana/friday/core/providers"
+	"github.com/basenana/friday/core/providers/replay"
 	"github.com/basenana/friday/core/session"
 	"github.com/basenana/friday/core/tools"
 	"github.com/basenana/friday/core/types"
@@ -66,7 +67,8 @@ func mustModel(t *testing.T, cfg *E2EConfig, name string) config.ModelConfig {
 	return m
 }
 
-// newClient builds a real providers.Client from a named model.
+// newClient builds a real providers.Client from a named model, recording or
+// replaying it as FRIDAY_PROVIDER_MODE says.
 func newClient(t *testing.T, cfg *E2EConfig, modelName string) providers.Client {
 	t.Helper()
 	m := mustModel(t, cfg, modelName)
@@ -74,6 +76,10 @@ func newClient(t *testing.T, cfg *E2EConfig, modelName string) providers.Client
 	if err != nil {
 		t.Fatalf("create provider client for %s: %v", modelName, err)
 	}
+	c, err = replay.FromEnv(c, replay.WithNamespace(modelName))
+	if err != nil {
+		t.Fatalf("wrap provider client for %s: %v", modelName, err)
+	}
 	return c
 }
 
@@ -59,13 +59,26 @@ func TestPermission_CompoundAllow(t *testing.T) {
 	}
 }
 
-// TestPermission_UnknownCommandDeny verifies the default-deny policy for
+// TestPermission_UnknownCommandAsk verifies the default policy asks for
 // commands absent from both allow and deny lists.
-func TestPermission_UnknownCommandDeny(t *testing.T) {
+func TestPermission_UnknownCommandAsk(t *testing.T) {
 	perm := sandbox.NewPermission(sandbox.DefaultConfig())
 	decision, _ := perm.Check("totally_unknown_binary_xyz")
+	if decision != sandbox.Ask {
+		t.Errorf("expected Ask for unknown command, got %s", decision)
+	}
+}
+
+// TestExecutor_UnknownCommandDenyWithoutApprover verifies that non-interactive
+// runs fall back to deny for commands that would need approval.
+func TestExecutor_UnknownCommandDenyWithoutApprover(t *testing.T) {
+	exec := sandbox.NewExecutor(sandbox.DefaultConfig())
+	decision, _, err := exec.Authorize(context.Background(), "totally_unknown_binary_xyz")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
 	if decision != sandbox.Deny {
-		t.Errorf("expected Deny for unknown command, got %s", decision)
+		t.Errorf("expected Deny without approver, got %s", decision)
 	}
 }
 
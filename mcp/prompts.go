@@ -0,0 +1,50 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// ListPrompts returns the prompts and prompt templates the server offers.
+func (s *Server) ListPrompts(ctx context.Context) ([]mcp.Prompt, error) {
+	c, err := s.activeClient()
+	if err != nil {
+		return nil, err
+	}
+	result, err := c.ListPrompts(ctx, mcp.ListPromptsRequest{})
+	if err != nil {
+		return nil, err
+	}
+	return result.Prompts, nil
+}
+
+// GetPrompt renders the prompt name with args and flattens its messages into
+// a single text suitable for sending to the agent as a user message.
+func (s *Server) GetPrompt(ctx context.Context, name string, args map[string]string) (string, error) {
+	c, err := s.activeClient()
+	if err != nil {
+		return "", err
+	}
+	result, err := c.GetPrompt(ctx, mcp.GetPromptRequest{
+		Params: mcp.GetPromptParams{Name: name, Arguments: args},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	parts := make([]string, 0, len(result.Messages))
+	for _, msg := range result.Messages {
+		switch content := msg.Content.(type) {
+		case mcp.TextContent:
+			parts = append(parts, content.Text)
+		case mcp.EmbeddedResource:
+			if text, ok := content.Resource.(mcp.TextResourceContents); ok {
+				parts = append(parts, fmt.Sprintf("<resource uri=%q>\n%s\n</resource>", text.URI, text.Text))
+			}
+		}
+	}
+	return strings.Join(parts, "\n\n"), nil
+}
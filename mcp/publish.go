@@ -0,0 +1,213 @@
+package mcp
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/basenana/friday/core/tools"
+	"github.com/basenana/friday/core/types"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// ChatToolName is the name of the tool that forwards a message to the agent.
+const ChatToolName = "chat"
+
+// ChatFunc runs one agent turn in sessionID and returns the assistant reply.
+type ChatFunc func(ctx context.Context, sessionID, message string) (string, error)
+
+// Publisher exposes Friday's own tools to external MCP clients. The tool
+// handlers are published as-is, so sandbox permission checks, protected paths
+// and write roots apply to remote callers exactly as they do to the agent.
+type Publisher struct {
+	server *server.MCPServer
+	tools  []string
+}
+
+// NewPublisher builds an MCP server publishing toolList. Tools without a
+// handler and repeated names are skipped.
+func NewPublisher(name, version string, toolList []*tools.Tool) *Publisher {
+	p := &Publisher{
+		server: server.NewMCPServer(name, version,
+			server.WithToolCapabilities(false),
+			server.WithRecovery(),
+		),
+	}
+	seen := make(map[string]bool, len(toolList))
+	for _, t := range toolList {
+		if t == nil || t.Handler == nil || seen[t.Name] {
+			continue
+		}
+		seen[t.Name] = true
+		p.server.AddTool(publishedTool(t), publishedHandler(t))
+		p.tools = append(p.tools, t.Name)
+	}
+	return p
+}
+
+// Tools returns the names of the published tools in registration order.
+func (p *Publisher) Tools() []string {
+	return p.tools
+}
+
+// ServeStdio serves the MCP protocol over in/out until ctx is cancelled or
+// in reaches EOF.
+func (p *Publisher) ServeStdio(ctx context.Context, in io.Reader, out io.Writer) error {
+	return server.NewStdioServer(p.server).Listen(ctx, in, out)
+}
+
+// HTTPHandler returns a streamable-HTTP handler serving the MCP endpoint at
+// /mcp. The published tools run commands, so every request needs authToken
+// as a Bearer token, and its Host and any Origin must be a loopback name or
+// one of allowedHosts, which keeps DNS-rebinding web pages out.
+func (p *Publisher) HTTPHandler(authToken string, allowedHosts ...string) (http.Handler, error) {
+	if authToken == "" {
+		return nil, errors.New("the http transport requires an auth token")
+	}
+	handler := authMiddleware(authToken, server.NewStreamableHTTPServer(p.server))
+	return hostMiddleware(allowedHosts, handler), nil
+}
+
+// NewChatTool returns the chat tool backed by chat. Callers may pass a
+// session_id to continue a conversation; otherwise a new session is started
+// and its id is returned alongside the reply.
+func NewChatTool(chat ChatFunc) *tools.Tool {
+	return tools.NewTool(ChatToolName,
+		tools.WithDescription(`Send a message to the Friday agent and wait for its reply.
+The agent can read and edit files, run commands and use its skills.
+Pass the returned session_id back to continue the same conversation.`),
+		tools.WithString("message",
+			tools.Required(),
+			tools.Description("The message to send to the agent"),
+		),
+		tools.WithString("session_id",
+			tools.Description("Session to continue; omit to start a new session"),
+		),
+		tools.WithToolHandler(func(ctx context.Context, req *tools.Request) (*tools.Result, error) {
+			message, _ := req.Arguments["message"].(string)
+			if strings.TrimSpace(message) == "" {
+				return tools.NewToolResultError("message is required"), nil
+			}
+			sessionID, _ := req.Arguments["session_id"].(string)
+			if sessionID == "" {
+				sessionID = types.NewID()
+			}
+
+			reply, err := chat(ctx, sessionID, message)
+			if err != nil {
+				return tools.NewToolResultError(fmt.Sprintf("session_id: %s\nerror: %s", sessionID, err)), nil
+			}
+			return &tools.Result{Content: []tools.Content{
+				tools.TextContent{Type: "text", Text: reply},
+				tools.TextContent{Type: "text", Text: "session_id: " + sessionID},
+			}}, nil
+		}),
+	)
+}
+
+func publishedTool(t *tools.Tool) mcp.Tool {
+	schemaType := t.InputSchema.Type
+	if schemaType == "" {
+		schemaType = "object"
+	}
+	properties := t.InputSchema.Properties
+	if properties == nil {
+		properties = map[string]any{}
+	}
+	return mcp.Tool{
+		Name:        t.Name,
+		Description: t.Description,
+		InputSchema: mcp.ToolInputSchema{
+			Type:       schemaType,
+			Properties: properties,
+			Required:   t.InputSchema.Required,
+		},
+	}
+}
+
+func publishedHandler(t *tools.Tool) server.ToolHandlerFunc {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		request := &tools.Request{Arguments: req.GetArguments()}
+		if session := server.ClientSessionFromContext(ctx); session != nil {
+			request.SessionID = session.SessionID()
+		}
+
+		result, err := t.Handler(ctx, request)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		if result == nil {
+			return mcp.NewToolResultText(""), nil
+		}
+
+		out := &mcp.CallToolResult{IsError: result.IsError}
+		for _, content := range result.Content {
+			if text, ok := content.(tools.TextContent); ok {
+				out.Content = append(out.Content, mcp.NewTextContent(text.Text))
+			}
+		}
+		return out, nil
+	}
+}
+
+// authMiddleware rejects requests without the expected Bearer token.
+func authMiddleware(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.SplitN(r.Header.Get("Authorization"), " ", 2)
+		if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") ||
+			subtle.ConstantTimeCompare([]byte(parts[1]), []byte(token)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// hostMiddleware rejects requests addressed to, or sent from pages of, hosts
+// other than loopback ones and allowed.
+func hostMiddleware(allowed []string, next http.Handler) http.Handler {
+	ok := func(host string) bool {
+		host = strings.ToLower(host)
+		if host == "localhost" {
+			return true
+		}
+		if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+			return true
+		}
+		for _, h := range allowed {
+			if strings.EqualFold(h, host) {
+				return true
+			}
+		}
+		return false
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !ok(hostname(r.Host)) {
+			http.Error(w, "host not allowed", http.StatusForbidden)
+			return
+		}
+		if origin := r.Header.Get("Origin"); origin != "" {
+			u, err := url.Parse(origin)
+			if err != nil || !ok(u.Hostname()) {
+				http.Error(w, "origin not allowed", http.StatusForbidden)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// hostname strips the port from a Host header.
+func hostname(hostport string) string {
+	if host, _, err := net.SplitHostPort(hostport); err == nil {
+		return host
+	}
+	return strings.Trim(hostport, "[]")
+}
@@ -0,0 +1,150 @@
+package mcp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/basenana/friday/core/tools"
+	"github.com/basenana/friday/sandbox"
+)
+
+func newPublishedClient(t *testing.T, publisher *Publisher, token string) []*tools.Tool {
+	t.Helper()
+	handler, err := publisher.HTTPHandler(token)
+	if err != nil {
+		t.Fatalf("HTTPHandler() error = %v", err)
+	}
+	httpServer := httptest.NewServer(handler)
+	t.Cleanup(httpServer.Close)
+
+	srv := &Server{
+		Name: "friday",
+		SSE: &MCPSse{
+			Endpoint: httpServer.URL,
+			Headers:  map[string]string{"Authorization": "Bearer " + token},
+		},
+	}
+	t.Cleanup(func() { _ = srv.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := srv.Start(ctx); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	ts, err := srv.InitTools(ctx)
+	if err != nil {
+		t.Fatalf("InitTools() error = %v", err)
+	}
+	return ts
+}
+
+func TestPublisherSkipsDuplicateTools(t *testing.T) {
+	local := tools.NewTool("local_tool", tools.WithToolHandler(func(ctx context.Context, req *tools.Request) (*tools.Result, error) {
+		return tools.NewToolResultText("ok"), nil
+	}))
+	noHandler := tools.NewTool("no_handler")
+
+	publisher := NewPublisher("friday", "test", []*tools.Tool{local, noHandler, local})
+	if got := strings.Join(publisher.Tools(), ","); got != "local_tool" {
+		t.Errorf("Tools() = %q, want local_tool", got)
+	}
+}
+
+func TestPublisherAppliesSandboxPermissions(t *testing.T) {
+	cfg := sandbox.DefaultConfig()
+	cfg.Sandbox.Enabled = false
+	cfg.Permissions.Deny = []string{"rm"}
+	exec := sandbox.NewExecutor(cfg)
+
+	publisher := NewPublisher("friday", "test", []*tools.Tool{sandbox.NewBashTool(exec, t.TempDir())})
+	ts := newPublishedClient(t, publisher, "secret")
+
+	out, err := callTool(t, ts, "bash", map[string]any{"command": "rm -rf /tmp/friday-publish-test"})
+	if err != nil {
+		t.Fatalf("call bash error = %v", err)
+	}
+	if !strings.Contains(strings.ToLower(out), "denied") {
+		t.Errorf("bash output = %q, want permission denied", out)
+	}
+}
+
+func TestPublisherChatTool(t *testing.T) {
+	var gotSession, gotMessage string
+	chat := func(ctx context.Context, sessionID, message string) (string, error) {
+		gotSession, gotMessage = sessionID, message
+		return "hello back", nil
+	}
+
+	publisher := NewPublisher("friday", "test", []*tools.Tool{NewChatTool(chat)})
+	ts := newPublishedClient(t, publisher, "secret")
+
+	out, err := callTool(t, ts, ChatToolName, map[string]any{"message": "hello", "session_id": "s1"})
+	if err != nil {
+		t.Fatalf("call chat error = %v", err)
+	}
+	if gotSession != "s1" || gotMessage != "hello" {
+		t.Errorf("chat called with (%q, %q), want (s1, hello)", gotSession, gotMessage)
+	}
+	if !strings.Contains(out, "hello back") || !strings.Contains(out, "session_id: s1") {
+		t.Errorf("chat output = %q, want reply and session id", out)
+	}
+
+	if _, err = callTool(t, ts, ChatToolName, map[string]any{"message": "again"}); err != nil {
+		t.Fatalf("call chat error = %v", err)
+	}
+	if gotSession == "" || gotSession == "s1" {
+		t.Errorf("new chat session id = %q, want a generated id", gotSession)
+	}
+}
+
+func TestPublisherHTTPRequiresToken(t *testing.T) {
+	publisher := NewPublisher("friday", "test", nil)
+	if _, err := publisher.HTTPHandler(""); err == nil {
+		t.Fatal("expected an error without an auth token")
+	}
+	handler, err := publisher.HTTPHandler("secret", "friday.internal")
+	if err != nil {
+		t.Fatalf("HTTPHandler() error = %v", err)
+	}
+	httpServer := httptest.NewServer(handler)
+	defer httpServer.Close()
+
+	tests := []struct {
+		name   string
+		host   string
+		origin string
+		token  string
+		want   int
+	}{
+		{name: "no token", want: http.StatusUnauthorized},
+		{name: "wrong token", token: "guess", want: http.StatusUnauthorized},
+		{name: "rebound host", host: "evil.example.com", token: "secret", want: http.StatusForbidden},
+		{name: "foreign origin", origin: "https://evil.example.com", token: "secret", want: http.StatusForbidden},
+		{name: "allowed host", host: "friday.internal:8998", origin: "http://localhost:3000", token: "secret", want: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest(http.MethodPost, httpServer.URL, strings.NewReader(`{}`))
+		req.Header.Set("Content-Type", "application/json")
+		if tt.host != "" {
+			req.Host = tt.host
+		}
+		if tt.origin != "" {
+			req.Header.Set("Origin", tt.origin)
+		}
+		if tt.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s: POST error = %v", tt.name, err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, resp.StatusCode, tt.want)
+		}
+	}
+}
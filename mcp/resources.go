@@ -0,0 +1,185 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/basenana/friday/core/tools"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	ListResourcesToolName = "mcp_list_resources"
+	ReadResourceToolName  = "mcp_read_resource"
+)
+
+// HasResources reports whether the server advertised the resources capability
+// during initialize.
+func (s *Server) HasResources() bool {
+	c := s.Client()
+	return c != nil && c.GetServerCapabilities().Resources != nil
+}
+
+// HasPrompts reports whether the server advertised the prompts capability
+// during initialize.
+func (s *Server) HasPrompts() bool {
+	c := s.Client()
+	return c != nil && c.GetServerCapabilities().Prompts != nil
+}
+
+// ListResources returns the concrete resources and resource templates the
+// server offers.
+func (s *Server) ListResources(ctx context.Context) ([]mcp.Resource, []mcp.ResourceTemplate, error) {
+	c, err := s.activeClient()
+	if err != nil {
+		return nil, nil, err
+	}
+	resources, err := c.ListResources(ctx, mcp.ListResourcesRequest{})
+	if err != nil {
+		return nil, nil, err
+	}
+	// Templates are optional; servers without any may reject the request.
+	var templates []mcp.ResourceTemplate
+	if result, err := c.ListResourceTemplates(ctx, mcp.ListResourceTemplatesRequest{}); err == nil {
+		templates = result.ResourceTemplates
+	}
+	return resources.Resources, templates, nil
+}
+
+// ReadResource reads uri and renders its contents as text. Binary contents
+// are summarized rather than inlined.
+func (s *Server) ReadResource(ctx context.Context, uri string) (string, error) {
+	c, err := s.activeClient()
+	if err != nil {
+		return "", err
+	}
+	result, err := c.ReadResource(ctx, mcp.ReadResourceRequest{
+		Params: mcp.ReadResourceParams{URI: uri},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	parts := make([]string, 0, len(result.Contents))
+	for _, content := range result.Contents {
+		switch c := content.(type) {
+		case mcp.TextResourceContents:
+			parts = append(parts, c.Text)
+		case mcp.BlobResourceContents:
+			parts = append(parts, fmt.Sprintf("[binary resource %s, %s, %d bytes base64]", c.URI, c.MIMEType, len(c.Blob)))
+		}
+	}
+	return strings.Join(parts, "\n\n"), nil
+}
+
+// NewResourceTools returns the mcp_list_resources / mcp_read_resource pair
+// covering every server that supports resources. It returns nil when none do.
+func NewResourceTools(servers []*Server) []*tools.Tool {
+	byName := make(map[string]*Server)
+	for _, s := range servers {
+		if s.HasResources() {
+			byName[s.Name] = s
+		}
+	}
+	if len(byName) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(byName))
+	for name := range byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	annotations := map[string]string{AnnotationServer: strings.Join(names, ",")}
+
+	return []*tools.Tool{
+		tools.NewTool(ListResourcesToolName,
+			tools.WithDescription(`List the resources (documents, files, records) offered by connected MCP servers.
+Returns each resource URI with its name and description; resource templates are listed with their URI pattern.
+Use mcp_read_resource to fetch the contents of a URI.`),
+			tools.WithString("server",
+				tools.Description("Only list resources from this MCP server"),
+				tools.Enum(names...),
+			),
+			tools.WithToolAnnotations(annotations),
+			tools.WithToolHandler(func(ctx context.Context, req *tools.Request) (*tools.Result, error) {
+				only, _ := req.Arguments["server"].(string)
+				if only != "" && byName[only] == nil {
+					return tools.NewToolResultError(fmt.Sprintf("unknown MCP server: %s", only)), nil
+				}
+
+				var b strings.Builder
+				for _, name := range names {
+					if only != "" && name != only {
+						continue
+					}
+					resources, templates, err := byName[name].ListResources(ctx)
+					if err != nil {
+						fmt.Fprintf(&b, "%s: error: %s\n", name, err)
+						continue
+					}
+					fmt.Fprintf(&b, "%s:\n", name)
+					if len(resources) == 0 && len(templates) == 0 {
+						b.WriteString("  (no resources)\n")
+					}
+					for _, r := range resources {
+						writeResourceLine(&b, r.URI, r.Name, r.MIMEType, r.Description)
+					}
+					for _, t := range templates {
+						uri := ""
+						if t.URITemplate != nil && t.URITemplate.Template != nil {
+							uri = t.URITemplate.Raw()
+						}
+						writeResourceLine(&b, uri, t.Name+" (template)", t.MIMEType, t.Description)
+					}
+				}
+				return tools.NewToolResultText(b.String()), nil
+			}),
+		),
+		tools.NewTool(ReadResourceToolName,
+			tools.WithDescription(`Read the contents of an MCP resource by URI.
+Use mcp_list_resources first to discover URIs.`),
+			tools.WithString("server",
+				tools.Required(),
+				tools.Description("The MCP server that offers the resource"),
+				tools.Enum(names...),
+			),
+			tools.WithString("uri",
+				tools.Required(),
+				tools.Description("The resource URI"),
+			),
+			tools.WithToolAnnotations(annotations),
+			tools.WithToolHandler(func(ctx context.Context, req *tools.Request) (*tools.Result, error) {
+				name, _ := req.Arguments["server"].(string)
+				uri, _ := req.Arguments["uri"].(string)
+				server := byName[name]
+				if server == nil {
+					return tools.NewToolResultError(fmt.Sprintf("unknown MCP server: %s", name)), nil
+				}
+				if uri == "" {
+					return tools.NewToolResultError("uri is required"), nil
+				}
+				text, err := server.ReadResource(ctx, uri)
+				if err != nil {
+					return tools.NewToolResultError(fmt.Sprintf("read %s: %s", uri, err)), nil
+				}
+				return tools.NewToolResultText(text), nil
+			}),
+		),
+	}
+}
+
+func writeResourceLine(b *strings.Builder, uri, name, mimeType, description string) {
+	fmt.Fprintf(b, "  - %s", uri)
+	if name != "" {
+		fmt.Fprintf(b, " — %s", name)
+	}
+	if mimeType != "" {
+		fmt.Fprintf(b, " [%s]", mimeType)
+	}
+	if description != "" {
+		fmt.Fprintf(b, ": %s", description)
+	}
+	b.WriteString("\n")
+}
@@ -0,0 +1,105 @@
+package mcp
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func newResourceServer(t *testing.T) *Server {
+	t.Helper()
+	srv := server.NewMCPServer("docs", "1.0.0",
+		server.WithResourceCapabilities(false, false),
+		server.WithPromptCapabilities(false),
+	)
+	srv.AddResource(mcp.NewResource("docs://readme", "readme",
+		mcp.WithResourceDescription("Project readme"),
+		mcp.WithMIMEType("text/markdown"),
+	), func(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		return []mcp.ResourceContents{mcp.TextResourceContents{URI: req.Params.URI, Text: "# Readme"}}, nil
+	})
+	srv.AddPrompt(mcp.NewPrompt("summarize",
+		mcp.WithPromptDescription("Summarize a topic"),
+		mcp.WithArgument("topic", mcp.RequiredArgument()),
+	), func(ctx context.Context, req mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+		return mcp.NewGetPromptResult("", []mcp.PromptMessage{
+			mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent("Summarize "+req.Params.Arguments["topic"])),
+		}), nil
+	})
+
+	httpServer := httptest.NewServer(server.NewStreamableHTTPServer(srv))
+	t.Cleanup(httpServer.Close)
+
+	s := &Server{Name: "docs", SSE: &MCPSse{Endpoint: httpServer.URL + "/mcp"}}
+	t.Cleanup(func() { _ = s.Close() })
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := s.Start(ctx); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	return s
+}
+
+func TestResourceTools(t *testing.T) {
+	s := newResourceServer(t)
+	if !s.HasResources() || !s.HasPrompts() {
+		t.Fatalf("HasResources() = %v, HasPrompts() = %v, want both true", s.HasResources(), s.HasPrompts())
+	}
+
+	ts := NewResourceTools([]*Server{s})
+	if len(ts) != 2 {
+		t.Fatalf("NewResourceTools() returned %d tools, want 2", len(ts))
+	}
+	for _, tool := range ts {
+		if tool.Annotations[AnnotationServer] != "docs" {
+			t.Errorf("%s annotation = %q, want docs", tool.Name, tool.Annotations[AnnotationServer])
+		}
+	}
+
+	out, err := callTool(t, ts, ListResourcesToolName, map[string]any{})
+	if err != nil {
+		t.Fatalf("list resources error = %v", err)
+	}
+	if !strings.Contains(out, "docs://readme") || !strings.Contains(out, "Project readme") {
+		t.Errorf("list output = %q, want readme resource", out)
+	}
+
+	out, err = callTool(t, ts, ReadResourceToolName, map[string]any{"server": "docs", "uri": "docs://readme"})
+	if err != nil {
+		t.Fatalf("read resource error = %v", err)
+	}
+	if !strings.Contains(out, "# Readme") {
+		t.Errorf("read output = %q, want readme text", out)
+	}
+}
+
+func TestResourceToolsSkipServersWithoutResources(t *testing.T) {
+	if ts := NewResourceTools([]*Server{{Name: "idle"}}); ts != nil {
+		t.Errorf("NewResourceTools() = %v, want nil", ts)
+	}
+}
+
+func TestGetPrompt(t *testing.T) {
+	s := newResourceServer(t)
+
+	prompts, err := s.ListPrompts(context.Background())
+	if err != nil {
+		t.Fatalf("ListPrompts() error = %v", err)
+	}
+	if len(prompts) != 1 || prompts[0].Name != "summarize" {
+		t.Fatalf("ListPrompts() = %+v, want summarize", prompts)
+	}
+
+	text, err := s.GetPrompt(context.Background(), "summarize", map[string]string{"topic": "mcp"})
+	if err != nil {
+		t.Fatalf("GetPrompt() error = %v", err)
+	}
+	if text != "Summarize mcp" {
+		t.Errorf("GetPrompt() = %q, want %q", text, "Summarize mcp")
+	}
+}
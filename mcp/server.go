@@ -2,39 +2,186 @@ package mcp
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/basenana/friday/core/logger"
 	"github.com/basenana/friday/core/tools"
 	"github.com/mark3labs/mcp-go/client"
 	"github.com/mark3labs/mcp-go/client/transport"
 	"github.com/mark3labs/mcp-go/mcp"
 )
 
+var errServerRestarting = errors.New("mcp server is restarting")
+
+// shutdownTimeout bounds how long Close waits for a graceful transport
+// shutdown before the stdio child process is killed.
+const shutdownTimeout = 5 * time.Second
+
+// Server is a connection to one MCP server. Exactly one of SSE, LegacySSE or
+// Stdio selects the transport.
 type Server struct {
 	Name     string
 	Describe string
 
-	SSE *MCPSse
+	SSE       *MCPSse
+	LegacySSE *MCPLegacySse
+	Stdio     *MCPStdio
 
-	client *client.Client
+	mu       sync.RWMutex
+	client   *client.Client
+	ctx      context.Context
+	cancel   context.CancelFunc
+	closed   bool
+	restarts int
 }
 
+// Connect creates the MCP client for the configured transport. No network
+// call or process launch happens until Start.
 func (s *Server) Connect() error {
-	httpTransport, err := transport.NewStreamableHTTP(s.SSE.Endpoint)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ctx == nil {
+		s.ctx, s.cancel = context.WithCancel(context.Background())
+	}
+	c, err := s.newClient()
+	if err != nil {
+		return err
+	}
+	s.client = c
+	return nil
+}
+
+func (s *Server) newClient() (*client.Client, error) {
+	switch {
+	case s.Stdio != nil:
+		if s.Stdio.Command == "" {
+			return nil, fmt.Errorf("stdio command is empty")
+		}
+		stdioTransport := transport.NewStdioWithOptions(s.Stdio.Command, s.Stdio.environ(), s.Stdio.Args,
+			transport.WithCommandFunc(s.Stdio.command),
+			transport.WithCommandLogger(s.log()),
+		)
+		return client.NewClient(stdioTransport), nil
+	case s.SSE != nil:
+		httpTransport, err := transport.NewStreamableHTTP(s.SSE.Endpoint,
+			transport.WithHTTPHeaders(s.SSE.Headers),
+			transport.WithHTTPLogger(s.log()),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create HTTP transport: %w", err)
+		}
+		return client.NewClient(httpTransport), nil
+	case s.LegacySSE != nil:
+		sseTransport, err := transport.NewSSE(s.LegacySSE.Endpoint,
+			transport.WithHeaders(s.LegacySSE.Headers),
+			transport.WithSSELogger(s.log()),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create SSE transport: %w", err)
+		}
+		return client.NewClient(sseTransport), nil
+	default:
+		return nil, fmt.Errorf("no transport configured")
+	}
+}
+
+// Start connects the server when needed and runs the MCP initialize handshake.
+// ctx only bounds the handshake: SSE streams and stdio child processes live
+// until Close.
+func (s *Server) Start(ctx context.Context) error {
+	if s.Client() == nil {
+		if err := s.Connect(); err != nil {
+			return err
+		}
+	}
+	c := s.Client()
+	if err := s.initialize(ctx, c); err != nil {
+		return err
+	}
+	if s.Stdio != nil {
+		s.supervise(c)
+	}
+	return nil
+}
+
+func (s *Server) initialize(ctx context.Context, c *client.Client) error {
+	if err := c.Start(s.ctx); err != nil {
+		return fmt.Errorf("start mcp client: %w", err)
+	}
+	_, err := c.Initialize(ctx, mcp.InitializeRequest{
+		Params: mcp.InitializeParams{
+			ProtocolVersion: mcp.LATEST_PROTOCOL_VERSION,
+			ClientInfo:      mcp.Implementation{Name: "friday", Version: "1.0.0"},
+		},
+	})
 	if err != nil {
-		return fmt.Errorf("failed to create HTTP transport: %w", err)
+		return fmt.Errorf("initialize mcp client: %w", err)
 	}
-	s.client = client.NewClient(httpTransport)
 	return nil
 }
 
+// Close shuts down the underlying MCP client. Stdio child processes get
+// shutdownTimeout to exit after stdin is closed before they are killed.
+// Close also stops a pending crash restart, even when no client is attached.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	closed := s.closed
+	s.closed = true
+	c, cancel := s.client, s.cancel
+	s.mu.Unlock()
+	if closed || c == nil {
+		if cancel != nil {
+			cancel()
+		}
+		return nil
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- c.Close() }()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-time.After(shutdownTimeout):
+		s.cancel()
+		err = <-done
+	}
+	s.cancel()
+	if err != nil && s.Stdio != nil && isProcessExit(err) {
+		return nil
+	}
+	return err
+}
+
 // Client returns the underlying MCP client, exposing it so callers can run
 // protocol initialization (Start + Initialize) before invoking InitTools.
-func (s *Server) Client() *client.Client { return s.client }
+// For stdio servers the client is replaced after a crash restart.
+func (s *Server) Client() *client.Client {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.client
+}
+
+func (s *Server) activeClient() (*client.Client, error) {
+	c := s.Client()
+	if c == nil {
+		return nil, fmt.Errorf("%s: %w", s.Name, errServerRestarting)
+	}
+	return c, nil
+}
 
 func (s *Server) InitTools(ctx context.Context) ([]*tools.Tool, error) {
-	result, err := s.client.ListTools(ctx, mcp.ListToolsRequest{Header: s.sseHeaders()})
+	c, err := s.activeClient()
+	if err != nil {
+		return nil, err
+	}
+	result, err := c.ListTools(ctx, mcp.ListToolsRequest{})
 	if err != nil {
 		return nil, err
 	}
@@ -47,22 +194,33 @@ func (s *Server) InitTools(ctx context.Context) ([]*tools.Tool, error) {
 	return tools, nil
 }
 
-func (s *Server) sseHeaders() http.Header {
-	if s.SSE.Headers == nil {
-		return http.Header{}
+// NamespacedTools returns InitTools with every tool renamed to
+// <server>__<tool>, so tools from different servers never collide with each
+// other or with Friday's builtin tools.
+func (s *Server) NamespacedTools(ctx context.Context) ([]*tools.Tool, error) {
+	result, err := s.InitTools(ctx)
+	if err != nil {
+		return nil, err
 	}
-	h := http.Header{}
-	for k, v := range s.SSE.Headers {
-		h.Set(k, v)
+	for _, t := range result {
+		t.Annotations[AnnotationServer] = s.Name
+		t.Name = ToolName(s.Name, t.Name)
 	}
-	return h
+	return result, nil
+}
+
+func (s *Server) log() logger.Logger {
+	return logger.New("mcp").With("server", s.Name)
 }
 
 func (s *Server) mcpToolAdaptor(mcpTool *mcp.Tool) tools.ToolHandlerFunc {
 	return func(ctx context.Context, request *tools.Request) (*tools.Result, error) {
-		result, err := s.client.CallTool(ctx, mcp.CallToolRequest{
+		c, err := s.activeClient()
+		if err != nil {
+			return nil, err
+		}
+		result, err := c.CallTool(ctx, mcp.CallToolRequest{
 			Request: mcp.Request{},
-			Header:  s.sseHeaders(),
 			Params: mcp.CallToolParams{
 				Name:      mcpTool.Name,
 				Arguments: request.Arguments,
@@ -90,7 +248,59 @@ func covertMCPTool(tool *mcp.Tool) *tools.Tool {
 
 }
 
+// AnnotationServer is the tool annotation carrying the originating MCP server name.
+const AnnotationServer = "mcp_server"
+
+// ToolName builds the namespaced tool name <server>__<tool>. Characters that
+// LLM providers reject in tool names are replaced with '_'.
+func ToolName(server, tool string) string {
+	return sanitizeName(server) + "__" + sanitizeName(tool)
+}
+
+func sanitizeName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
+
+// MCPSse is the streamable HTTP transport, which streams responses as SSE.
 type MCPSse struct {
 	Endpoint string
 	Headers  map[string]string
 }
+
+// MCPLegacySse is the deprecated HTTP+SSE transport (MCP 2024-11-05), where
+// requests are POSTed to an endpoint announced on a long-lived GET stream.
+type MCPLegacySse struct {
+	Endpoint string
+	Headers  map[string]string
+}
+
+// MCPStdio launches the server as a child process speaking JSON-RPC over
+// stdin/stdout. Env entries are added to the inherited environment.
+type MCPStdio struct {
+	Command string
+	Args    []string
+	Env     map[string]string
+	Cwd     string
+}
+
+func (m *MCPStdio) environ() []string {
+	env := make([]string, 0, len(m.Env))
+	for k, v := range m.Env {
+		env = append(env, k+"="+v)
+	}
+	return env
+}
+
+func (m *MCPStdio) command(ctx context.Context, command string, env []string, args []string) (*exec.Cmd, error) {
+	cmd := exec.CommandContext(ctx, command, args...)
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Dir = m.Cwd
+	return cmd, nil
+}
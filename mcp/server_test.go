@@ -0,0 +1,209 @@
+package mcp
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/basenana/friday/core/tools"
+)
+
+// TestMain doubles as a stdio MCP server when FRIDAY_MCP_TEST_SERVER is set,
+// so stdio transport tests can launch the test binary itself as the child.
+func TestMain(m *testing.M) {
+	if os.Getenv("FRIDAY_MCP_TEST_SERVER") == "1" {
+		runTestStdioServer()
+		return
+	}
+	os.Exit(m.Run())
+}
+
+func runTestStdioServer() {
+	srv := server.NewMCPServer("test-stdio", "1.0.0")
+	srv.AddTool(mcp.NewTool("echo", mcp.WithString("text")), func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args, _ := req.Params.Arguments.(map[string]any)
+		text, _ := args["text"].(string)
+		return mcp.NewToolResultText("echo: " + text + " " + os.Getenv("FRIDAY_MCP_TEST_GREETING")), nil
+	})
+	srv.AddTool(mcp.NewTool("crash"), func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		os.Stderr.WriteString("crashing on purpose\n")
+		os.Exit(3)
+		return nil, nil
+	})
+	_ = server.ServeStdio(srv)
+}
+
+func newTestStdioServer(t *testing.T) *Server {
+	t.Helper()
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatalf("os.Executable() error = %v", err)
+	}
+	return &Server{
+		Name: "local",
+		Stdio: &MCPStdio{
+			Command: exe,
+			Args:    []string{"-test.run=^$"},
+			Env: map[string]string{
+				"FRIDAY_MCP_TEST_SERVER":   "1",
+				"FRIDAY_MCP_TEST_GREETING": "hi",
+			},
+			Cwd: t.TempDir(),
+		},
+	}
+}
+
+func callTool(t *testing.T, ts []*tools.Tool, name string, args map[string]any) (string, error) {
+	t.Helper()
+	result, err := ts[indexOfTool(t, ts, name)].Handler(context.Background(), &tools.Request{Arguments: args})
+	if err != nil {
+		return "", err
+	}
+	return tools.Res2Str(result), nil
+}
+
+func indexOfTool(t *testing.T, ts []*tools.Tool, name string) int {
+	t.Helper()
+	for i, tool := range ts {
+		if tool.Name == name {
+			return i
+		}
+	}
+	t.Fatalf("tool %s not found", name)
+	return -1
+}
+
+func TestToolName(t *testing.T) {
+	tests := []struct {
+		server string
+		tool   string
+		want   string
+	}{
+		{server: "github", tool: "create_issue", want: "github__create_issue"},
+		{server: "my docs", tool: "search.v2", want: "my_docs__search_v2"},
+		{server: "k8s-prod", tool: "get-pods", want: "k8s-prod__get-pods"},
+	}
+
+	for _, tt := range tests {
+		if got := ToolName(tt.server, tt.tool); got != tt.want {
+			t.Errorf("ToolName(%q, %q) = %q, want %q", tt.server, tt.tool, got, tt.want)
+		}
+	}
+}
+
+func TestStdioServerCallsTools(t *testing.T) {
+	srv := newTestStdioServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := srv.Start(ctx); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer srv.Close()
+
+	ts, err := srv.NamespacedTools(ctx)
+	if err != nil {
+		t.Fatalf("NamespacedTools() error = %v", err)
+	}
+	got, err := callTool(t, ts, "local__echo", map[string]any{"text": "hello"})
+	if err != nil {
+		t.Fatalf("call echo error = %v", err)
+	}
+	if !strings.Contains(got, "echo: hello hi") {
+		t.Fatalf("unexpected echo result %q", got)
+	}
+}
+
+func TestStdioServerRestartsAfterCrash(t *testing.T) {
+	srv := newTestStdioServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := srv.Start(ctx); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer srv.Close()
+
+	ts, err := srv.NamespacedTools(ctx)
+	if err != nil {
+		t.Fatalf("NamespacedTools() error = %v", err)
+	}
+	crashed := srv.Client()
+	// The crash call itself fails: the process exits before responding.
+	crashCtx, crashCancel := context.WithTimeout(ctx, 500*time.Millisecond)
+	_, _ = ts[indexOfTool(t, ts, "local__crash")].Handler(crashCtx, &tools.Request{Arguments: map[string]any{}})
+	crashCancel()
+
+	deadline := time.Now().Add(8 * time.Second)
+	for time.Now().Before(deadline) {
+		if c := srv.Client(); c != nil && c != crashed {
+			got, err := callTool(t, ts, "local__echo", map[string]any{"text": "again"})
+			if err != nil {
+				t.Fatalf("call echo after restart error = %v", err)
+			}
+			if !strings.Contains(got, "echo: again") {
+				t.Fatalf("unexpected echo result %q", got)
+			}
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	t.Fatalf("stdio server was not restarted after crash")
+}
+
+func TestCloseStopsStdioServer(t *testing.T) {
+	srv := newTestStdioServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := srv.Start(ctx); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if err := srv.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if _, err := srv.InitTools(ctx); err == nil {
+		t.Fatalf("expected InitTools to fail after Close")
+	}
+}
+
+func TestCloseDuringRestart(t *testing.T) {
+	srv := newTestStdioServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := srv.Start(ctx); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	ts, err := srv.NamespacedTools(ctx)
+	if err != nil {
+		t.Fatalf("NamespacedTools() error = %v", err)
+	}
+	crashCtx, crashCancel := context.WithTimeout(ctx, 500*time.Millisecond)
+	_, _ = ts[indexOfTool(t, ts, "local__crash")].Handler(crashCtx, &tools.Request{Arguments: map[string]any{}})
+	crashCancel()
+
+	// Wait for the crashed client to be detached, then close mid-backoff.
+	deadline := time.Now().Add(5 * time.Second)
+	for srv.Client() != nil {
+		if time.Now().After(deadline) {
+			t.Fatal("crashed client was not detached")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := srv.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if srv.ctx.Err() == nil {
+		t.Fatal("Close() did not cancel the server context")
+	}
+	time.Sleep(restartBackoff + 500*time.Millisecond)
+	if srv.Client() != nil {
+		t.Fatal("server restarted after Close")
+	}
+}
@@ -0,0 +1,129 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"os/exec"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+)
+
+const (
+	// maxRestarts is how many consecutive crashes are tolerated before the
+	// server is given up on.
+	maxRestarts = 5
+	// restartBackoff is multiplied by the attempt number between restarts.
+	restartBackoff = time.Second
+	// healthyAfter resets the restart budget once a process has stayed up this long.
+	healthyAfter = time.Minute
+	// restartInitTimeout bounds the initialize handshake of a restarted process.
+	restartInitTimeout = 30 * time.Second
+)
+
+// supervise forwards the child's stderr to the Friday log and restarts the
+// process when it exits without Close having been called. Stderr reaching EOF
+// is the crash signal, since the transport owns the exec.Cmd and its Wait.
+func (s *Server) supervise(c *client.Client) {
+	stdio, ok := c.GetTransport().(*transport.Stdio)
+	if !ok {
+		return
+	}
+	stderr := stdio.Stderr()
+	if stderr == nil {
+		return
+	}
+
+	startedAt := time.Now()
+	go func() {
+		log := s.log()
+		scanner := bufio.NewScanner(stderr)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			log.Infow("stderr", "line", scanner.Text())
+		}
+		s.restart(c, time.Since(startedAt))
+	}()
+}
+
+func (s *Server) restart(crashed *client.Client, uptime time.Duration) {
+	s.mu.Lock()
+	if s.closed || s.client != crashed {
+		s.mu.Unlock()
+		return
+	}
+	if uptime >= healthyAfter {
+		s.restarts = 0
+	}
+	// Detach the crashed client so Close never races with this goroutine
+	// over it; callers see errServerRestarting until the new one is ready.
+	s.client = nil
+	s.mu.Unlock()
+
+	log := s.log()
+	log.Warnw("mcp server process exited", "uptime", uptime, "err", crashed.Close())
+
+	for {
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			return
+		}
+		if s.restarts >= maxRestarts {
+			s.mu.Unlock()
+			log.Errorw("mcp server crashed too often, giving up", "restarts", maxRestarts)
+			return
+		}
+		s.restarts++
+		attempt := s.restarts
+		s.mu.Unlock()
+
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-time.After(restartBackoff * time.Duration(attempt)):
+		}
+
+		s.mu.Lock()
+		closed := s.closed
+		s.mu.Unlock()
+		if closed {
+			return
+		}
+		c, err := s.newClient()
+		if err == nil {
+			ctx, cancel := context.WithTimeout(s.ctx, restartInitTimeout)
+			err = s.initialize(ctx, c)
+			cancel()
+		}
+		if err != nil {
+			log.Warnw("restart mcp server failed", "attempt", attempt, "err", err)
+			if c != nil {
+				_ = c.Close()
+			}
+			continue
+		}
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			_ = c.Close()
+			return
+		}
+		s.client = c
+		s.mu.Unlock()
+
+		log.Infow("mcp server restarted", "attempt", attempt)
+		s.supervise(c)
+		return
+	}
+}
+
+// isProcessExit reports whether err only describes how the child process
+// ended, which is expected when it is killed or crashes.
+func isProcessExit(err error) bool {
+	var exitErr *exec.ExitError
+	return errors.As(err, &exitErr)
+}
@@ -6,6 +6,8 @@ import (
 	"path/filepath"
 	"strings"
 	"time"
+
+	"github.com/basenana/friday/utils/crypt"
 )
 
 type MemoryType string
@@ -18,6 +20,7 @@ const (
 type MemorySystem struct {
 	basePath string
 	days     int
+	cipher   *crypt.Cipher
 }
 
 func NewMemorySystem(basePath string, days int) *MemorySystem {
@@ -27,6 +30,12 @@ func NewMemorySystem(basePath string, days int) *MemorySystem {
 	}
 }
 
+// SetCipher encrypts the memory logs written from now on. Logs written
+// before are still read.
+func (m *MemorySystem) SetCipher(c *crypt.Cipher) {
+	m.cipher = c
+}
+
 func (m *MemorySystem) EnsureDir() error {
 	return os.MkdirAll(m.basePath, 0755)
 }
@@ -47,7 +56,7 @@ func (m *MemorySystem) EnsureTodayMemory() error {
 	todayPath := m.todayPath()
 	if _, err := os.Stat(todayPath); os.IsNotExist(err) {
 		header := fmt.Sprintf("# %s\n\n", time.Now().Format("2006-01-02"))
-		return os.WriteFile(todayPath, []byte(header), 0644)
+		return m.cipher.WriteFile(todayPath, []byte(header), 0644)
 	}
 	return nil
 }
@@ -81,7 +90,7 @@ func (m *MemorySystem) LoadRecentLogs() ([]string, error) {
 
 		daysDiff := int(now.Sub(logDate).Hours() / 24)
 		if daysDiff >= 0 && daysDiff < m.days {
-			data, err := os.ReadFile(filepath.Join(m.basePath, entry.Name()))
+			data, err := m.cipher.ReadFile(filepath.Join(m.basePath, entry.Name()))
 			if err != nil {
 				continue
 			}
@@ -98,7 +107,7 @@ func (m *MemorySystem) Search(query string) ([]string, error) {
 	}
 
 	memoryPath := filepath.Join(m.basePath, "MEMORY.md")
-	data, err := os.ReadFile(memoryPath)
+	data, err := m.cipher.ReadFile(memoryPath)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return []string{}, nil
@@ -126,25 +135,11 @@ func (m *MemorySystem) Write(content string, memType MemoryType) error {
 		}
 
 		entry := fmt.Sprintf("## %s\n\n%s\n\n", time.Now().Format(time.RFC3339), content)
-		fs, err := os.OpenFile(m.todayPath(), os.O_APPEND|os.O_WRONLY, 0644)
-		if err != nil {
-			return err
-		}
-		defer fs.Close()
-
-		_, err = fs.WriteString(entry)
-		return err
+		return m.cipher.AppendFile(m.todayPath(), []byte(entry), 0644)
 	}
 
 	memoryPath := filepath.Join(m.basePath, "MEMORY.md")
 	entry := fmt.Sprintf("\n## %s\n\n%s\n", time.Now().Format("2006-01-02"), content)
 
-	fs, err := os.OpenFile(memoryPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		return err
-	}
-	defer fs.Close()
-
-	_, err = fs.WriteString(entry)
-	return err
+	return m.cipher.AppendFile(memoryPath, []byte(entry), 0644)
 }
@@ -113,7 +113,7 @@ func TestTeamStrategyLeaderSessionIsProposalScoped(t *testing.T) {
 		&teams.Team{Name: "alpha"},
 		[]teams.Member{{Name: "lead", Role: teams.RoleLeader}},
 		nil,
-		"",
+		t.TempDir(),
 		nil,
 		sessionFactory,
 	)
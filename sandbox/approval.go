@@ -0,0 +1,39 @@
+package sandbox
+
+import "context"
+
+// Approval is the answer to an approval request.
+type Approval int
+
+const (
+	// Reject blocks the command
+	Reject Approval = iota
+	// ApproveOnce lets this invocation run
+	ApproveOnce
+	// ApproveAlways lets this invocation run and adds its patterns to the
+	// allow list so later invocations do not ask again
+	ApproveAlways
+)
+
+func (a Approval) String() string {
+	switch a {
+	case ApproveOnce:
+		return "approve"
+	case ApproveAlways:
+		return "always"
+	default:
+		return "reject"
+	}
+}
+
+// ApprovalRequest describes a command that resolved to Ask.
+type ApprovalRequest struct {
+	Command string
+	Reason  string
+	// Patterns are the allow rules added when the answer is ApproveAlways.
+	Patterns []string
+}
+
+// Approver asks someone whether a command may run. It blocks until answered
+// or ctx is done; an error is treated as Reject.
+type Approver func(ctx context.Context, req ApprovalRequest) (Approval, error)
@@ -37,6 +37,8 @@ type Task struct {
 	FinishedAt *time.Time
 	ExitCode   int
 	Output     string
+	// LimitExceeded names the resource limit that killed the task, if any
+	LimitExceeded string
 }
 
 type managedTask struct {
@@ -64,7 +66,12 @@ func generateTaskID() string {
 }
 
 func (tm *TaskManager) Start(command, workdir string) (*Task, error) {
-	decision, reason, err := tm.exec.CheckPermission(command)
+	return tm.StartContext(context.Background(), command, workdir)
+}
+
+// StartContext is Start with a context bounding the permission approval.
+func (tm *TaskManager) StartContext(ctx context.Context, command, workdir string) (*Task, error) {
+	decision, reason, err := tm.exec.Authorize(ctx, command)
 	if err != nil {
 		return nil, fmt.Errorf("permission check failed: %w", err)
 	}
@@ -83,25 +90,34 @@ func (tm *TaskManager) Start(command, workdir string) (*Task, error) {
 		return nil, fmt.Errorf("failed to wrap command: %w", err)
 	}
 
-	cmd := exec.Command("bash", "-c", wrappedCmd)
-	cmd.Dir = dir
+	var pgid int
+	guard := newLimitGuard(parseLimits(tm.exec.config.Sandbox.Limits), func() {
+		_ = signalTaskGroup(pgid, syscall.SIGKILL)
+	})
+
+	cmd := exec.Command("bash", "-c", guard.wrap(wrappedCmd))
+	cmd.Dir = tm.exec.commandDir(dir)
 	cmd.Env = os.Environ()
 	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	guard.attach(cmd)
 
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
+		guard.close()
 		return nil, fmt.Errorf("failed to create stdout pipe: %w", err)
 	}
 	stderr, err := cmd.StderrPipe()
 	if err != nil {
+		guard.close()
 		return nil, fmt.Errorf("failed to create stderr pipe: %w", err)
 	}
 
 	if err := cmd.Start(); err != nil {
+		guard.close()
 		return nil, fmt.Errorf("failed to start command: %w", err)
 	}
 
-	pgid, err := syscall.Getpgid(cmd.Process.Pid)
+	pgid, err = syscall.Getpgid(cmd.Process.Pid)
 	if err != nil {
 		pgid = cmd.Process.Pid
 	}
@@ -132,17 +148,19 @@ func (tm *TaskManager) Start(command, workdir string) (*Task, error) {
 	collector := &outputCollector{}
 
 	readers.Add(2)
-	go collectOutput(stdout, collector, &readers)
-	go collectOutput(stderr, collector, &readers)
+	go collectOutput(guard.reader(stdout), collector, &readers)
+	go collectOutput(guard.reader(stderr), collector, &readers)
 
 	go func() {
 		defer close(task.done)
 		if cleanup != nil {
 			defer cleanup()
 		}
+		defer guard.close()
 
-		waitErr := cmd.Wait()
+		// Drain the pipes before Wait, which closes them.
 		readers.Wait()
+		waitErr := cmd.Wait()
 		output := collector.Output()
 
 		tm.mu.Lock()
@@ -150,6 +168,7 @@ func (tm *TaskManager) Start(command, workdir string) (*Task, error) {
 
 		task.Output = output
 		task.ExitCode = exitCodeFromCmd(cmd, waitErr)
+		task.LimitExceeded = guard.exceeded(cmd.ProcessState)
 		now := time.Now()
 		task.FinishedAt = &now
 
@@ -385,7 +404,7 @@ func backgroundTaskHandler(tm *TaskManager, defaultWorkdir string) tools.ToolHan
 			workdir = w
 		}
 
-		task, err := tm.Start(command, workdir)
+		task, err := tm.StartContext(ctx, command, workdir)
 		if err != nil {
 			return tools.NewToolResultError(err.Error()), nil
 		}
@@ -496,6 +515,9 @@ func waitTaskHandler(tm *TaskManager) tools.ToolHandlerFunc {
 		sb.WriteString(fmt.Sprintf("Task %s\n", task.ID))
 		sb.WriteString(fmt.Sprintf("Status: %s\n", task.Status))
 		sb.WriteString(fmt.Sprintf("Exit code: %d\n", task.ExitCode))
+		if task.LimitExceeded != "" {
+			sb.WriteString(fmt.Sprintf("Killed: exceeded the %s\n", describeLimit(tm.exec.config.Sandbox.Limits, task.LimitExceeded)))
+		}
 		if task.Output != "" {
 			sb.WriteString("Output:\n")
 			sb.WriteString(task.Output)
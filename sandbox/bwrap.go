@@ -31,6 +31,10 @@ func (b *Bwrap) WrapCommand(cmd string, opts ExecOptions) (string, func(), error
 
 	// Build bwrap arguments
 	args := b.buildArgs(opts.Workdir)
+	if opts.Overlay != nil {
+		// Show the shadow copy at the workdir's own path
+		args = append(args, "--bind", shellQuote(opts.Overlay.Tree()), shellQuote(opts.Overlay.Root()))
+	}
 
 	// Safely quote the command for bash -c using syntax.Quote
 	quotedCmd, err := syntax.Quote(cmd, syntax.LangBash)
@@ -38,7 +42,12 @@ func (b *Bwrap) WrapCommand(cmd string, opts ExecOptions) (string, func(), error
 		// Fallback to basic escaping if Quote fails
 		quotedCmd = "'" + strings.ReplaceAll(cmd, "'", "'\\''") + "'"
 	}
-	wrappedCmd := fmt.Sprintf("bwrap %s -- bash -c %s", strings.Join(args, " "), quotedCmd)
+	command := "bash -c " + quotedCmd
+	if netArgs, bridge, ok := b.proxyArgs(opts.Proxy); ok {
+		args = append(args, netArgs...)
+		command = bridge + " -- " + command
+	}
+	wrappedCmd := fmt.Sprintf("bwrap %s -- %s", strings.Join(args, " "), command)
 	cleanup := func() {}
 
 	return wrappedCmd, cleanup, nil
@@ -111,12 +120,39 @@ func (b *Bwrap) buildArgs(workdir string) []string {
 	// Network isolation
 	if b.config.Sandbox.Network.Isolation {
 		args = append(args, "--unshare-net")
-		// If network is needed, we'd set up a proxy
-		// For now, just unshare network completely
-		if len(b.config.Sandbox.Network.Allow) > 0 {
-			// TODO: Set up proxy for allowed domains
-		}
 	}
 
 	return args
 }
+
+// proxyArgs returns the extra bwrap arguments and the bridge command that give
+// a network-isolated sandbox access to proxy. Inside the new network namespace
+// the bridge listens on the proxy's loopback port and forwards to its unix
+// socket, so the allow list is the only way out. ok is false when the
+// sandbox keeps no network at all.
+func (b *Bwrap) proxyArgs(proxy *NetworkProxy) (args []string, bridge string, ok bool) {
+	if proxy == nil || !b.config.Sandbox.Network.Isolation || len(BridgeCommand) == 0 {
+		return nil, "", false
+	}
+	addr, socket := proxy.Addr(), proxy.Socket()
+	if addr == "" || socket == "" {
+		return nil, "", false
+	}
+
+	sockDir := filepath.Dir(socket)
+	args = append(args, "--bind", sockDir, sockDir)
+	if exe := BridgeCommand[0]; filepath.IsAbs(exe) {
+		args = append(args, "--ro-bind", exe, exe)
+	}
+	for _, kv := range proxy.Env() {
+		name, value, _ := strings.Cut(kv, "=")
+		args = append(args, "--setenv", name, shellQuote(value))
+	}
+
+	argv := append(append([]string{}, BridgeCommand...), "--listen", addr, "--socket", socket)
+	quoted := make([]string, len(argv))
+	for i, arg := range argv {
+		quoted[i] = shellQuote(arg)
+	}
+	return args, strings.Join(quoted, " "), true
+}
@@ -3,6 +3,8 @@
 package sandbox
 
 import (
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -40,3 +42,38 @@ func containsSeq(args []string, seq ...string) bool {
 	}
 	return false
 }
+
+func TestBuildArgsNetworkProxy(t *testing.T) {
+	cfg := DefaultConfig()
+	b := NewBwrap(cfg)
+
+	proxy := NewNetworkProxy(cfg.Sandbox.Network.Allow)
+	if err := proxy.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer proxy.Close()
+
+	// Without a bridge the sandbox keeps no network at all.
+	orig := BridgeCommand
+	defer func() { BridgeCommand = orig }()
+	BridgeCommand = nil
+	if _, _, ok := b.proxyArgs(proxy); ok {
+		t.Error("expected no proxy args without a bridge command")
+	}
+
+	BridgeCommand = []string{"/usr/local/bin/friday", "sandbox", "bridge"}
+	wrapped, _, err := b.WrapCommand("go mod download", ExecOptions{Workdir: "/tmp", Proxy: proxy})
+	if err != nil {
+		t.Fatalf("WrapCommand: %v", err)
+	}
+	for _, want := range []string{
+		"--unshare-net",
+		"--setenv HTTPS_PROXY http://" + proxy.Addr(),
+		"--bind " + filepath.Dir(proxy.Socket()),
+		"/usr/local/bin/friday sandbox bridge --listen " + proxy.Addr() + " --socket " + proxy.Socket() + " -- bash -c",
+	} {
+		if !strings.Contains(wrapped, want) {
+			t.Errorf("wrapped command missing %q:\n%s", want, wrapped)
+		}
+	}
+}
@@ -1,5 +1,16 @@
 package sandbox
 
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
 // Config is the top-level configuration for sandbox
 type Config struct {
 	Permissions PermissionsConfig `json:"permissions" yaml:"permissions"`
@@ -10,14 +21,33 @@ type Config struct {
 type PermissionsConfig struct {
 	Allow []string `json:"allow" yaml:"allow"`
 	Deny  []string `json:"deny" yaml:"deny"`
+	// Ask rules always require approval, even when an allow rule matches
+	Ask []string `json:"ask,omitempty" yaml:"ask,omitempty"`
+	// Default is the decision for commands matching no rule: "deny" or "ask"
+	Default string `json:"default,omitempty" yaml:"default,omitempty"`
+	// AskFallback resolves "ask" when no one can be asked (non-interactive
+	// runs): "deny" (default) or "allow"
+	AskFallback string `json:"ask_fallback,omitempty" yaml:"ask_fallback,omitempty"`
 }
 
 // SandboxConfig defines sandbox isolation settings
 type SandboxConfig struct {
-	Enabled    bool             `json:"enabled" yaml:"enabled"`
+	Enabled bool `json:"enabled" yaml:"enabled"`
+	// Backend selects the implementation: "" for the platform default
+	// (bubblewrap on Linux, Seatbelt on macOS) or "container"
+	Backend    string           `json:"backend,omitempty" yaml:"backend,omitempty"`
+	Container  ContainerConfig  `json:"container,omitempty" yaml:"container,omitempty"`
 	Filesystem FilesystemConfig `json:"filesystem" yaml:"filesystem"`
 	Network    NetworkConfig    `json:"network" yaml:"network"`
 	Defaults   DefaultsConfig   `json:"defaults" yaml:"defaults"`
+	Limits     LimitsConfig     `json:"limits,omitempty" yaml:"limits,omitempty"`
+	// Overlay runs commands and file tools against a shadow copy of the
+	// workdir; changes reach the real tree only through "friday changes apply"
+	Overlay bool `json:"overlay,omitempty" yaml:"overlay,omitempty"`
+	// TrustPolicy lets repository policy files add allow rules, writable
+	// paths and network hosts. Without it they may only tighten the config.
+	// It is read from the user config only.
+	TrustPolicy bool `json:"trust_policy,omitempty" yaml:"trust_policy,omitempty"`
 }
 
 // FilesystemConfig defines filesystem access control
@@ -43,14 +73,245 @@ type DefaultsConfig struct {
 	Timeout string `json:"timeout" yaml:"timeout"` // e.g. "5m"
 }
 
-// LoadConfig loads sandbox configuration from file
+// ContainerConfig configures the container backend
+type ContainerConfig struct {
+	// Runtime is the container CLI: "podman", "docker", or "" for whichever
+	// is installed
+	Runtime string `json:"runtime,omitempty" yaml:"runtime,omitempty"`
+	// Image is the image commands run in, e.g. "docker.io/library/golang:1.25"
+	Image string `json:"image,omitempty" yaml:"image,omitempty"`
+	// Args are extra arguments for the run command
+	Args []string `json:"args,omitempty" yaml:"args,omitempty"`
+}
+
+// LimitsConfig defines per-command resource limits. Memory and process
+// count need a cgroup v2 or the container backend and are not enforced
+// otherwise; the rest use rlimits. Empty or zero values mean unlimited.
+type LimitsConfig struct {
+	// Memory caps the memory of the command, e.g. "2G"
+	Memory string `json:"memory,omitempty" yaml:"memory,omitempty"`
+	// CPUTime caps the CPU time of each process, e.g. "10m"
+	CPUTime string `json:"cpu_time,omitempty" yaml:"cpu_time,omitempty"`
+	// MaxPids caps the number of processes
+	MaxPids int `json:"max_pids,omitempty" yaml:"max_pids,omitempty"`
+	// MaxFileSize caps the size of any file written, e.g. "1G"
+	MaxFileSize string `json:"max_file_size,omitempty" yaml:"max_file_size,omitempty"`
+	// MaxOutputBytes kills the command once it prints more than this, e.g. "10M"
+	MaxOutputBytes string `json:"max_output_bytes,omitempty" yaml:"max_output_bytes,omitempty"`
+}
+
+// PolicyFileNames are the repository policy files FindPolicyFile looks for,
+// relative to each directory it visits.
+var PolicyFileNames = []string{
+	filepath.Join(".friday", "sandbox.yaml"),
+	filepath.Join(".friday", "sandbox.yml"),
+	filepath.Join(".friday", "sandbox.json"),
+}
+
+// LoadConfig loads sandbox configuration from file. Settings present in the
+// file replace the defaults; an empty path yields the defaults.
 func LoadConfig(path string) (*Config, error) {
 	cfg := DefaultConfig()
 	if path == "" {
 		return cfg, nil
 	}
+	if err := decodeConfigFile(path, cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
 
-	// Try to load from file
-	// TODO: implement file loading with JSON/YAML support
+// LoadPolicy loads a policy file without defaults, so only the rules in the
+// file are layered on top of the user config by Merge.
+func LoadPolicy(path string) (*Config, error) {
+	cfg := &Config{}
+	if err := decodeConfigFile(path, cfg); err != nil {
+		return nil, err
+	}
 	return cfg, nil
 }
+
+func decodeConfigFile(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if strings.HasSuffix(path, ".json") {
+		err = json.Unmarshal(data, cfg)
+	} else {
+		err = yaml.Unmarshal(data, cfg)
+	}
+	if err != nil {
+		return fmt.Errorf("parse sandbox config %s: %w", path, err)
+	}
+	return nil
+}
+
+// FindPolicyFile looks for a repository policy file in dir and its parents,
+// stopping at the repository root (the first directory containing .git).
+// It returns "" when there is none.
+func FindPolicyFile(dir string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	for {
+		for _, name := range PolicyFileNames {
+			path := filepath.Join(dir, name)
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				return path
+			}
+		}
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return ""
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// Merge returns a new Config with policy layered on top of c. A policy comes
+// with the repository, so it may only tighten c: its deny, ask, read-only and
+// protected rules are unioned in, so they always apply (deny is checked
+// before ask and allow), while its allow rules, writable paths and network
+// hosts are ignored unless c sets TrustPolicy. Scalar settings take the stricter
+// value: the sandbox and network isolation are on if either layer enables
+// them, "deny" beats "ask" for unlisted commands and "deny" beats "allow" for
+// ask_fallback, and the shorter timeout and smaller resource limits win. The
+// policy may pick the backend, but the container runtime and image come from
+// the user config only.
+func (c *Config) Merge(policy *Config) *Config {
+	rulesMu.RLock()
+	defer rulesMu.RUnlock()
+
+	var widen Config
+	if c.Sandbox.TrustPolicy {
+		widen.Permissions.Allow = policy.Permissions.Allow
+		widen.Sandbox.Filesystem.Write = policy.Sandbox.Filesystem.Write
+		widen.Sandbox.Network.Allow = policy.Sandbox.Network.Allow
+	}
+
+	merged := &Config{
+		Permissions: PermissionsConfig{
+			Allow:       union(c.Permissions.Allow, widen.Permissions.Allow),
+			Deny:        union(c.Permissions.Deny, policy.Permissions.Deny),
+			Ask:         union(c.Permissions.Ask, policy.Permissions.Ask),
+			Default:     stricterDecision(c.Permissions.Default, policy.Permissions.Default),
+			AskFallback: stricterDecision(c.Permissions.AskFallback, policy.Permissions.AskFallback),
+		},
+		Sandbox: SandboxConfig{
+			Enabled: c.Sandbox.Enabled || policy.Sandbox.Enabled,
+			Backend: override(c.Sandbox.Backend, policy.Sandbox.Backend),
+			// The runtime runs on the host outside any sandbox, and the image
+			// and extra run arguments decide what the isolation is, so only
+			// the user config may set them.
+			Container: ContainerConfig{
+				Runtime: c.Sandbox.Container.Runtime,
+				Image:   c.Sandbox.Container.Image,
+				Args:    append([]string{}, c.Sandbox.Container.Args...),
+			},
+			Overlay:     c.Sandbox.Overlay || policy.Sandbox.Overlay,
+			TrustPolicy: c.Sandbox.TrustPolicy,
+			Filesystem: FilesystemConfig{
+				ReadOnly:  union(c.Sandbox.Filesystem.ReadOnly, policy.Sandbox.Filesystem.ReadOnly),
+				Deny:      union(c.Sandbox.Filesystem.Deny, policy.Sandbox.Filesystem.Deny),
+				Write:     union(c.Sandbox.Filesystem.Write, widen.Sandbox.Filesystem.Write),
+				Protected: union(c.Sandbox.Filesystem.Protected, policy.Sandbox.Filesystem.Protected),
+			},
+			Network: NetworkConfig{
+				Isolation: c.Sandbox.Network.Isolation || policy.Sandbox.Network.Isolation,
+				Allow:     union(c.Sandbox.Network.Allow, widen.Sandbox.Network.Allow),
+			},
+			Defaults: DefaultsConfig{
+				Timeout: shorterTimeout(c.Sandbox.Defaults.Timeout, policy.Sandbox.Defaults.Timeout),
+			},
+			Limits: LimitsConfig{
+				Memory:         smallerSize(c.Sandbox.Limits.Memory, policy.Sandbox.Limits.Memory),
+				CPUTime:        shorterTimeout(c.Sandbox.Limits.CPUTime, policy.Sandbox.Limits.CPUTime),
+				MaxPids:        smallerCount(c.Sandbox.Limits.MaxPids, policy.Sandbox.Limits.MaxPids),
+				MaxFileSize:    smallerSize(c.Sandbox.Limits.MaxFileSize, policy.Sandbox.Limits.MaxFileSize),
+				MaxOutputBytes: smallerSize(c.Sandbox.Limits.MaxOutputBytes, policy.Sandbox.Limits.MaxOutputBytes),
+			},
+		},
+	}
+	return merged
+}
+
+func union(a, b []string) []string {
+	out := make([]string, 0, len(a)+len(b))
+	seen := make(map[string]bool, len(a)+len(b))
+	for _, list := range [][]string{a, b} {
+		for _, v := range list {
+			if !seen[v] {
+				seen[v] = true
+				out = append(out, v)
+			}
+		}
+	}
+	return out
+}
+
+// stricterDecision picks the more restrictive of two decision settings.
+// Unset values defer to the other layer.
+func stricterDecision(a, b string) string {
+	if a == "" {
+		return b
+	}
+	if b == "" {
+		return a
+	}
+	rank := map[Decision]int{Allow: 0, Ask: 1, Deny: 2}
+	if rank[ParseDecision(b, Deny)] > rank[ParseDecision(a, Deny)] {
+		return b
+	}
+	return a
+}
+
+// override returns the policy value b when set, else a.
+func override(a, b string) string {
+	if b != "" {
+		return b
+	}
+	return a
+}
+
+func shorterTimeout(a, b string) string {
+	da, errA := time.ParseDuration(a)
+	db, errB := time.ParseDuration(b)
+	switch {
+	case errA != nil:
+		return b
+	case errB != nil:
+		return a
+	case db < da:
+		return b
+	default:
+		return a
+	}
+}
+
+// smallerSize picks the smaller of two size limits; unset values defer to
+// the other layer.
+func smallerSize(a, b string) string {
+	sa, sb := parseMemoryLimit(a), parseMemoryLimit(b)
+	switch {
+	case sa <= 0:
+		return b
+	case sb <= 0:
+		return a
+	case sb < sa:
+		return b
+	default:
+		return a
+	}
+}
+
+func smallerCount(a, b int) int {
+	if a <= 0 || (b > 0 && b < a) {
+		return b
+	}
+	return a
+}
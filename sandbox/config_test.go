@@ -0,0 +1,250 @@
+package sandbox
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writePolicy(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	cfg, err := LoadConfig("")
+	if err != nil {
+		t.Fatalf("LoadConfig(\"\") error = %v", err)
+	}
+	if len(cfg.Permissions.Allow) != len(DefaultAllowedCommands) {
+		t.Errorf("empty path should return defaults, got allow %v", cfg.Permissions.Allow)
+	}
+
+	dir := t.TempDir()
+	yamlPath := filepath.Join(dir, "sandbox.yaml")
+	writePolicy(t, yamlPath, `
+permissions:
+  allow: [git]
+sandbox:
+  defaults:
+    timeout: 30s
+`)
+	cfg, err = LoadConfig(yamlPath)
+	if err != nil {
+		t.Fatalf("LoadConfig(yaml) error = %v", err)
+	}
+	if len(cfg.Permissions.Allow) != 1 || cfg.Permissions.Allow[0] != "git" {
+		t.Errorf("allow = %v, want [git]", cfg.Permissions.Allow)
+	}
+	if len(cfg.Permissions.Deny) != len(DefaultDeniedCommands) {
+		t.Errorf("deny should keep defaults, got %v", cfg.Permissions.Deny)
+	}
+	if cfg.Sandbox.Defaults.Timeout != "30s" {
+		t.Errorf("timeout = %q, want 30s", cfg.Sandbox.Defaults.Timeout)
+	}
+
+	jsonPath := filepath.Join(dir, "sandbox.json")
+	writePolicy(t, jsonPath, `{"permissions": {"deny": ["docker"]}}`)
+	policy, err := LoadPolicy(jsonPath)
+	if err != nil {
+		t.Fatalf("LoadPolicy(json) error = %v", err)
+	}
+	if len(policy.Permissions.Allow) != 0 || len(policy.Permissions.Deny) != 1 {
+		t.Errorf("policy should only hold the file's rules, got %+v", policy.Permissions)
+	}
+
+	writePolicy(t, yamlPath, "permissions: [")
+	if _, err = LoadConfig(yamlPath); err == nil {
+		t.Error("LoadConfig() with invalid yaml should fail")
+	}
+}
+
+func TestFindPolicyFile(t *testing.T) {
+	root := t.TempDir()
+	repo := filepath.Join(root, "repo")
+	sub := filepath.Join(repo, "pkg", "sub")
+	if err := os.MkdirAll(filepath.Join(repo, ".git"), 0o755); err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+	if err := os.MkdirAll(sub, 0o755); err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+
+	// A policy above the repository root must not be picked up.
+	writePolicy(t, filepath.Join(root, ".friday", "sandbox.yaml"), "permissions: {}")
+	if got := FindPolicyFile(sub); got != "" {
+		t.Errorf("FindPolicyFile() = %q, want none above the repo root", got)
+	}
+
+	want := filepath.Join(repo, ".friday", "sandbox.yaml")
+	writePolicy(t, want, "permissions: {}")
+	if got := FindPolicyFile(sub); got != want {
+		t.Errorf("FindPolicyFile() = %q, want %q", got, want)
+	}
+}
+
+func TestMergeDenyWins(t *testing.T) {
+	user := &Config{
+		Permissions: PermissionsConfig{
+			Allow:       []string{"git", "docker"},
+			Default:     "ask",
+			AskFallback: "allow",
+		},
+		Sandbox: SandboxConfig{
+			Filesystem: FilesystemConfig{Write: []string{"/tmp"}},
+			Defaults:   DefaultsConfig{Timeout: "5m"},
+		},
+	}
+	policy := &Config{
+		Permissions: PermissionsConfig{
+			Allow:   []string{"kubectl"},
+			Deny:    []string{"git push", "docker"},
+			Default: "deny",
+		},
+		Sandbox: SandboxConfig{
+			Enabled:    true,
+			Filesystem: FilesystemConfig{Protected: []string{"/tmp/secrets"}},
+			Network:    NetworkConfig{Isolation: true},
+			Defaults:   DefaultsConfig{Timeout: "1m"},
+		},
+	}
+
+	merged := user.Merge(policy)
+	perm := NewPermission(merged)
+	tests := []struct {
+		cmd  string
+		want Decision
+	}{
+		{"git status", Allow},
+		{"git push", Deny},
+		{"docker ps", Deny},
+		{"kubectl get pods", Deny}, // the policy may not add allow rules
+		{"terraform plan", Deny},   // policy default deny beats user ask
+	}
+	for _, tt := range tests {
+		if got, _ := perm.Check(tt.cmd); got != tt.want {
+			t.Errorf("merged Check(%q) = %v, want %v", tt.cmd, got, tt.want)
+		}
+	}
+
+	if merged.Permissions.AskFallback != "allow" {
+		t.Errorf("ask_fallback = %q, want user value when policy leaves it unset", merged.Permissions.AskFallback)
+	}
+	if !merged.Sandbox.Enabled || !merged.Sandbox.Network.Isolation {
+		t.Error("sandbox and network isolation should be enabled by the policy")
+	}
+	if merged.Sandbox.Defaults.Timeout != "1m" {
+		t.Errorf("timeout = %q, want the shorter 1m", merged.Sandbox.Defaults.Timeout)
+	}
+	if len(merged.Sandbox.Filesystem.Write) != 1 || len(merged.Sandbox.Filesystem.Protected) != 1 {
+		t.Errorf("filesystem lists should be unioned, got %+v", merged.Sandbox.Filesystem)
+	}
+	if len(user.Permissions.Deny) != 0 {
+		t.Error("Merge must not modify the user config")
+	}
+}
+
+func TestMergePolicyWidening(t *testing.T) {
+	user := &Config{
+		Permissions: PermissionsConfig{Allow: []string{"git"}, Default: "ask"},
+		Sandbox: SandboxConfig{
+			Filesystem: FilesystemConfig{Write: []string{"/tmp"}},
+			Network:    NetworkConfig{Allow: []string{"proxy.golang.org"}},
+		},
+	}
+	policy := &Config{
+		Permissions: PermissionsConfig{Allow: []string{"curl"}, Ask: []string{"git push"}},
+		Sandbox: SandboxConfig{
+			TrustPolicy: true,
+			Filesystem:  FilesystemConfig{Write: []string{"/"}, ReadOnly: []string{"/tmp/ro"}},
+			Network:     NetworkConfig{Allow: []string{"evil.example.com"}},
+		},
+	}
+
+	merged := user.Merge(policy)
+	if strings.Join(merged.Permissions.Allow, ",") != "git" {
+		t.Errorf("allow = %v, want the policy's allow rules ignored", merged.Permissions.Allow)
+	}
+	if strings.Join(merged.Sandbox.Filesystem.Write, ",") != "/tmp" {
+		t.Errorf("write = %v, want the policy's writable paths ignored", merged.Sandbox.Filesystem.Write)
+	}
+	if strings.Join(merged.Sandbox.Network.Allow, ",") != "proxy.golang.org" {
+		t.Errorf("network allow = %v, want the policy's hosts ignored", merged.Sandbox.Network.Allow)
+	}
+	if merged.Sandbox.TrustPolicy {
+		t.Error("a policy must not trust itself")
+	}
+	// Tightening still applies.
+	if got, _ := NewPermission(merged).Check("git push"); got != Ask {
+		t.Errorf("Check(git push) = %v, want the policy ask rule", got)
+	}
+	if strings.Join(merged.Sandbox.Filesystem.ReadOnly, ",") != "/tmp/ro" {
+		t.Errorf("readonly = %v, want the policy's read-only path", merged.Sandbox.Filesystem.ReadOnly)
+	}
+
+	// With the user's opt-in the policy may widen.
+	user.Sandbox.TrustPolicy = true
+	merged = user.Merge(policy)
+	if got, _ := NewPermission(merged).Check("curl example.com"); got != Allow {
+		t.Errorf("trusted Check(curl) = %v, want Allow", got)
+	}
+	if len(merged.Sandbox.Filesystem.Write) != 2 || len(merged.Sandbox.Network.Allow) != 2 {
+		t.Errorf("trusted policy should widen write and network, got %v %v", merged.Sandbox.Filesystem.Write, merged.Sandbox.Network.Allow)
+	}
+}
+
+func TestMergeLimits(t *testing.T) {
+	user := &Config{Sandbox: SandboxConfig{Limits: LimitsConfig{
+		Memory:  "4G",
+		CPUTime: "1m",
+		MaxPids: 512,
+	}}}
+	policy := &Config{Sandbox: SandboxConfig{Limits: LimitsConfig{
+		Memory:         "512M",
+		CPUTime:        "10m",
+		MaxOutputBytes: "1M",
+	}}}
+
+	got := user.Merge(policy).Sandbox.Limits
+	want := LimitsConfig{Memory: "512M", CPUTime: "1m", MaxPids: 512, MaxOutputBytes: "1M"}
+	if got != want {
+		t.Errorf("merged limits = %+v, want %+v", got, want)
+	}
+}
+
+func TestMergeContainer(t *testing.T) {
+	user := &Config{Sandbox: SandboxConfig{Container: ContainerConfig{
+		Runtime: "podman",
+		Image:   "example/base:1",
+		Args:    []string{"--platform", "linux/amd64"},
+	}}}
+	policy := &Config{Sandbox: SandboxConfig{
+		Backend: BackendContainer,
+		Container: ContainerConfig{
+			Runtime: "./evil",
+			Image:   "example/toolchain:2",
+			Args:    []string{"--privileged"},
+		},
+	}}
+
+	merged := user.Merge(policy).Sandbox
+	if merged.Backend != BackendContainer || merged.Container.Image != "example/base:1" || merged.Container.Runtime != "podman" {
+		t.Errorf("policy must not set the runtime or image, got %q %+v", merged.Backend, merged.Container)
+	}
+
+	// Without a runtime in the user config the installed one is looked up,
+	// never the policy's.
+	user.Sandbox.Container.Runtime = ""
+	if got := user.Merge(policy).Sandbox.Container.Runtime; got != "" {
+		t.Errorf("runtime = %q, want the policy runtime ignored", got)
+	}
+	if strings.Join(merged.Container.Args, " ") != "--platform linux/amd64" {
+		t.Errorf("policy must not add run args, got %v", merged.Container.Args)
+	}
+}
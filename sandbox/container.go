@@ -0,0 +1,221 @@
+package sandbox
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// BackendContainer selects the container sandbox in SandboxConfig.Backend
+const BackendContainer = "container"
+
+// containerRuntimes are the supported container CLIs, in lookup order
+var containerRuntimes = []string{"podman", "docker"}
+
+// Container implements Sandbox by running each command in a fresh OCI
+// container from a configured image, using the podman or docker CLI. The
+// workdir and the configured filesystem paths are bind-mounted at the same
+// locations, so paths in commands and output match the host.
+type Container struct {
+	config *Config
+}
+
+// NewContainer creates a new container sandbox
+func NewContainer(cfg *Config) *Container {
+	return &Container{config: cfg}
+}
+
+// WrapCommand wraps a command to run in a container
+func (c *Container) WrapCommand(cmd string, opts ExecOptions) (string, func(), error) {
+	if !c.config.Sandbox.Enabled {
+		return cmd, func() {}, nil
+	}
+	if c.config.Sandbox.Container.Image == "" {
+		return "", nil, fmt.Errorf("sandbox.container.image is required for the container backend")
+	}
+	rt := c.runtime()
+	if rt == "" {
+		return "", nil, fmt.Errorf("no container runtime found (tried %s)", strings.Join(containerRuntimes, ", "))
+	}
+
+	name := "friday-" + generateTaskID()
+	args, prefix := c.buildArgs(rt, name, opts)
+	args = append(args, shellQuote(c.config.Sandbox.Container.Image))
+	args = append(args, prefix...)
+	args = append(args, "bash", "-c", shellQuote(cmd))
+	wrappedCmd := shellQuote(rt) + " " + strings.Join(args, " ")
+
+	// Killing the CLI does not stop a detached container; remove it
+	// explicitly once the command is done.
+	cleanup := func() {
+		_ = exec.Command(rt, "rm", "-f", name).Run()
+	}
+	return wrappedCmd, cleanup, nil
+}
+
+// IsAvailable checks if a container runtime is installed
+func (c *Container) IsAvailable() bool {
+	return c.runtime() != ""
+}
+
+// Name returns the name of this sandbox
+func (c *Container) Name() string {
+	if rt := c.runtime(); rt != "" {
+		return "container (" + filepath.Base(rt) + ")"
+	}
+	return "container"
+}
+
+// runtime returns the configured container CLI, or the first one installed.
+func (c *Container) runtime() string {
+	candidates := containerRuntimes
+	if rt := c.config.Sandbox.Container.Runtime; rt != "" {
+		candidates = []string{rt}
+	}
+	for _, rt := range candidates {
+		if path, err := exec.LookPath(rt); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// buildArgs builds the "run" arguments up to, but not including, the image,
+// and the arguments that go between the image and the command.
+func (c *Container) buildArgs(rt, name string, opts ExecOptions) (args, prefix []string) {
+	sb := c.config.Sandbox
+	args = []string{"run", "--rm", "-i", "--init", "--name", name}
+
+	// Run as the calling user so files in the workdir keep their owner
+	if strings.Contains(filepath.Base(rt), "podman") {
+		args = append(args, "--userns=keep-id")
+	} else {
+		args = append(args, "--user", fmt.Sprintf("%d:%d", os.Getuid(), os.Getgid()))
+	}
+
+	// Mounts come from the overlay's shadow copy when one is active
+	mounted := make(map[string]bool)
+	mount := func(path string, readOnly bool) {
+		src := opts.Overlay.Translate(path)
+		if _, err := os.Stat(src); err != nil || mounted[path] {
+			return
+		}
+		mounted[path] = true
+		spec := src + ":" + path
+		if readOnly {
+			spec += ":ro"
+		}
+		args = append(args, "-v", shellQuote(spec))
+	}
+
+	// Workdir and write paths (rw), then readonly paths
+	var absWorkdir string
+	if opts.Workdir != "" {
+		absWorkdir, _ = filepath.Abs(opts.Workdir)
+		mount(absWorkdir, false)
+	}
+	for _, path := range sb.Filesystem.Write {
+		mount(expandPath(path, absWorkdir), false)
+	}
+	for _, path := range sb.Filesystem.ReadOnly {
+		mount(expandPath(path, absWorkdir), true)
+	}
+
+	// Protected paths inside a mount are remounted read-only and denied
+	// directories are hidden behind an empty tmpfs.
+	for _, path := range sb.Filesystem.Protected {
+		expanded := expandPath(path, absWorkdir)
+		if underMount(expanded, mounted) {
+			delete(mounted, expanded)
+			mount(expanded, true)
+		}
+	}
+	for _, path := range sb.Filesystem.Deny {
+		expanded := expandPath(path, absWorkdir)
+		if info, err := os.Stat(opts.Overlay.Translate(expanded)); err == nil && info.IsDir() && underMount(expanded, mounted) {
+			args = append(args, "--tmpfs", shellQuote(expanded))
+		}
+	}
+	if absWorkdir != "" {
+		args = append(args, "-w", shellQuote(absWorkdir))
+	}
+
+	// Network: none when isolated, except through the filtering proxy
+	if sb.Network.Isolation {
+		args = append(args, "--network", "none")
+		if proxyArgs, bridge, ok := c.proxyArgs(opts.Proxy); ok {
+			args = append(args, proxyArgs...)
+			prefix = bridge
+		}
+	}
+
+	args = append(args, c.limitArgs()...)
+	for _, arg := range sb.Container.Args {
+		args = append(args, shellQuote(arg))
+	}
+	return args, prefix
+}
+
+// proxyArgs mounts the proxy socket and bridge binary into the container and
+// makes the bridge its entrypoint; bridge holds the entrypoint's arguments,
+// which precede the command. It needs a Linux host, where the bridge binary
+// can run inside the container.
+func (c *Container) proxyArgs(proxy *NetworkProxy) (args, bridge []string, ok bool) {
+	if proxy == nil || runtime.GOOS != "linux" || len(BridgeCommand) == 0 || !filepath.IsAbs(BridgeCommand[0]) {
+		return nil, nil, false
+	}
+	addr, socket := proxy.Addr(), proxy.Socket()
+	if addr == "" || socket == "" {
+		return nil, nil, false
+	}
+
+	sockDir := filepath.Dir(socket)
+	exe := BridgeCommand[0]
+	args = []string{
+		"-v", shellQuote(sockDir + ":" + sockDir),
+		"-v", shellQuote(exe + ":" + exe + ":ro"),
+		"--entrypoint", shellQuote(exe),
+	}
+	for _, kv := range proxy.Env() {
+		args = append(args, "-e", shellQuote(kv))
+	}
+	for _, arg := range append(append([]string{}, BridgeCommand[1:]...), "--listen", addr, "--socket", socket, "--") {
+		bridge = append(bridge, shellQuote(arg))
+	}
+	return args, bridge, true
+}
+
+// limitArgs translates sandbox.limits into container resource flags, since
+// rlimits and cgroups applied to the CLI do not reach the container.
+func (c *Container) limitArgs() []string {
+	limits := parseLimits(c.config.Sandbox.Limits)
+	var args []string
+	if limits.memory > 0 {
+		args = append(args, "--memory", fmt.Sprintf("%d", limits.memory), "--memory-swap", fmt.Sprintf("%d", limits.memory))
+	}
+	if limits.maxPids > 0 {
+		args = append(args, "--pids-limit", fmt.Sprintf("%d", limits.maxPids))
+	}
+	if limits.cpuTime > 0 {
+		secs := ceilDiv(int64(limits.cpuTime), int64(time.Second))
+		args = append(args, "--ulimit", fmt.Sprintf("cpu=%d:%d", secs, secs+1))
+	}
+	if limits.maxFileSize > 0 {
+		args = append(args, "--ulimit", fmt.Sprintf("fsize=%d", limits.maxFileSize))
+	}
+	return args
+}
+
+// underMount reports whether path lies inside one of the mounted paths.
+func underMount(path string, mounted map[string]bool) bool {
+	for m := range mounted {
+		if path == m || strings.HasPrefix(path, m+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,132 @@
+package sandbox
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeRuntime installs a "docker" on PATH that logs its arguments and runs
+// the container command on the host.
+func fakeRuntime(t *testing.T) (logFile string) {
+	t.Helper()
+	dir := t.TempDir()
+	logFile = filepath.Join(dir, "args.log")
+	script := `#!/bin/bash
+printf '%s\n' "$@" > ` + logFile + `
+[ "$1" = rm ] && exit 0
+while [ $# -gt 0 ]; do
+  if [ "$1" = example/toolchain:1 ]; then shift; exec "$@"; fi
+  shift
+done
+exit 99
+`
+	if err := os.WriteFile(filepath.Join(dir, "docker"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return logFile
+}
+
+func newContainerConfig() *Config {
+	cfg := DefaultConfig()
+	cfg.Sandbox.Backend = BackendContainer
+	cfg.Sandbox.Container = ContainerConfig{Runtime: "docker", Image: "example/toolchain:1"}
+	cfg.Sandbox.Network.Isolation = false
+	return cfg
+}
+
+func TestContainerWrapCommand(t *testing.T) {
+	fakeRuntime(t)
+	workdir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(workdir, ".git", "hooks"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	cfg := newContainerConfig()
+	cfg.Sandbox.Network.Isolation = true
+	cfg.Sandbox.Limits = LimitsConfig{Memory: "1G", MaxPids: 64}
+	c := NewContainer(cfg)
+
+	wrapped, cleanup, err := c.WrapCommand("go test ./...", ExecOptions{Workdir: workdir})
+	if err != nil {
+		t.Fatalf("WrapCommand: %v", err)
+	}
+	defer cleanup()
+
+	for _, want := range []string{
+		" run --rm -i --init --name friday-",
+		"-v " + workdir + ":" + workdir + " ",
+		"-v " + filepath.Join(workdir, ".git", "hooks") + ":" + filepath.Join(workdir, ".git", "hooks") + ":ro",
+		"-w " + workdir,
+		"--network none",
+		"--memory 1073741824",
+		"--pids-limit 64",
+		"example/toolchain:1 bash -c 'go test ./...'",
+	} {
+		if !strings.Contains(wrapped, want) {
+			t.Errorf("wrapped command missing %q:\n%s", want, wrapped)
+		}
+	}
+}
+
+func TestContainerWrapCommandProxy(t *testing.T) {
+	fakeRuntime(t)
+	orig := BridgeCommand
+	defer func() { BridgeCommand = orig }()
+	BridgeCommand = []string{"/usr/local/bin/friday", "sandbox", "bridge"}
+
+	cfg := newContainerConfig()
+	cfg.Sandbox.Network.Isolation = true
+	proxy := NewNetworkProxy(cfg.Sandbox.Network.Allow)
+	if err := proxy.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer proxy.Close()
+
+	wrapped, _, err := NewContainer(cfg).WrapCommand("go mod download", ExecOptions{Proxy: proxy})
+	if err != nil {
+		t.Fatalf("WrapCommand: %v", err)
+	}
+	for _, want := range []string{
+		"--entrypoint /usr/local/bin/friday",
+		"-e 'HTTPS_PROXY=http://" + proxy.Addr() + "'",
+		"example/toolchain:1 sandbox bridge --listen " + proxy.Addr() + " --socket " + proxy.Socket() + " -- bash -c",
+	} {
+		if !strings.Contains(wrapped, want) {
+			t.Errorf("wrapped command missing %q:\n%s", want, wrapped)
+		}
+	}
+}
+
+func TestContainerRequiresImage(t *testing.T) {
+	fakeRuntime(t)
+	cfg := newContainerConfig()
+	cfg.Sandbox.Container.Image = ""
+	if _, _, err := NewContainer(cfg).WrapCommand("ls", ExecOptions{}); err == nil {
+		t.Error("expected an error without sandbox.container.image")
+	}
+}
+
+func TestExecutorContainerBackend(t *testing.T) {
+	logFile := fakeRuntime(t)
+	exec := NewExecutor(newContainerConfig())
+	if !strings.HasPrefix(exec.SandboxName(), "container") {
+		t.Fatalf("SandboxName = %q, want container backend", exec.SandboxName())
+	}
+
+	result, err := exec.Run(context.Background(), "echo from-container", ExecOptions{Workdir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if strings.TrimSpace(result.Stdout) != "from-container" {
+		t.Errorf("Stdout = %q, stderr = %q", result.Stdout, result.Stderr)
+	}
+
+	// The cleanup removes the container by name.
+	data, _ := os.ReadFile(logFile)
+	if !strings.HasPrefix(string(data), "rm\n-f\nfriday-") {
+		t.Errorf("last runtime call = %q, want rm -f of the container", data)
+	}
+}
@@ -34,6 +34,7 @@ var DefaultAllowedDomains = []string{
 	"api.github.com",
 	"registry.npmjs.org",
 	"proxy.golang.org",
+	"sum.golang.org",
 	"pkg.go.dev",
 }
 
@@ -82,6 +83,9 @@ func DefaultConfig() *Config {
 		Permissions: PermissionsConfig{
 			Allow: append([]string{}, DefaultAllowedCommands...),
 			Deny:  append([]string{}, DefaultDeniedCommands...),
+			// Unlisted commands ask interactive callers and are denied
+			// elsewhere unless ask_fallback says otherwise.
+			Default: "ask",
 		},
 		Sandbox: SandboxConfig{
 			Enabled: true,
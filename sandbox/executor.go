@@ -9,7 +9,11 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
+
+	"github.com/basenana/friday/utils/crypt"
 )
 
 const (
@@ -19,13 +23,26 @@ const (
 	MaxOutputLines = 300
 	// MaxOutputBytes is the maximum output size in bytes
 	MaxOutputBytes = 512 * 1024 // 512KB
+
+	// killWaitDelay is how long a killed command may keep its output open
+	killWaitDelay = 2 * time.Second
 )
 
 // Executor handles command execution with sandboxing
 type Executor struct {
-	config  *Config
-	perm    *Permission
-	sandbox Sandbox
+	config   *Config
+	perm     *Permission
+	sandbox  Sandbox
+	approver Approver
+
+	proxyOnce sync.Once
+	proxy     *NetworkProxy
+	proxyErr  error
+
+	overlay *Overlay
+
+	cipherDir string
+	cipher    *crypt.Cipher
 }
 
 // NewExecutor creates a new Executor
@@ -50,10 +67,93 @@ func NewExecutor(cfg *Config) *Executor {
 	}
 }
 
+// SetApprover sets the callback consulted for commands that resolve to Ask.
+// Without one, Ask falls back to the configured permissions.ask_fallback.
+func (e *Executor) SetApprover(approver Approver) {
+	e.approver = approver
+}
+
+// SetOverlay routes commands and file tools through overlay, so changes to
+// its workdir land in the shadow copy. nil writes to the real tree.
+func (e *Executor) SetOverlay(overlay *Overlay) {
+	e.overlay = overlay
+}
+
+// SetCipher makes the file tools decrypt the files they read under dir and
+// encrypt the ones they write there. Commands still see the files as stored.
+func (e *Executor) SetCipher(dir string, c *crypt.Cipher) {
+	e.cipherDir = dir
+	e.cipher = c
+}
+
+// cipherFor returns the cipher for the file at absPath, nil outside the
+// directory set with SetCipher.
+func (e *Executor) cipherFor(absPath string) *crypt.Cipher {
+	if e.cipher == nil || !pathWithinRoot(absPath, e.cipherDir) {
+		return nil
+	}
+	return e.cipher
+}
+
+// SupportsOverlay reports whether the sandbox confines commands to an
+// overlay. Only bubblewrap and the container backend mount the shadow copy
+// over the workdir; elsewhere a command can still write to the real tree by
+// its absolute path.
+func (e *Executor) SupportsOverlay() bool {
+	switch e.sandbox.(type) {
+	case *Bwrap, *Container:
+		return true
+	default:
+		return false
+	}
+}
+
+// Overlay returns the overlay set with SetOverlay, if any.
+func (e *Executor) Overlay() *Overlay {
+	return e.overlay
+}
+
+// Authorize checks cmd against the permission rules and resolves Ask through
+// the approver, so the result is always Allow or Deny.
+func (e *Executor) Authorize(ctx context.Context, cmd string) (Decision, string, error) {
+	decision, reason, err := e.perm.CheckWithReason(cmd)
+	if err != nil || decision != Ask {
+		return decision, reason, err
+	}
+
+	if e.approver == nil {
+		if ParseDecision(e.config.Permissions.AskFallback, Deny) == Allow {
+			return Allow, reason + " (allowed by ask_fallback)", nil
+		}
+		return Deny, reason + " (approval required, no approver available)", nil
+	}
+
+	req := ApprovalRequest{
+		Command:  cmd,
+		Reason:   reason,
+		Patterns: e.perm.ApprovalPatterns(cmd),
+	}
+	approval, err := e.approver(ctx, req)
+	if err != nil {
+		return Deny, reason + " (approval failed: " + err.Error() + ")", nil
+	}
+	switch approval {
+	case ApproveAlways:
+		for _, pattern := range req.Patterns {
+			e.config.AllowCommand(pattern)
+		}
+		return Allow, "approved by user (always)", nil
+	case ApproveOnce:
+		return Allow, "approved by user", nil
+	default:
+		return Deny, reason + " (rejected by user)", nil
+	}
+}
+
 // Run executes a command with sandboxing and permission checks
 func (e *Executor) Run(ctx context.Context, cmd string, opts ExecOptions) (*Result, error) {
 	// 1. Check permissions
-	decision, reason, err := e.perm.CheckWithReason(cmd)
+	decision, reason, err := e.Authorize(ctx, cmd)
 	if err != nil {
 		return nil, fmt.Errorf("permission check failed: %w", err)
 	}
@@ -74,7 +174,7 @@ func (e *Executor) Run(ctx context.Context, cmd string, opts ExecOptions) (*Resu
 	defer cancel()
 
 	// 4. Wrap command with sandbox
-	wrappedCmd, cleanup, err := e.sandbox.WrapCommand(cmd, opts)
+	wrappedCmd, cleanup, err := e.WrapCommand(cmd, opts)
 	if cleanup != nil {
 		defer cleanup()
 	}
@@ -93,13 +193,27 @@ func (e *Executor) Run(ctx context.Context, cmd string, opts ExecOptions) (*Resu
 
 // execute runs the actual command
 func (e *Executor) execute(ctx context.Context, cmdStr string, opts ExecOptions) (*Result, error) {
+	// Output beyond the limit cancels the run
+	ctx, kill := context.WithCancel(ctx)
+	defer kill()
+	guard := newLimitGuard(parseLimits(e.config.Sandbox.Limits), kill)
+	defer guard.close()
+
 	// Use bash -c to handle complex commands
-	cmd := exec.CommandContext(ctx, "bash", "-c", cmdStr)
+	cmd := exec.CommandContext(ctx, "bash", "-c", guard.wrap(cmdStr))
+	// Run it in its own process group and kill the whole group on timeout
+	// or overflow: children left behind keep the output pipes open, so Wait
+	// would never return. WaitDelay bounds the wait for any that escaped
+	// the group.
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = killWaitDelay
+	guard.attach(cmd)
 
 	// Set working directory
-	if opts.Workdir != "" {
-		cmd.Dir = opts.Workdir
-	}
+	cmd.Dir = e.commandDir(opts.Workdir)
 
 	// Set environment
 	if len(opts.Env) > 0 {
@@ -110,8 +224,8 @@ func (e *Executor) execute(ctx context.Context, cmdStr string, opts ExecOptions)
 
 	// Capture output
 	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	cmd.Stdout = guard.writer(&stdout)
+	cmd.Stderr = guard.writer(&stderr)
 
 	// Handle stdin if provided
 	if opts.Stdin != "" {
@@ -140,6 +254,9 @@ func (e *Executor) execute(ctx context.Context, cmdStr string, opts ExecOptions)
 			result.ExitCode = 1
 		}
 	}
+	if !result.TimedOut {
+		result.LimitExceeded = guard.exceeded(cmd.ProcessState)
+	}
 
 	return result, nil
 }
@@ -174,7 +291,8 @@ func truncateOutput(output string) string {
 	return strings.Join(lines, "\n")
 }
 
-// CheckPermission checks if a command would be allowed without executing it
+// CheckPermission checks if a command would be allowed without executing it.
+// Unlike Authorize it never asks, so the decision may be Ask.
 func (e *Executor) CheckPermission(cmd string) (Decision, string, error) {
 	return e.perm.CheckWithReason(cmd)
 }
@@ -196,10 +314,68 @@ func GetOSInfo() string {
 
 // WrapCommand wraps a command with sandbox isolation.
 // Returns the wrapped command string, a cleanup function, and any error.
+// Network-isolated commands are routed through the executor's filtering proxy.
 func (e *Executor) WrapCommand(cmd string, opts ExecOptions) (string, func(), error) {
+	proxy, err := e.networkProxy()
+	if err != nil {
+		return "", nil, err
+	}
+	opts.Proxy = proxy
+	opts.Overlay = e.overlay
 	return e.sandbox.WrapCommand(cmd, opts)
 }
 
+// commandDir is the directory a command is started in: workdir, or its
+// shadow copy when an overlay is active. Sandboxes that can mount the copy
+// over the workdir still present the original path to the command.
+func (e *Executor) commandDir(workdir string) string {
+	if e.overlay == nil {
+		return workdir
+	}
+	if workdir == "" {
+		workdir, _ = os.Getwd()
+	}
+	return e.overlay.Translate(workdir)
+}
+
+// resolvePath checks a file tool path against the sandbox rules and returns
+// where to access it, which is inside the overlay when one is active.
+func (e *Executor) resolvePath(workdir, path string, mode fsAccessMode) (string, error) {
+	absPath, err := resolveToolPath(e.config, workdir, path, mode)
+	if err != nil {
+		return "", err
+	}
+	return e.overlay.Translate(absPath), nil
+}
+
+// networkProxy starts the filtering proxy on first use. It returns nil when
+// the sandbox is disabled, not isolating the network or allows no domains.
+func (e *Executor) networkProxy() (*NetworkProxy, error) {
+	network := e.config.Sandbox.Network
+	if !e.config.Sandbox.Enabled || !network.Isolation || len(network.Allow) == 0 {
+		return nil, nil
+	}
+	e.proxyOnce.Do(func() {
+		proxy := NewNetworkProxy(network.Allow)
+		if err := proxy.Start(); err != nil {
+			e.proxyErr = fmt.Errorf("start network proxy: %w", err)
+			return
+		}
+		e.proxy = proxy
+	})
+	return e.proxy, e.proxyErr
+}
+
+// Close stops the network proxy, if one was started. Commands wrapped after
+// Close get no network.
+func (e *Executor) Close() error {
+	e.proxyOnce.Do(func() {})
+	if e.proxy == nil {
+		return nil
+	}
+	return e.proxy.Close()
+}
+
 // ValidateWorkdir validates and expands the working directory
 func ValidateWorkdir(workdir string) (string, error) {
 	if workdir == "" {
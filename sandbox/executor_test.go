@@ -190,3 +190,51 @@ func TestGetOSInfo(t *testing.T) {
 		t.Error("GetOSInfo should not return empty string")
 	}
 }
+
+func TestExecutorAuthorizeAsk(t *testing.T) {
+	newAskExecutor := func(fallback string) *Executor {
+		cfg := DefaultConfig()
+		cfg.Sandbox.Enabled = false
+		cfg.Permissions.Default = "ask"
+		cfg.Permissions.AskFallback = fallback
+		return NewExecutor(cfg)
+	}
+	ctx := context.Background()
+
+	exec := newAskExecutor("")
+	if decision, _, _ := exec.Authorize(ctx, "kubectl get"); decision != Deny {
+		t.Errorf("no approver, default fallback: decision = %v, want Deny", decision)
+	}
+
+	exec = newAskExecutor("allow")
+	if decision, _, _ := exec.Authorize(ctx, "kubectl get"); decision != Allow {
+		t.Errorf("no approver, allow fallback: decision = %v, want Allow", decision)
+	}
+
+	exec = newAskExecutor("")
+	exec.SetApprover(func(ctx context.Context, req ApprovalRequest) (Approval, error) {
+		return Reject, nil
+	})
+	if _, err := exec.Run(ctx, "kubectl get", ExecOptions{}); !IsDenied(err) {
+		t.Errorf("rejected command: err = %v, want permission denied", err)
+	}
+
+	exec = newAskExecutor("")
+	var asked []ApprovalRequest
+	exec.SetApprover(func(ctx context.Context, req ApprovalRequest) (Approval, error) {
+		asked = append(asked, req)
+		return ApproveAlways, nil
+	})
+	if decision, _, _ := exec.Authorize(ctx, "kubectl get"); decision != Allow {
+		t.Errorf("approved always: decision = %v, want Allow", decision)
+	}
+	if decision, _, _ := exec.Authorize(ctx, "kubectl get pods -A"); decision != Allow {
+		t.Errorf("second call: decision = %v, want Allow", decision)
+	}
+	if len(asked) != 1 {
+		t.Fatalf("approver called %d times, want 1", len(asked))
+	}
+	if got := strings.Join(asked[0].Patterns, ","); got != "kubectl get" {
+		t.Errorf("approval patterns = %q, want %q", got, "kubectl get")
+	}
+}
@@ -64,7 +64,7 @@ func fsReadHandler(exec *Executor, workdir string) tools.ToolHandlerFunc {
 			return tools.NewToolResultError("path is required"), nil
 		}
 
-		absPath, err := resolveToolPath(exec.config, workdir, path, fsAccessRead)
+		absPath, err := exec.resolvePath(workdir, path, fsAccessRead)
 		if err != nil {
 			return tools.NewToolResultError(fmt.Sprintf("invalid path: %s", err)), nil
 		}
@@ -77,7 +77,7 @@ func fsReadHandler(exec *Executor, workdir string) tools.ToolHandlerFunc {
 			return tools.NewToolResultError(fmt.Sprintf("failed to read file: path is a directory: %s", path)), nil
 		}
 
-		content, err := os.ReadFile(absPath)
+		content, err := exec.cipherFor(absPath).ReadFile(absPath)
 		if err != nil {
 			return tools.NewToolResultError(fmt.Sprintf("failed to read file: %s", err)), nil
 		}
@@ -114,12 +114,12 @@ func fsWriteHandler(exec *Executor, workdir string) tools.ToolHandlerFunc {
 			return tools.NewToolResultError("content is required"), nil
 		}
 
-		absPath, err := resolveToolPath(exec.config, workdir, path, fsAccessWrite)
+		absPath, err := exec.resolvePath(workdir, path, fsAccessWrite)
 		if err != nil {
 			return tools.NewToolResultError(fmt.Sprintf("invalid path: %s", err)), nil
 		}
 
-		if err := writeFileAtomic(absPath, []byte(content), 0o644); err != nil {
+		if err := writeFileAtomic(absPath, exec.cipherFor(absPath).Seal(absPath, []byte(content)), 0o644); err != nil {
 			return tools.NewToolResultError(fmt.Sprintf("failed to write file: %s", err)), nil
 		}
 
@@ -149,7 +149,7 @@ func fsListHandler(exec *Executor, workdir string) tools.ToolHandlerFunc {
 			path = "."
 		}
 
-		absPath, err := resolveToolPath(exec.config, workdir, path, fsAccessRead)
+		absPath, err := exec.resolvePath(workdir, path, fsAccessRead)
 		if err != nil {
 			return tools.NewToolResultError(fmt.Sprintf("invalid path: %s", err)), nil
 		}
@@ -193,7 +193,7 @@ func fsDeleteHandler(exec *Executor, workdir string) tools.ToolHandlerFunc {
 			return tools.NewToolResultError("path is required"), nil
 		}
 
-		absPath, err := resolveToolPath(exec.config, workdir, path, fsAccessWrite)
+		absPath, err := exec.resolvePath(workdir, path, fsAccessWrite)
 		if err != nil {
 			return tools.NewToolResultError(fmt.Sprintf("invalid path: %s", err)), nil
 		}
@@ -228,7 +228,7 @@ func fsMkdirHandler(exec *Executor, workdir string) tools.ToolHandlerFunc {
 			return tools.NewToolResultError("path is required"), nil
 		}
 
-		absPath, err := resolveToolPath(exec.config, workdir, path, fsAccessWrite)
+		absPath, err := exec.resolvePath(workdir, path, fsAccessWrite)
 		if err != nil {
 			return tools.NewToolResultError(fmt.Sprintf("invalid path: %s", err)), nil
 		}
@@ -291,7 +291,7 @@ func fsEditHandler(exec *Executor, workdir string) tools.ToolHandlerFunc {
 
 		replaceAll := occurrences == "all"
 
-		absPath, err := resolveToolPath(exec.config, workdir, path, fsAccessWrite)
+		absPath, err := exec.resolvePath(workdir, path, fsAccessWrite)
 		if err != nil {
 			return tools.NewToolResultError(fmt.Sprintf("invalid path: %s", err)), nil
 		}
@@ -307,7 +307,7 @@ func fsEditHandler(exec *Executor, workdir string) tools.ToolHandlerFunc {
 			return tools.NewToolResultError(fmt.Sprintf("file too large (%d bytes), maximum allowed is %d bytes", fileInfo.Size(), maxEditFileSize)), nil
 		}
 
-		content, err := os.ReadFile(absPath)
+		content, err := exec.cipherFor(absPath).ReadFile(absPath)
 		if err != nil {
 			return tools.NewToolResultError(fmt.Sprintf("failed to read file: %s", err)), nil
 		}
@@ -332,7 +332,7 @@ func fsEditHandler(exec *Executor, workdir string) tools.ToolHandlerFunc {
 			return tools.NewToolResultError(fmt.Sprintf("result file too large (%d bytes), maximum allowed is %d bytes", len(newContent), maxEditFileSize)), nil
 		}
 
-		if err := writeFileAtomic(absPath, []byte(newContent), 0o644); err != nil {
+		if err := writeFileAtomic(absPath, exec.cipherFor(absPath).Seal(absPath, []byte(newContent)), 0o644); err != nil {
 			return tools.NewToolResultError(fmt.Sprintf("failed to write file: %s", err)), nil
 		}
 
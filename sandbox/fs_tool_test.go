@@ -1,6 +1,7 @@
 package sandbox
 
 import (
+	"bytes"
 	"context"
 	"os"
 	"path/filepath"
@@ -8,6 +9,7 @@ import (
 	"testing"
 
 	"github.com/basenana/friday/core/tools"
+	"github.com/basenana/friday/utils/crypt"
 )
 
 func textResult(t *testing.T, result *tools.Result) string {
@@ -201,3 +203,56 @@ func TestFsHandlersUseSharedAccessRules(t *testing.T) {
 		t.Fatalf("delete should be allowed: %s", textResult(t, deleteResult))
 	}
 }
+
+func TestFsToolsEncryptCipherDir(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Sandbox.Enabled = false
+	workdir := t.TempDir()
+	memoryDir := filepath.Join(workdir, "memory")
+	c, err := crypt.New(bytes.Repeat([]byte{7}, crypt.KeySize))
+	if err != nil {
+		t.Fatalf("crypt.New() error: %v", err)
+	}
+
+	exec := NewExecutor(cfg)
+	exec.SetCipher(memoryDir, c)
+
+	for _, path := range []string{filepath.Join(memoryDir, "MEMORY.md"), filepath.Join(workdir, "notes.md")} {
+		result, err := fsWriteHandler(exec, workdir)(context.Background(), &tools.Request{
+			Arguments: map[string]any{"path": path, "content": "prefers tabs\n"},
+		})
+		if err != nil || result.IsError {
+			t.Fatalf("fsWriteHandler(%s) = %v, %v", path, result, err)
+		}
+		result, err = fsEditHandler(exec, workdir)(context.Background(), &tools.Request{
+			Arguments: map[string]any{"path": path, "search_string": "tabs", "replace_string": "spaces"},
+		})
+		if err != nil || result.IsError {
+			t.Fatalf("fsEditHandler(%s) = %v, %v", path, result, err)
+		}
+		result, err = fsReadHandler(exec, workdir)(context.Background(), &tools.Request{
+			Arguments: map[string]any{"path": path},
+		})
+		if err != nil {
+			t.Fatalf("fsReadHandler(%s) error: %v", path, err)
+		}
+		if got := textResult(t, result); got != "prefers spaces\n" {
+			t.Fatalf("fsReadHandler(%s) = %q", path, got)
+		}
+	}
+
+	raw, err := os.ReadFile(filepath.Join(memoryDir, "MEMORY.md"))
+	if err != nil {
+		t.Fatalf("os.ReadFile() error: %v", err)
+	}
+	if !crypt.IsEncrypted(raw) {
+		t.Fatalf("memory file is not encrypted: %q", raw)
+	}
+	raw, err = os.ReadFile(filepath.Join(workdir, "notes.md"))
+	if err != nil {
+		t.Fatalf("os.ReadFile() error: %v", err)
+	}
+	if string(raw) != "prefers spaces\n" {
+		t.Fatalf("file outside the cipher dir = %q", raw)
+	}
+}
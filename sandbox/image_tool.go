@@ -138,6 +138,7 @@ func readImageBytes(ctx context.Context, exec *Executor, workdir, imageRef strin
 	if err := validateImagePathAccess(exec.config, workdir, absPath); err != nil {
 		return nil, "", err
 	}
+	absPath = exec.overlay.Translate(absPath)
 
 	info, err := os.Stat(absPath)
 	if err != nil {
@@ -0,0 +1,253 @@
+package sandbox
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Limit names reported in Result.LimitExceeded and Task.LimitExceeded
+const (
+	LimitMemory      = "memory"
+	LimitCPUTime     = "cpu_time"
+	LimitMaxPids     = "max_pids"
+	LimitMaxFileSize = "max_file_size"
+	LimitMaxOutput   = "max_output_bytes"
+)
+
+// resourceLimits is the parsed form of LimitsConfig
+type resourceLimits struct {
+	memory      int64
+	cpuTime     time.Duration
+	maxPids     int
+	maxFileSize int64
+	maxOutput   int64
+}
+
+func parseLimits(cfg LimitsConfig) resourceLimits {
+	l := resourceLimits{
+		memory:      parseMemoryLimit(cfg.Memory),
+		maxPids:     cfg.MaxPids,
+		maxFileSize: parseMemoryLimit(cfg.MaxFileSize),
+		maxOutput:   parseMemoryLimit(cfg.MaxOutputBytes),
+	}
+	if d, err := time.ParseDuration(cfg.CPUTime); err == nil && d > 0 {
+		l.cpuTime = d
+	}
+	return l
+}
+
+// limitGuard applies resource limits to one command and works out which
+// limit, if any, ended it.
+//
+// Usage: wrap the command string, attach to the exec.Cmd before starting it,
+// route output through writer or reader, then call exceeded and close once the
+// command has finished.
+type limitGuard struct {
+	limits resourceLimits
+	cgroup *cgroup
+
+	mu       sync.Mutex
+	output   int64
+	overflow bool
+	onLimit  func()
+}
+
+// newLimitGuard returns a guard for limits; onLimit is called once when the
+// output limit is hit and should kill the command.
+func newLimitGuard(limits resourceLimits, onLimit func()) *limitGuard {
+	g := &limitGuard{limits: limits, onLimit: onLimit}
+	if limits.memory > 0 || limits.maxPids > 0 {
+		g.cgroup = newCgroup(limits)
+	}
+	return g
+}
+
+// wrap prefixes cmd with the ulimit calls for the CPU time and file size
+// limits. Memory and process count have no per-command rlimit: RLIMIT_NPROC
+// counts every process of the user, and RLIMIT_AS caps address space, which
+// the Go and Node runtimes reserve far beyond their use at startup. Without a
+// cgroup they are left unenforced; see Config.UnenforcedLimits.
+func (g *limitGuard) wrap(cmd string) string {
+	var opts []string
+	if g.limits.maxFileSize > 0 {
+		opts = append(opts, fmt.Sprintf("-f %d", ceilDiv(g.limits.maxFileSize, 1024)))
+	}
+
+	var calls []string
+	if len(opts) > 0 {
+		calls = append(calls, "ulimit "+strings.Join(opts, " "))
+	}
+	if g.limits.cpuTime > 0 {
+		// A soft limit below the hard one gets SIGXCPU rather than SIGKILL,
+		// so the cause can be reported.
+		secs := ceilDiv(int64(g.limits.cpuTime), int64(time.Second))
+		calls = append(calls, fmt.Sprintf("ulimit -S -t %d", secs), fmt.Sprintf("ulimit -H -t %d", secs+1))
+	}
+	if len(calls) == 0 {
+		return cmd
+	}
+	return strings.Join(calls, " && ") + " || exit 126\n" + cmd
+}
+
+// UnenforcedLimits returns the configured limits that commands would run
+// without, because they need a cgroup v2 that cannot be created here. The
+// container backend enforces every limit itself.
+func (c *Config) UnenforcedLimits() []string {
+	limits := parseLimits(c.Sandbox.Limits)
+	if limits.memory <= 0 && limits.maxPids <= 0 {
+		return nil
+	}
+	if c.Sandbox.Enabled && c.Sandbox.Backend == BackendContainer {
+		return nil
+	}
+	if cg := newCgroup(limits); cg != nil {
+		cg.close()
+		return nil
+	}
+	var missing []string
+	if limits.memory > 0 {
+		missing = append(missing, LimitMemory)
+	}
+	if limits.maxPids > 0 {
+		missing = append(missing, LimitMaxPids)
+	}
+	return missing
+}
+
+// attach places cmd in the guard's cgroup, if there is one.
+func (g *limitGuard) attach(cmd *exec.Cmd) {
+	if g.cgroup == nil {
+		return
+	}
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	g.cgroup.attach(cmd.SysProcAttr)
+}
+
+// writer counts output written through w against the output limit.
+func (g *limitGuard) writer(w io.Writer) io.Writer {
+	if g.limits.maxOutput <= 0 {
+		return w
+	}
+	return &limitedWriter{w: w, guard: g}
+}
+
+// reader counts output read through r against the output limit.
+func (g *limitGuard) reader(r io.Reader) io.Reader {
+	if g.limits.maxOutput <= 0 {
+		return r
+	}
+	return &limitedReader{r: r, guard: g}
+}
+
+// countOutput records n bytes of output and reports whether they fit.
+func (g *limitGuard) countOutput(n int) bool {
+	g.mu.Lock()
+	g.output += int64(n)
+	hit := !g.overflow && g.output > g.limits.maxOutput
+	if hit {
+		g.overflow = true
+	}
+	overflow := g.overflow
+	g.mu.Unlock()
+
+	if hit && g.onLimit != nil {
+		g.onLimit()
+	}
+	return !overflow
+}
+
+// exceeded returns the limit that ended the process, or "".
+func (g *limitGuard) exceeded(state *os.ProcessState) string {
+	g.mu.Lock()
+	overflow := g.overflow
+	g.mu.Unlock()
+	if overflow {
+		return LimitMaxOutput
+	}
+	if g.cgroup != nil {
+		if limit := g.cgroup.exceeded(); limit != "" {
+			return limit
+		}
+	}
+	if state == nil {
+		return ""
+	}
+
+	// A process killed by a signal may be reported directly or as 128+N by
+	// the wrapping shell.
+	var sig syscall.Signal
+	if ws, ok := state.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+		sig = ws.Signal()
+	} else if code := state.ExitCode(); code > 128 {
+		sig = syscall.Signal(code - 128)
+	}
+	switch {
+	case sig == syscall.SIGXCPU && g.limits.cpuTime > 0:
+		return LimitCPUTime
+	case sig == syscall.SIGXFSZ && g.limits.maxFileSize > 0:
+		return LimitMaxFileSize
+	}
+	return ""
+}
+
+// close kills anything left in the cgroup and removes it.
+func (g *limitGuard) close() {
+	if g.cgroup != nil {
+		g.cgroup.close()
+	}
+}
+
+type limitedWriter struct {
+	w     io.Writer
+	guard *limitGuard
+}
+
+func (w *limitedWriter) Write(p []byte) (int, error) {
+	if !w.guard.countOutput(len(p)) {
+		// Swallow the rest so the command is not also killed by EPIPE.
+		return len(p), nil
+	}
+	return w.w.Write(p)
+}
+
+type limitedReader struct {
+	r     io.Reader
+	guard *limitGuard
+}
+
+func (r *limitedReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if n > 0 && !r.guard.countOutput(n) {
+		return 0, io.EOF
+	}
+	return n, err
+}
+
+func ceilDiv(a, b int64) int64 {
+	return (a + b - 1) / b
+}
+
+// describeLimit renders the configured value of a limit for messages.
+func describeLimit(cfg LimitsConfig, limit string) string {
+	switch limit {
+	case LimitMemory:
+		return "memory limit " + cfg.Memory
+	case LimitCPUTime:
+		return "CPU time limit " + cfg.CPUTime
+	case LimitMaxPids:
+		return fmt.Sprintf("process limit %d", cfg.MaxPids)
+	case LimitMaxFileSize:
+		return "file size limit " + cfg.MaxFileSize
+	case LimitMaxOutput:
+		return "output limit " + cfg.MaxOutputBytes
+	}
+	return limit
+}
@@ -0,0 +1,147 @@
+//go:build linux
+
+package sandbox
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// cgroupRoot is where the unified (v2) cgroup hierarchy is mounted
+const cgroupRoot = "/sys/fs/cgroup"
+
+// cgroup is a per-command cgroup v2 holding the memory and pids limits.
+type cgroup struct {
+	dir string
+	fd  *os.File
+}
+
+// newCgroup creates a cgroup with the memory and pids limits applied, next
+// to the current process's cgroup: under cgroup v2 a cgroup other than the
+// root may not both hold processes and pass controllers to children. It
+// returns nil when cgroups v2 is unavailable or the parent is not delegated
+// to us, in which case those limits are not enforced.
+func newCgroup(limits resourceLimits) *cgroup {
+	own, ok := ownCgroupDir()
+	if !ok {
+		return nil
+	}
+	parent := own
+	if own != cgroupRoot {
+		parent = filepath.Dir(own)
+	}
+	enabled, err := os.ReadFile(filepath.Join(parent, "cgroup.subtree_control"))
+	if err != nil {
+		return nil
+	}
+	controllers := strings.Fields(string(enabled))
+	if (limits.memory > 0 && !contains(controllers, "memory")) ||
+		(limits.maxPids > 0 && !contains(controllers, "pids")) {
+		return nil
+	}
+
+	dir, err := os.MkdirTemp(parent, "friday-")
+	if err != nil {
+		return nil
+	}
+	cg := &cgroup{dir: dir}
+	if limits.memory > 0 {
+		if err := cg.write("memory.max", strconv.FormatInt(limits.memory, 10)); err != nil {
+			cg.close()
+			return nil
+		}
+		// Without this the limit only pushes the command into swap.
+		_ = cg.write("memory.swap.max", "0")
+	}
+	if limits.maxPids > 0 {
+		if err := cg.write("pids.max", strconv.Itoa(limits.maxPids)); err != nil {
+			cg.close()
+			return nil
+		}
+	}
+	fd, err := os.Open(dir)
+	if err != nil {
+		cg.close()
+		return nil
+	}
+	cg.fd = fd
+	return cg
+}
+
+// attach makes the command start inside the cgroup.
+func (c *cgroup) attach(attr *syscall.SysProcAttr) {
+	attr.UseCgroupFD = true
+	attr.CgroupFD = int(c.fd.Fd())
+}
+
+// exceeded reports the limit the kernel enforced, or "".
+func (c *cgroup) exceeded() string {
+	if c.eventCount("memory.events", "oom_kill") > 0 {
+		return LimitMemory
+	}
+	if c.eventCount("pids.events", "max") > 0 {
+		return LimitMaxPids
+	}
+	return ""
+}
+
+// close kills any processes left behind and removes the cgroup.
+func (c *cgroup) close() {
+	if c.fd != nil {
+		c.fd.Close()
+	}
+	_ = c.write("cgroup.kill", "1")
+	_ = os.Remove(c.dir)
+}
+
+func (c *cgroup) write(name, value string) error {
+	return os.WriteFile(filepath.Join(c.dir, name), []byte(value), 0o644)
+}
+
+func (c *cgroup) eventCount(file, key string) int64 {
+	f, err := os.Open(filepath.Join(c.dir, file))
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == key {
+			n, _ := strconv.ParseInt(fields[1], 10, 64)
+			return n
+		}
+	}
+	return 0
+}
+
+// ownCgroupDir returns the cgroup v2 directory of the current process.
+func ownCgroupDir() (string, bool) {
+	data, err := os.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		return "", false
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if path, ok := strings.CutPrefix(line, "0::"); ok {
+			dir := filepath.Join(cgroupRoot, path)
+			if _, err := os.Stat(filepath.Join(dir, "cgroup.controllers")); err != nil {
+				return "", false
+			}
+			return dir, true
+		}
+	}
+	return "", false
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
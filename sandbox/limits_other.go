@@ -0,0 +1,17 @@
+//go:build !linux
+
+package sandbox
+
+import "syscall"
+
+// cgroup is unavailable outside Linux; memory and process limits are not
+// enforced there.
+type cgroup struct{}
+
+func newCgroup(limits resourceLimits) *cgroup { return nil }
+
+func (c *cgroup) attach(attr *syscall.SysProcAttr) {}
+
+func (c *cgroup) exceeded() string { return "" }
+
+func (c *cgroup) close() {}
@@ -0,0 +1,168 @@
+package sandbox
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newLimitedExecutor(limits LimitsConfig) *Executor {
+	cfg := DefaultConfig()
+	cfg.Sandbox.Enabled = false
+	cfg.Sandbox.Limits = limits
+	cfg.Permissions.Allow = append(cfg.Permissions.Allow, "yes", "bash")
+	return NewExecutor(cfg)
+}
+
+func TestLimitGuardWrap(t *testing.T) {
+	guard := newLimitGuard(parseLimits(LimitsConfig{CPUTime: "90s", MaxFileSize: "1M"}), nil)
+	defer guard.close()
+
+	wrapped := guard.wrap("go build ./...")
+	if !strings.HasPrefix(wrapped, "ulimit -f 1024 && ulimit -S -t 90 && ulimit -H -t 91 || exit 126\n") {
+		t.Errorf("wrap = %q", wrapped)
+	}
+	if !strings.HasSuffix(wrapped, "\ngo build ./...") {
+		t.Errorf("wrap lost the command: %q", wrapped)
+	}
+
+	none := newLimitGuard(parseLimits(LimitsConfig{}), nil)
+	if got := none.wrap("ls"); got != "ls" {
+		t.Errorf("wrap without limits = %q, want unchanged", got)
+	}
+
+	// Memory and process count are never turned into rlimits, whether or
+	// not a cgroup could be created.
+	cg := newLimitGuard(parseLimits(LimitsConfig{Memory: "1G", MaxPids: 64}), nil)
+	defer cg.close()
+	if got := cg.wrap("ls"); got != "ls" {
+		t.Errorf("wrap with memory and max_pids = %q, want unchanged", got)
+	}
+}
+
+func TestUnenforcedLimits(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Sandbox.Limits = LimitsConfig{CPUTime: "1m", MaxFileSize: "1G"}
+	if got := cfg.UnenforcedLimits(); len(got) != 0 {
+		t.Errorf("rlimit-only limits reported unenforced: %v", got)
+	}
+
+	cfg.Sandbox.Limits = LimitsConfig{Memory: "1G", MaxPids: 64}
+	cfg.Sandbox.Enabled = true
+	cfg.Sandbox.Backend = BackendContainer
+	if got := cfg.UnenforcedLimits(); len(got) != 0 {
+		t.Errorf("container backend reported unenforced limits: %v", got)
+	}
+
+	cfg.Sandbox.Backend = ""
+	got := cfg.UnenforcedLimits()
+	probe := newCgroup(parseLimits(cfg.Sandbox.Limits))
+	if probe == nil {
+		want := []string{LimitMemory, LimitMaxPids}
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Errorf("UnenforcedLimits() = %v, want %v", got, want)
+		}
+	} else {
+		probe.close()
+		if len(got) != 0 {
+			t.Errorf("UnenforcedLimits() = %v with a cgroup available", got)
+		}
+	}
+}
+
+func TestExecutorOutputLimit(t *testing.T) {
+	exec := newLimitedExecutor(LimitsConfig{MaxOutputBytes: "4K"})
+
+	result, err := exec.Run(context.Background(), "yes friday", ExecOptions{})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if result.LimitExceeded != LimitMaxOutput {
+		t.Errorf("LimitExceeded = %q, want %q", result.LimitExceeded, LimitMaxOutput)
+	}
+	if len(result.Stdout) > 8*1024 {
+		t.Errorf("kept %d bytes of output past the limit", len(result.Stdout))
+	}
+}
+
+func TestExecutorOutputLimitKillsChildren(t *testing.T) {
+	exec := newLimitedExecutor(LimitsConfig{MaxOutputBytes: "1K"})
+
+	// bash forks yes here instead of exec'ing it, so killing bash alone
+	// leaves yes holding the output pipe.
+	start := time.Now()
+	result, err := exec.Run(context.Background(), "yes; yes", ExecOptions{Timeout: 30 * time.Second})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Run took %v after hitting the output limit", elapsed)
+	}
+	if result.LimitExceeded != LimitMaxOutput {
+		t.Errorf("LimitExceeded = %q, want %q", result.LimitExceeded, LimitMaxOutput)
+	}
+}
+
+func TestExecutorFileSizeLimit(t *testing.T) {
+	exec := newLimitedExecutor(LimitsConfig{MaxFileSize: "64K"})
+	target := filepath.Join(t.TempDir(), "big")
+
+	result, err := exec.Run(context.Background(), "head -c 1048576 /dev/zero > "+target, ExecOptions{})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if result.LimitExceeded != LimitMaxFileSize {
+		t.Errorf("LimitExceeded = %q (exit %d, stderr %q), want %q",
+			result.LimitExceeded, result.ExitCode, result.Stderr, LimitMaxFileSize)
+	}
+	if info, err := os.Stat(target); err == nil && info.Size() > 64*1024 {
+		t.Errorf("file grew to %d bytes", info.Size())
+	}
+}
+
+func TestExecutorCPUTimeLimit(t *testing.T) {
+	if testing.Short() {
+		t.Skip("burns a second of CPU")
+	}
+	exec := newLimitedExecutor(LimitsConfig{CPUTime: "1s"})
+
+	result, err := exec.Run(context.Background(), "bash -c 'while :; do :; done'", ExecOptions{})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if result.LimitExceeded != LimitCPUTime {
+		t.Errorf("LimitExceeded = %q (exit %d), want %q", result.LimitExceeded, result.ExitCode, LimitCPUTime)
+	}
+}
+
+func TestExecutorWithinLimits(t *testing.T) {
+	exec := newLimitedExecutor(LimitsConfig{Memory: "1G", CPUTime: "10s", MaxPids: 4096, MaxFileSize: "1M", MaxOutputBytes: "1M"})
+
+	result, err := exec.Run(context.Background(), "echo ok", ExecOptions{})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if result.ExitCode != 0 || result.LimitExceeded != "" || strings.TrimSpace(result.Stdout) != "ok" {
+		t.Errorf("Run = exit %d, limit %q, stdout %q, stderr %q",
+			result.ExitCode, result.LimitExceeded, result.Stdout, result.Stderr)
+	}
+}
+
+func TestTaskManagerOutputLimit(t *testing.T) {
+	tm := NewTaskManager(newLimitedExecutor(LimitsConfig{MaxOutputBytes: "4K"}))
+
+	task, err := tm.Start("yes friday", t.TempDir())
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	done, err := tm.Wait(task.ID, 10*time.Second)
+	if err != nil {
+		t.Fatalf("Wait: %v", err)
+	}
+	if done.LimitExceeded != LimitMaxOutput || done.Status != TaskFailed {
+		t.Errorf("task = status %s, limit %q; want failed, %q", done.Status, done.LimitExceeded, LimitMaxOutput)
+	}
+}
@@ -0,0 +1,488 @@
+package sandbox
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	overlayTreeDir  = "tree"
+	overlayManifest = "overlay.json"
+)
+
+// ChangeKind classifies a change in an overlay
+type ChangeKind string
+
+const (
+	ChangeAdded    ChangeKind = "added"
+	ChangeModified ChangeKind = "modified"
+	ChangeDeleted  ChangeKind = "deleted"
+)
+
+// Change is one file that differs between an overlay and its workdir.
+type Change struct {
+	// Path is relative to the workdir, with forward slashes
+	Path string
+	Kind ChangeKind
+	// Conflict is set when the real file also changed since the overlay was
+	// created; Apply skips it unless forced.
+	Conflict bool
+}
+
+// String renders the change as a status line, e.g. "M  src/main.go", with
+// a "!" after the status letter for conflicts.
+func (c Change) String() string {
+	status := map[ChangeKind]string{ChangeAdded: "A", ChangeModified: "M", ChangeDeleted: "D"}[c.Kind]
+	if c.Conflict {
+		status += "!"
+	} else {
+		status += " "
+	}
+	return status + " " + c.Path
+}
+
+// fileStamp identifies a version of a file without reading it
+type fileStamp struct {
+	Size    int64       `json:"size"`
+	ModTime int64       `json:"mtime"`
+	Mode    fs.FileMode `json:"mode"`
+	Link    string      `json:"link,omitempty"`
+}
+
+type overlayMeta struct {
+	Root    string               `json:"root"`
+	Created time.Time            `json:"created"`
+	Files   map[string]fileStamp `json:"files"`
+}
+
+// Overlay is a copy-on-write view of a workdir. Commands and file tools
+// work on a shadow copy of the tree, so nothing touches the real workdir
+// until the changes are reviewed and applied.
+//
+// The overlay state lives in its own directory: the shadow tree plus a
+// manifest of the workdir's files as they were when it was copied, used
+// to tell the agent's changes from edits made to the real tree since.
+type Overlay struct {
+	root string
+	dir  string
+
+	mu   sync.Mutex
+	meta overlayMeta
+}
+
+// OverlayStateDir returns the directory holding the overlay for root under
+// baseDir (typically ~/.friday/overlays).
+func OverlayStateDir(baseDir, root string) string {
+	sum := sha256.Sum256([]byte(filepath.Clean(root)))
+	return filepath.Join(baseDir, hex.EncodeToString(sum[:8]))
+}
+
+// OpenOverlay returns the overlay for root, creating the shadow copy when
+// there is none yet.
+func OpenOverlay(baseDir, root string) (*Overlay, error) {
+	o, err := LoadOverlay(baseDir, root)
+	if err == nil || !os.IsNotExist(err) {
+		return o, err
+	}
+
+	root, err = filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	o = &Overlay{root: root, dir: OverlayStateDir(baseDir, root)}
+	o.meta = overlayMeta{Root: root, Created: time.Now(), Files: make(map[string]fileStamp)}
+	if err := os.MkdirAll(o.dir, 0o700); err != nil {
+		return nil, fmt.Errorf("create overlay: %w", err)
+	}
+	if err := o.copyTree(); err != nil {
+		os.RemoveAll(o.dir)
+		return nil, fmt.Errorf("copy workdir into overlay: %w", err)
+	}
+	if err := o.save(); err != nil {
+		os.RemoveAll(o.dir)
+		return nil, err
+	}
+	return o, nil
+}
+
+// LoadOverlay returns the existing overlay for root. The error satisfies
+// os.IsNotExist when there is none.
+func LoadOverlay(baseDir, root string) (*Overlay, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	o := &Overlay{root: root, dir: OverlayStateDir(baseDir, root)}
+	data, err := os.ReadFile(filepath.Join(o.dir, overlayManifest))
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &o.meta); err != nil {
+		return nil, fmt.Errorf("read overlay manifest: %w", err)
+	}
+	if o.meta.Files == nil {
+		o.meta.Files = make(map[string]fileStamp)
+	}
+	return o, nil
+}
+
+// Root returns the real workdir.
+func (o *Overlay) Root() string { return o.root }
+
+// Tree returns the shadow copy of the workdir.
+func (o *Overlay) Tree() string { return filepath.Join(o.dir, overlayTreeDir) }
+
+// Created returns when the shadow copy was made.
+func (o *Overlay) Created() time.Time { return o.meta.Created }
+
+// Translate maps a path inside the real workdir to its shadow copy. Other
+// paths are returned unchanged.
+func (o *Overlay) Translate(path string) string {
+	if o == nil {
+		return path
+	}
+	rel, err := filepath.Rel(o.root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return path
+	}
+	return filepath.Join(o.Tree(), rel)
+}
+
+// overlayPaths returns path and, when it lies in the overlay's workdir, its
+// shadow copy, so sandbox rules cover both.
+func overlayPaths(path string, o *Overlay) []string {
+	if shadow := o.Translate(path); shadow != path {
+		return []string{path, shadow}
+	}
+	return []string{path}
+}
+
+// Changes lists the files the overlay added, modified or deleted, sorted by
+// path.
+func (o *Overlay) Changes() ([]Change, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.changes()
+}
+
+func (o *Overlay) changes() ([]Change, error) {
+	tree, err := scanTree(o.Tree())
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []Change
+	for rel, stamp := range tree {
+		base, known := o.meta.Files[rel]
+		switch {
+		case !known:
+			changes = append(changes, Change{Path: rel, Kind: ChangeAdded, Conflict: o.realExists(rel)})
+		case stamp != base && !o.sameAsReal(rel):
+			changes = append(changes, Change{Path: rel, Kind: ChangeModified, Conflict: o.realChanged(rel, base)})
+		}
+	}
+	for rel, base := range o.meta.Files {
+		if _, ok := tree[rel]; !ok {
+			changes = append(changes, Change{Path: rel, Kind: ChangeDeleted, Conflict: o.realChanged(rel, base)})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes, nil
+}
+
+// Diff renders the changes under paths (all when empty) as a unified diff
+// against the real workdir.
+func (o *Overlay) Diff(paths ...string) (string, error) {
+	changes, err := o.Changes()
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	for _, c := range filterChanges(changes, paths) {
+		var before, after []byte
+		if c.Kind != ChangeAdded {
+			before, _ = os.ReadFile(filepath.Join(o.root, filepath.FromSlash(c.Path)))
+		}
+		if c.Kind != ChangeDeleted {
+			after, _ = os.ReadFile(filepath.Join(o.Tree(), filepath.FromSlash(c.Path)))
+		}
+		oldName, newName := "a/"+c.Path, "b/"+c.Path
+		if c.Kind == ChangeAdded {
+			oldName = "/dev/null"
+		}
+		if c.Kind == ChangeDeleted {
+			newName = "/dev/null"
+		}
+		b.WriteString(unifiedDiff(oldName, newName, before, after))
+	}
+	return b.String(), nil
+}
+
+// Apply copies the changes under paths (all when empty) into the real
+// workdir and returns those applied. Conflicting changes are skipped unless
+// force is set.
+func (o *Overlay) Apply(force bool, paths ...string) ([]Change, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	changes, err := o.changes()
+	if err != nil {
+		return nil, err
+	}
+	var applied []Change
+	for _, c := range filterChanges(changes, paths) {
+		if c.Conflict && !force {
+			continue
+		}
+		src := filepath.Join(o.Tree(), filepath.FromSlash(c.Path))
+		dst := filepath.Join(o.root, filepath.FromSlash(c.Path))
+		if c.Kind == ChangeDeleted {
+			if err := os.Remove(dst); err != nil && !os.IsNotExist(err) {
+				return applied, fmt.Errorf("apply %s: %w", c.Path, err)
+			}
+			delete(o.meta.Files, c.Path)
+		} else {
+			stamp, err := copyEntry(src, dst)
+			if err != nil {
+				return applied, fmt.Errorf("apply %s: %w", c.Path, err)
+			}
+			o.meta.Files[c.Path] = stamp
+		}
+		applied = append(applied, c)
+	}
+	return applied, o.save()
+}
+
+// Discard drops the changes under paths by restoring them from the real
+// workdir. With no paths the shadow tree is copied afresh.
+func (o *Overlay) Discard(paths ...string) ([]Change, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	changes, err := o.changes()
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		if err := os.RemoveAll(o.Tree()); err != nil {
+			return nil, err
+		}
+		o.meta = overlayMeta{Root: o.root, Created: time.Now(), Files: make(map[string]fileStamp)}
+		if err := o.copyTree(); err != nil {
+			return nil, fmt.Errorf("copy workdir into overlay: %w", err)
+		}
+		return changes, o.save()
+	}
+
+	var discarded []Change
+	for _, c := range filterChanges(changes, paths) {
+		realPath := filepath.Join(o.root, filepath.FromSlash(c.Path))
+		shadow := filepath.Join(o.Tree(), filepath.FromSlash(c.Path))
+		if _, err := os.Lstat(realPath); os.IsNotExist(err) {
+			if err := os.Remove(shadow); err != nil && !os.IsNotExist(err) {
+				return discarded, fmt.Errorf("discard %s: %w", c.Path, err)
+			}
+			delete(o.meta.Files, c.Path)
+		} else {
+			stamp, err := copyEntry(realPath, shadow)
+			if err != nil {
+				return discarded, fmt.Errorf("discard %s: %w", c.Path, err)
+			}
+			o.meta.Files[c.Path] = stamp
+		}
+		discarded = append(discarded, c)
+	}
+	return discarded, o.save()
+}
+
+// copyTree fills the shadow tree from the workdir and records the manifest.
+func (o *Overlay) copyTree() error {
+	tree := o.Tree()
+	overlays := filepath.Dir(o.dir)
+	return filepath.WalkDir(o.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		// Never copy the overlays into themselves when the workdir holds them.
+		if d.IsDir() && path == overlays {
+			return filepath.SkipDir
+		}
+		rel, _ := filepath.Rel(o.root, path)
+		dst := filepath.Join(tree, rel)
+		if d.IsDir() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			return os.MkdirAll(dst, info.Mode().Perm()|0o700)
+		}
+		if !d.Type().IsRegular() && d.Type()&fs.ModeSymlink == 0 {
+			return nil
+		}
+		stamp, err := copyEntry(path, dst)
+		if err != nil {
+			return err
+		}
+		o.meta.Files[filepath.ToSlash(rel)] = stamp
+		return nil
+	})
+}
+
+func (o *Overlay) save() error {
+	data, err := json.Marshal(o.meta)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(o.dir, overlayManifest), data, 0o600)
+}
+
+func (o *Overlay) realExists(rel string) bool {
+	_, err := os.Lstat(filepath.Join(o.root, filepath.FromSlash(rel)))
+	return err == nil
+}
+
+// realChanged reports whether the real file no longer matches base.
+func (o *Overlay) realChanged(rel string, base fileStamp) bool {
+	stamp, err := stampOf(filepath.Join(o.root, filepath.FromSlash(rel)))
+	if err != nil {
+		return true
+	}
+	return stamp != base
+}
+
+// sameAsReal reports whether the shadow file has the same content and mode
+// as the real one, e.g. after a rewrite that changed nothing.
+func (o *Overlay) sameAsReal(rel string) bool {
+	shadow := filepath.Join(o.Tree(), filepath.FromSlash(rel))
+	realPath := filepath.Join(o.root, filepath.FromSlash(rel))
+	a, errA := stampOf(shadow)
+	b, errB := stampOf(realPath)
+	if errA != nil || errB != nil || a.Size != b.Size || a.Mode != b.Mode || a.Link != b.Link {
+		return false
+	}
+	if a.Link != "" {
+		return true
+	}
+	da, errA := os.ReadFile(shadow)
+	db, errB := os.ReadFile(realPath)
+	return errA == nil && errB == nil && bytes.Equal(da, db)
+}
+
+// scanTree stamps every file and symlink under dir, keyed by slash path.
+func scanTree(dir string) (map[string]fileStamp, error) {
+	files := make(map[string]fileStamp)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == dir {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || (!d.Type().IsRegular() && d.Type()&fs.ModeSymlink == 0) {
+			return nil
+		}
+		stamp, err := stampOf(path)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		files[filepath.ToSlash(rel)] = stamp
+		return nil
+	})
+	return files, err
+}
+
+func stampOf(path string) (fileStamp, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return fileStamp{}, err
+	}
+	stamp := fileStamp{Mode: info.Mode()}
+	if info.Mode()&fs.ModeSymlink != 0 {
+		stamp.Link, err = os.Readlink(path)
+		return stamp, err
+	}
+	stamp.Size = info.Size()
+	stamp.ModTime = info.ModTime().UnixNano()
+	return stamp, nil
+}
+
+// copyEntry copies a file or symlink, keeping its mode and modification
+// time so the copy has the same stamp as the source.
+func copyEntry(src, dst string) (fileStamp, error) {
+	info, err := os.Lstat(src)
+	if err != nil {
+		return fileStamp{}, err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return fileStamp{}, err
+	}
+	if err := os.Remove(dst); err != nil && !os.IsNotExist(err) {
+		return fileStamp{}, err
+	}
+
+	if info.Mode()&fs.ModeSymlink != 0 {
+		target, err := os.Readlink(src)
+		if err != nil {
+			return fileStamp{}, err
+		}
+		if err := os.Symlink(target, dst); err != nil {
+			return fileStamp{}, err
+		}
+		return stampOf(dst)
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return fileStamp{}, err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return fileStamp{}, err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return fileStamp{}, err
+	}
+	if err := out.Close(); err != nil {
+		return fileStamp{}, err
+	}
+	if err := os.Chmod(dst, info.Mode()); err != nil {
+		return fileStamp{}, err
+	}
+	if err := os.Chtimes(dst, info.ModTime(), info.ModTime()); err != nil {
+		return fileStamp{}, err
+	}
+	return stampOf(dst)
+}
+
+// filterChanges keeps the changes at or below one of paths; no paths keeps
+// everything.
+func filterChanges(changes []Change, paths []string) []Change {
+	if len(paths) == 0 {
+		return changes
+	}
+	var out []Change
+	for _, c := range changes {
+		for _, p := range paths {
+			p = strings.TrimSuffix(filepath.ToSlash(filepath.Clean(p)), "/")
+			if p == "." || c.Path == p || strings.HasPrefix(c.Path, p+"/") {
+				out = append(out, c)
+				break
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,211 @@
+package sandbox
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/basenana/friday/core/tools"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("os.ReadFile() error: %v", err)
+	}
+	return string(data)
+}
+
+// newOverlayExecutor returns an executor working on a fresh overlay of a
+// workdir holding files.
+func newOverlayExecutor(t *testing.T, files map[string]string) (*Executor, *Overlay, string) {
+	t.Helper()
+	workdir := t.TempDir()
+	writeFiles(t, workdir, files)
+	overlay, err := OpenOverlay(t.TempDir(), workdir)
+	if err != nil {
+		t.Fatalf("OpenOverlay: %v", err)
+	}
+
+	cfg := DefaultConfig()
+	cfg.Sandbox.Enabled = false
+	cfg.Permissions.Allow = append(cfg.Permissions.Allow, "rm")
+	exec := NewExecutor(cfg)
+	exec.SetOverlay(overlay)
+	return exec, overlay, workdir
+}
+
+func TestOverlayKeepsChangesOutOfWorkdir(t *testing.T) {
+	exec, overlay, workdir := newOverlayExecutor(t, map[string]string{
+		"main.go":     "package main\n\nfunc main() {}\n",
+		"docs/old.md": "old\n",
+	})
+
+	result, err := fsWriteHandler(exec, workdir)(context.Background(), &tools.Request{
+		Arguments: map[string]any{
+			"path":    filepath.Join(workdir, "main.go"),
+			"content": "package main\n\nfunc main() { println(1) }\n",
+		},
+	})
+	if err != nil || result.IsError {
+		t.Fatalf("fs_write failed: %v", err)
+	}
+	if _, err := exec.Run(context.Background(), "rm docs/old.md", ExecOptions{Workdir: workdir}); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	writeFiles(t, overlay.Tree(), map[string]string{"new.txt": "hello\n"})
+
+	if got := readFile(t, filepath.Join(workdir, "main.go")); got != "package main\n\nfunc main() {}\n" {
+		t.Errorf("workdir main.go changed: %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(workdir, "docs", "old.md")); err != nil {
+		t.Errorf("workdir docs/old.md removed: %v", err)
+	}
+
+	changes, err := overlay.Changes()
+	if err != nil {
+		t.Fatalf("Changes: %v", err)
+	}
+	want := []Change{
+		{Path: "docs/old.md", Kind: ChangeDeleted},
+		{Path: "main.go", Kind: ChangeModified},
+		{Path: "new.txt", Kind: ChangeAdded},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("Changes = %v, want %v", changes, want)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Errorf("Changes[%d] = %v, want %v", i, changes[i], want[i])
+		}
+	}
+
+	diff, err := overlay.Diff("main.go")
+	if err != nil {
+		t.Fatalf("Diff: %v", err)
+	}
+	wantDiff := "--- a/main.go\n+++ b/main.go\n@@ -1,3 +1,3 @@\n" +
+		" package main\n \n-func main() {}\n+func main() { println(1) }\n"
+	if diff != wantDiff {
+		t.Errorf("Diff =\n%s\nwant\n%s", diff, wantDiff)
+	}
+
+	applied, err := overlay.Apply(false)
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	if len(applied) != 3 {
+		t.Errorf("applied %d changes, want 3", len(applied))
+	}
+	if got := readFile(t, filepath.Join(workdir, "new.txt")); got != "hello\n" {
+		t.Errorf("new.txt = %q after apply", got)
+	}
+	if _, err := os.Stat(filepath.Join(workdir, "docs", "old.md")); !os.IsNotExist(err) {
+		t.Errorf("docs/old.md still exists after apply: %v", err)
+	}
+	if changes, _ := overlay.Changes(); len(changes) != 0 {
+		t.Errorf("Changes after apply = %v, want none", changes)
+	}
+}
+
+func TestOverlayConflict(t *testing.T) {
+	_, overlay, workdir := newOverlayExecutor(t, map[string]string{"a.txt": "base\n"})
+
+	writeFiles(t, overlay.Tree(), map[string]string{"a.txt": "agent\n"})
+	writeFiles(t, workdir, map[string]string{"a.txt": "user edit\n"})
+
+	changes, err := overlay.Changes()
+	if err != nil {
+		t.Fatalf("Changes: %v", err)
+	}
+	if len(changes) != 1 || !changes[0].Conflict {
+		t.Fatalf("Changes = %v, want one conflict", changes)
+	}
+	if changes[0].String() != "M! a.txt" {
+		t.Errorf("String() = %q", changes[0].String())
+	}
+
+	if applied, _ := overlay.Apply(false); len(applied) != 0 {
+		t.Errorf("Apply without force applied %v", applied)
+	}
+	if got := readFile(t, filepath.Join(workdir, "a.txt")); got != "user edit\n" {
+		t.Errorf("conflicting apply overwrote the user edit: %q", got)
+	}
+	if applied, _ := overlay.Apply(true); len(applied) != 1 {
+		t.Errorf("Apply with force applied %v", applied)
+	}
+	if got := readFile(t, filepath.Join(workdir, "a.txt")); got != "agent\n" {
+		t.Errorf("a.txt = %q after forced apply", got)
+	}
+}
+
+func TestOverlayDiscard(t *testing.T) {
+	_, overlay, workdir := newOverlayExecutor(t, map[string]string{"a.txt": "a\n", "b.txt": "b\n"})
+	writeFiles(t, overlay.Tree(), map[string]string{"a.txt": "changed\n", "b.txt": "changed\n", "c.txt": "c\n"})
+
+	if discarded, err := overlay.Discard("a.txt", "c.txt"); err != nil || len(discarded) != 2 {
+		t.Fatalf("Discard(a.txt, c.txt) = %v, %v", discarded, err)
+	}
+	changes, _ := overlay.Changes()
+	if len(changes) != 1 || changes[0].Path != "b.txt" {
+		t.Fatalf("Changes after partial discard = %v, want b.txt only", changes)
+	}
+
+	if _, err := overlay.Discard(); err != nil {
+		t.Fatalf("Discard: %v", err)
+	}
+	if changes, _ := overlay.Changes(); len(changes) != 0 {
+		t.Errorf("Changes after discard = %v, want none", changes)
+	}
+	if got := readFile(t, filepath.Join(overlay.Tree(), "b.txt")); got != "b\n" {
+		t.Errorf("shadow b.txt = %q after discard", got)
+	}
+	if got := readFile(t, filepath.Join(workdir, "b.txt")); got != "b\n" {
+		t.Errorf("workdir b.txt = %q after discard", got)
+	}
+}
+
+func TestLoadOverlayMissing(t *testing.T) {
+	if _, err := LoadOverlay(t.TempDir(), t.TempDir()); !os.IsNotExist(err) {
+		t.Errorf("LoadOverlay error = %v, want not exist", err)
+	}
+}
+
+func TestUnifiedDiffNoNewline(t *testing.T) {
+	diff := unifiedDiff("a/f", "b/f", []byte("one\ntwo"), []byte("one\ntwo\n"))
+	if !strings.Contains(diff, "-two\n\\ No newline at end of file\n+two\n") {
+		t.Errorf("diff =\n%s", diff)
+	}
+}
+
+func TestSupportsOverlay(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Sandbox.Enabled = false
+	if NewExecutor(cfg).SupportsOverlay() {
+		t.Error("disabled sandbox supports overlay")
+	}
+	if (&Executor{sandbox: NewSeatbelt(cfg)}).SupportsOverlay() {
+		t.Error("seatbelt supports overlay")
+	}
+	for _, sb := range []Sandbox{NewBwrap(cfg), NewContainer(cfg)} {
+		if !(&Executor{sandbox: sb}).SupportsOverlay() {
+			t.Errorf("%s does not support overlay", sb.Name())
+		}
+	}
+}
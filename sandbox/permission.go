@@ -2,6 +2,8 @@ package sandbox
 
 import (
 	"errors"
+	"strings"
+	"sync"
 )
 
 // Decision represents the result of a permission check
@@ -12,13 +14,49 @@ const (
 	Allow Decision = iota
 	// Deny means the command is blocked
 	Deny
+	// Ask means the command needs approval before it may run
+	Ask
 )
 
 func (d Decision) String() string {
-	if d == Allow {
+	switch d {
+	case Allow:
 		return "allow"
+	case Ask:
+		return "ask"
+	default:
+		return "deny"
 	}
-	return "deny"
+}
+
+// ParseDecision parses "allow", "deny" or "ask". Anything else yields def.
+func ParseDecision(s string, def Decision) Decision {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "allow":
+		return Allow
+	case "deny":
+		return Deny
+	case "ask":
+		return Ask
+	default:
+		return def
+	}
+}
+
+// rulesMu guards the permission rule lists of every Config, since approved
+// "always allow" rules are appended while other runs are checking commands.
+var rulesMu sync.RWMutex
+
+// AllowCommand appends pattern to the allow list unless it is already there.
+func (c *Config) AllowCommand(pattern string) {
+	rulesMu.Lock()
+	defer rulesMu.Unlock()
+	for _, p := range c.Permissions.Allow {
+		if p == pattern {
+			return
+		}
+	}
+	c.Permissions.Allow = append(c.Permissions.Allow, pattern)
 }
 
 // Permission handles permission checking for commands
@@ -45,14 +83,20 @@ func (p *Permission) Check(cmdStr string) (Decision, error) {
 		return Allow, nil
 	}
 
+	rulesMu.RLock()
+	defer rulesMu.RUnlock()
+
+	result := Allow
 	for _, cmd := range commands {
-		decision := p.checkCommand(cmd)
-		if decision == Deny {
+		switch p.checkCommand(cmd) {
+		case Deny:
 			return Deny, nil
+		case Ask:
+			result = Ask
 		}
 	}
 
-	return Allow, nil
+	return result, nil
 }
 
 // checkCommand checks a single command against the permission rules
@@ -64,6 +108,13 @@ func (p *Permission) checkCommand(cmd Command) Decision {
 		}
 	}
 
+	// Ask rules override allow rules
+	for _, pattern := range p.config.Permissions.Ask {
+		if cmd.MatchPattern(pattern) {
+			return Ask
+		}
+	}
+
 	// Check allow rules
 	for _, pattern := range p.config.Permissions.Allow {
 		if cmd.MatchPattern(pattern) {
@@ -71,11 +122,29 @@ func (p *Permission) checkCommand(cmd Command) Decision {
 		}
 	}
 
-	// If not in allow list, deny by default
+	// Not in any list: deny unless the config asks instead
+	return p.unlisted()
+}
+
+func (p *Permission) matchesAny(cmd Command, patterns []string) bool {
+	for _, pattern := range patterns {
+		if cmd.MatchPattern(pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// unlisted is the decision for commands matching no rule.
+func (p *Permission) unlisted() Decision {
+	if ParseDecision(p.config.Permissions.Default, Deny) == Ask {
+		return Ask
+	}
 	return Deny
 }
 
-// CheckWithReason checks if a command is allowed and returns the reason
+// CheckWithReason checks if a command is allowed and returns the reason.
+// Deny wins over Ask, which wins over Allow, across all subcommands.
 func (p *Permission) CheckWithReason(cmdStr string) (Decision, string, error) {
 	commands, err := ParseCommands(cmdStr)
 	if err != nil {
@@ -86,6 +155,11 @@ func (p *Permission) CheckWithReason(cmdStr string) (Decision, string, error) {
 		return Allow, "empty command", nil
 	}
 
+	rulesMu.RLock()
+	defer rulesMu.RUnlock()
+
+	askReason := ""
+	var allowRules []string
 	for _, cmd := range commands {
 		// Check deny rules first
 		for _, pattern := range p.config.Permissions.Deny {
@@ -94,21 +168,76 @@ func (p *Permission) CheckWithReason(cmdStr string) (Decision, string, error) {
 			}
 		}
 
+		// Check ask rules
+		asked := false
+		for _, pattern := range p.config.Permissions.Ask {
+			if cmd.MatchPattern(pattern) {
+				asked = true
+				if askReason == "" {
+					askReason = "command '" + cmd.Name + "' matched ask rule: " + pattern
+				}
+				break
+			}
+		}
+		if asked {
+			continue
+		}
+
 		// Check allow rules
 		allowed := false
 		for _, pattern := range p.config.Permissions.Allow {
 			if cmd.MatchPattern(pattern) {
 				allowed = true
+				allowRules = append(allowRules, "'"+cmd.Name+"' matched allow rule: "+pattern)
 				break
 			}
 		}
 
 		if !allowed {
-			return Deny, "command '" + cmd.Name + "' is not in allow list", nil
+			if p.unlisted() == Deny {
+				return Deny, "command '" + cmd.Name + "' is not in allow list", nil
+			}
+			if askReason == "" {
+				askReason = "command '" + cmd.Name + "' is not in allow list"
+			}
 		}
 	}
 
-	return Allow, "all commands allowed", nil
+	if askReason != "" {
+		return Ask, askReason, nil
+	}
+	return Allow, "all commands allowed (" + strings.Join(allowRules, "; ") + ")", nil
+}
+
+// ApprovalPatterns returns the allow patterns that would let the unlisted
+// subcommands of cmdStr run without asking again: "<name> <subcmd>" when the
+// command has a subcommand, otherwise "<name>". Subcommands matching an ask
+// rule are not included since ask rules take precedence over allow rules.
+func (p *Permission) ApprovalPatterns(cmdStr string) []string {
+	commands, err := ParseCommands(cmdStr)
+	if err != nil {
+		return nil
+	}
+
+	rulesMu.RLock()
+	defer rulesMu.RUnlock()
+
+	var patterns []string
+	seen := make(map[string]bool)
+	for _, cmd := range commands {
+		if p.checkCommand(cmd) != Ask || p.matchesAny(cmd, p.config.Permissions.Ask) {
+			continue
+		}
+		pattern := cmd.Name
+		if cmd.Subcmd != "" {
+			pattern += " " + cmd.Subcmd
+		}
+		if !seen[pattern] {
+			seen[pattern] = true
+			patterns = append(patterns, pattern)
+		}
+	}
+	return patterns
 }
 
 // ErrPermissionDenied is returned when a command is denied
@@ -1,6 +1,7 @@
 package sandbox
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -325,3 +326,66 @@ func TestWildcardMatching(t *testing.T) {
 		}
 	}
 }
+
+func TestPermissionAsk(t *testing.T) {
+	cfg := &Config{
+		Permissions: PermissionsConfig{
+			Allow:   []string{"git", "ls"},
+			Deny:    []string{"sudo"},
+			Ask:     []string{"git push"},
+			Default: "ask",
+		},
+	}
+	perm := NewPermission(cfg)
+
+	tests := []struct {
+		cmd  string
+		want Decision
+	}{
+		{"git status", Allow},
+		{"git push origin main", Ask},   // ask rule overrides allow
+		{"docker ps", Ask},              // unlisted, default ask
+		{"ls && docker ps", Ask},        // any ask wins over allow
+		{"docker ps && sudo ls", Deny},  // deny wins over ask
+		{"git push; sudo rm -rf", Deny}, // deny wins over ask rule
+	}
+	for _, tt := range tests {
+		got, reason, err := perm.CheckWithReason(tt.cmd)
+		if err != nil {
+			t.Errorf("CheckWithReason(%q) error = %v", tt.cmd, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("CheckWithReason(%q) = %v (%s), want %v", tt.cmd, got, reason, tt.want)
+		}
+		if check, _ := perm.Check(tt.cmd); check != tt.want {
+			t.Errorf("Check(%q) = %v, want %v", tt.cmd, check, tt.want)
+		}
+	}
+}
+
+func TestPermissionApprovalPatterns(t *testing.T) {
+	cfg := &Config{
+		Permissions: PermissionsConfig{
+			Allow:   []string{"ls"},
+			Ask:     []string{"git push"},
+			Default: "ask",
+		},
+	}
+	perm := NewPermission(cfg)
+
+	got := perm.ApprovalPatterns("ls && docker ps -a && make && git push && docker ps")
+	want := []string{"docker ps", "make"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("ApprovalPatterns() = %v, want %v", got, want)
+	}
+
+	cfg.AllowCommand("docker ps")
+	cfg.AllowCommand("docker ps")
+	if decision, _ := perm.Check("docker ps -a"); decision != Allow {
+		t.Errorf("after AllowCommand, docker ps = %v, want Allow", decision)
+	}
+	if n := len(cfg.Permissions.Allow); n != 2 {
+		t.Errorf("AllowCommand should not duplicate rules, got %v", cfg.Permissions.Allow)
+	}
+}
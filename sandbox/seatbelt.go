@@ -4,6 +4,7 @@ package sandbox
 
 import (
 	"fmt"
+	"net"
 	"os"
 	"os/exec"
 	"runtime"
@@ -29,7 +30,7 @@ func (s *Seatbelt) WrapCommand(cmd string, opts ExecOptions) (string, func(), er
 	}
 
 	// Generate the sandbox profile
-	profile := s.generateProfile(opts.Workdir)
+	profile := s.generateProfile(opts.Workdir, opts.Proxy, opts.Overlay)
 
 	// Write profile to temp file
 	tmpFile, err := os.CreateTemp("", "friday-sandbox-*.sb")
@@ -52,6 +53,13 @@ func (s *Seatbelt) WrapCommand(cmd string, opts ExecOptions) (string, func(), er
 		quotedCmd = "'" + strings.ReplaceAll(cmd, "'", "'\\''") + "'"
 	}
 	wrappedCmd := fmt.Sprintf("sandbox-exec -f %s -- bash -c %s", profilePath, quotedCmd)
+	if s.proxied(opts.Proxy) {
+		env := make([]string, 0, 8)
+		for _, kv := range opts.Proxy.Env() {
+			env = append(env, shellQuote(kv))
+		}
+		wrappedCmd = "env " + strings.Join(env, " ") + " " + wrappedCmd
+	}
 
 	cleanup := func() {
 		os.Remove(profilePath)
@@ -75,7 +83,7 @@ func (s *Seatbelt) Name() string {
 }
 
 // generateProfile generates a Seatbelt profile
-func (s *Seatbelt) generateProfile(workdir string) string {
+func (s *Seatbelt) generateProfile(workdir string, proxy *NetworkProxy, overlay *Overlay) string {
 	var sb strings.Builder
 
 	sb.WriteString("(version 1)\n")
@@ -83,35 +91,46 @@ func (s *Seatbelt) generateProfile(workdir string) string {
 
 	// Deny reading sensitive paths
 	for _, path := range s.config.Sandbox.Filesystem.Deny {
-		expanded := expandPath(path, workdir)
-		sb.WriteString(fmt.Sprintf("(deny file-read* (subpath %q))\n", expanded))
+		for _, expanded := range overlayPaths(expandPath(path, workdir), overlay) {
+			sb.WriteString(fmt.Sprintf("(deny file-read* (subpath %q))\n", expanded))
+		}
 	}
 
 	// Allow writing to specified paths
 	for _, path := range s.config.Sandbox.Filesystem.Write {
-		expanded := expandPath(path, workdir)
-		sb.WriteString(fmt.Sprintf("(allow file-write* (subpath %q))\n", expanded))
+		for _, expanded := range overlayPaths(expandPath(path, workdir), overlay) {
+			sb.WriteString(fmt.Sprintf("(allow file-write* (subpath %q))\n", expanded))
+		}
 	}
 
 	// Deny writing to protected paths (even if in write list)
 	for _, path := range s.config.Sandbox.Filesystem.Protected {
-		expanded := expandPath(path, workdir)
-		sb.WriteString(fmt.Sprintf("(deny file-write* (subpath %q))\n", expanded))
+		for _, expanded := range overlayPaths(expandPath(path, workdir), overlay) {
+			sb.WriteString(fmt.Sprintf("(deny file-write* (subpath %q))\n", expanded))
+		}
 	}
 
 	// Mount readonly paths as read-only
 	for _, path := range s.config.Sandbox.Filesystem.ReadOnly {
-		expanded := expandPath(path, workdir)
-		sb.WriteString(fmt.Sprintf("(allow file-read* (subpath %q))\n", expanded))
-		sb.WriteString(fmt.Sprintf("(deny file-write* (subpath %q))\n", expanded))
+		for _, expanded := range overlayPaths(expandPath(path, workdir), overlay) {
+			sb.WriteString(fmt.Sprintf("(allow file-read* (subpath %q))\n", expanded))
+			sb.WriteString(fmt.Sprintf("(deny file-write* (subpath %q))\n", expanded))
+		}
 	}
 
-	// Network restrictions - deny by default for security
-	sb.WriteString("(deny network*)\n")
+	// Network isolation - only the filtering proxy is reachable
 	if s.config.Sandbox.Network.Isolation {
-		// Allow network outbound if explicitly enabled
-		sb.WriteString("(allow network-outbound)\n")
+		sb.WriteString("(deny network*)\n")
+		if s.proxied(proxy) {
+			_, port, _ := net.SplitHostPort(proxy.Addr())
+			sb.WriteString(fmt.Sprintf("(allow network-outbound (remote ip \"localhost:%s\"))\n", port))
+		}
 	}
 
 	return sb.String()
 }
+
+// proxied reports whether isolated commands are routed through proxy
+func (s *Seatbelt) proxied(proxy *NetworkProxy) bool {
+	return proxy != nil && s.config.Sandbox.Network.Isolation && proxy.Addr() != ""
+}
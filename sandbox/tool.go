@@ -18,7 +18,7 @@ const (
 
 IMPORTANT: Always use this tool for bash commands, even if you think you could answer directly.
 Commands are executed with safety restrictions:
-- Commands must be in the allow list
+- Commands must be in the allow list; others may need user approval or be denied
 - Dangerous commands are blocked
 - File system and network access may be restricted
 - Commands have a timeout
@@ -122,6 +122,11 @@ func bashToolHandler(exec *Executor) tools.ToolHandlerFunc {
 			return tools.NewToolResultError(fmt.Sprintf("Command timed out.\n%s", output.String())), nil
 		}
 
+		if result.LimitExceeded != "" {
+			return tools.NewToolResultError(fmt.Sprintf("Command killed: exceeded the %s.\n%s",
+				describeLimit(exec.config.Sandbox.Limits, result.LimitExceeded), output.String())), nil
+		}
+
 		if result.ExitCode != 0 {
 			return tools.NewToolResultError(fmt.Sprintf("Command exited with code %d.\n%s", result.ExitCode, output.String())), nil
 		}
@@ -5,6 +5,8 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+
+	"mvdan.cc/sh/v3/syntax"
 )
 
 // expandPath expands ~ and relative paths
@@ -26,6 +28,15 @@ func expandPath(path string, workdir string) string {
 	return path
 }
 
+// shellQuote quotes s as a single bash word
+func shellQuote(s string) string {
+	quoted, err := syntax.Quote(s, syntax.LangBash)
+	if err != nil {
+		return "'" + strings.ReplaceAll(s, "'", "'\\''") + "'"
+	}
+	return quoted
+}
+
 // parseMemoryLimit parses memory limit string like "2G", "512M", "1024K" (case insensitive)
 func parseMemoryLimit(limit string) int64 {
 	if limit == "" {
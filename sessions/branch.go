@@ -0,0 +1,120 @@
+package sessions
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/basenana/friday/core/contextmgr"
+	coresession "github.com/basenana/friday/core/session"
+	"github.com/basenana/friday/core/types"
+)
+
+// SessionMemoryDeleter is implemented by stores that can drop the session
+// memory of a session, so it is rebuilt from the history.
+type SessionMemoryDeleter interface {
+	DeleteSessionMemory(sessionID string) error
+}
+
+// Fork creates a session that starts with the first at messages of
+// sessionID and returns its ID. A negative at counts from the end of the
+// history. A tool call whose results fall beyond at is left out of the fork.
+// The parent is left untouched and the current session is not changed.
+func (m *Manager) Fork(sessionID string, at int) (string, error) {
+	importer, ok := m.store.(Importer)
+	if !ok {
+		return "", fmt.Errorf("session store %T cannot fork sessions", m.store)
+	}
+	parent, err := m.store.GetMeta(sessionID)
+	if err != nil {
+		return "", err
+	}
+	history, err := m.store.LoadMessages(sessionID)
+	if err != nil {
+		return "", err
+	}
+	if at < 0 {
+		at += len(history)
+	}
+	if at < 0 || at > len(history) {
+		return "", fmt.Errorf("branch point %d out of range: session %s has %d messages", at, sessionID, len(history))
+	}
+	kept := coresession.TruncateHistory(history, at)
+
+	now := time.Now()
+	meta := SessionMeta{
+		ID:           types.NewID(),
+		Alias:        m.generateAlias(),
+		CreatedAt:    now,
+		UpdatedAt:    now,
+		SystemPrompt: parent.SystemPrompt,
+		ParentID:     sessionID,
+		BranchPoint:  len(kept),
+	}
+	if err := importer.ImportSession(meta, kept, nil); err != nil {
+		return "", err
+	}
+	return meta.ID, nil
+}
+
+// Rewind drops the last turns user turns of sessionID, each with the replies
+// and tool calls that followed it, and returns how many messages are left.
+// The dropped messages are kept in the store's backup of replaced histories.
+// Session memory that covers dropped messages is deleted so it is rebuilt.
+func (m *Manager) Rewind(sessionID string, turns int) (int, error) {
+	if turns < 1 {
+		return 0, fmt.Errorf("turns must be at least 1, got %d", turns)
+	}
+	writer, ok := m.store.(coresession.MessageWriter)
+	if !ok {
+		return 0, fmt.Errorf("session store %T cannot rewrite sessions", m.store)
+	}
+	history, err := m.store.LoadMessages(sessionID)
+	if err != nil {
+		return 0, err
+	}
+
+	cut, found := len(history), 0
+	for cut > 0 && found < turns {
+		cut--
+		if history[cut].Role == types.RoleUser {
+			found++
+		}
+	}
+	if found < turns {
+		return 0, fmt.Errorf("session %s has only %d user turns", sessionID, found)
+	}
+	kept := coresession.TruncateHistory(history, cut)
+	if err := writer.ReplaceMessages(sessionID, kept...); err != nil {
+		return 0, err
+	}
+
+	if err := m.dropStaleSessionMemory(sessionID, kept); err != nil {
+		return len(kept), err
+	}
+	return len(kept), nil
+}
+
+// dropStaleSessionMemory deletes the session memory of sessionID when it was
+// synced past the last message of history.
+func (m *Manager) dropStaleSessionMemory(sessionID string, history []types.Message) error {
+	memories, ok := m.store.(contextmgr.SessionMemoryStore)
+	if !ok {
+		return nil
+	}
+	deleter, ok := m.store.(SessionMemoryDeleter)
+	if !ok {
+		return nil
+	}
+	record, err := memories.ReadSessionMemory(sessionID)
+	if err != nil || record == nil {
+		return err
+	}
+	var last time.Time
+	if len(history) > 0 {
+		last = history[len(history)-1].Time
+	}
+	if !record.LastSyncAt.After(last) {
+		return nil
+	}
+	return deleter.DeleteSessionMemory(sessionID)
+}
@@ -0,0 +1,163 @@
+// Package export writes sessions in formats meant to be shared outside of
+// friday: Markdown and HTML transcripts for people, the Chat Completions
+// messages array for replaying a conversation against other tools, and a
+// JSONL form that keeps everything friday stores so it can be imported again.
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/basenana/friday/core/contextmgr"
+	"github.com/basenana/friday/core/types"
+	"github.com/basenana/friday/sessions"
+)
+
+const (
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+	FormatJSONL    = "jsonl"
+	FormatOpenAI   = "openai-messages"
+)
+
+// Formats lists the formats Write accepts.
+var Formats = []string{FormatMarkdown, FormatHTML, FormatJSONL, FormatOpenAI}
+
+// jsonlVersion is written to the header of JSONL exports; ReadJSONL rejects
+// newer versions.
+const jsonlVersion = 1
+
+// Transcript is a session with everything an export carries.
+type Transcript struct {
+	Meta     sessions.SessionMeta
+	Messages []types.Message
+	Memory   *contextmgr.SessionMemoryRecord
+}
+
+// Load reads the session sessionID from store.
+func Load(store sessions.Store, sessionID string) (*Transcript, error) {
+	meta, err := store.GetMeta(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	msgs, err := store.LoadMessages(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	t := &Transcript{Meta: *meta, Messages: msgs}
+	if memories, ok := store.(contextmgr.SessionMemoryStore); ok {
+		if t.Memory, err = memories.ReadSessionMemory(sessionID); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// Write renders t to w in format.
+func Write(w io.Writer, t *Transcript, format string) error {
+	switch format {
+	case FormatMarkdown:
+		return writeMarkdown(w, t)
+	case FormatHTML:
+		return writeHTML(w, t)
+	case FormatJSONL:
+		return writeJSONL(w, t)
+	case FormatOpenAI:
+		return writeOpenAI(w, t)
+	default:
+		return fmt.Errorf("unknown export format %q: want markdown, html, jsonl or openai-messages", format)
+	}
+}
+
+// jsonlRecord is one line of a JSONL export. The first line is the session
+// header, each following line one message.
+type jsonlRecord struct {
+	Type    string                          `json:"type"`
+	Version int                             `json:"version,omitempty"`
+	Session *sessions.SessionMeta           `json:"session,omitempty"`
+	Memory  *contextmgr.SessionMemoryRecord `json:"memory,omitempty"`
+	Message *types.Message                  `json:"message,omitempty"`
+}
+
+const (
+	recordSession = "session"
+	recordMessage = "message"
+)
+
+func writeJSONL(w io.Writer, t *Transcript) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	meta := t.Meta
+	if err := enc.Encode(jsonlRecord{Type: recordSession, Version: jsonlVersion, Session: &meta, Memory: t.Memory}); err != nil {
+		return err
+	}
+	for i := range t.Messages {
+		if err := enc.Encode(jsonlRecord{Type: recordMessage, Message: &t.Messages[i]}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadJSONL reads a session written in the JSONL format.
+func ReadJSONL(r io.Reader) (*Transcript, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+
+	var t *Transcript
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var rec jsonlRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		switch {
+		case rec.Type == recordSession && t == nil:
+			if rec.Version > jsonlVersion {
+				return nil, fmt.Errorf("line %d: export version %d is newer than this friday supports", line, rec.Version)
+			}
+			if rec.Session == nil || rec.Session.ID == "" {
+				return nil, fmt.Errorf("line %d: session header without an id", line)
+			}
+			if !validID(rec.Session.ID) {
+				return nil, fmt.Errorf("line %d: invalid session id %q", line, rec.Session.ID)
+			}
+			t = &Transcript{Meta: *rec.Session, Memory: rec.Memory}
+		case t == nil:
+			return nil, fmt.Errorf("line %d: expected the session header first", line)
+		case rec.Type == recordMessage && rec.Message != nil:
+			t.Messages = append(t.Messages, *rec.Message)
+		default:
+			return nil, fmt.Errorf("line %d: unexpected %q record", line, rec.Type)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if t == nil {
+		return nil, errors.New("empty export")
+	}
+	t.Meta.MessageCount = len(t.Messages)
+	return t, nil
+}
+
+// validID reports whether id can name a session: exports are shared, and the
+// file store uses the ID as a directory name.
+func validID(id string) bool {
+	if len(id) > 128 {
+		return false
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,97 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/basenana/friday/core/types"
+)
+
+// openAIMessage is a message of the Chat Completions API. Content is either
+// a string or a list of content parts.
+type openAIMessage struct {
+	Role             string           `json:"role"`
+	Content          any              `json:"content,omitempty"`
+	ReasoningContent string           `json:"reasoning_content,omitempty"`
+	ToolCalls        []openAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID       string           `json:"tool_call_id,omitempty"`
+}
+
+type openAIToolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+type openAIContentPart struct {
+	Type     string `json:"type"`
+	Text     string `json:"text,omitempty"`
+	ImageURL *struct {
+		URL string `json:"url"`
+	} `json:"image_url,omitempty"`
+}
+
+// writeOpenAI writes the history as the messages array of a Chat Completions
+// request, led by the session's system prompt. Reasoning goes in the
+// reasoning_content field compatible providers accept.
+func writeOpenAI(w io.Writer, t *Transcript) error {
+	var out []openAIMessage
+	if t.Meta.SystemPrompt != "" {
+		out = append(out, openAIMessage{Role: string(types.RoleSystem), Content: t.Meta.SystemPrompt})
+	}
+	for _, msg := range t.Messages {
+		switch msg.Role {
+		case types.RoleAgent:
+			continue
+		case types.RoleTool:
+			if msg.ToolResult == nil {
+				continue
+			}
+			out = append(out, openAIMessage{Role: string(types.RoleTool), Content: msg.ToolResult.Content, ToolCallID: msg.ToolResult.CallID})
+			continue
+		}
+
+		om := openAIMessage{Role: string(msg.Role), ReasoningContent: msg.Reasoning}
+		if msg.Image != nil {
+			om.Content = imageParts(msg)
+		} else if msg.Content != "" || len(msg.ToolCalls) == 0 {
+			om.Content = msg.Content
+		}
+		for _, tc := range msg.ToolCalls {
+			call := openAIToolCall{ID: tc.ID, Type: "function"}
+			call.Function.Name = tc.Name
+			call.Function.Arguments = tc.Arguments
+			om.ToolCalls = append(om.ToolCalls, call)
+		}
+		out = append(out, om)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+func imageParts(msg types.Message) []openAIContentPart {
+	var parts []openAIContentPart
+	if msg.Content != "" {
+		parts = append(parts, openAIContentPart{Type: "text", Text: msg.Content})
+	}
+	image := openAIContentPart{Type: "image_url"}
+	image.ImageURL = &struct {
+		URL string `json:"url"`
+	}{URL: imageURL(msg.Image)}
+	return append(parts, image)
+}
+
+// imageURL returns the URL of an image, as a data URI for inline images.
+func imageURL(img *types.ImageContent) string {
+	if img.Type == types.ImageTypeBase64 {
+		return fmt.Sprintf("data:%s;base64,%s", img.MediaType, img.Data)
+	}
+	return img.URL
+}
@@ -0,0 +1,116 @@
+package export
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/basenana/friday/core/contextmgr"
+	"github.com/basenana/friday/core/types"
+)
+
+// Redacted replaces the secrets Redact finds.
+const Redacted = "[REDACTED]"
+
+// RedactOptions selects what Redact removes.
+type RedactOptions struct {
+	// ToolOutputs drops the content of tool results, which often holds file
+	// contents and command output.
+	ToolOutputs bool
+	// Secrets replaces API keys, tokens, passwords and private keys.
+	Secrets bool
+}
+
+// secretPatterns match secrets in text. Patterns with groups keep the text
+// they capture around the secret, such as the key in "password: hunter2".
+var secretPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`),
+	regexp.MustCompile(`\bsk-(?:ant-|proj-)?[A-Za-z0-9_-]{20,}`),
+	regexp.MustCompile(`\b(?:ghp|gho|ghu|ghs|ghr)_[A-Za-z0-9]{36,}\b`),
+	regexp.MustCompile(`\bgithub_pat_[A-Za-z0-9_]{40,}\b`),
+	regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}`),
+	regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`),
+	regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`),
+	regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`),
+	regexp.MustCompile(`(?i)(\bbearer\s+)[A-Za-z0-9._~+/-]{16,}=*`),
+	regexp.MustCompile(`(?i)(\b[a-z0-9_]*(?:api[_-]?key|secret|token|passw(?:or)?d)["']?\s*[:=]\s*["']?)[^\s"',;]{6,}`),
+	regexp.MustCompile(`(?i)(\b[a-z][a-z0-9+.-]*://[^\s:/@]+:)[^\s@/]+(@)`),
+}
+
+// RedactSecrets replaces the secrets in text.
+func RedactSecrets(text string) string {
+	for _, re := range secretPatterns {
+		if re.NumSubexp() > 0 {
+			text = re.ReplaceAllString(text, "${1}"+Redacted+"${2}")
+		} else {
+			text = re.ReplaceAllString(text, Redacted)
+		}
+	}
+	return text
+}
+
+// Redact removes what opts selects from t. t is modified in place; its
+// messages are copied first so the history it was loaded from is untouched.
+func Redact(t *Transcript, opts RedactOptions) {
+	if !opts.ToolOutputs && !opts.Secrets {
+		return
+	}
+	msgs := make([]types.Message, len(t.Messages))
+	copy(msgs, t.Messages)
+	t.Messages = msgs
+
+	for i := range t.Messages {
+		msg := &t.Messages[i]
+		if msg.ToolResult != nil {
+			result := *msg.ToolResult
+			if opts.ToolOutputs {
+				result.Content = fmt.Sprintf("[tool output redacted, %d bytes]", len(result.Content))
+			}
+			msg.ToolResult = &result
+		}
+		if !opts.Secrets {
+			continue
+		}
+		msg.Content = RedactSecrets(msg.Content)
+		msg.Reasoning = RedactSecrets(msg.Reasoning)
+		if len(msg.ToolCalls) > 0 {
+			calls := make([]types.ToolCall, len(msg.ToolCalls))
+			for j, tc := range msg.ToolCalls {
+				tc.Arguments = RedactSecrets(tc.Arguments)
+				calls[j] = tc
+			}
+			msg.ToolCalls = calls
+		}
+		if msg.ToolResult != nil {
+			msg.ToolResult.Content = RedactSecrets(msg.ToolResult.Content)
+		}
+	}
+
+	if opts.Secrets {
+		t.Meta.SystemPrompt = RedactSecrets(t.Meta.SystemPrompt)
+		t.Meta.Summary = RedactSecrets(t.Meta.Summary)
+		if t.Memory != nil {
+			t.Memory = redactMemory(*t.Memory)
+		}
+	}
+}
+
+func redactMemory(r contextmgr.SessionMemoryRecord) *contextmgr.SessionMemoryRecord {
+	redactAll := func(lines []string) []string {
+		if lines == nil {
+			return nil
+		}
+		out := make([]string, len(lines))
+		for i, line := range lines {
+			out[i] = RedactSecrets(line)
+		}
+		return out
+	}
+	r.TaskObjective = RedactSecrets(r.TaskObjective)
+	r.CurrentStatus = RedactSecrets(r.CurrentStatus)
+	r.KeyDecisions = redactAll(r.KeyDecisions)
+	r.RecentWork = redactAll(r.RecentWork)
+	r.PendingItems = redactAll(r.PendingItems)
+	r.FileReferences = redactAll(r.FileReferences)
+	r.ImportantCtx = RedactSecrets(r.ImportantCtx)
+	return &r
+}
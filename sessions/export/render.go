@@ -0,0 +1,260 @@
+package export
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/basenana/friday/core/contextmgr"
+	"github.com/basenana/friday/core/types"
+)
+
+const timeLayout = "2006-01-02 15:04:05"
+
+// title names a transcript by its alias, falling back to the session ID.
+func title(t *Transcript) string {
+	if t.Meta.Alias != "" {
+		return fmt.Sprintf("Session %s (%s)", t.Meta.Alias, t.Meta.ID)
+	}
+	return "Session " + t.Meta.ID
+}
+
+// toolNames maps tool call IDs to the names of the tools called.
+func toolNames(msgs []types.Message) map[string]string {
+	names := make(map[string]string)
+	for _, msg := range msgs {
+		for _, tc := range msg.ToolCalls {
+			names[tc.ID] = tc.Name
+		}
+	}
+	return names
+}
+
+func formatTime(at time.Time) string {
+	if at.IsZero() {
+		return ""
+	}
+	return at.Local().Format(timeLayout)
+}
+
+// fence returns a code fence longer than any run of backticks in text.
+func fence(text string) string {
+	longest, run := 0, 0
+	for _, r := range text {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return strings.Repeat("`", max(3, longest+1))
+}
+
+func writeFenced(b *strings.Builder, lang, text string) {
+	f := fence(text)
+	fmt.Fprintf(b, "%s%s\n%s\n%s\n\n", f, lang, strings.TrimRight(text, "\n"), f)
+}
+
+func writeMarkdown(w io.Writer, t *Transcript) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", title(t))
+	fmt.Fprintf(&b, "- Created: %s\n", formatTime(t.Meta.CreatedAt))
+	if t.Meta.ParentID != "" {
+		fmt.Fprintf(&b, "- Forked from: %s at [%d]\n", t.Meta.ParentID, t.Meta.BranchPoint)
+	}
+	fmt.Fprintf(&b, "- Messages: %d\n", len(t.Messages))
+	if t.Meta.Cost > 0 {
+		fmt.Fprintf(&b, "- Cost: $%.4f\n", t.Meta.Cost)
+	}
+	b.WriteString("\n")
+
+	if t.Meta.SystemPrompt != "" {
+		b.WriteString("## System prompt\n\n")
+		writeFenced(&b, "", t.Meta.SystemPrompt)
+	}
+
+	names := toolNames(t.Messages)
+	for i, msg := range t.Messages {
+		if msg.Role == types.RoleAgent {
+			continue
+		}
+		heading := fmt.Sprintf("[%d] %s", i, msg.Role)
+		if msg.Role == types.RoleTool && msg.ToolResult != nil {
+			heading += " " + names[msg.ToolResult.CallID]
+		}
+		if at := formatTime(msg.Time); at != "" {
+			heading += " · " + at
+		}
+		fmt.Fprintf(&b, "### %s\n\n", heading)
+
+		if msg.Reasoning != "" {
+			b.WriteString("<details><summary>Reasoning</summary>\n\n")
+			writeFenced(&b, "", msg.Reasoning)
+			b.WriteString("</details>\n\n")
+		}
+		if msg.Content != "" {
+			b.WriteString(strings.TrimRight(msg.Content, "\n") + "\n\n")
+		}
+		if msg.Image != nil {
+			if msg.Image.Type == types.ImageTypeURL {
+				fmt.Fprintf(&b, "![image](%s)\n\n", msg.Image.URL)
+			} else {
+				fmt.Fprintf(&b, "_[%s image, %d bytes base64]_\n\n", msg.Image.MediaType, len(msg.Image.Data))
+			}
+		}
+		for _, tc := range msg.ToolCalls {
+			fmt.Fprintf(&b, "**Tool call** `%s` (%s)\n\n", tc.Name, tc.ID)
+			writeFenced(&b, "json", tc.Arguments)
+		}
+		if msg.ToolResult != nil {
+			writeFenced(&b, "", msg.ToolResult.Content)
+		}
+	}
+
+	if t.Memory != nil {
+		b.WriteString("## Session memory\n\n")
+		for _, f := range memoryFields(t.Memory) {
+			fmt.Fprintf(&b, "**%s**\n\n", f.Name)
+			for _, line := range f.Lines {
+				fmt.Fprintf(&b, "- %s\n", line)
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+type memoryField struct {
+	Name  string
+	Lines []string
+}
+
+// memoryFields lists the non-empty fields of a session memory record.
+func memoryFields(r *contextmgr.SessionMemoryRecord) []memoryField {
+	var fields []memoryField
+	add := func(name string, lines ...string) {
+		var kept []string
+		for _, line := range lines {
+			if strings.TrimSpace(line) != "" {
+				kept = append(kept, line)
+			}
+		}
+		if len(kept) > 0 {
+			fields = append(fields, memoryField{Name: name, Lines: kept})
+		}
+	}
+	add("Task objective", r.TaskObjective)
+	add("Current status", r.CurrentStatus)
+	add("Key decisions", r.KeyDecisions...)
+	add("Recent work", r.RecentWork...)
+	add("Pending items", r.PendingItems...)
+	add("File references", r.FileReferences...)
+	add("Important context", r.ImportantCtx)
+	return fields
+}
+
+type htmlMessage struct {
+	Index     int
+	Role      string
+	Tool      string
+	Time      string
+	Reasoning string
+	Content   string
+	ImageURL  template.URL
+	ToolCalls []types.ToolCall
+	Result    *types.ToolResult
+}
+
+var htmlTemplate = template.Must(template.New("session").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", sans-serif; max-width: 900px; margin: 2em auto; padding: 0 1em; color: #1f2328; }
+.meta { color: #59636e; }
+.msg { border: 1px solid #d1d9e0; border-radius: 6px; margin: 1em 0; padding: 0.5em 1em; }
+.msg h3 { font-size: 0.9em; margin: 0.3em 0; color: #59636e; }
+.user { background: #f6f8fa; }
+.tool { background: #fbfbf3; }
+.system { background: #f3f3fb; }
+.content { white-space: pre-wrap; }
+pre { background: #f6f8fa; padding: 0.5em; overflow-x: auto; white-space: pre-wrap; }
+img { max-width: 100%; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p class="meta">Created {{.Created}}{{if .ParentID}} · forked from {{.ParentID}} at [{{.BranchPoint}}]{{end}} · {{.Count}} messages{{if .Cost}} · ${{printf "%.4f" .Cost}}{{end}}</p>
+{{if .SystemPrompt}}<details><summary>System prompt</summary><pre>{{.SystemPrompt}}</pre></details>{{end}}
+{{range .Messages}}<div class="msg {{.Role}}">
+<h3>[{{.Index}}] {{.Role}}{{if .Tool}} {{.Tool}}{{end}}{{if .Time}} · {{.Time}}{{end}}</h3>
+{{if .Reasoning}}<details><summary>Reasoning</summary><pre>{{.Reasoning}}</pre></details>{{end}}
+{{if .Content}}<div class="content">{{.Content}}</div>{{end}}
+{{if .ImageURL}}<img src="{{.ImageURL}}" alt="image">{{end}}
+{{range .ToolCalls}}<p><strong>Tool call</strong> <code>{{.Name}}</code> ({{.ID}})</p><pre>{{.Arguments}}</pre>{{end}}
+{{if .Result}}<pre>{{.Result.Content}}</pre>{{end}}
+</div>
+{{end}}{{if .Memory}}<h2>Session memory</h2>
+{{range .Memory}}<h4>{{.Name}}</h4><ul>{{range .Lines}}<li>{{.}}</li>{{end}}</ul>
+{{end}}{{end}}</body>
+</html>
+`))
+
+func writeHTML(w io.Writer, t *Transcript) error {
+	data := struct {
+		Title        string
+		Created      string
+		ParentID     string
+		BranchPoint  int
+		Count        int
+		Cost         float64
+		SystemPrompt string
+		Messages     []htmlMessage
+		Memory       []memoryField
+	}{
+		Title:        title(t),
+		Created:      formatTime(t.Meta.CreatedAt),
+		ParentID:     t.Meta.ParentID,
+		BranchPoint:  t.Meta.BranchPoint,
+		Count:        len(t.Messages),
+		Cost:         t.Meta.Cost,
+		SystemPrompt: t.Meta.SystemPrompt,
+	}
+	if t.Memory != nil {
+		data.Memory = memoryFields(t.Memory)
+	}
+
+	names := toolNames(t.Messages)
+	for i, msg := range t.Messages {
+		if msg.Role == types.RoleAgent {
+			continue
+		}
+		hm := htmlMessage{
+			Index:     i,
+			Role:      string(msg.Role),
+			Time:      formatTime(msg.Time),
+			Reasoning: msg.Reasoning,
+			Content:   msg.Content,
+			ToolCalls: msg.ToolCalls,
+			Result:    msg.ToolResult,
+		}
+		if msg.ToolResult != nil {
+			hm.Tool = names[msg.ToolResult.CallID]
+		}
+		if msg.Image != nil {
+			// The URL comes from the session itself; only data URIs and web
+			// URLs are embedded.
+			if url := imageURL(msg.Image); strings.HasPrefix(url, "data:image/") || strings.HasPrefix(url, "https://") || strings.HasPrefix(url, "http://") {
+				hm.ImageURL = template.URL(url)
+			}
+		}
+		data.Messages = append(data.Messages, hm)
+	}
+	return htmlTemplate.Execute(w, data)
+}
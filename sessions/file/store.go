@@ -1,11 +1,13 @@
 package file
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/basenana/friday/core/contextmgr"
@@ -13,18 +15,38 @@ import (
 	coresession "github.com/basenana/friday/core/session"
 	"github.com/basenana/friday/core/types"
 	"github.com/basenana/friday/sessions"
+	"github.com/basenana/friday/sessions/search"
+	"github.com/basenana/friday/utils/crypt"
 )
 
 type FileSessionStore struct {
 	basePath string
+	index    *search.Index
+	cipher   *crypt.Cipher
+
+	// metaMu serializes read-modify-write updates of session.json.
+	metaMu sync.Mutex
 }
 
 func NewFileSessionStore(basePath string) *FileSessionStore {
 	return &FileSessionStore{
 		basePath: basePath,
+		index:    search.NewIndex(filepath.Join(basePath, ".index")),
 	}
 }
 
+// SetCipher encrypts the files the store writes from now on, including the
+// search index. Files written before are still read.
+func (s *FileSessionStore) SetCipher(c *crypt.Cipher) {
+	s.cipher = c
+	s.index.SetCipher(c)
+}
+
+// Cipher returns the cipher set with SetCipher, if any.
+func (s *FileSessionStore) Cipher() *crypt.Cipher {
+	return s.cipher
+}
+
 func (s *FileSessionStore) EnsureDir() error {
 	return os.MkdirAll(s.basePath, 0755)
 }
@@ -73,7 +95,7 @@ func (s *FileSessionStore) Create(sessionID string, llm providers.Client, opts .
 		return nil, err
 	}
 
-	if err := os.WriteFile(s.metaPath(sessionID), metaData, 0644); err != nil {
+	if err := s.cipher.WriteFile(s.metaPath(sessionID), metaData, 0644); err != nil {
 		return nil, err
 	}
 
@@ -87,7 +109,7 @@ func (s *FileSessionStore) Create(sessionID string, llm providers.Client, opts .
 
 func (s *FileSessionStore) Load(sessionID string, llm providers.Client, opts ...coresession.Option) (*coresession.Session, error) {
 	metaPath := s.metaPath(sessionID)
-	data, err := os.ReadFile(metaPath)
+	data, err := s.cipher.ReadFile(metaPath)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil, fmt.Errorf("session not found: %s: %w", sessionID, err)
@@ -107,6 +129,7 @@ func (s *FileSessionStore) Load(sessionID string, llm providers.Client, opts ...
 
 	sess := coresession.New(sessionID, llm, append(opts,
 		coresession.WithHistory(messages...),
+		coresession.WithCost(meta.Cost),
 		coresession.WithMessageWriter(s),
 	)...)
 
@@ -145,7 +168,7 @@ func (s *FileSessionStore) listFiltered(activeOnly bool) ([]sessions.SessionMeta
 		sessionID := entry.Name()
 		metaPath := s.metaPath(sessionID)
 
-		data, err := os.ReadFile(metaPath)
+		data, err := s.cipher.ReadFile(metaPath)
 		if err != nil {
 			continue
 		}
@@ -199,28 +222,28 @@ func (s *FileSessionStore) Unarchive(sessionID string) error {
 func (s *FileSessionStore) AppendMessages(sessionID string, msgs ...types.Message) error {
 	historyPath := s.historyPath(sessionID)
 
-	file, err := os.OpenFile(historyPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	appendedCount := 0
+	var (
+		buf      bytes.Buffer
+		appended []types.Message
+	)
 	for _, msg := range msgs {
 		data, err := json.Marshal(msg)
 		if err != nil {
 			continue
 		}
-		if _, err := file.Write(append(data, '\n')); err != nil {
-			continue
-		}
-		appendedCount++
+		buf.Write(append(data, '\n'))
+		appended = append(appended, msg)
 	}
-
-	if appendedCount > 0 {
-		s.updateMeta(sessionID, appendedCount)
+	if len(appended) == 0 {
+		return nil
+	}
+	if err := s.cipher.AppendFile(historyPath, buf.Bytes(), 0644); err != nil {
+		return err
 	}
 
+	if start, err := s.updateMeta(sessionID, len(appended)); err == nil {
+		s.index.Add(sessionID, start, appended...)
+	}
 	return nil
 }
 
@@ -255,7 +278,7 @@ func (s *FileSessionStore) UpdateMessageTokens(sessionID string, updates map[int
 func (s *FileSessionStore) LoadMessages(sessionID string) ([]types.Message, error) {
 	historyPath := s.historyPath(sessionID)
 
-	data, err := os.ReadFile(historyPath)
+	data, err := s.cipher.ReadFile(historyPath)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return []types.Message{}, nil
@@ -287,7 +310,7 @@ func (s *FileSessionStore) ReplaceMessages(sessionID string, msgs ...types.Messa
 	if _, err := os.Stat(historyPath); err == nil {
 		timestamp := time.Now().Format("20060102_150405")
 		backupPath := filepath.Join(s.sessionDir(sessionID), fmt.Sprintf("history_origin_%s.jsonl", timestamp))
-		if err := os.Rename(historyPath, backupPath); err != nil {
+		if err := s.backupHistory(historyPath, backupPath); err != nil {
 			return fmt.Errorf("failed to backup history: %w", err)
 		}
 	}
@@ -297,27 +320,41 @@ func (s *FileSessionStore) ReplaceMessages(sessionID string, msgs ...types.Messa
 		return err
 	}
 
-	// 3. Update metadata
-	return s.updateMetaCount(sessionID, len(msgs))
+	// 3. Update metadata and the search index
+	if err := s.updateMetaCount(sessionID, len(msgs)); err != nil {
+		return err
+	}
+	s.index.Add(sessionID, 0, msgs...)
+	return nil
 }
 
-func (s *FileSessionStore) writeHistory(historyPath string, msgs []types.Message) error {
-	file, err := os.OpenFile(historyPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+// backupHistory moves the history at historyPath to backupPath. Encrypted
+// records are bound to their file name, so an encrypted history is sealed
+// again for the backup instead of being renamed.
+func (s *FileSessionStore) backupHistory(historyPath, backupPath string) error {
+	if s.cipher == nil {
+		return os.Rename(historyPath, backupPath)
+	}
+	data, err := s.cipher.ReadFile(historyPath)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
+	if err := s.cipher.WriteFile(backupPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Remove(historyPath)
+}
 
+func (s *FileSessionStore) writeHistory(historyPath string, msgs []types.Message) error {
+	var buf bytes.Buffer
 	for _, msg := range msgs {
 		data, err := json.Marshal(msg)
 		if err != nil {
 			continue
 		}
-		if _, err := file.Write(append(data, '\n')); err != nil {
-			return err
-		}
+		buf.Write(append(data, '\n'))
 	}
-	return nil
+	return s.cipher.WriteFile(historyPath, buf.Bytes(), 0644)
 }
 
 func (s *FileSessionStore) WriteSessionMemory(sessionID string, record *contextmgr.SessionMemoryRecord) error {
@@ -334,11 +371,11 @@ func (s *FileSessionStore) WriteSessionMemory(sessionID string, record *contextm
 		return err
 	}
 
-	return os.WriteFile(s.sessionMemoryPath(sessionID), data, 0644)
+	return s.cipher.WriteFile(s.sessionMemoryPath(sessionID), data, 0644)
 }
 
 func (s *FileSessionStore) ReadSessionMemory(sessionID string) (*contextmgr.SessionMemoryRecord, error) {
-	data, err := os.ReadFile(s.sessionMemoryPath(sessionID))
+	data, err := s.cipher.ReadFile(s.sessionMemoryPath(sessionID))
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil, nil
@@ -353,7 +390,62 @@ func (s *FileSessionStore) ReadSessionMemory(sessionID string) (*contextmgr.Sess
 	return &record, nil
 }
 
+// DeleteSessionMemory drops the session memory of sessionID, if any.
+func (s *FileSessionStore) DeleteSessionMemory(sessionID string) error {
+	if err := os.Remove(s.sessionMemoryPath(sessionID)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// RecordUsage adds the usage of one model call to the session's metadata.
+func (s *FileSessionStore) RecordUsage(sessionID string, usage providers.Tokens, at time.Time) error {
+	s.metaMu.Lock()
+	defer s.metaMu.Unlock()
+
+	meta, err := s.loadMeta(sessionID)
+	if err != nil {
+		return err
+	}
+	meta.AddUsage(usage, at)
+	return s.saveMeta(sessionID, meta)
+}
+
+// ImportSession stores a session taken over from another store, replacing
+// any session with the same ID.
+func (s *FileSessionStore) ImportSession(meta sessions.SessionMeta, msgs []types.Message, memory *contextmgr.SessionMemoryRecord) error {
+	if err := os.MkdirAll(s.sessionDir(meta.ID), 0755); err != nil {
+		return err
+	}
+	if err := s.writeHistory(s.historyPath(meta.ID), msgs); err != nil {
+		return err
+	}
+	meta.MessageCount = len(msgs)
+	s.index.Add(meta.ID, 0, msgs...)
+
+	s.metaMu.Lock()
+	err := s.saveMeta(meta.ID, &meta)
+	s.metaMu.Unlock()
+	if err != nil {
+		return err
+	}
+	return s.WriteSessionMemory(meta.ID, memory)
+}
+
+// Search finds messages across all sessions.
+func (s *FileSessionStore) Search(q search.Query) ([]search.Hit, error) {
+	return sessions.SearchIndex(s.index, s, q)
+}
+
+// Reindex rebuilds the search index from all sessions.
+func (s *FileSessionStore) Reindex() (int, error) {
+	return sessions.Reindex(s.index, s)
+}
+
 func (s *FileSessionStore) updateMetaCount(sessionID string, count int) error {
+	s.metaMu.Lock()
+	defer s.metaMu.Unlock()
+
 	meta, err := s.loadMeta(sessionID)
 	if err != nil {
 		return err
@@ -363,32 +455,38 @@ func (s *FileSessionStore) updateMetaCount(sessionID string, count int) error {
 	return s.saveMeta(sessionID, meta)
 }
 
-func (s *FileSessionStore) updateMeta(sessionID string, added int) error {
+// updateMeta counts added appended messages and returns the index of the
+// first one.
+func (s *FileSessionStore) updateMeta(sessionID string, added int) (int, error) {
+	s.metaMu.Lock()
+	defer s.metaMu.Unlock()
+
 	metaPath := s.metaPath(sessionID)
-	data, err := os.ReadFile(metaPath)
+	data, err := s.cipher.ReadFile(metaPath)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	var meta sessions.SessionMeta
 	if err := json.Unmarshal(data, &meta); err != nil {
-		return err
+		return 0, err
 	}
 
+	start := meta.MessageCount
 	meta.UpdatedAt = time.Now()
 	meta.MessageCount += added
 
 	metaData, err := json.MarshalIndent(meta, "", "  ")
 	if err != nil {
-		return err
+		return 0, err
 	}
 
-	return os.WriteFile(metaPath, metaData, 0644)
+	return start, s.cipher.WriteFile(metaPath, metaData, 0644)
 }
 
 func (s *FileSessionStore) loadMeta(sessionID string) (*sessions.SessionMeta, error) {
 	metaPath := s.metaPath(sessionID)
-	data, err := os.ReadFile(metaPath)
+	data, err := s.cipher.ReadFile(metaPath)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil, fmt.Errorf("session not found: %s: %w", sessionID, err)
@@ -408,5 +506,5 @@ func (s *FileSessionStore) saveMeta(sessionID string, meta *sessions.SessionMeta
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(metaPath, metaData, 0644)
+	return s.cipher.WriteFile(metaPath, metaData, 0644)
 }
@@ -1,13 +1,19 @@
 package file
 
 import (
+	"bytes"
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/basenana/friday/core/contextmgr"
+	"github.com/basenana/friday/core/providers"
 	"github.com/basenana/friday/core/types"
+	"github.com/basenana/friday/sessions"
+	"github.com/basenana/friday/sessions/search"
+	"github.com/basenana/friday/utils/crypt"
 )
 
 func TestReplaceMessages(t *testing.T) {
@@ -189,3 +195,214 @@ func TestCalibratedMessageTokensPersistAcrossReload(t *testing.T) {
 		t.Fatalf("expected persisted calibrated tokens=42, got %d", history[0].Tokens)
 	}
 }
+
+func TestRecordUsagePersistsCostAcrossReload(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "session_usage_test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	store := NewFileSessionStore(tmpDir)
+	sessionID := "test-session-usage-001"
+	sess, err := store.Create(sessionID, nil)
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+
+	sess.RecordUsage(providers.Tokens{Model: "gpt-4o", PromptTokens: 100, CompletionTokens: 10, Cost: 0.25})
+	sess.RecordUsage(providers.Tokens{Model: "gpt-4o", PromptTokens: 200, CachedPromptTokens: 50, CompletionTokens: 20, Cost: 0.5})
+	sess.RecordUsage(providers.Tokens{Model: "o3", CompletionTokens: 30, ReasoningTokens: 25, Cost: 0.25})
+
+	meta, err := store.GetMeta(sessionID)
+	if err != nil {
+		t.Fatalf("failed to load meta: %v", err)
+	}
+	if meta.Cost != 1 {
+		t.Fatalf("expected cost 1, got %v", meta.Cost)
+	}
+	if len(meta.Usage) != 2 {
+		t.Fatalf("expected usage per model, got %+v", meta.Usage)
+	}
+	gpt := meta.Usage[0]
+	if gpt.Model != "gpt-4o" || gpt.Calls != 2 || gpt.PromptTokens != 300 || gpt.CachedPromptTokens != 50 || gpt.Cost != 0.75 {
+		t.Fatalf("unexpected gpt-4o usage %+v", gpt)
+	}
+	if gpt.Date != time.Now().Format("2006-01-02") {
+		t.Fatalf("unexpected usage date %q", gpt.Date)
+	}
+
+	reloaded, err := store.Load(sessionID, nil)
+	if err != nil {
+		t.Fatalf("failed to reload session: %v", err)
+	}
+	if reloaded.Cost() != 1 {
+		t.Fatalf("expected reloaded cost 1, got %v", reloaded.Cost())
+	}
+}
+
+func TestSearchIndexesAppendedMessages(t *testing.T) {
+	store := NewFileSessionStore(t.TempDir())
+	sess, err := store.Create("test-session-search-001", nil)
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+	sess.AppendMessage(&types.Message{Role: types.RoleUser, Content: "Why does the proxy bug come back?"})
+
+	// The first search builds the index from every session.
+	hits, err := store.Search(search.Query{Text: "proxy bug"})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(hits) != 1 || hits[0].SessionID != sess.ID || hits[0].Index != 0 {
+		t.Fatalf("unexpected hits %+v", hits)
+	}
+
+	// Later appends are indexed incrementally.
+	sess.AppendMessage(
+		&types.Message{Role: types.RoleAssistant, Content: "Looking into it."},
+		&types.Message{Role: types.RoleAssistant, Content: "The proxy bug was a stale env var."},
+	)
+	hits, err = store.Search(search.Query{Text: "proxy bug", Role: types.RoleAssistant})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(hits) != 1 || hits[0].Index != 2 {
+		t.Fatalf("expected the appended assistant message, got %+v", hits)
+	}
+
+	sessions, err := store.List()
+	if err != nil || len(sessions) != 1 {
+		t.Fatalf("index directory should not be listed as a session: %+v, %v", sessions, err)
+	}
+}
+
+func TestEncryptedStore(t *testing.T) {
+	dir := t.TempDir()
+	plain := NewFileSessionStore(dir)
+	sess, err := plain.Create("test-session-crypt-001", nil)
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+	sess.AppendMessage(&types.Message{Role: types.RoleUser, Content: "Where is the proxy config?"})
+
+	// Turning encryption on keeps the plain history readable.
+	c, err := crypt.New(bytes.Repeat([]byte{1}, crypt.KeySize))
+	if err != nil {
+		t.Fatalf("crypt.New failed: %v", err)
+	}
+	store := NewFileSessionStore(dir)
+	store.SetCipher(c)
+	if err := store.UpdateAlias(sess.ID, "proxy-hunt"); err != nil {
+		t.Fatalf("UpdateAlias failed: %v", err)
+	}
+	if err := store.AppendMessages(sess.ID, types.Message{Role: types.RoleAssistant, Content: "The proxy config lives in ~/.netrc."}); err != nil {
+		t.Fatalf("AppendMessages failed: %v", err)
+	}
+	if err := store.WriteSessionMemory(sess.ID, &contextmgr.SessionMemoryRecord{TaskObjective: "find the proxy config"}); err != nil {
+		t.Fatalf("WriteSessionMemory failed: %v", err)
+	}
+	hits, err := store.Search(search.Query{Text: "proxy config"})
+	if err != nil || len(hits) != 2 {
+		t.Fatalf("Search = %+v, %v", hits, err)
+	}
+
+	msgs, err := store.LoadMessages(sess.ID)
+	if err != nil || len(msgs) != 2 {
+		t.Fatalf("LoadMessages = %d messages, %v", len(msgs), err)
+	}
+	meta, err := store.GetMeta(sess.ID)
+	if err != nil || meta.Alias != "proxy-hunt" {
+		t.Fatalf("Get = %+v, %v", meta, err)
+	}
+
+	// The backup of a replaced history is sealed for its own file name.
+	if err := store.ReplaceMessages(sess.ID, msgs[1:]...); err != nil {
+		t.Fatalf("ReplaceMessages failed: %v", err)
+	}
+	backups, _ := filepath.Glob(filepath.Join(dir, sess.ID, "history_origin_*.jsonl"))
+	if len(backups) != 1 {
+		t.Fatalf("expected one history backup, got %v", backups)
+	}
+	if data, err := c.ReadFile(backups[0]); err != nil || !bytes.Contains(data, []byte("Where is the proxy config?")) {
+		t.Fatalf("read history backup = %q, %v", data, err)
+	}
+
+	err = filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if bytes.Contains(raw, []byte("proxy")) {
+			t.Errorf("%s holds plain text", path)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewFileSessionStore(dir).LoadMessages(sess.ID); !errors.Is(err, crypt.ErrEncrypted) {
+		t.Fatalf("LoadMessages without a key error = %v, want ErrEncrypted", err)
+	}
+}
+
+func TestForkAndRewind(t *testing.T) {
+	dir := t.TempDir()
+	store := NewFileSessionStore(filepath.Join(dir, "sessions"))
+	manager := sessions.NewManager(store, filepath.Join(dir, "current"), "")
+	sess, err := store.Create("test-session-branch-001", nil)
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+	start := time.Now().Add(-time.Hour)
+	sess.AppendMessage(
+		&types.Message{Role: types.RoleUser, Content: "List the files", Time: start},
+		&types.Message{Role: types.RoleAssistant, ToolCalls: []types.ToolCall{{ID: "call-1", Name: "bash"}}, Time: start},
+		&types.Message{Role: types.RoleTool, ToolResult: &types.ToolResult{CallID: "call-1", Content: "go.mod"}, Time: start},
+		&types.Message{Role: types.RoleAssistant, Content: "There is go.mod.", Time: start},
+		&types.Message{Role: types.RoleUser, Content: "Now delete it", Time: start.Add(time.Minute)},
+		&types.Message{Role: types.RoleAssistant, Content: "Done.", Time: start.Add(time.Minute)},
+	)
+
+	// Forking between a tool call and its result leaves the call out.
+	forkID, err := manager.Fork(sess.ID, 2)
+	if err != nil {
+		t.Fatalf("Fork failed: %v", err)
+	}
+	fork, err := store.GetMeta(forkID)
+	if err != nil {
+		t.Fatalf("failed to load fork meta: %v", err)
+	}
+	if fork.ParentID != sess.ID || fork.BranchPoint != 1 || fork.MessageCount != 1 {
+		t.Fatalf("unexpected fork meta %+v", fork)
+	}
+	if parent, _ := store.LoadMessages(sess.ID); len(parent) != 6 {
+		t.Fatalf("fork must leave the parent alone, got %d messages", len(parent))
+	}
+	if _, err := manager.Fork(sess.ID, 7); err == nil {
+		t.Fatal("expected an error forking past the end of the history")
+	}
+
+	// Session memory synced past the rewound turn is dropped.
+	if err := store.WriteSessionMemory(sess.ID, &contextmgr.SessionMemoryRecord{LastSyncAt: start.Add(time.Minute)}); err != nil {
+		t.Fatalf("failed to write session memory: %v", err)
+	}
+	left, err := manager.Rewind(sess.ID, 1)
+	if err != nil || left != 4 {
+		t.Fatalf("Rewind() = %d, %v; want 4 messages", left, err)
+	}
+	msgs, _ := store.LoadMessages(sess.ID)
+	if len(msgs) != 4 || msgs[3].Content != "There is go.mod." {
+		t.Fatalf("unexpected history after rewind %+v", msgs)
+	}
+	if record, _ := store.ReadSessionMemory(sess.ID); record != nil {
+		t.Fatalf("expected stale session memory to be deleted, got %+v", record)
+	}
+	if _, err := manager.Rewind(sess.ID, 2); err == nil {
+		t.Fatal("expected an error rewinding more turns than the session has")
+	}
+}
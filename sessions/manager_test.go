@@ -284,3 +284,43 @@ func TestManager_ExistsMissing(t *testing.T) {
 		t.Fatal("Exists(missing) = true, want false")
 	}
 }
+
+func TestSummarizeUsage(t *testing.T) {
+	metas := []SessionMeta{
+		{ID: "a", Usage: []UsageEntry{
+			{Date: "2026-10-01", Model: "gpt-4o", Calls: 2, PromptTokens: 100, Cost: 0.5},
+			{Date: "2026-10-02", Model: "o3", Calls: 1, PromptTokens: 50, Cost: 1},
+		}},
+		{ID: "b", Usage: []UsageEntry{
+			{Date: "2026-10-02", Model: "gpt-4o", Calls: 1, PromptTokens: 10, Cost: 0.25},
+		}},
+	}
+
+	byModel, err := SummarizeUsage(metas, UsageByModel, "")
+	if err != nil {
+		t.Fatalf("SummarizeUsage() error = %v", err)
+	}
+	if len(byModel) != 2 || byModel[0].Key != "o3" || byModel[1].Key != "gpt-4o" || byModel[1].Calls != 3 || byModel[1].Cost != 0.75 {
+		t.Fatalf("by model = %+v", byModel)
+	}
+
+	byDay, err := SummarizeUsage(metas, UsageByDay, "2026-10-02")
+	if err != nil {
+		t.Fatalf("SummarizeUsage() error = %v", err)
+	}
+	if len(byDay) != 1 || byDay[0].Key != "2026-10-02" || byDay[0].PromptTokens != 60 {
+		t.Fatalf("by day since 2026-10-02 = %+v", byDay)
+	}
+
+	bySession, _ := SummarizeUsage(metas, UsageBySession, "")
+	if len(bySession) != 2 || bySession[0].Key != "a" || bySession[0].Cost != 1.5 {
+		t.Fatalf("by session = %+v", bySession)
+	}
+
+	if _, err := SummarizeUsage(metas, "week", ""); err == nil {
+		t.Fatal("expected error for unknown grouping")
+	}
+	if _, err := SummarizeUsage(metas, UsageByDay, "yesterday"); err == nil {
+		t.Fatal("expected error for invalid date")
+	}
+}
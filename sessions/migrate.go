@@ -0,0 +1,53 @@
+package sessions
+
+import (
+	"fmt"
+
+	"github.com/basenana/friday/core/contextmgr"
+	"github.com/basenana/friday/core/types"
+)
+
+// Importer is implemented by stores that can take over a session from another
+// store with its metadata, history and session memory intact.
+type Importer interface {
+	ImportSession(meta SessionMeta, msgs []types.Message, memory *contextmgr.SessionMemoryRecord) error
+}
+
+// Migrate copies every session of src into dst, skipping sessions dst
+// already has, and returns how many it copied. src is left untouched.
+func Migrate(src, dst Store) (int, error) {
+	importer, ok := dst.(Importer)
+	if !ok {
+		return 0, fmt.Errorf("session store %T cannot import sessions", dst)
+	}
+	if err := dst.EnsureDir(); err != nil {
+		return 0, err
+	}
+	metas, err := src.List()
+	if err != nil {
+		return 0, err
+	}
+	memories, _ := src.(contextmgr.SessionMemoryStore)
+
+	copied := 0
+	for _, meta := range metas {
+		if _, err := dst.GetMeta(meta.ID); err == nil {
+			continue
+		}
+		msgs, err := src.LoadMessages(meta.ID)
+		if err != nil {
+			return copied, fmt.Errorf("load session %s: %w", meta.ID, err)
+		}
+		var memory *contextmgr.SessionMemoryRecord
+		if memories != nil {
+			if memory, err = memories.ReadSessionMemory(meta.ID); err != nil {
+				return copied, fmt.Errorf("read session memory of %s: %w", meta.ID, err)
+			}
+		}
+		if err := importer.ImportSession(meta, msgs, memory); err != nil {
+			return copied, fmt.Errorf("import session %s: %w", meta.ID, err)
+		}
+		copied++
+	}
+	return copied, nil
+}
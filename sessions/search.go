@@ -0,0 +1,38 @@
+package sessions
+
+import (
+	"github.com/basenana/friday/sessions/search"
+)
+
+// Searcher is implemented by stores that index their histories for search.
+type Searcher interface {
+	Search(q search.Query) ([]search.Hit, error)
+	// Reindex rebuilds the index from all sessions and returns how many
+	// messages it indexed.
+	Reindex() (int, error)
+}
+
+// SearchIndex searches the index of store, building it from all sessions
+// first when it has never been built.
+func SearchIndex(idx *search.Index, store Store, q search.Query) ([]search.Hit, error) {
+	if !idx.Built() {
+		if _, err := Reindex(idx, store); err != nil {
+			return nil, err
+		}
+	}
+	return idx.Search(q, store.LoadMessages)
+}
+
+// Reindex rebuilds the index of store from all its sessions, archived ones
+// included.
+func Reindex(idx *search.Index, store Store) (int, error) {
+	metas, err := store.List()
+	if err != nil {
+		return 0, err
+	}
+	ids := make([]string, 0, len(metas))
+	for _, meta := range metas {
+		ids = append(ids, meta.ID)
+	}
+	return idx.Rebuild(ids, store.LoadMessages)
+}
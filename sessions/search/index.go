@@ -0,0 +1,402 @@
+// Package search keeps an inverted index of session histories. Stores add
+// messages as they append them; postings are appended to files bucketed by
+// term, so indexing a message never rewrites the index.
+//
+// Postings only select candidates: a search loads the candidate messages and
+// checks them again, so postings left behind by replaced histories or deleted
+// sessions never show up as hits.
+package search
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/basenana/friday/core/types"
+	"github.com/basenana/friday/utils/crypt"
+)
+
+const (
+	buckets       = 64
+	indexVersion  = "1"
+	maxTermRunes  = 64
+	snippetRunes  = 80
+	defaultLimit  = 20
+	builtFileName = "built"
+)
+
+// Query selects the messages to find. All terms of Text must occur in a
+// message; the other fields narrow the search and are ignored when empty.
+type Query struct {
+	Text      string
+	Role      types.MessageRole
+	SessionID string
+	// Since and Until bound the message time; Until is exclusive.
+	Since time.Time
+	Until time.Time
+	// Limit caps the hits returned, 20 by default.
+	Limit int
+}
+
+// Hit is a message matching a query.
+type Hit struct {
+	SessionID string
+	Index     int
+	Role      types.MessageRole
+	Time      time.Time
+	Snippet   string
+}
+
+// LoadFunc returns the history of a session.
+type LoadFunc func(sessionID string) ([]types.Message, error)
+
+// Index is an inverted index kept in a directory.
+type Index struct {
+	dir    string
+	cipher *crypt.Cipher
+	mu     sync.Mutex
+}
+
+func NewIndex(dir string) *Index {
+	return &Index{dir: dir}
+}
+
+// SetCipher encrypts the postings added from now on; Rebuild encrypts all
+// of them.
+func (x *Index) SetCipher(c *crypt.Cipher) {
+	x.cipher = c
+}
+
+func (x *Index) bucketPath(term string) string {
+	h := fnv.New32a()
+	h.Write([]byte(term))
+	return filepath.Join(x.dir, fmt.Sprintf("%02d.tsv", h.Sum32()%buckets))
+}
+
+// Built reports whether the index was built from all sessions, so that it
+// also covers sessions created before it existed.
+func (x *Index) Built() bool {
+	data, err := x.cipher.ReadFile(filepath.Join(x.dir, builtFileName))
+	return err == nil && strings.TrimSpace(string(data)) == indexVersion
+}
+
+// Add indexes msgs as the messages of sessionID from index start on.
+func (x *Index) Add(sessionID string, start int, msgs ...types.Message) error {
+	lines := make(map[string]*strings.Builder)
+	now := time.Now()
+	for i, msg := range msgs {
+		at := msg.Time
+		if at.IsZero() {
+			at = now
+		}
+		for _, term := range uniqueTerms(MessageText(msg)) {
+			path := x.bucketPath(term)
+			b, ok := lines[path]
+			if !ok {
+				b = &strings.Builder{}
+				lines[path] = b
+			}
+			fmt.Fprintf(b, "%s\t%s\t%d\t%s\t%d\n", term, sessionID, start+i, msg.Role, at.Unix())
+		}
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if err := os.MkdirAll(x.dir, 0755); err != nil {
+		return err
+	}
+	for path, b := range lines {
+		if err := x.cipher.AppendFile(path, []byte(b.String()), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Rebuild replaces the index with one of the given sessions and marks it
+// built. It returns how many messages it indexed.
+func (x *Index) Rebuild(sessionIDs []string, load LoadFunc) (int, error) {
+	x.mu.Lock()
+	if err := os.RemoveAll(x.dir); err != nil {
+		x.mu.Unlock()
+		return 0, err
+	}
+	x.mu.Unlock()
+
+	indexed := 0
+	for _, id := range sessionIDs {
+		msgs, err := load(id)
+		if err != nil {
+			return indexed, fmt.Errorf("load session %s: %w", id, err)
+		}
+		if err := x.Add(id, 0, msgs...); err != nil {
+			return indexed, err
+		}
+		indexed += len(msgs)
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if err := os.MkdirAll(x.dir, 0755); err != nil {
+		return indexed, err
+	}
+	return indexed, x.cipher.WriteFile(filepath.Join(x.dir, builtFileName), []byte(indexVersion+"\n"), 0644)
+}
+
+type docKey struct {
+	session string
+	index   int
+}
+
+type posting struct {
+	at time.Time
+}
+
+// Search returns the messages matching q, newest first, loading the
+// candidate sessions with load.
+func (x *Index) Search(q Query, load LoadFunc) ([]Hit, error) {
+	terms := uniqueTerms(q.Text)
+	if len(terms) == 0 {
+		return nil, errors.New("query has no searchable terms")
+	}
+
+	var candidates map[docKey]posting
+	for _, term := range terms {
+		found, err := x.postings(term, q)
+		if err != nil {
+			return nil, err
+		}
+		if candidates == nil {
+			candidates = found
+			continue
+		}
+		for key := range candidates {
+			if _, ok := found[key]; !ok {
+				delete(candidates, key)
+			}
+		}
+	}
+
+	bySession := make(map[string][]int)
+	for key := range candidates {
+		bySession[key.session] = append(bySession[key.session], key.index)
+	}
+
+	var hits []Hit
+	for sessionID, indices := range bySession {
+		msgs, err := load(sessionID)
+		if err != nil {
+			continue // deleted since it was indexed
+		}
+		for _, idx := range indices {
+			if idx >= len(msgs) {
+				continue
+			}
+			msg := msgs[idx]
+			if q.Role != "" && msg.Role != q.Role {
+				continue
+			}
+			at := msg.Time
+			if at.IsZero() {
+				at = candidates[docKey{sessionID, idx}].at
+			}
+			if !inRange(at, q) {
+				continue
+			}
+			snippet, ok := matchSnippet(MessageText(msg), terms)
+			if !ok {
+				continue // replaced since it was indexed
+			}
+			hits = append(hits, Hit{SessionID: sessionID, Index: idx, Role: msg.Role, Time: at, Snippet: snippet})
+		}
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if !hits[i].Time.Equal(hits[j].Time) {
+			return hits[i].Time.After(hits[j].Time)
+		}
+		if hits[i].SessionID != hits[j].SessionID {
+			return hits[i].SessionID < hits[j].SessionID
+		}
+		return hits[i].Index < hits[j].Index
+	})
+	limit := q.Limit
+	if limit <= 0 {
+		limit = defaultLimit
+	}
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits, nil
+}
+
+// postings returns the indexed messages containing term that may match q.
+func (x *Index) postings(term string, q Query) (map[docKey]posting, error) {
+	found := make(map[docKey]posting)
+	data, err := x.cipher.ReadFile(x.bucketPath(term))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return found, nil
+		}
+		return nil, err
+	}
+
+	prefix := term + "\t"
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, prefix) {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) != 5 {
+			continue
+		}
+		idx, err := strconv.Atoi(fields[2])
+		if err != nil {
+			continue
+		}
+		if q.SessionID != "" && fields[1] != q.SessionID {
+			continue
+		}
+		if q.Role != "" && types.MessageRole(fields[3]) != q.Role {
+			continue
+		}
+		unix, _ := strconv.ParseInt(fields[4], 10, 64)
+		found[docKey{fields[1], idx}] = posting{at: time.Unix(unix, 0)}
+	}
+	return found, scanner.Err()
+}
+
+func inRange(at time.Time, q Query) bool {
+	if !q.Since.IsZero() && at.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !at.Before(q.Until) {
+		return false
+	}
+	return true
+}
+
+// MessageText is the searchable text of a message: its content, tool call
+// arguments and tool result.
+func MessageText(msg types.Message) string {
+	parts := []string{msg.Content}
+	for _, tc := range msg.ToolCalls {
+		parts = append(parts, tc.Name+" "+tc.Arguments)
+	}
+	if msg.ToolResult != nil {
+		parts = append(parts, msg.ToolResult.Content)
+	}
+	return strings.TrimSpace(strings.Join(parts, "\n"))
+}
+
+// Terms splits text into lowercase words. Han, kana and hangul characters
+// are terms of their own, since those scripts do not separate words with
+// spaces; single letters are dropped.
+func Terms(text string) []string {
+	var (
+		terms []string
+		word  []rune
+	)
+	flush := func() {
+		if len(word) > 1 || (len(word) == 1 && unicode.IsDigit(word[0])) {
+			if len(word) > maxTermRunes {
+				word = word[:maxTermRunes]
+			}
+			terms = append(terms, string(word))
+		}
+		word = word[:0]
+	}
+	for _, r := range text {
+		switch {
+		case isIdeographic(r):
+			flush()
+			terms = append(terms, string(unicode.ToLower(r)))
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			word = append(word, unicode.ToLower(r))
+		default:
+			flush()
+		}
+	}
+	flush()
+	return terms
+}
+
+func uniqueTerms(text string) []string {
+	seen := make(map[string]bool)
+	var terms []string
+	for _, term := range Terms(text) {
+		if !seen[term] {
+			seen[term] = true
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
+
+func isIdeographic(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// matchSnippet checks that text contains every term and returns the text
+// around the first one.
+func matchSnippet(text string, terms []string) (string, bool) {
+	runes := []rune(text)
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	first, firstLen := -1, 0
+	for _, term := range terms {
+		pos := indexRunes(lower, []rune(term))
+		if pos < 0 {
+			return "", false
+		}
+		if first < 0 || pos < first {
+			first, firstLen = pos, len([]rune(term))
+		}
+	}
+
+	start := max(first-snippetRunes/2, 0)
+	end := min(first+firstLen+snippetRunes, len(runes))
+	snippet := strings.Join(strings.Fields(string(runes[start:end])), " ")
+	if start > 0 {
+		snippet = "…" + snippet
+	}
+	if end < len(runes) {
+		snippet += "…"
+	}
+	return snippet, true
+}
+
+func indexRunes(s, sub []rune) int {
+	for i := 0; i+len(sub) <= len(s); i++ {
+		match := true
+		for j := range sub {
+			if s[i+j] != sub[j] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return i
+		}
+	}
+	return -1
+}
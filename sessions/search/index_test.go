@@ -0,0 +1,100 @@
+package search
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/basenana/friday/core/types"
+)
+
+func TestTerms(t *testing.T) {
+	got := Terms("Fix the HTTP_PROXY bug in a proxy.go, 修复代理")
+	want := []string{"fix", "the", "http", "proxy", "bug", "in", "proxy", "go", "修", "复", "代", "理"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Terms() = %q, want %q", got, want)
+	}
+}
+
+type memoryHistories map[string][]types.Message
+
+func (m memoryHistories) load(sessionID string) ([]types.Message, error) {
+	return m[sessionID], nil
+}
+
+func TestIndexSearch(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2026, 10, d, 12, 0, 0, 0, time.UTC) }
+	histories := memoryHistories{
+		"s1": {
+			{Role: types.RoleUser, Content: "The proxy setting is ignored", Time: day(1)},
+			{Role: types.RoleAssistant, Content: "I'll check how the client reads HTTPS_PROXY.", Time: day(1)},
+			{Role: types.RoleTool, ToolResult: &types.ToolResult{CallID: "call-1", Content: "proxy: " + strings.Repeat("x", 200) + " bug fixed"}, Time: day(2)},
+		},
+		"s2": {
+			{Role: types.RoleUser, Content: "Fix the proxy bug in the router", Time: day(5)},
+		},
+	}
+	idx := NewIndex(t.TempDir())
+	for id, msgs := range histories {
+		if err := idx.Add(id, 0, msgs...); err != nil {
+			t.Fatalf("Add(%s) error = %v", id, err)
+		}
+	}
+
+	hits, err := idx.Search(Query{Text: "proxy bug"}, histories.load)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(hits) != 2 || hits[0].SessionID != "s2" || hits[1].SessionID != "s1" || hits[1].Index != 2 {
+		t.Fatalf("Search() = %+v, want s2#0 then s1#2", hits)
+	}
+	if hits[0].Snippet != "Fix the proxy bug in the router" {
+		t.Fatalf("unexpected snippet %q", hits[0].Snippet)
+	}
+	if !strings.HasPrefix(hits[1].Snippet, "proxy: ") || !strings.HasSuffix(hits[1].Snippet, "…") {
+		t.Fatalf("expected a truncated snippet starting at the match, got %q", hits[1].Snippet)
+	}
+
+	hits, _ = idx.Search(Query{Text: "proxy", Role: types.RoleUser, Until: day(3)}, histories.load)
+	if len(hits) != 1 || hits[0].SessionID != "s1" || hits[0].Index != 0 {
+		t.Fatalf("role and date filtered Search() = %+v, want s1#0", hits)
+	}
+
+	// Replaced histories leave postings behind that must not match.
+	histories["s2"] = []types.Message{{Role: types.RoleSystem, Content: "Summary: routing fixed", Time: day(6)}}
+	if err := idx.Add("s2", 0, histories["s2"]...); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	hits, _ = idx.Search(Query{Text: "proxy bug"}, histories.load)
+	if len(hits) != 1 || hits[0].SessionID != "s1" {
+		t.Fatalf("Search() after replace = %+v, want only s1", hits)
+	}
+	if hits, _ = idx.Search(Query{Text: "routing"}, histories.load); len(hits) != 1 || hits[0].Role != types.RoleSystem {
+		t.Fatalf("Search() for the replaced history = %+v", hits)
+	}
+
+	if _, err := idx.Search(Query{Text: "?!"}, histories.load); err == nil {
+		t.Fatal("expected an error for a query without terms")
+	}
+}
+
+func TestIndexRebuild(t *testing.T) {
+	histories := memoryHistories{
+		"s1": {{Role: types.RoleUser, Content: "deploy the proxy"}},
+	}
+	idx := NewIndex(t.TempDir())
+	if idx.Built() {
+		t.Fatal("new index should not be built")
+	}
+	idx.Add("gone", 0, types.Message{Role: types.RoleUser, Content: "proxy"})
+
+	indexed, err := idx.Rebuild([]string{"s1"}, histories.load)
+	if err != nil || indexed != 1 || !idx.Built() {
+		t.Fatalf("Rebuild() = %d, %v; built %v", indexed, err, idx.Built())
+	}
+	hits, err := idx.Search(Query{Text: "proxy"}, histories.load)
+	if err != nil || len(hits) != 1 || hits[0].SessionID != "s1" {
+		t.Fatalf("Search() = %+v, %v; want s1 only", hits, err)
+	}
+}
@@ -0,0 +1,91 @@
+package sessions
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/basenana/friday/core/tools"
+	"github.com/basenana/friday/core/types"
+	"github.com/basenana/friday/sessions/search"
+)
+
+// NewSearchTool returns the session_search tool, which finds messages in
+// past sessions for the agent to cite.
+func NewSearchTool(searcher Searcher) *tools.Tool {
+	return tools.NewTool("session_search",
+		tools.WithDescription(`Search the messages of all past sessions, archived ones included. Every term of the query must occur in a message.
+
+Use this when the user refers to an earlier conversation ("the session where we fixed the proxy bug") or when past work would help. Results are newest first, one per line as "<session id> #<message index> <role> <time>: <snippet>"; cite them by session id and message index.`),
+		tools.WithString("query", tools.Required(), tools.Description("Words to search for")),
+		tools.WithString("role", tools.Description("Only match messages of this role"), tools.Enum("user", "assistant", "tool", "system")),
+		tools.WithString("since", tools.Description("Only match messages from this date on (YYYY-MM-DD)")),
+		tools.WithString("until", tools.Description("Only match messages up to and including this date (YYYY-MM-DD)")),
+		tools.WithString("session_id", tools.Description("Only search this session")),
+		tools.WithNumber("limit", tools.Description("Maximum number of results, 20 by default"), tools.Min(1), tools.Max(100)),
+		tools.WithToolHandler(func(ctx context.Context, req *tools.Request) (*tools.Result, error) {
+			text, _ := req.Arguments["query"].(string)
+			if strings.TrimSpace(text) == "" {
+				return tools.NewToolResultError("missing required parameter: query"), nil
+			}
+			role, _ := req.Arguments["role"].(string)
+			since, _ := req.Arguments["since"].(string)
+			until, _ := req.Arguments["until"].(string)
+			sessionID, _ := req.Arguments["session_id"].(string)
+			limit, _ := req.Arguments["limit"].(float64)
+
+			q, err := NewSearchQuery(text, role, since, until)
+			if err != nil {
+				return tools.NewToolResultError(err.Error()), nil
+			}
+			q.SessionID = sessionID
+			q.Limit = int(limit)
+
+			hits, err := searcher.Search(q)
+			if err != nil {
+				return tools.NewToolResultError(err.Error()), nil
+			}
+			if len(hits) == 0 {
+				return tools.NewToolResultText("No messages found"), nil
+			}
+			return tools.NewToolResultText(FormatHits(hits)), nil
+		}),
+	)
+}
+
+// NewSearchQuery builds a query from command line or tool arguments, with
+// dates in the UsageDateLayout. until includes the whole day.
+func NewSearchQuery(text, role, since, until string) (search.Query, error) {
+	q := search.Query{Text: text, Role: types.MessageRole(role)}
+	switch q.Role {
+	case "", types.RoleUser, types.RoleAssistant, types.RoleTool, types.RoleSystem:
+	default:
+		return q, fmt.Errorf("invalid role %q: want user, assistant, tool or system", role)
+	}
+	if since != "" {
+		day, err := time.ParseInLocation(UsageDateLayout, since, time.Local)
+		if err != nil {
+			return q, fmt.Errorf("invalid date %q: want YYYY-MM-DD", since)
+		}
+		q.Since = day
+	}
+	if until != "" {
+		day, err := time.ParseInLocation(UsageDateLayout, until, time.Local)
+		if err != nil {
+			return q, fmt.Errorf("invalid date %q: want YYYY-MM-DD", until)
+		}
+		q.Until = day.AddDate(0, 0, 1)
+	}
+	return q, nil
+}
+
+// FormatHits renders hits one per line.
+func FormatHits(hits []search.Hit) string {
+	var b strings.Builder
+	for _, hit := range hits {
+		fmt.Fprintf(&b, "%s #%d %s %s: %s\n",
+			hit.SessionID, hit.Index, hit.Role, hit.Time.Local().Format("2006-01-02 15:04"), hit.Snippet)
+	}
+	return b.String()
+}
@@ -0,0 +1,611 @@
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/basenana/friday/core/contextmgr"
+	"github.com/basenana/friday/core/providers"
+	coresession "github.com/basenana/friday/core/session"
+	"github.com/basenana/friday/core/types"
+	"github.com/basenana/friday/sessions"
+	"github.com/basenana/friday/sessions/search"
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS sessions (
+	id            TEXT PRIMARY KEY,
+	alias         TEXT NOT NULL DEFAULT '',
+	archived      INTEGER NOT NULL DEFAULT 0,
+	created_at    INTEGER NOT NULL,
+	updated_at    INTEGER NOT NULL,
+	message_count INTEGER NOT NULL DEFAULT 0,
+	summary       TEXT NOT NULL DEFAULT '',
+	system_prompt TEXT NOT NULL DEFAULT '',
+	cost          REAL NOT NULL DEFAULT 0,
+	usage         TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS sessions_archived_created ON sessions (archived, created_at);
+
+CREATE TABLE IF NOT EXISTS messages (
+	session_id TEXT NOT NULL,
+	idx        INTEGER NOT NULL,
+	tokens     INTEGER NOT NULL DEFAULT 0,
+	data       TEXT NOT NULL,
+	PRIMARY KEY (session_id, idx)
+) WITHOUT ROWID;
+
+CREATE TABLE IF NOT EXISTS replaced_messages (
+	session_id  TEXT NOT NULL,
+	replaced_at INTEGER NOT NULL,
+	idx         INTEGER NOT NULL,
+	tokens      INTEGER NOT NULL DEFAULT 0,
+	data        TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS replaced_messages_session ON replaced_messages (session_id, replaced_at);
+
+CREATE TABLE IF NOT EXISTS session_memory (
+	session_id TEXT PRIMARY KEY,
+	data       TEXT NOT NULL
+);
+`
+
+// migrations upgrade databases created with an older schema. The database's
+// user_version counts the migrations applied; new ones go at the end.
+var migrations = []string{
+	`ALTER TABLE sessions ADD COLUMN parent_id TEXT NOT NULL DEFAULT '';
+	ALTER TABLE sessions ADD COLUMN branch_point INTEGER NOT NULL DEFAULT 0;`,
+}
+
+const metaColumns = `id, alias, archived, created_at, updated_at, message_count, summary, system_prompt, cost, usage, parent_id, branch_point`
+
+// SQLiteSessionStore keeps sessions, their histories and session memory in
+// one SQLite database, so listing reads an index instead of every session's
+// metadata and history changes only touch the affected rows.
+type SQLiteSessionStore struct {
+	path  string
+	index *search.Index
+
+	mu sync.Mutex
+	db *sql.DB
+}
+
+func NewSQLiteSessionStore(path string) *SQLiteSessionStore {
+	return &SQLiteSessionStore{
+		path:  path,
+		index: search.NewIndex(strings.TrimSuffix(path, filepath.Ext(path)) + ".index"),
+	}
+}
+
+// EnsureDir creates the database and its schema on first use.
+func (s *SQLiteSessionStore) EnsureDir() error {
+	_, err := s.open()
+	return err
+}
+
+// Close releases the database; the store reopens it when used again.
+func (s *SQLiteSessionStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.db == nil {
+		return nil
+	}
+	err := s.db.Close()
+	s.db = nil
+	return err
+}
+
+func (s *SQLiteSessionStore) open() (*sql.DB, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.db != nil {
+		return s.db, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return nil, err
+	}
+	// Several friday processes share the database: WAL lets readers run
+	// alongside a writer, and immediate transactions take the write lock up
+	// front so busy_timeout can wait out the others' writes.
+	dsn := "file:" + s.path + "?_txlock=immediate&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=synchronous(NORMAL)"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("open session database: %w", err)
+	}
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("create session schema: %w", err)
+	}
+	if err := migrate(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("migrate session schema: %w", err)
+	}
+	s.db = db
+	return db, nil
+}
+
+func migrate(db *sql.DB) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var version int
+	if err := tx.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+		return err
+	}
+	if version >= len(migrations) {
+		return nil
+	}
+	for _, stmt := range migrations[version:] {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, len(migrations))); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// withTx runs fn in a transaction, committing when it returns nil.
+func (s *SQLiteSessionStore) withTx(fn func(tx *sql.Tx) error) error {
+	db, err := s.open()
+	if err != nil {
+		return err
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// Store interface implementation
+
+func (s *SQLiteSessionStore) Create(sessionID string, llm providers.Client, opts ...coresession.Option) (*coresession.Session, error) {
+	now := time.Now()
+	meta := sessions.SessionMeta{
+		ID:        sessionID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	err := s.withTx(func(tx *sql.Tx) error {
+		if err := deleteSession(tx, sessionID); err != nil {
+			return err
+		}
+		return insertMeta(tx, &meta)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sess := coresession.New(sessionID, llm, append(opts, coresession.WithMessageWriter(s))...)
+	return sess, nil
+}
+
+func (s *SQLiteSessionStore) Load(sessionID string, llm providers.Client, opts ...coresession.Option) (*coresession.Session, error) {
+	meta, err := s.GetMeta(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	messages, err := s.LoadMessages(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	sess := coresession.New(sessionID, llm, append(opts,
+		coresession.WithHistory(messages...),
+		coresession.WithCost(meta.Cost),
+		coresession.WithMessageWriter(s),
+	)...)
+
+	return sess, nil
+}
+
+func (s *SQLiteSessionStore) Delete(sessionID string) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		return deleteSession(tx, sessionID)
+	})
+}
+
+func (s *SQLiteSessionStore) List() ([]sessions.SessionMeta, error) {
+	return s.listFiltered(`SELECT ` + metaColumns + ` FROM sessions ORDER BY created_at`)
+}
+
+func (s *SQLiteSessionStore) ListActive() ([]sessions.SessionMeta, error) {
+	return s.listFiltered(`SELECT ` + metaColumns + ` FROM sessions WHERE archived = 0 ORDER BY created_at`)
+}
+
+func (s *SQLiteSessionStore) listFiltered(query string) ([]sessions.SessionMeta, error) {
+	db, err := s.open()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []sessions.SessionMeta{}
+	for rows.Next() {
+		meta, err := scanMeta(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *meta)
+	}
+	return result, rows.Err()
+}
+
+func (s *SQLiteSessionStore) GetMeta(sessionID string) (*sessions.SessionMeta, error) {
+	db, err := s.open()
+	if err != nil {
+		return nil, err
+	}
+	return loadMeta(db, sessionID)
+}
+
+func (s *SQLiteSessionStore) UpdateAlias(sessionID, alias string) error {
+	return s.updateColumn(sessionID, "alias", alias)
+}
+
+func (s *SQLiteSessionStore) Archive(sessionID string) error {
+	return s.updateColumn(sessionID, "archived", true)
+}
+
+func (s *SQLiteSessionStore) Unarchive(sessionID string) error {
+	return s.updateColumn(sessionID, "archived", false)
+}
+
+func (s *SQLiteSessionStore) updateColumn(sessionID, column string, value any) error {
+	db, err := s.open()
+	if err != nil {
+		return err
+	}
+	res, err := db.Exec(`UPDATE sessions SET `+column+` = ? WHERE id = ?`, value, sessionID)
+	if err != nil {
+		return err
+	}
+	return requireSession(res, sessionID)
+}
+
+// AppendMessages adds msgs to the end of the history and updates the message
+// count in one transaction.
+func (s *SQLiteSessionStore) AppendMessages(sessionID string, msgs ...types.Message) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+	var start int
+	var appended []types.Message
+	err := s.withTx(func(tx *sql.Tx) error {
+		var count int
+		err := tx.QueryRow(`SELECT message_count FROM sessions WHERE id = ?`, sessionID).Scan(&count)
+		if errors.Is(err, sql.ErrNoRows) {
+			return notFound(sessionID)
+		}
+		if err != nil {
+			return err
+		}
+		n, err := insertMessages(tx, sessionID, count, msgs)
+		if err != nil {
+			return err
+		}
+		start, appended = count, msgs[:n]
+		_, err = tx.Exec(`UPDATE sessions SET message_count = ?, updated_at = ? WHERE id = ?`,
+			count+n, time.Now().UnixNano(), sessionID)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	s.index.Add(sessionID, start, appended...)
+	return nil
+}
+
+func (s *SQLiteSessionStore) UpdateMessageTokens(sessionID string, updates map[int]int64) error {
+	if len(updates) == 0 {
+		return nil
+	}
+	return s.withTx(func(tx *sql.Tx) error {
+		stmt, err := tx.Prepare(`UPDATE messages SET tokens = ? WHERE session_id = ? AND idx = ?`)
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+		for idx, tokens := range updates {
+			if _, err := stmt.Exec(tokens, sessionID, idx); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *SQLiteSessionStore) LoadMessages(sessionID string) ([]types.Message, error) {
+	db, err := s.open()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.Query(`SELECT tokens, data FROM messages WHERE session_id = ? ORDER BY idx`, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := []types.Message{}
+	for rows.Next() {
+		var (
+			tokens int64
+			data   string
+		)
+		if err := rows.Scan(&tokens, &data); err != nil {
+			return nil, err
+		}
+		var msg types.Message
+		if err := json.Unmarshal([]byte(data), &msg); err != nil {
+			continue
+		}
+		msg.Tokens = tokens
+		messages = append(messages, msg)
+	}
+	return messages, rows.Err()
+}
+
+// ReplaceMessages swaps the history for msgs, moving the old history to
+// replaced_messages like the file store keeps it as a backup file.
+func (s *SQLiteSessionStore) ReplaceMessages(sessionID string, msgs ...types.Message) error {
+	err := s.withTx(func(tx *sql.Tx) error {
+		now := time.Now().UnixNano()
+		_, err := tx.Exec(`INSERT INTO replaced_messages (session_id, replaced_at, idx, tokens, data)
+			SELECT session_id, ?, idx, tokens, data FROM messages WHERE session_id = ?`, now, sessionID)
+		if err != nil {
+			return fmt.Errorf("failed to backup history: %w", err)
+		}
+		if _, err := tx.Exec(`DELETE FROM messages WHERE session_id = ?`, sessionID); err != nil {
+			return err
+		}
+		count, err := insertMessages(tx, sessionID, 0, msgs)
+		if err != nil {
+			return err
+		}
+		res, err := tx.Exec(`UPDATE sessions SET message_count = ?, updated_at = ? WHERE id = ?`, count, now, sessionID)
+		if err != nil {
+			return err
+		}
+		return requireSession(res, sessionID)
+	})
+	if err != nil {
+		return err
+	}
+	s.index.Add(sessionID, 0, msgs...)
+	return nil
+}
+
+func (s *SQLiteSessionStore) WriteSessionMemory(sessionID string, record *contextmgr.SessionMemoryRecord) error {
+	if record == nil {
+		return nil
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	db, err := s.open()
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`INSERT INTO session_memory (session_id, data) VALUES (?, ?)
+		ON CONFLICT (session_id) DO UPDATE SET data = excluded.data`, sessionID, string(data))
+	return err
+}
+
+func (s *SQLiteSessionStore) ReadSessionMemory(sessionID string) (*contextmgr.SessionMemoryRecord, error) {
+	db, err := s.open()
+	if err != nil {
+		return nil, err
+	}
+	var data string
+	err = db.QueryRow(`SELECT data FROM session_memory WHERE session_id = ?`, sessionID).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var record contextmgr.SessionMemoryRecord
+	if err := json.Unmarshal([]byte(data), &record); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// DeleteSessionMemory drops the session memory of sessionID, if any.
+func (s *SQLiteSessionStore) DeleteSessionMemory(sessionID string) error {
+	db, err := s.open()
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`DELETE FROM session_memory WHERE session_id = ?`, sessionID)
+	return err
+}
+
+// RecordUsage adds the usage of one model call to the session's metadata.
+func (s *SQLiteSessionStore) RecordUsage(sessionID string, usage providers.Tokens, at time.Time) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		meta, err := loadMeta(tx, sessionID)
+		if err != nil {
+			return err
+		}
+		meta.AddUsage(usage, at)
+		usageData, err := json.Marshal(meta.Usage)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`UPDATE sessions SET cost = ?, usage = ? WHERE id = ?`, meta.Cost, string(usageData), sessionID)
+		return err
+	})
+}
+
+// ImportSession stores a session taken over from another store, replacing
+// any session with the same ID.
+func (s *SQLiteSessionStore) ImportSession(meta sessions.SessionMeta, msgs []types.Message, memory *contextmgr.SessionMemoryRecord) error {
+	err := s.withTx(func(tx *sql.Tx) error {
+		if err := deleteSession(tx, meta.ID); err != nil {
+			return err
+		}
+		count, err := insertMessages(tx, meta.ID, 0, msgs)
+		if err != nil {
+			return err
+		}
+		meta.MessageCount = count
+		return insertMeta(tx, &meta)
+	})
+	if err != nil {
+		return err
+	}
+	s.index.Add(meta.ID, 0, msgs...)
+	return s.WriteSessionMemory(meta.ID, memory)
+}
+
+// Search finds messages across all sessions.
+func (s *SQLiteSessionStore) Search(q search.Query) ([]search.Hit, error) {
+	return sessions.SearchIndex(s.index, s, q)
+}
+
+// Reindex rebuilds the search index from all sessions.
+func (s *SQLiteSessionStore) Reindex() (int, error) {
+	return sessions.Reindex(s.index, s)
+}
+
+// querier is what reading metadata needs of a *sql.DB or *sql.Tx.
+type querier interface {
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func loadMeta(q querier, sessionID string) (*sessions.SessionMeta, error) {
+	meta, err := scanMeta(q.QueryRow(`SELECT `+metaColumns+` FROM sessions WHERE id = ?`, sessionID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, notFound(sessionID)
+	}
+	return meta, err
+}
+
+func scanMeta(row scanner) (*sessions.SessionMeta, error) {
+	var (
+		meta                 sessions.SessionMeta
+		createdAt, updatedAt int64
+		usage                string
+	)
+	err := row.Scan(&meta.ID, &meta.Alias, &meta.Archived, &createdAt, &updatedAt,
+		&meta.MessageCount, &meta.Summary, &meta.SystemPrompt, &meta.Cost, &usage, &meta.ParentID, &meta.BranchPoint)
+	if err != nil {
+		return nil, err
+	}
+	meta.CreatedAt = time.Unix(0, createdAt)
+	meta.UpdatedAt = time.Unix(0, updatedAt)
+	if usage != "" {
+		if err := json.Unmarshal([]byte(usage), &meta.Usage); err != nil {
+			return nil, fmt.Errorf("decode usage of session %s: %w", meta.ID, err)
+		}
+	}
+	return &meta, nil
+}
+
+func insertMeta(tx *sql.Tx, meta *sessions.SessionMeta) error {
+	var usage string
+	if len(meta.Usage) > 0 {
+		data, err := json.Marshal(meta.Usage)
+		if err != nil {
+			return err
+		}
+		usage = string(data)
+	}
+	_, err := tx.Exec(`INSERT INTO sessions (`+metaColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		meta.ID, meta.Alias, meta.Archived, meta.CreatedAt.UnixNano(), meta.UpdatedAt.UnixNano(),
+		meta.MessageCount, meta.Summary, meta.SystemPrompt, meta.Cost, usage, meta.ParentID, meta.BranchPoint)
+	return err
+}
+
+// insertMessages stores msgs from index start on and returns how many it
+// stored. Token counts live in their own column so updating them does not
+// rewrite the messages.
+func insertMessages(tx *sql.Tx, sessionID string, start int, msgs []types.Message) (int, error) {
+	stmt, err := tx.Prepare(`INSERT INTO messages (session_id, idx, tokens, data) VALUES (?, ?, ?, ?)`)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	count := 0
+	for _, msg := range msgs {
+		tokens := msg.Tokens
+		msg.Tokens = 0
+		data, err := json.Marshal(msg)
+		if err != nil {
+			continue
+		}
+		if _, err := stmt.Exec(sessionID, start+count, tokens, string(data)); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+func deleteSession(tx *sql.Tx, sessionID string) error {
+	for _, table := range []string{"messages", "replaced_messages", "session_memory"} {
+		if _, err := tx.Exec(`DELETE FROM `+table+` WHERE session_id = ?`, sessionID); err != nil {
+			return err
+		}
+	}
+	_, err := tx.Exec(`DELETE FROM sessions WHERE id = ?`, sessionID)
+	return err
+}
+
+func requireSession(res sql.Result, sessionID string) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return notFound(sessionID)
+	}
+	return nil
+}
+
+func notFound(sessionID string) error {
+	return fmt.Errorf("session not found: %s: %w", sessionID, os.ErrNotExist)
+}
+
+var (
+	_ sessions.Store                  = (*SQLiteSessionStore)(nil)
+	_ sessions.Importer               = (*SQLiteSessionStore)(nil)
+	_ sessions.Searcher               = (*SQLiteSessionStore)(nil)
+	_ sessions.SessionMemoryDeleter   = (*SQLiteSessionStore)(nil)
+	_ coresession.MessageWriter       = (*SQLiteSessionStore)(nil)
+	_ coresession.MessageTokenUpdater = (*SQLiteSessionStore)(nil)
+	_ coresession.UsageWriter         = (*SQLiteSessionStore)(nil)
+	_ contextmgr.SessionMemoryStore   = (*SQLiteSessionStore)(nil)
+)
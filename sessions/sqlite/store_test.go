@@ -0,0 +1,300 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/basenana/friday/core/contextmgr"
+	"github.com/basenana/friday/core/providers"
+	"github.com/basenana/friday/core/types"
+	"github.com/basenana/friday/sessions"
+	"github.com/basenana/friday/sessions/file"
+)
+
+func newTestStore(t *testing.T) *SQLiteSessionStore {
+	t.Helper()
+	store := NewSQLiteSessionStore(filepath.Join(t.TempDir(), "sessions.db"))
+	if err := store.EnsureDir(); err != nil {
+		t.Fatalf("failed to ensure dir: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestAppendAndReplaceMessages(t *testing.T) {
+	store := newTestStore(t)
+	sessionID := "test-session-001"
+	sess, err := store.Create(sessionID, nil)
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+
+	sess.AppendMessage(
+		&types.Message{Role: types.RoleUser, Content: "Hello"},
+		&types.Message{Role: types.RoleAssistant, Content: "Hi there!", ToolCalls: []types.ToolCall{{ID: "call-1", Name: "bash", Arguments: `{"command":"ls"}`}}},
+	)
+	if err := store.AppendMessages(sessionID, types.Message{Role: types.RoleTool, ToolResult: &types.ToolResult{CallID: "call-1", Content: "go.mod"}}); err != nil {
+		t.Fatalf("failed to append messages: %v", err)
+	}
+
+	loaded, err := store.LoadMessages(sessionID)
+	if err != nil {
+		t.Fatalf("failed to load messages: %v", err)
+	}
+	if len(loaded) != 3 || loaded[1].ToolCalls[0].Name != "bash" || loaded[2].ToolResult.Content != "go.mod" {
+		t.Fatalf("unexpected history %+v", loaded)
+	}
+	if meta, _ := store.GetMeta(sessionID); meta.MessageCount != 3 {
+		t.Fatalf("expected message count 3, got %d", meta.MessageCount)
+	}
+
+	if err := store.ReplaceMessages(sessionID, types.Message{Role: types.RoleSystem, Content: "Summary of previous conversation..."}); err != nil {
+		t.Fatalf("failed to replace messages: %v", err)
+	}
+	loaded, err = store.LoadMessages(sessionID)
+	if err != nil {
+		t.Fatalf("failed to load messages: %v", err)
+	}
+	if len(loaded) != 1 || loaded[0].Role != types.RoleSystem {
+		t.Fatalf("expected only the summary after replace, got %+v", loaded)
+	}
+	if meta, _ := store.GetMeta(sessionID); meta.MessageCount != 1 {
+		t.Fatalf("expected message count 1 after replace, got %d", meta.MessageCount)
+	}
+
+	var backedUp int
+	if err := store.db.QueryRow(`SELECT COUNT(*) FROM replaced_messages WHERE session_id = ?`, sessionID).Scan(&backedUp); err != nil {
+		t.Fatalf("failed to count replaced messages: %v", err)
+	}
+	if backedUp != 3 {
+		t.Fatalf("expected the 3 replaced messages to be kept, got %d", backedUp)
+	}
+
+	// Appends continue after the replaced history.
+	if err := store.AppendMessages(sessionID, types.Message{Role: types.RoleUser, Content: "Go on"}); err != nil {
+		t.Fatalf("failed to append after replace: %v", err)
+	}
+	if loaded, _ = store.LoadMessages(sessionID); len(loaded) != 2 || loaded[1].Content != "Go on" {
+		t.Fatalf("unexpected history after append %+v", loaded)
+	}
+}
+
+func TestAppendMessagesToMissingSession(t *testing.T) {
+	store := newTestStore(t)
+	err := store.AppendMessages("missing", types.Message{Role: types.RoleUser, Content: "Hello"})
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected ErrNotExist, got %v", err)
+	}
+}
+
+func TestCalibratedMessageTokensPersistAcrossReload(t *testing.T) {
+	store := newTestStore(t)
+	sessionID := "test-session-tokens-001"
+	sess, err := store.Create(sessionID, nil)
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+
+	sess.AppendMessage(
+		&types.Message{Role: types.RoleUser, Content: "Hello world", Tokens: 42},
+		&types.Message{Role: types.RoleAssistant, Content: "Hi"},
+	)
+	if err := store.UpdateMessageTokens(sessionID, map[int]int64{1: 7, 5: 9}); err != nil {
+		t.Fatalf("failed to update tokens: %v", err)
+	}
+
+	reloaded, err := store.Load(sessionID, nil)
+	if err != nil {
+		t.Fatalf("failed to reload session: %v", err)
+	}
+	history := reloaded.GetHistory()
+	if len(history) != 2 || history[0].Tokens != 42 || history[1].Tokens != 7 {
+		t.Fatalf("expected persisted tokens 42 and 7, got %+v", history)
+	}
+}
+
+func TestListOrdersByCreationAndFiltersArchived(t *testing.T) {
+	store := newTestStore(t)
+	for _, id := range []string{"c", "a", "b"} {
+		if _, err := store.Create(id, nil); err != nil {
+			t.Fatalf("failed to create session %s: %v", id, err)
+		}
+	}
+	if err := store.UpdateAlias("a", "sess_pts1"); err != nil {
+		t.Fatalf("failed to update alias: %v", err)
+	}
+	if err := store.Archive("c"); err != nil {
+		t.Fatalf("failed to archive: %v", err)
+	}
+
+	all, err := store.List()
+	if err != nil {
+		t.Fatalf("failed to list: %v", err)
+	}
+	if len(all) != 3 || all[0].ID != "c" || all[1].ID != "a" || all[2].ID != "b" {
+		t.Fatalf("expected sessions in creation order, got %+v", all)
+	}
+	if !all[0].Archived || all[1].Alias != "sess_pts1" {
+		t.Fatalf("unexpected metadata %+v", all)
+	}
+
+	active, err := store.ListActive()
+	if err != nil {
+		t.Fatalf("failed to list active: %v", err)
+	}
+	if len(active) != 2 || active[0].ID != "a" {
+		t.Fatalf("expected archived session to be filtered, got %+v", active)
+	}
+
+	if err := store.Unarchive("c"); err != nil {
+		t.Fatalf("failed to unarchive: %v", err)
+	}
+	if err := store.Delete("a"); err != nil {
+		t.Fatalf("failed to delete: %v", err)
+	}
+	if active, _ = store.ListActive(); len(active) != 2 || active[0].ID != "c" {
+		t.Fatalf("unexpected sessions after unarchive and delete %+v", active)
+	}
+	if err := store.Archive("a"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected ErrNotExist archiving a deleted session, got %v", err)
+	}
+}
+
+func TestRecordUsagePersistsCostAcrossReload(t *testing.T) {
+	store := newTestStore(t)
+	sessionID := "test-session-usage-001"
+	sess, err := store.Create(sessionID, nil)
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+
+	sess.RecordUsage(providers.Tokens{Model: "gpt-4o", PromptTokens: 100, CompletionTokens: 10, Cost: 0.25})
+	sess.RecordUsage(providers.Tokens{Model: "gpt-4o", PromptTokens: 200, CachedPromptTokens: 50, CompletionTokens: 20, Cost: 0.5})
+
+	meta, err := store.GetMeta(sessionID)
+	if err != nil {
+		t.Fatalf("failed to load meta: %v", err)
+	}
+	if meta.Cost != 0.75 || len(meta.Usage) != 1 || meta.Usage[0].Calls != 2 || meta.Usage[0].CachedPromptTokens != 50 {
+		t.Fatalf("unexpected usage %+v", meta)
+	}
+
+	reloaded, err := store.Load(sessionID, nil)
+	if err != nil {
+		t.Fatalf("failed to reload session: %v", err)
+	}
+	if reloaded.Cost() != 0.75 {
+		t.Fatalf("expected reloaded cost 0.75, got %v", reloaded.Cost())
+	}
+}
+
+func TestSessionMemoryRoundTrip(t *testing.T) {
+	store := newTestStore(t)
+	sessionID := "test-session-memory-001"
+	if _, err := store.Create(sessionID, nil); err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+
+	if record, err := store.ReadSessionMemory(sessionID); err != nil || record != nil {
+		t.Fatalf("expected no session memory yet, got %+v, %v", record, err)
+	}
+	for _, status := range []string{"started", "wrote session memory"} {
+		record := &contextmgr.SessionMemoryRecord{TaskObjective: "persist session memory", CurrentStatus: status}
+		if err := store.WriteSessionMemory(sessionID, record); err != nil {
+			t.Fatalf("WriteSessionMemory failed: %v", err)
+		}
+	}
+
+	loaded, err := store.ReadSessionMemory(sessionID)
+	if err != nil {
+		t.Fatalf("ReadSessionMemory failed: %v", err)
+	}
+	if loaded == nil || loaded.CurrentStatus != "wrote session memory" {
+		t.Fatalf("expected the latest session memory record, got %+v", loaded)
+	}
+}
+
+func TestMigrateFromFileStore(t *testing.T) {
+	src := file.NewFileSessionStore(filepath.Join(t.TempDir(), "sessions"))
+	sess, err := src.Create("migrated", nil)
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+	sess.AppendMessage(
+		&types.Message{Role: types.RoleUser, Content: "Hello", Tokens: 3},
+		&types.Message{Role: types.RoleAssistant, Content: "Hi there!"},
+	)
+	sess.RecordUsage(providers.Tokens{Model: "gpt-4o", PromptTokens: 10, Cost: 0.5})
+	if err := src.UpdateAlias("migrated", "sess_pts1"); err != nil {
+		t.Fatalf("failed to update alias: %v", err)
+	}
+	if err := src.Archive("migrated"); err != nil {
+		t.Fatalf("failed to archive: %v", err)
+	}
+	if err := src.WriteSessionMemory("migrated", &contextmgr.SessionMemoryRecord{TaskObjective: "migrate"}); err != nil {
+		t.Fatalf("failed to write session memory: %v", err)
+	}
+	srcMeta, _ := src.GetMeta("migrated")
+
+	dst := newTestStore(t)
+	copied, err := sessions.Migrate(src, dst)
+	if err != nil || copied != 1 {
+		t.Fatalf("Migrate() = %d, %v; want 1 session", copied, err)
+	}
+
+	meta, err := dst.GetMeta("migrated")
+	if err != nil {
+		t.Fatalf("failed to load migrated meta: %v", err)
+	}
+	if meta.Alias != "sess_pts1" || !meta.Archived || meta.Cost != 0.5 || meta.MessageCount != 2 || len(meta.Usage) != 1 {
+		t.Fatalf("unexpected migrated meta %+v", meta)
+	}
+	if !meta.CreatedAt.Equal(srcMeta.CreatedAt) {
+		t.Fatalf("expected creation time %v, got %v", srcMeta.CreatedAt, meta.CreatedAt)
+	}
+	msgs, err := dst.LoadMessages("migrated")
+	if err != nil || len(msgs) != 2 || msgs[0].Tokens != 3 {
+		t.Fatalf("unexpected migrated history %+v, %v", msgs, err)
+	}
+	if record, err := dst.ReadSessionMemory("migrated"); err != nil || record == nil || record.TaskObjective != "migrate" {
+		t.Fatalf("unexpected migrated session memory %+v, %v", record, err)
+	}
+
+	// A second run skips sessions the target already has.
+	if copied, err := sessions.Migrate(src, dst); err != nil || copied != 0 {
+		t.Fatalf("second Migrate() = %d, %v; want 0 sessions", copied, err)
+	}
+}
+
+func TestMigrateAddsLineageColumns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sessions.db")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	if _, err := db.Exec(schema); err != nil {
+		t.Fatalf("failed to create the original schema: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO sessions (id, created_at, updated_at) VALUES ('old', 1, 1)`); err != nil {
+		t.Fatalf("failed to insert session: %v", err)
+	}
+	db.Close()
+
+	store := NewSQLiteSessionStore(path)
+	defer store.Close()
+	meta, err := store.GetMeta("old")
+	if err != nil || meta.ParentID != "" || meta.BranchPoint != 0 {
+		t.Fatalf("unexpected meta after migration %+v, %v", meta, err)
+	}
+
+	fork := sessions.SessionMeta{ID: "fork", ParentID: "old", BranchPoint: 3}
+	if err := store.ImportSession(fork, nil, nil); err != nil {
+		t.Fatalf("failed to import session: %v", err)
+	}
+	if meta, err = store.GetMeta("fork"); err != nil || meta.ParentID != "old" || meta.BranchPoint != 3 {
+		t.Fatalf("unexpected lineage %+v, %v", meta, err)
+	}
+}
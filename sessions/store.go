@@ -18,6 +18,16 @@ type SessionMeta struct {
 	MessageCount int       `json:"message_count"`
 	Summary      string    `json:"summary,omitempty"`
 	SystemPrompt string    `json:"system_prompt,omitempty"`
+
+	// Cost is what the session has spent on model calls, in USD; Usage
+	// breaks it down per day and model.
+	Cost  float64      `json:"cost,omitempty"`
+	Usage []UsageEntry `json:"usage,omitempty"`
+
+	// ParentID is the session this one was forked from; the fork started
+	// with the parent's first BranchPoint messages.
+	ParentID    string `json:"parent_id,omitempty"`
+	BranchPoint int    `json:"branch_point,omitempty"`
 }
 
 // Store defines the interface for session storage operations
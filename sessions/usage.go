@@ -0,0 +1,128 @@
+package sessions
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/basenana/friday/core/providers"
+)
+
+// UsageEntry aggregates the model calls of a session made on one day with
+// one model.
+type UsageEntry struct {
+	Date               string  `json:"date"`
+	Model              string  `json:"model,omitempty"`
+	Calls              int     `json:"calls"`
+	PromptTokens       int64   `json:"prompt_tokens"`
+	CachedPromptTokens int64   `json:"cached_prompt_tokens,omitempty"`
+	CompletionTokens   int64   `json:"completion_tokens"`
+	ReasoningTokens    int64   `json:"reasoning_tokens,omitempty"`
+	Cost               float64 `json:"cost"`
+}
+
+// UsageDateLayout is the layout of UsageEntry.Date.
+const UsageDateLayout = "2006-01-02"
+
+// AddUsage adds one model call made at the given time to the session's cost
+// and usage breakdown.
+func (m *SessionMeta) AddUsage(usage providers.Tokens, at time.Time) {
+	m.Cost += usage.Cost
+
+	date := at.Local().Format(UsageDateLayout)
+	var entry *UsageEntry
+	for i := range m.Usage {
+		if m.Usage[i].Date == date && m.Usage[i].Model == usage.Model {
+			entry = &m.Usage[i]
+			break
+		}
+	}
+	if entry == nil {
+		m.Usage = append(m.Usage, UsageEntry{Date: date, Model: usage.Model})
+		entry = &m.Usage[len(m.Usage)-1]
+	}
+	entry.Calls++
+	entry.PromptTokens += usage.PromptTokens
+	entry.CachedPromptTokens += usage.CachedPromptTokens
+	entry.CompletionTokens += usage.CompletionTokens
+	entry.ReasoningTokens += usage.ReasoningTokens
+	entry.Cost += usage.Cost
+}
+
+// Usage groupings of SummarizeUsage.
+const (
+	UsageByDay     = "day"
+	UsageByModel   = "model"
+	UsageBySession = "session"
+)
+
+// UsageSummary is the usage of one group of a cost report.
+type UsageSummary struct {
+	Key                string
+	Calls              int
+	PromptTokens       int64
+	CachedPromptTokens int64
+	CompletionTokens   int64
+	ReasoningTokens    int64
+	Cost               float64
+}
+
+// SummarizeUsage groups the usage of metas by day, model or session,
+// skipping usage recorded before since (a UsageDateLayout date, or empty for
+// all). Days are sorted chronologically, models and sessions by cost.
+func SummarizeUsage(metas []SessionMeta, by, since string) ([]UsageSummary, error) {
+	switch by {
+	case UsageByDay, UsageByModel, UsageBySession:
+	default:
+		return nil, fmt.Errorf("invalid grouping %q: want %s, %s or %s", by, UsageByDay, UsageByModel, UsageBySession)
+	}
+	if since != "" {
+		if _, err := time.Parse(UsageDateLayout, since); err != nil {
+			return nil, fmt.Errorf("invalid date %q: want YYYY-MM-DD", since)
+		}
+	}
+
+	groups := make(map[string]*UsageSummary)
+	for _, meta := range metas {
+		for _, u := range meta.Usage {
+			if since != "" && u.Date < since {
+				continue
+			}
+			var key string
+			switch by {
+			case UsageByDay:
+				key = u.Date
+			case UsageByModel:
+				key = u.Model
+				if key == "" {
+					key = "unknown"
+				}
+			case UsageBySession:
+				key = meta.ID
+			}
+			g, ok := groups[key]
+			if !ok {
+				g = &UsageSummary{Key: key}
+				groups[key] = g
+			}
+			g.Calls += u.Calls
+			g.PromptTokens += u.PromptTokens
+			g.CachedPromptTokens += u.CachedPromptTokens
+			g.CompletionTokens += u.CompletionTokens
+			g.ReasoningTokens += u.ReasoningTokens
+			g.Cost += u.Cost
+		}
+	}
+
+	result := make([]UsageSummary, 0, len(groups))
+	for _, g := range groups {
+		result = append(result, *g)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if by == UsageByDay || result[i].Cost == result[j].Cost {
+			return result[i].Key < result[j].Key
+		}
+		return result[i].Cost > result[j].Cost
+	})
+	return result, nil
+}
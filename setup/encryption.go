@@ -0,0 +1,34 @@
+package setup
+
+import (
+	"fmt"
+
+	"github.com/basenana/friday/config"
+	"github.com/basenana/friday/utils/crypt"
+)
+
+// DataKeyEnv is the environment variable holding the data encryption key.
+const DataKeyEnv = "FRIDAY_DATA_KEY"
+
+// DataKey loads the data encryption key from FRIDAY_DATA_KEY or the key file
+// or command of cfg, whether or not encryption is enabled.
+func DataKey(cfg *config.Config) ([]byte, error) {
+	return crypt.LoadKey(crypt.KeySource{
+		Env:     DataKeyEnv,
+		File:    cfg.ResolvePath(cfg.Encryption.KeyFile),
+		Command: cfg.Encryption.KeyCommand,
+	})
+}
+
+// DataCipher returns the cipher for sessions, memory logs and agent state,
+// or nil when encryption is disabled.
+func DataCipher(cfg *config.Config) (*crypt.Cipher, error) {
+	if !cfg.Encryption.Enabled {
+		return nil, nil
+	}
+	key, err := DataKey(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("load encryption key: %w", err)
+	}
+	return crypt.New(key)
+}
@@ -0,0 +1,150 @@
+package setup
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/basenana/friday/config"
+	"github.com/basenana/friday/core/tools"
+	"github.com/basenana/friday/mcp"
+)
+
+const mcpConnectTimeout = 30 * time.Second
+
+// MCPToolset is the result of connecting every enabled MCP server.
+// readOnly and mutating hold the namespaced tool names used to extend the
+// subagent tool policies.
+type MCPToolset struct {
+	servers  []*mcp.Server
+	tools    []*tools.Tool
+	readOnly []string
+	mutating []string
+}
+
+// ConnectMCP connects every enabled MCP server of cfg, so several agents can
+// share the connections through WithMCP. The caller closes them.
+func ConnectMCP(ctx context.Context, cfg *config.Config) *MCPToolset {
+	return connectMCPServers(ctx, cfg.MCPServers)
+}
+
+// Servers returns the connected servers of the set.
+func (s *MCPToolset) Servers() []*mcp.Server {
+	return s.servers
+}
+
+// Close disconnects every server of the set.
+func (s *MCPToolset) Close() {
+	for _, server := range s.servers {
+		_ = server.Close()
+	}
+}
+
+// CreateMCPServer builds an unconnected mcp.Server from its config entry.
+func CreateMCPServer(serverCfg config.MCPServerConfig) (*mcp.Server, error) {
+	if strings.TrimSpace(serverCfg.Name) == "" {
+		return nil, fmt.Errorf("mcp server name is empty")
+	}
+
+	switch serverCfg.TransportName() {
+	case "http":
+		if strings.TrimSpace(serverCfg.Endpoint) == "" {
+			return nil, fmt.Errorf("mcp server %s: endpoint is empty", serverCfg.Name)
+		}
+		return &mcp.Server{
+			Name:     serverCfg.Name,
+			Describe: serverCfg.Endpoint,
+			SSE: &mcp.MCPSse{
+				Endpoint: serverCfg.Endpoint,
+				Headers:  serverCfg.Headers,
+			},
+		}, nil
+	case "sse":
+		if strings.TrimSpace(serverCfg.Endpoint) == "" {
+			return nil, fmt.Errorf("mcp server %s: endpoint is empty", serverCfg.Name)
+		}
+		return &mcp.Server{
+			Name:     serverCfg.Name,
+			Describe: serverCfg.Endpoint,
+			LegacySSE: &mcp.MCPLegacySse{
+				Endpoint: serverCfg.Endpoint,
+				Headers:  serverCfg.Headers,
+			},
+		}, nil
+	case "stdio":
+		if strings.TrimSpace(serverCfg.Command) == "" {
+			return nil, fmt.Errorf("mcp server %s: command is empty", serverCfg.Name)
+		}
+		return &mcp.Server{
+			Name:     serverCfg.Name,
+			Describe: strings.Join(append([]string{serverCfg.Command}, serverCfg.Args...), " "),
+			Stdio: &mcp.MCPStdio{
+				Command: serverCfg.Command,
+				Args:    serverCfg.Args,
+				Env:     serverCfg.Env,
+				Cwd:     serverCfg.Cwd,
+			},
+		}, nil
+	default:
+		return nil, fmt.Errorf("mcp server %s: unknown transport: %s", serverCfg.Name, serverCfg.Transport)
+	}
+}
+
+// ConnectMCPServer creates, starts and initializes the server, then lists its
+// namespaced tools. The server is closed again when any step fails.
+func ConnectMCPServer(ctx context.Context, serverCfg config.MCPServerConfig) (*mcp.Server, []*tools.Tool, error) {
+	server, err := CreateMCPServer(serverCfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, mcpConnectTimeout)
+	defer cancel()
+
+	if err = server.Start(ctx); err != nil {
+		_ = server.Close()
+		return nil, nil, fmt.Errorf("mcp server %s: %w", serverCfg.Name, err)
+	}
+	serverTools, err := server.NamespacedTools(ctx)
+	if err != nil {
+		_ = server.Close()
+		return nil, nil, fmt.Errorf("mcp server %s: list tools: %w", serverCfg.Name, err)
+	}
+	return server, serverTools, nil
+}
+
+// connectMCPServers connects every enabled server. A server that fails to
+// connect is reported and skipped so one broken endpoint does not prevent
+// the agent from starting.
+func connectMCPServers(ctx context.Context, servers []config.MCPServerConfig) *MCPToolset {
+	result := &MCPToolset{}
+	for _, serverCfg := range servers {
+		if !serverCfg.IsEnabled() {
+			continue
+		}
+		server, serverTools, err := ConnectMCPServer(ctx, serverCfg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to connect MCP server: %v\n", err)
+			continue
+		}
+		result.servers = append(result.servers, server)
+		result.tools = append(result.tools, serverTools...)
+		for _, t := range serverTools {
+			if serverCfg.ReadOnly {
+				result.readOnly = append(result.readOnly, t.Name)
+			} else {
+				result.mutating = append(result.mutating, t.Name)
+			}
+		}
+	}
+
+	// Resources are read-only by nature, so the resource tools are offered to
+	// the read-only subagents as well.
+	for _, t := range mcp.NewResourceTools(result.servers) {
+		result.tools = append(result.tools, t)
+		result.readOnly = append(result.readOnly, t.Name)
+	}
+	return result
+}
@@ -0,0 +1,114 @@
+package setup
+
+import (
+	"context"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	mcpgo "github.com/mark3labs/mcp-go/mcp"
+	mcpserver "github.com/mark3labs/mcp-go/server"
+
+	"github.com/basenana/friday/config"
+	"github.com/basenana/friday/core/tools"
+	"github.com/basenana/friday/sessions"
+	"github.com/basenana/friday/sessions/file"
+)
+
+func startTestMCPServer(t *testing.T, names ...string) string {
+	t.Helper()
+	srv := mcpserver.NewMCPServer("test-mcp", "1.0.0")
+	for _, name := range names {
+		srv.AddTool(mcpgo.NewTool(name, mcpgo.WithDescription(name)), func(ctx context.Context, req mcpgo.CallToolRequest) (*mcpgo.CallToolResult, error) {
+			return mcpgo.NewToolResultText("ok: " + req.Params.Name), nil
+		})
+	}
+	httpSrv := httptest.NewServer(mcpserver.NewStreamableHTTPServer(srv))
+	t.Cleanup(httpSrv.Close)
+	return httpSrv.URL + "/mcp"
+}
+
+func TestCreateMCPServerValidation(t *testing.T) {
+	if _, err := CreateMCPServer(config.MCPServerConfig{Endpoint: "http://localhost"}); err == nil {
+		t.Fatalf("expected error for missing name")
+	}
+	if _, err := CreateMCPServer(config.MCPServerConfig{Name: "x"}); err == nil {
+		t.Fatalf("expected error for missing endpoint")
+	}
+	if _, err := CreateMCPServer(config.MCPServerConfig{Name: "x", Transport: "carrier-pigeon", Endpoint: "http://localhost"}); err == nil {
+		t.Fatalf("expected error for unknown transport")
+	}
+}
+
+func TestConnectMCPServersNamespacesTools(t *testing.T) {
+	githubURL := startTestMCPServer(t, "create_issue")
+	docsURL := startTestMCPServer(t, "search")
+	disabled := false
+
+	set := connectMCPServers(context.Background(), []config.MCPServerConfig{
+		{Name: "github", Endpoint: githubURL},
+		{Name: "docs", Endpoint: docsURL, ReadOnly: true},
+		{Name: "off", Endpoint: docsURL, Enabled: &disabled},
+		{Name: "broken", Endpoint: "http://127.0.0.1:1/mcp"},
+	})
+	defer set.Close()
+
+	if len(set.servers) != 2 {
+		t.Fatalf("expected 2 connected servers, got %d", len(set.servers))
+	}
+	if len(set.mutating) != 1 || set.mutating[0] != "github__create_issue" {
+		t.Fatalf("unexpected mutating tools: %v", set.mutating)
+	}
+	if len(set.readOnly) != 1 || set.readOnly[0] != "docs__search" {
+		t.Fatalf("unexpected read-only tools: %v", set.readOnly)
+	}
+
+	var createIssue *tools.Tool
+	for _, tool := range set.tools {
+		if tool.Name == "github__create_issue" {
+			createIssue = tool
+		}
+	}
+	if createIssue == nil {
+		t.Fatalf("github__create_issue not found in %d tools", len(set.tools))
+	}
+	result, err := createIssue.Handler(context.Background(), &tools.Request{Arguments: map[string]any{}})
+	if err != nil {
+		t.Fatalf("Handler() error = %v", err)
+	}
+	if got := tools.Res2Str(result); got == "" {
+		t.Fatalf("expected tool result content")
+	}
+}
+
+func TestNewAgentWithMCPSharesServers(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := config.DefaultConfig()
+	cfg.DataDir = filepath.Join(tmpDir, "data")
+	cfg.Workspace = filepath.Join(tmpDir, "workspace")
+	cfg.Model.Provider = "openai"
+	cfg.Model.Model = "test-model"
+	cfg.MCPServers = []config.MCPServerConfig{{Name: "github", Endpoint: startTestMCPServer(t, "create_issue")}}
+
+	set := ConnectMCP(context.Background(), cfg)
+	defer set.Close()
+	sessionMgr := sessions.NewManager(file.NewFileSessionStore(cfg.SessionsPath()), filepath.Join(cfg.DataDirPath(), "current"), "")
+
+	for i := 0; i < 2; i++ {
+		agentCtx, err := NewAgent(sessionMgr, cfg, WithMCP(set))
+		if err != nil {
+			t.Fatalf("NewAgent failed: %v", err)
+		}
+		var found bool
+		for _, tool := range agentCtx.Tools {
+			found = found || tool.Name == "github__create_issue"
+		}
+		if !found || len(agentCtx.MCPServers) != 0 {
+			t.Fatalf("run %d: expected the shared MCP tools and no owned servers, got %d servers", i, len(agentCtx.MCPServers))
+		}
+		agentCtx.Close()
+	}
+	if _, err := set.tools[0].Handler(context.Background(), &tools.Request{Arguments: map[string]any{}}); err != nil {
+		t.Fatalf("shared MCP server unusable after the agents closed: %v", err)
+	}
+}
@@ -8,10 +8,17 @@ import (
 	"sync"
 
 	"github.com/basenana/friday/config"
+	"github.com/basenana/friday/core/pricing"
 	"github.com/basenana/friday/core/providers"
 	"github.com/basenana/friday/core/providers/anthropics"
 	"github.com/basenana/friday/core/providers/fallback"
+	"github.com/basenana/friday/core/providers/gemini"
+	"github.com/basenana/friday/core/providers/ollama"
 	"github.com/basenana/friday/core/providers/openai"
+	"github.com/basenana/friday/core/providers/ratelimit"
+	"github.com/basenana/friday/core/providers/replay"
+	"github.com/basenana/friday/core/providers/router"
+	"github.com/basenana/friday/core/tokenizer"
 	"github.com/basenana/friday/core/types"
 )
 
@@ -51,7 +58,7 @@ func (a *imageAnalyzer) Analyze(ctx context.Context, prompt, modelOverride strin
 }
 
 func (a *imageAnalyzer) clientForModel(modelCfg config.ModelConfig) (providers.Client, error) {
-	key := fmt.Sprintf("%s|%s|%s|%s|%d|%d|%g|%d|%s",
+	key := fmt.Sprintf("%s|%s|%s|%s|%d|%d|%g|%d|%s|%s|%s|%t",
 		modelCfg.Provider,
 		modelCfg.BaseURL,
 		modelCfg.Key,
@@ -61,6 +68,9 @@ func (a *imageAnalyzer) clientForModel(modelCfg config.ModelConfig) (providers.C
 		modelCfg.Temperature,
 		modelCfg.QPM,
 		modelCfg.Proxy,
+		modelCfg.KeepAlive,
+		modelCfg.API,
+		modelCfg.ChainResponses,
 	)
 
 	a.mu.Lock()
@@ -70,7 +80,7 @@ func (a *imageAnalyzer) clientForModel(modelCfg config.ModelConfig) (providers.C
 		return client, nil
 	}
 
-	client, err := CreateProviderClientFromModel(modelCfg)
+	client, err := createConfiguredClient(a.cfg, modelCfg)
 	if err != nil {
 		return nil, err
 	}
@@ -78,29 +88,125 @@ func (a *imageAnalyzer) clientForModel(modelCfg config.ModelConfig) (providers.C
 	return client, nil
 }
 
+// CreateProviderClient creates the client of the configured chat models,
+// wrapped for recording or replay when FRIDAY_PROVIDER_MODE is set.
 func CreateProviderClient(cfg *config.Config) (providers.Client, error) {
+	client, err := createChatClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return replay.FromEnv(client)
+}
+
+func createChatClient(cfg *config.Config) (providers.Client, error) {
 	models := cfg.ChatModels()
 	if len(models) <= 1 {
 		// Single model — no fallback needed.
 		if len(models) == 1 {
-			return CreateProviderClientFromModel(models[0])
+			return createConfiguredClient(cfg, models[0])
 		}
-		return CreateProviderClientFromModel(cfg.PrimaryModel())
+		return createConfiguredClient(cfg, cfg.PrimaryModel())
+	}
+
+	if cfg.Router.Enabled {
+		return createRouterClient(cfg, models)
 	}
 
 	// Multiple models — wrap in FallbackClient.
 	entries := make([]fallback.ModelEntry, 0, len(models))
 	for _, m := range models {
-		c, err := CreateProviderClientFromModel(m)
+		c, err := createConfiguredClient(cfg, m)
 		if err != nil {
 			return nil, fmt.Errorf("create provider client for model %s: %w", m.Model, err)
 		}
 		entries = append(entries, fallback.ModelEntry{Client: c, Name: m.Model})
 	}
-	return fallback.NewFallbackClient(entries), nil
+	return fallback.NewFallbackClient(entries, fallback.WithStatePath(cfg.ModelHealthPath())), nil
 }
 
+func createRouterClient(cfg *config.Config, models []config.ModelConfig) (providers.Client, error) {
+	routes := make([]router.Route, 0, len(models))
+	for _, m := range models {
+		c, err := createConfiguredClient(cfg, m)
+		if err != nil {
+			return nil, fmt.Errorf("create provider client for model %s: %w", m.Model, err)
+		}
+		routes = append(routes, router.Route{
+			Client:        c,
+			Name:          m.Model,
+			ContextWindow: m.ContextWindow,
+			MaxTokens:     int64(m.MaxTokens),
+			Images:        m.HasInput("image"),
+			MaxTools:      m.MaxTools,
+			Tier:          strings.ToLower(strings.TrimSpace(m.Tier)),
+		})
+	}
+	return router.New(routes, router.WithSimpleTokens(cfg.Router.SimpleTokens)), nil
+}
+
+// CreateProviderClientFromModel creates the client of one model, metered with
+// its configured price or else its list price.
 func CreateProviderClientFromModel(modelCfg config.ModelConfig) (providers.Client, error) {
+	return createMeteredClient(modelCfg, nil)
+}
+
+// createConfiguredClient is CreateProviderClientFromModel pacing the model
+// with the limiter shared between processes when cfg asks for one.
+func createConfiguredClient(cfg *config.Config, modelCfg config.ModelConfig) (providers.Client, error) {
+	return createMeteredClient(modelCfg, sharedLimiter(cfg, modelCfg))
+}
+
+func createMeteredClient(modelCfg config.ModelConfig, limiter ratelimit.Limiter) (providers.Client, error) {
+	client, err := createModelClient(modelCfg, limiter)
+	if err != nil {
+		return nil, err
+	}
+	return pricing.Meter(client, modelCfg.Model, modelPrice(modelCfg)), nil
+}
+
+// sharedLimiter returns the limiter of the model's account shared under the
+// data dir, or nil when rate limits are not shared. Models on the same
+// provider, endpoint and key draw from one bucket.
+func sharedLimiter(cfg *config.Config, modelCfg config.ModelConfig) ratelimit.Limiter {
+	if cfg == nil || !cfg.RateLimit.Shared {
+		return nil
+	}
+	provider := strings.ToLower(modelCfg.Provider)
+	if provider == "" {
+		provider = "openai"
+	}
+	qpm := modelCfg.QPM
+	if qpm == 0 && provider != "ollama" {
+		qpm = 20 // the clients' default
+	}
+	key := strings.Join([]string{provider, modelCfg.BaseURL, modelCfg.Key}, "|")
+	return ratelimit.Shared(cfg.RateLimitPath(), key, qpm)
+}
+
+// ModelTokenizer returns the tokenizer configured for a model, else the one
+// matching its provider and name.
+func ModelTokenizer(modelCfg config.ModelConfig) (tokenizer.Tokenizer, error) {
+	if name := strings.TrimSpace(modelCfg.Tokenizer); name != "" {
+		tok, err := tokenizer.Get(name)
+		if err != nil {
+			return nil, fmt.Errorf("model %s: %w", modelCfg.Model, err)
+		}
+		return tok, nil
+	}
+	return tokenizer.ForModel(modelCfg.Provider, modelCfg.Model), nil
+}
+
+// modelPrice returns the configured price of a model, else its built-in
+// list price; models without either are free.
+func modelPrice(modelCfg config.ModelConfig) pricing.Price {
+	if p := modelCfg.Pricing; p != nil {
+		return pricing.Price{Input: p.Input, CachedInput: p.CachedInput, Output: p.Output, Reasoning: p.Reasoning}
+	}
+	price, _ := pricing.Lookup(modelCfg.Model)
+	return price
+}
+
+func createModelClient(modelCfg config.ModelConfig, limiter ratelimit.Limiter) (providers.Client, error) {
 	provider := strings.ToLower(modelCfg.Provider)
 
 	switch provider {
@@ -116,23 +222,56 @@ func CreateProviderClientFromModel(modelCfg config.ModelConfig) (providers.Clien
 			Temperature:   &temp,
 			MaxTokens:     &maxTokens,
 			QPM:           modelCfg.QPM,
+			Limiter:       limiter,
 			Proxy:         modelCfg.Proxy,
 			ContextWindow: modelCfg.ContextWindow,
 		}), nil
-	case "openai", "":
+	case "gemini":
 		host := modelCfg.BaseURL
 		if host == "" {
-			host = "https://api.openai.com/v1"
+			host = "https://generativelanguage.googleapis.com/v1beta"
 		}
 		temp := modelCfg.Temperature
-		return openai.New(host, modelCfg.Key, openai.Model{
+		maxTokens := int64(modelCfg.MaxTokens)
+		return gemini.New(host, modelCfg.Key, gemini.Model{
+			Name:          modelCfg.Model,
+			Temperature:   &temp,
+			MaxTokens:     &maxTokens,
+			QPM:           modelCfg.QPM,
+			Limiter:       limiter,
+			Proxy:         modelCfg.Proxy,
+			ContextWindow: modelCfg.ContextWindow,
+		}), nil
+	case "ollama":
+		temp := modelCfg.Temperature
+		return ollama.New(modelCfg.BaseURL, ollama.Model{
 			Name:          modelCfg.Model,
 			Temperature:   &temp,
 			MaxTokens:     int64(modelCfg.MaxTokens),
+			KeepAlive:     modelCfg.KeepAlive,
 			QPM:           modelCfg.QPM,
+			Limiter:       limiter,
 			Proxy:         modelCfg.Proxy,
 			ContextWindow: modelCfg.ContextWindow,
 		}), nil
+	case "openai", "":
+		host := modelCfg.BaseURL
+		if host == "" {
+			host = "https://api.openai.com/v1"
+		}
+		temp := modelCfg.Temperature
+		return openai.New(host, modelCfg.Key, openai.Model{
+			Name:           modelCfg.Model,
+			Temperature:    &temp,
+			MaxTokens:      int64(modelCfg.MaxTokens),
+			QPM:            modelCfg.QPM,
+			Limiter:        limiter,
+			Proxy:          modelCfg.Proxy,
+			ContextWindow:  modelCfg.ContextWindow,
+			API:            modelCfg.API,
+			ChainResponses: modelCfg.ChainResponses,
+			Cache:          providers.CacheStrategy(modelCfg.Cache),
+		}), nil
 	default:
 		return nil, fmt.Errorf("unknown provider: %s", modelCfg.Provider)
 	}
@@ -0,0 +1,85 @@
+package setup
+
+import (
+	"testing"
+
+	"github.com/basenana/friday/config"
+	"github.com/basenana/friday/core/providers"
+	"github.com/basenana/friday/core/providers/fallback"
+	"github.com/basenana/friday/core/providers/router"
+	"github.com/basenana/friday/core/tokenizer"
+	"github.com/basenana/friday/core/types"
+)
+
+func TestCreateChatClientRoutesWhenEnabled(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Models = []config.ModelConfig{
+		{Provider: "openai", Model: "gpt-4o", ContextWindow: 128000, Input: "text,image"},
+		{Provider: "openai", Model: "gpt-4o-mini", ContextWindow: 128000, Tier: "Cheap"},
+	}
+
+	client, err := createChatClient(cfg)
+	if err != nil {
+		t.Fatalf("createChatClient() error = %v", err)
+	}
+	if _, ok := client.(*fallback.FallbackClient); !ok {
+		t.Fatalf("expected fallback client without router, got %T", client)
+	}
+
+	cfg.Router = config.RouterConfig{Enabled: true, SimpleTokens: 1000}
+	client, err = createChatClient(cfg)
+	if err != nil {
+		t.Fatalf("createChatClient() error = %v", err)
+	}
+	r, ok := client.(*router.Router)
+	if !ok {
+		t.Fatalf("expected router client, got %T", client)
+	}
+	if got := r.Route(providers.NewPromptRequest("hi")).Name; got != "gpt-4o-mini" {
+		t.Fatalf("simple turn routed to %s, want gpt-4o-mini", got)
+	}
+	img := providers.NewRequest("", types.Message{Role: types.RoleUser, Image: &types.ImageContent{Type: types.ImageTypeURL, URL: "http://x/a.png"}})
+	if got := r.Route(img).Name; got != "gpt-4o" {
+		t.Fatalf("image turn routed to %s, want gpt-4o", got)
+	}
+}
+
+func TestSharedLimiterPerAccount(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.DataDir = t.TempDir()
+	model := config.ModelConfig{Provider: "openai", Key: "k1", Model: "gpt-4o"}
+
+	if sharedLimiter(cfg, model) != nil {
+		t.Fatal("expected no shared limiter unless rate_limit.shared is set")
+	}
+
+	cfg.RateLimit.Shared = true
+	l := sharedLimiter(cfg, model)
+	if l == nil {
+		t.Fatal("expected a shared limiter")
+	}
+	mini := model
+	mini.Model = "gpt-4o-mini"
+	if sharedLimiter(cfg, mini) != l {
+		t.Fatal("models of one account should share a limiter")
+	}
+	other := model
+	other.Key = "k2"
+	if sharedLimiter(cfg, other) == l {
+		t.Fatal("accounts with different keys should not share a limiter")
+	}
+}
+
+func TestModelTokenizer(t *testing.T) {
+	tok, err := ModelTokenizer(config.ModelConfig{Provider: "anthropic", Model: "claude-sonnet-4-5"})
+	if err != nil || tok.Name() != tokenizer.NameClaude {
+		t.Fatalf("ModelTokenizer() = %v, %v; want claude", tok, err)
+	}
+	tok, err = ModelTokenizer(config.ModelConfig{Provider: "ollama", Model: "qwen3:8b", Tokenizer: "o200k_base"})
+	if err != nil || tok.Name() != tokenizer.NameO200k {
+		t.Fatalf("ModelTokenizer() = %v, %v; want the configured o200k_base", tok, err)
+	}
+	if _, err = ModelTokenizer(config.ModelConfig{Model: "gpt-4o", Tokenizer: "nope"}); err == nil {
+		t.Fatal("expected an error for an unknown tokenizer")
+	}
+}
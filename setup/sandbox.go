@@ -0,0 +1,28 @@
+package setup
+
+import (
+	"fmt"
+
+	"github.com/basenana/friday/config"
+	"github.com/basenana/friday/sandbox"
+)
+
+// SandboxConfig returns the effective sandbox config for workdir: the user
+// config with the repository policy file (if any) merged on top. The second
+// return value is the policy file that was applied, or "".
+func SandboxConfig(cfg *config.Config, workdir string) (*sandbox.Config, string, error) {
+	userCfg := cfg.Sandbox
+	if userCfg == nil {
+		userCfg = sandbox.DefaultConfig()
+	}
+
+	policyPath := sandbox.FindPolicyFile(workdir)
+	if policyPath == "" {
+		return userCfg, "", nil
+	}
+	policy, err := sandbox.LoadPolicy(policyPath)
+	if err != nil {
+		return nil, policyPath, fmt.Errorf("load sandbox policy: %w", err)
+	}
+	return userCfg.Merge(policy), policyPath, nil
+}
@@ -16,12 +16,14 @@ import (
 	coreSession "github.com/basenana/friday/core/session"
 	"github.com/basenana/friday/core/subagents"
 	"github.com/basenana/friday/core/tools"
+	"github.com/basenana/friday/mcp"
 	"github.com/basenana/friday/memory"
 	"github.com/basenana/friday/proposals"
 	"github.com/basenana/friday/sandbox"
 	"github.com/basenana/friday/sessions"
 	"github.com/basenana/friday/skills"
 	"github.com/basenana/friday/teams"
+	"github.com/basenana/friday/utils/crypt"
 	"github.com/basenana/friday/workspace"
 )
 
@@ -32,6 +34,15 @@ type AgentContext struct {
 	Agent       agents.Agent
 	Memory      *memory.MemorySystem
 	TaskManager *sandbox.TaskManager
+	// MCPServers are the servers this context connected and closes; it is
+	// empty for agents built WithMCP.
+	MCPServers []*mcp.Server
+	// Tools is the tool set given to the main agent, including MCP tools.
+	Tools []*tools.Tool
+	// Skills is the registry behind the skill hook's list/load tools.
+	Skills *skills.Registry
+
+	sandboxExec *sandbox.Executor
 }
 
 type Option func(*options)
@@ -42,6 +53,8 @@ type options struct {
 	temporary  bool
 	verbose    bool
 	extraTools []*tools.Tool
+	approver   sandbox.Approver
+	mcp        *MCPToolset
 }
 
 type SessionManager interface {
@@ -83,6 +96,22 @@ func WithExtraTools(t []*tools.Tool) Option {
 	}
 }
 
+// WithApprover lets sandboxed commands that need approval ask the user.
+// Without it they fall back to the sandbox ask_fallback policy.
+func WithApprover(approver sandbox.Approver) Option {
+	return func(o *options) {
+		o.approver = approver
+	}
+}
+
+// WithMCP uses the already connected MCP servers of set instead of
+// connecting them for this agent. The agent does not close them.
+func WithMCP(set *MCPToolset) Option {
+	return func(o *options) {
+		o.mcp = set
+	}
+}
+
 func NewAgent(sessionMgr SessionManager, cfg *config.Config, opts ...Option) (*AgentContext, error) {
 	options := &options{}
 	for _, opt := range opts {
@@ -101,8 +130,22 @@ func NewAgent(sessionMgr SessionManager, cfg *config.Config, opts ...Option) (*A
 		return nil, fmt.Errorf("create workspace: %w", err)
 	}
 
-	fileState := workspace.NewFileState(cfg.StatePath())
-	sessionOpts := []coreSession.Option{coreSession.WithState(fileState)}
+	tok, err := ModelTokenizer(cfg.PrimaryModel())
+	if err != nil {
+		return nil, err
+	}
+
+	dataCipher := cipherFromManager(sessionMgr)
+	if cfg.Encryption.Enabled && dataCipher == nil {
+		return nil, fmt.Errorf("encryption is enabled but the session store does not encrypt")
+	}
+
+	fileState := newFileState(cfg, dataCipher)
+	sessionOpts := []coreSession.Option{
+		coreSession.WithState(fileState),
+		coreSession.WithCostBudget(cfg.Session.Budget),
+		coreSession.WithTokenizer(tok),
+	}
 
 	var sess *coreSession.Session
 	switch {
@@ -138,6 +181,7 @@ func NewAgent(sessionMgr SessionManager, cfg *config.Config, opts ...Option) (*A
 	}
 
 	memSys := memory.NewMemorySystem(cfg.MemoryPath(), cfg.Memory.Days)
+	memSys.SetCipher(dataCipher)
 	if err = memSys.EnsureTodayMemory(); err != nil {
 		fmt.Fprintf(os.Stderr, "Warning: failed to ensure memory log: %v\n", err)
 	}
@@ -174,11 +218,23 @@ func NewAgent(sessionMgr SessionManager, cfg *config.Config, opts ...Option) (*A
 	workdir, _ := os.Getwd()
 
 	var allTools []*tools.Tool
-	sandboxCfg := cfg.Sandbox
-	if sandboxCfg == nil {
-		sandboxCfg = sandbox.DefaultConfig()
+	sandboxCfg, _, err := SandboxConfig(cfg, workdir)
+	if err != nil {
+		return nil, err
 	}
 	sandboxExec := sandbox.NewExecutor(sandboxCfg)
+	sandboxExec.SetApprover(options.approver)
+	sandboxExec.SetCipher(cfg.MemoryPath(), dataCipher)
+	if sandboxCfg.Sandbox.Overlay {
+		if !sandboxExec.SupportsOverlay() {
+			return nil, fmt.Errorf("sandbox overlay requires the bubblewrap or container backend (current sandbox: %s)", sandboxExec.SandboxName())
+		}
+		overlay, err := sandbox.OpenOverlay(cfg.OverlaysPath(), workdir)
+		if err != nil {
+			return nil, fmt.Errorf("open sandbox overlay: %w", err)
+		}
+		sandboxExec.SetOverlay(overlay)
+	}
 	fsTools := sandbox.NewFsTools(sandboxExec, workdir)
 	allTools = append(allTools, fsTools...)
 	imageTool := sandbox.NewImageTool(sandboxExec, workdir, newImageAnalyzer(cfg))
@@ -188,6 +244,25 @@ func NewAgent(sessionMgr SessionManager, cfg *config.Config, opts ...Option) (*A
 	taskManager := sandbox.NewTaskManager(sandboxExec)
 	bgTools := sandbox.NewBackgroundTaskTools(taskManager, workdir)
 	allTools = append(allTools, bgTools...)
+	if searcher := sessionSearcherFromManager(sessionMgr); searcher != nil {
+		allTools = append(allTools, sessions.NewSearchTool(searcher))
+	}
+
+	// MCP servers: remote tools are namespaced as <server>__<tool>. Read-only
+	// servers are also offered to the read-only subagents; the rest are kept
+	// away from them.
+	mcpSet := options.mcp
+	var ownedMCP []*mcp.Server
+	if mcpSet == nil {
+		mcpSet = connectMCPServers(context.Background(), cfg.MCPServers)
+		ownedMCP = mcpSet.servers
+	}
+	closeMCP := func() {
+		for _, server := range ownedMCP {
+			_ = server.Close()
+		}
+	}
+	allTools = append(allTools, mcpSet.tools...)
 
 	if len(options.extraTools) > 0 {
 		allTools = append(allTools, options.extraTools...)
@@ -202,11 +277,15 @@ func NewAgent(sessionMgr SessionManager, cfg *config.Config, opts ...Option) (*A
 	// (possibly overridden) provider client and a tool set filtered by the
 	// spec's ToolPolicy. The explorer reuses the main system prompt so forked
 	// sessions share the same cache prefix.
-	factory := coderagents.NewClientFactory(client, cfg.PrimaryModel(), CreateProviderClientFromModel)
+	factory := coderagents.NewClientFactory(client, cfg.PrimaryModel(), func(m config.ModelConfig) (providers.Client, error) {
+		return createConfiguredClient(cfg, m)
+	})
 	exploreSpec := coderagents.ExplorerSpec(cfg.AgentModel(coderagents.NameExplorer))
 	exploreSpec.SystemPrompt = workspace.ComposeSystemPrompt(loaded)
+	exploreSpec.ToolPolicy = exploreSpec.ToolPolicy.WithExternal(mcpSet.readOnly, mcpSet.mutating)
 	exploreAgent, err := factory.BuildAgent(exploreSpec, allTools)
 	if err != nil {
+		closeMCP()
 		return nil, fmt.Errorf("build explore agent: %w", err)
 	}
 
@@ -216,8 +295,12 @@ func NewAgent(sessionMgr SessionManager, cfg *config.Config, opts ...Option) (*A
 		coderagents.ReviewerSpec(cfg.AgentModel(coderagents.NameReviewer)),
 		coderagents.AdvisorSpec(cfg.AgentModel(coderagents.NameAdvisor)),
 	}
+	for _, spec := range expertSpecs {
+		spec.ToolPolicy = spec.ToolPolicy.WithExternal(mcpSet.readOnly, mcpSet.mutating)
+	}
 	expertAgents, err := factory.BuildExpertAgents(expertSpecs, allTools)
 	if err != nil {
+		closeMCP()
 		return nil, fmt.Errorf("build expert agents: %w", err)
 	}
 
@@ -247,7 +330,7 @@ func NewAgent(sessionMgr SessionManager, cfg *config.Config, opts ...Option) (*A
 	// package stays free of agent-construction concerns.
 	proposalLoader := proposals.NewLoader(cfg.ProposalsPath())
 	proposalRunnerFactory := buildProposalRunnerFactory(
-		client, allTools, sharedHooks, sessionMgr, teamRegistry, cfg, loaded,
+		client, allTools, sharedHooks, sessionMgr, dataCipher, teamRegistry, cfg, loaded,
 	)
 	sess.RegisterHook(proposals.NewHook(proposalLoader, proposalRunnerFactory))
 
@@ -258,6 +341,10 @@ func NewAgent(sessionMgr SessionManager, cfg *config.Config, opts ...Option) (*A
 		Agent:       agent,
 		Memory:      memSys,
 		TaskManager: taskManager,
+		MCPServers:  ownedMCP,
+		Tools:       allTools,
+		Skills:      skillRegistry,
+		sandboxExec: sandboxExec,
 	}, nil
 }
 
@@ -265,6 +352,12 @@ func NewAgent(sessionMgr SessionManager, cfg *config.Config, opts ...Option) (*A
 // KillAll runs first so in-flight tasks are stopped before the session event bus is torn down.
 func (ac *AgentContext) Close() {
 	ac.TaskManager.KillAll()
+	for _, server := range ac.MCPServers {
+		_ = server.Close()
+	}
+	if ac.sandboxExec != nil {
+		_ = ac.sandboxExec.Close()
+	}
 	ac.Session.Close()
 }
 
@@ -289,6 +382,41 @@ func sessionMemoryStoreFromManager(sessionMgr SessionManager) contextmgr.Session
 	return store
 }
 
+// newFileState returns the agent state store, encrypted with c.
+func newFileState(cfg *config.Config, c *crypt.Cipher) *workspace.FileState {
+	fileState := workspace.NewFileState(cfg.StatePath())
+	fileState.SetCipher(c)
+	return fileState
+}
+
+// cipherFromManager returns the cipher of the session store, so memory logs
+// and agent state are encrypted like the sessions.
+func cipherFromManager(sessionMgr SessionManager) *crypt.Cipher {
+	provider, ok := sessionMgr.(interface{ GetStore() sessions.Store })
+	if !ok {
+		return nil
+	}
+
+	store, ok := provider.GetStore().(interface{ Cipher() *crypt.Cipher })
+	if !ok {
+		return nil
+	}
+	return store.Cipher()
+}
+
+func sessionSearcherFromManager(sessionMgr SessionManager) sessions.Searcher {
+	provider, ok := sessionMgr.(interface{ GetStore() sessions.Store })
+	if !ok {
+		return nil
+	}
+
+	searcher, ok := provider.GetStore().(sessions.Searcher)
+	if !ok {
+		return nil
+	}
+	return searcher
+}
+
 func (ac *AgentContext) ChatWithImageRefs(ctx context.Context, message string, imageRefs ...string) *api.Response {
 	if len(imageRefs) > 0 {
 		message = appendImageRefsToMessage(message, imageRefs)
@@ -354,6 +482,7 @@ func buildProposalRunnerFactory(
 	allTools []*tools.Tool,
 	sharedHooks []coreSession.Hook,
 	sessionMgr SessionManager,
+	dataCipher *crypt.Cipher,
 	teamRegistry *teams.Registry,
 	cfg *config.Config,
 	loaded *workspace.LoadedContent,
@@ -389,7 +518,7 @@ func buildProposalRunnerFactory(
 			sessionFactory := proposals.SessionFactory(func(proposalID, assignee string) (*coreSession.Session, error) {
 				key := fmt.Sprintf("proposal-%s-%s", proposalID, assignee)
 				s, _, err := getOrCreateManagedSession(
-					sessionMgr, key, sharedHooks, coreSession.WithState(workspace.NewFileState(cfg.StatePath())),
+					sessionMgr, key, sharedHooks, coreSession.WithState(newFileState(cfg, dataCipher)),
 				)
 				if err != nil {
 					return nil, err
@@ -414,7 +543,7 @@ func buildProposalRunnerFactory(
 		proposalKey := fmt.Sprintf("proposal-%s", proposal.ID)
 		proposalSession, _, err := getOrCreateManagedSession(
 			sessionMgr, proposalKey, sharedHooks,
-			coreSession.WithState(workspace.NewFileState(cfg.StatePath())),
+			coreSession.WithState(newFileState(cfg, dataCipher)),
 		)
 		if err != nil {
 			return nil, nil, fmt.Errorf("create proposal session: %w", err)
@@ -0,0 +1,130 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/basenana/friday/sandbox"
+)
+
+// approvalRequest is a sandbox Ask decision waiting for the user. reply is
+// buffered so answering never blocks, even after the run was cancelled.
+type approvalRequest struct {
+	req   sandbox.ApprovalRequest
+	reply chan sandbox.Approval
+}
+
+// approvalMsg delivers a pending approvalRequest to the Update loop.
+type approvalMsg approvalRequest
+
+// approvalBroker hands approval requests from actor goroutines to the TUI.
+type approvalBroker struct {
+	requests chan approvalRequest
+}
+
+func newApprovalBroker() *approvalBroker {
+	return &approvalBroker{requests: make(chan approvalRequest)}
+}
+
+// approve is the sandbox.Approver installed on every actor run. It blocks
+// until the user answers or the run is cancelled.
+func (b *approvalBroker) approve(ctx context.Context, req sandbox.ApprovalRequest) (sandbox.Approval, error) {
+	pending := approvalRequest{req: req, reply: make(chan sandbox.Approval, 1)}
+	select {
+	case b.requests <- pending:
+	case <-ctx.Done():
+		return sandbox.Reject, ctx.Err()
+	}
+	select {
+	case approval := <-pending.reply:
+		return approval, nil
+	case <-ctx.Done():
+		return sandbox.Reject, ctx.Err()
+	}
+}
+
+// waitForApproval returns a tea.Cmd that blocks for the next approval request.
+func (m *model) waitForApproval() tea.Cmd {
+	if m.approvals == nil {
+		return nil
+	}
+	requests := m.approvals.requests
+	return func() tea.Msg {
+		return approvalMsg(<-requests)
+	}
+}
+
+// showApproval makes req the pending approval and prints the prompt.
+func (m *model) showApproval(req approvalRequest) {
+	m.flushStreaming()
+	m.pendingApproval = &req
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Permission required: %s\n", req.req.Command)
+	fmt.Fprintf(&b, "  %s\n", req.req.Reason)
+	b.WriteString("  [y] approve once")
+	if len(req.req.Patterns) > 0 {
+		fmt.Fprintf(&b, "  [a] always allow %s", strings.Join(req.req.Patterns, ", "))
+	}
+	b.WriteString("  [n] deny")
+	m.appendBlock(chatBlock{kind: blockError, content: b.String()})
+}
+
+// handleApprovalKey answers the pending approval from a key press. It
+// reports false when the key is not an answer.
+func (m *model) handleApprovalKey(msg tea.KeyMsg) (tea.Cmd, bool) {
+	var approval sandbox.Approval
+	switch {
+	case msg.Type == tea.KeyEsc:
+		approval = sandbox.Reject
+	case msg.Type == tea.KeyRunes && len(msg.Runes) == 1:
+		switch msg.Runes[0] {
+		case 'y', 'Y':
+			approval = sandbox.ApproveOnce
+		case 'a', 'A':
+			approval = sandbox.ApproveAlways
+		case 'n', 'N':
+			approval = sandbox.Reject
+		default:
+			return nil, false
+		}
+	default:
+		return nil, false
+	}
+	return m.answerApproval(approval), true
+}
+
+// answerApproval replies to the pending approval. "Always" rules are added to
+// the in-memory config and to the allow list in the config file so future
+// sessions do not ask again.
+func (m *model) answerApproval(approval sandbox.Approval) tea.Cmd {
+	pending := m.pendingApproval
+	if pending == nil {
+		return nil
+	}
+	m.pendingApproval = nil
+
+	if approval == sandbox.ApproveAlways {
+		if len(pending.req.Patterns) == 0 {
+			approval = sandbox.ApproveOnce
+		} else if m.cfg == nil {
+			m.appendBlock(chatBlock{kind: blockError, content: "no config loaded: the allow rule applies to this run only"})
+		} else {
+			if m.cfg.Sandbox == nil {
+				m.cfg.Sandbox = sandbox.DefaultConfig()
+			}
+			for _, pattern := range pending.req.Patterns {
+				m.cfg.Sandbox.AllowCommand(pattern)
+			}
+			if err := m.cfg.SaveAllowRules(pending.req.Patterns...); err != nil {
+				m.appendBlock(chatBlock{kind: blockError, content: "failed to save allow rule: " + err.Error()})
+			}
+		}
+	}
+	pending.reply <- approval
+	m.appendBlock(chatBlock{kind: blockUser, content: "[" + approval.String() + "] " + pending.req.Command})
+	return m.waitForApproval()
+}
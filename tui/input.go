@@ -31,6 +31,7 @@ func (m *model) handleSlash(text string) (tea.Model, tea.Cmd) {
 		SessMgr:   m.sessMgr,
 		ActorReg:  m.registry,
 		Config:    m.cfg,
+		Busy:      m.running,
 	})
 	if err != nil {
 		m.appendBlock(chatBlock{kind: blockError, content: err.Error()})
@@ -57,6 +58,13 @@ func (m *model) applyResult(r *codercmds.Result) (tea.Model, tea.Cmd) {
 			cmds = append(cmds, cmd)
 		}
 	}
+	if r.ReloadSession && (r.SwitchSession == "" || r.SwitchSession == m.sessionID) {
+		if cmd, err := m.switchSession(m.sessionID); err != nil {
+			m.appendBlock(chatBlock{kind: blockError, content: err.Error()})
+		} else if cmd != nil {
+			cmds = append(cmds, cmd)
+		}
+	}
 	if r.Quit {
 		m.quitting = true
 		m.closeSubscription()
@@ -68,12 +76,33 @@ func (m *model) applyResult(r *codercmds.Result) (tea.Model, tea.Cmd) {
 			cmds = append(cmds, cmd)
 		}
 	}
+	if r.UserMessage != "" {
+		if m.running {
+			m.appendBlock(chatBlock{kind: blockError, content: "a task is already running; wait or cancel first"})
+		} else if cmd := m.sendUserMessage(r.UserMessage); cmd != nil {
+			cmds = append(cmds, cmd)
+		}
+	}
 	if r.Message != "" {
 		m.appendBlock(chatBlock{kind: blockAssistant, content: r.Message})
 	}
 	return m, tea.Batch(cmds...)
 }
 
+// sendUserMessage shows text as a user block and sends it to the main actor.
+func (m *model) sendUserMessage(text string) tea.Cmd {
+	m.appendBlock(chatBlock{kind: blockUser, content: text})
+	if !m.actor.Send(actor.Message{Content: text}) {
+		m.appendBlock(chatBlock{kind: blockError, content: "inbox full, try again"})
+		return nil
+	}
+	m.running = true
+	m.textBuf.Reset()
+	m.reasonBuf.Reset()
+	m.toolCalls = make(map[string]*toolCallBlock)
+	return m.spinner.Tick
+}
+
 // switchSession synchronously tears down the current actor binding and binds
 // to newID. Returns a tea.Cmd (waitForActorEvent) for the new subscription.
 func (m *model) switchSession(newID string) (tea.Cmd, error) {
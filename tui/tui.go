@@ -1,7 +1,9 @@
 package tui
 
 import (
+	"context"
 	"fmt"
+	"os"
 	"strings"
 
 	"github.com/charmbracelet/bubbles/spinner"
@@ -13,7 +15,10 @@ import (
 	"github.com/basenana/friday/actor"
 	codercmds "github.com/basenana/friday/coder/commands"
 	"github.com/basenana/friday/config"
+	"github.com/basenana/friday/mcp"
+	"github.com/basenana/friday/sandbox"
 	"github.com/basenana/friday/sessions"
+	"github.com/basenana/friday/setup"
 )
 
 const subscriptionBuffer = 256
@@ -33,7 +38,18 @@ func Run(sessMgr *sessions.Manager, cfg *config.Config, sessionID string) error
 		}
 	}
 
-	registry := actor.NewRegistry(sessMgr, cfg, actor.DefaultRegistryConfig())
+	// Commands that need sandbox approval are asked for in the chat view.
+	approvals := newApprovalBroker()
+	registryCfg := actor.DefaultRegistryConfig()
+	registryCfg.AgentOptions = append(registryCfg.AgentOptions, setup.WithApprover(approvals.approve))
+
+	// MCP servers are connected once and shared by every session's actor,
+	// so their prompts can be listed before the first run.
+	mcpSet := setup.ConnectMCP(context.Background(), cfg)
+	defer mcpSet.Close()
+	registryCfg.MCP = mcpSet
+
+	registry := actor.NewRegistry(sessMgr, cfg, registryCfg)
 	defer registry.ShutdownAll()
 
 	cmdRegistry := codercmds.NewRegistry()
@@ -41,10 +57,16 @@ func Run(sessMgr *sessions.Manager, cfg *config.Config, sessionID string) error
 	codercmds.RegisterInfoCommands(cmdRegistry)
 	codercmds.RegisterAgentCommands(cmdRegistry)
 
+	// MCP prompts become /<server>:<prompt> commands.
+	if err := codercmds.RegisterMCPPromptCommands(context.Background(), cmdRegistry, promptServers(mcpSet)); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+
 	m, err := initialModel(sessMgr, registry, cmdRegistry, cfg, sessionID)
 	if err != nil {
 		return err
 	}
+	m.approvals = approvals
 	p := tea.NewProgram(m, tea.WithAltScreen(), tea.WithMouseCellMotion())
 	_, err = p.Run()
 	return err
@@ -60,6 +82,9 @@ type model struct {
 	cmdRegistry *codercmds.Registry
 	cfg         *config.Config
 
+	approvals       *approvalBroker
+	pendingApproval *approvalRequest
+
 	unsubscribe       func()
 	subscriptionToken uint64
 
@@ -121,6 +146,7 @@ func (m *model) Init() tea.Cmd {
 		textarea.Blink,
 		m.spinner.Tick,
 		m.waitForActorEvent(),
+		m.waitForApproval(),
 	)
 }
 
@@ -139,7 +165,16 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.invalidateRendered()
 		return m, nil
 
+	case approvalMsg:
+		m.showApproval(approvalRequest(msg))
+		return m, nil
+
 	case tea.KeyMsg:
+		if m.pendingApproval != nil {
+			if cmd, ok := m.handleApprovalKey(msg); ok {
+				return m, cmd
+			}
+		}
 		switch msg.Type {
 		case tea.KeyCtrlC:
 			if m.running {
@@ -168,16 +203,7 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if strings.HasPrefix(text, "/") {
 				return m.handleSlash(text)
 			}
-			m.appendBlock(chatBlock{kind: blockUser, content: text})
-			if !m.actor.Send(actor.Message{Content: text}) {
-				m.appendBlock(chatBlock{kind: blockError, content: "inbox full, try again"})
-				return m, nil
-			}
-			m.running = true
-			m.textBuf.Reset()
-			m.reasonBuf.Reset()
-			m.toolCalls = make(map[string]*toolCallBlock)
-			return m, m.spinner.Tick
+			return m, m.sendUserMessage(text)
 		}
 
 	case actorEventMsg:
@@ -225,6 +251,7 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 // cancelRun aborts the current agent run while keeping the TUI alive.
 func (m *model) cancelRun() (tea.Model, tea.Cmd) {
+	approvalCmd := m.answerApproval(sandbox.Reject)
 	m.closeSubscription()
 	m.registry.Shutdown(m.sessionID)
 	m.flushStreaming()
@@ -232,9 +259,9 @@ func (m *model) cancelRun() (tea.Model, tea.Cmd) {
 	m.running = false
 	if err := m.bindSession(m.sessionID); err != nil {
 		m.appendBlock(chatBlock{kind: blockError, content: err.Error()})
-		return m, nil
+		return m, approvalCmd
 	}
-	return m, m.waitForActorEvent()
+	return m, tea.Batch(m.waitForActorEvent(), approvalCmd)
 }
 
 // handleActorEvent maps an AG-UI event to model state mutations.
@@ -398,3 +425,14 @@ func toInt(v any) (int, bool) {
 	}
 	return 0, false
 }
+
+// promptServers returns the servers of set that offer prompts.
+func promptServers(set *setup.MCPToolset) []*mcp.Server {
+	var servers []*mcp.Server
+	for _, server := range set.Servers() {
+		if server.HasPrompts() {
+			servers = append(servers, server)
+		}
+	}
+	return servers
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/basenana/friday/actor"
 	codercmds "github.com/basenana/friday/coder/commands"
 	"github.com/basenana/friday/config"
+	"github.com/basenana/friday/sandbox"
 	"github.com/basenana/friday/sessions"
 	sessionfile "github.com/basenana/friday/sessions/file"
 )
@@ -194,3 +195,51 @@ func TestViewAutoScrollsOnlyWhenAlreadyAtBottom(t *testing.T) {
 		t.Fatalf("YOffset = %d, want %d while reading history", m.viewport.YOffset, offset)
 	}
 }
+
+func TestApprovalPromptAnswers(t *testing.T) {
+	m, _, _ := newTestModel(t)
+	cfg, err := config.Load(filepath.Join(t.TempDir(), "config.json"))
+	if err != nil {
+		t.Fatalf("config.Load() failed: %v", err)
+	}
+	m.cfg = cfg
+	m.approvals = newApprovalBroker()
+
+	ask := func(key rune) sandbox.Approval {
+		t.Helper()
+		req := approvalRequest{
+			req:   sandbox.ApprovalRequest{Command: "terraform plan", Reason: "not in allow list", Patterns: []string{"terraform plan"}},
+			reply: make(chan sandbox.Approval, 1),
+		}
+		m.Update(approvalMsg(req))
+		if m.pendingApproval == nil {
+			t.Fatal("approval should be pending")
+		}
+		m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{key}})
+		if m.pendingApproval != nil {
+			t.Fatal("approval should be answered")
+		}
+		return <-req.reply
+	}
+
+	if got := ask('y'); got != sandbox.ApproveOnce {
+		t.Errorf("y answered %v, want approve", got)
+	}
+	if got := ask('n'); got != sandbox.Reject {
+		t.Errorf("n answered %v, want reject", got)
+	}
+	// A config without a sandbox section gets one for the rule.
+	cfg.Sandbox = nil
+	if got := ask('a'); got != sandbox.ApproveAlways {
+		t.Errorf("a answered %v, want always", got)
+	}
+
+	saved, err := config.Load(cfg.Path())
+	if err != nil {
+		t.Fatalf("reload config failed: %v", err)
+	}
+	allow := saved.Sandbox.Permissions.Allow
+	if len(allow) == 0 || allow[len(allow)-1] != "terraform plan" {
+		t.Errorf("saved allow list = %v, want terraform plan appended", allow)
+	}
+}
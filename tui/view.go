@@ -242,7 +242,9 @@ func (m *model) renderStatus() string {
 	if m.iteration > 0 {
 		parts = append(parts, "loop:"+itoa(m.iteration))
 	}
-	if m.running {
+	if m.pendingApproval != nil {
+		parts = append(parts, "? approve [y/a/n]")
+	} else if m.running {
 		parts = append(parts, "● running")
 	}
 	return strings.Join(parts, " · ")
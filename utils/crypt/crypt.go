@@ -0,0 +1,303 @@
+// Package crypt encrypts friday's data files with AES-256-GCM.
+//
+// An encrypted file starts with a header line followed by one line per
+// record, each the base64 of a random nonce and the sealed data. Appending a
+// record leaves the earlier ones alone, so append-only files such as session
+// histories stay cheap to write. Files without the header are read as plain
+// text, which lets encryption be turned on for data written before.
+//
+// Each record is authenticated together with the name of its file (the
+// parent directory and base name) and its offset in the file, so records
+// cannot be reordered, dropped from the middle or moved to another file, and
+// files cannot be swapped, without failing to decrypt. Dropping records from
+// the end of a file is not detected.
+//
+// A nil *Cipher reads and writes plain files, so callers can use one
+// unconditionally.
+package crypt
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// KeySize is the length of a key in bytes.
+const KeySize = 32
+
+const header = "friday-encrypted-v2\n"
+
+// headerV1 marks files whose records are not bound to their file and offset.
+// They are still read, and rewritten in the current format on append.
+const headerV1 = "friday-encrypted-v1\n"
+
+// ErrEncrypted is returned when an encrypted file is read without a key.
+var ErrEncrypted = errors.New("file is encrypted; configure an encryption key to read it")
+
+// Cipher encrypts and decrypts files with one key.
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+// New returns a Cipher for a KeySize-byte key.
+func New(key []byte) (*Cipher, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("encryption key must be %d bytes, got %d", KeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Cipher{aead: aead}, nil
+}
+
+// GenerateKey returns a new random key, base64 encoded.
+func GenerateKey() (string, error) {
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// ParseKey decodes a base64 or hex encoded key.
+func ParseKey(text string) ([]byte, error) {
+	text = strings.TrimSpace(text)
+	if key, err := hex.DecodeString(text); err == nil && len(key) == KeySize {
+		return key, nil
+	}
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if key, err := enc.DecodeString(text); err == nil && len(key) == KeySize {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("encryption key must be %d bytes, base64 or hex encoded", KeySize)
+}
+
+// IsEncrypted reports whether data is the content of an encrypted file.
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(header)) || bytes.HasPrefix(data, []byte(headerV1))
+}
+
+// recordName is the name records of the file at path are bound to. It
+// leaves out the directories above the parent, so the data directory can be
+// moved.
+func recordName(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return filepath.Base(filepath.Dir(path)) + "/" + filepath.Base(path)
+}
+
+// recordAD is the additional data authenticated with the record at offset
+// of the file called name.
+func recordAD(name string, offset int64) []byte {
+	return fmt.Appendf(nil, "%s\x00%d", name, offset)
+}
+
+// sealRecord encrypts data into the record line at offset of the file called
+// name.
+func (c *Cipher) sealRecord(name string, offset int64, data []byte) []byte {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		panic(fmt.Sprintf("crypt: read random nonce: %v", err))
+	}
+	sealed := c.aead.Seal(nonce, nonce, data, recordAD(name, offset))
+	line := make([]byte, base64.StdEncoding.EncodedLen(len(sealed))+1)
+	base64.StdEncoding.Encode(line, sealed)
+	line[len(line)-1] = '\n'
+	return line
+}
+
+// Seal returns data as the content of an encrypted file to be stored at
+// path. A nil Cipher returns data unchanged.
+func (c *Cipher) Seal(path string, data []byte) []byte {
+	if c == nil {
+		return data
+	}
+	return append([]byte(header), c.sealRecord(recordName(path), int64(len(header)), data)...)
+}
+
+// Open returns the plain content of the file stored at path. Plain files are
+// returned as they are; encrypted ones need a Cipher.
+func (c *Cipher) Open(path string, data []byte) ([]byte, error) {
+	if !IsEncrypted(data) {
+		return data, nil
+	}
+	if c == nil {
+		return nil, ErrEncrypted
+	}
+
+	name := recordName(path)
+	bound := bytes.HasPrefix(data, []byte(header))
+	offset := int64(len(header))
+	var plain []byte
+	scanner := bufio.NewScanner(bytes.NewReader(data[len(header):]))
+	scanner.Buffer(make([]byte, 0, 64*1024), 256*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		start := offset
+		offset += int64(len(line)) + 1
+		if len(line) == 0 {
+			continue
+		}
+		sealed := make([]byte, base64.StdEncoding.DecodedLen(len(line)))
+		n, err := base64.StdEncoding.Decode(sealed, line)
+		if err != nil {
+			return nil, fmt.Errorf("decode encrypted record: %w", err)
+		}
+		sealed = sealed[:n]
+		size := c.aead.NonceSize()
+		if len(sealed) < size {
+			return nil, errors.New("encrypted record too short")
+		}
+		var ad []byte
+		if bound {
+			ad = recordAD(name, start)
+		}
+		record, err := c.aead.Open(nil, sealed[:size], sealed[size:], ad)
+		if err != nil {
+			return nil, errors.New("decrypt record: wrong key or corrupted file")
+		}
+		plain = append(plain, record...)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return plain, nil
+}
+
+// ReadFile reads the plain content of the file at path.
+func (c *Cipher) ReadFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return c.Open(path, data)
+}
+
+// WriteFile writes data to path, encrypted unless c is nil.
+func (c *Cipher) WriteFile(path string, data []byte, perm os.FileMode) error {
+	return os.WriteFile(path, c.Seal(path, data), perm)
+}
+
+// AppendFile appends data to the file at path, creating it if needed. A
+// plain file, or one in an older encrypted format, is encrypted as a whole
+// before the first append; a nil Cipher refuses to append plain text to an
+// encrypted file.
+func (c *Cipher) AppendFile(path string, data []byte, perm os.FileMode) error {
+	head, size, err := fileState(path)
+	if err != nil {
+		return err
+	}
+	encrypted := IsEncrypted(head)
+
+	switch {
+	case c == nil && encrypted:
+		return ErrEncrypted
+	case c == nil:
+		return appendBytes(path, data, perm)
+	case size == 0:
+		return os.WriteFile(path, c.Seal(path, data), perm)
+	case string(head) != header:
+		existing, err := c.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return writeFileAtomic(path, c.Seal(path, append(existing, data...)), perm)
+	default:
+		return appendBytes(path, c.sealRecord(recordName(path), size, data), perm)
+	}
+}
+
+// fileState returns the start of the file at path, up to the length of the
+// header, and its size; a missing file is empty.
+func fileState(path string) ([]byte, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, 0, nil
+		}
+		return nil, 0, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, 0, err
+	}
+	head := make([]byte, len(header))
+	n, err := io.ReadFull(f, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return nil, 0, err
+	}
+	return head[:n], info.Size(), nil
+}
+
+func appendBytes(path string, data []byte, perm os.FileMode) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, perm)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(data)
+	return err
+}
+
+// writeFileAtomic replaces the file at path through a temporary file, so a
+// crash never leaves it half written.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Convert rewrites the file at path encrypted with to, or in plain text when
+// to is nil, reading it with from. Files already in that form are left alone,
+// while ones in an older encrypted format are rewritten; it reports whether
+// the file changed.
+func Convert(path string, from, to *Cipher) (bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	if to != nil && bytes.HasPrefix(data, []byte(header)) || to == nil && !IsEncrypted(data) {
+		return false, nil
+	}
+	plain, err := from.Open(path, data)
+	if err != nil {
+		return false, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	return true, writeFileAtomic(path, to.Seal(path, plain), info.Mode().Perm())
+}
@@ -0,0 +1,189 @@
+package crypt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func testCipher(t *testing.T) *Cipher {
+	t.Helper()
+	text, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	key, err := ParseKey(text)
+	if err != nil {
+		t.Fatalf("ParseKey() error = %v", err)
+	}
+	c, err := New(key)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return c
+}
+
+func TestAppendFile(t *testing.T) {
+	c := testCipher(t)
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+
+	// Plain lines written before encryption was turned on are kept.
+	if err := os.WriteFile(path, []byte("one\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"two\n", "three\n"} {
+		if err := c.AppendFile(path, []byte(line), 0600); err != nil {
+			t.Fatalf("AppendFile() error = %v", err)
+		}
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsEncrypted(raw) || bytes.Contains(raw, []byte("two")) {
+		t.Fatalf("file is not encrypted: %q", raw)
+	}
+	got, err := c.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if string(got) != "one\ntwo\nthree\n" {
+		t.Fatalf("ReadFile() = %q", got)
+	}
+
+	var plain *Cipher
+	if _, err := plain.ReadFile(path); !errors.Is(err, ErrEncrypted) {
+		t.Fatalf("ReadFile() without a key error = %v, want ErrEncrypted", err)
+	}
+	if err := plain.AppendFile(path, []byte("four\n"), 0600); !errors.Is(err, ErrEncrypted) {
+		t.Fatalf("AppendFile() without a key error = %v, want ErrEncrypted", err)
+	}
+	if _, err := testCipher(t).ReadFile(path); err == nil {
+		t.Fatal("expected an error reading with the wrong key")
+	}
+}
+
+func TestConvert(t *testing.T) {
+	c := testCipher(t)
+	path := filepath.Join(t.TempDir(), "app_state.json")
+	if err := os.WriteFile(path, []byte(`{"k":"v"}`), 0640); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, want := range []bool{true, false} {
+		changed, err := Convert(path, c, c)
+		if err != nil {
+			t.Fatalf("Convert() error = %v", err)
+		}
+		if changed != want {
+			t.Fatalf("Convert() #%d changed = %v, want %v", i, changed, want)
+		}
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0640 {
+		t.Fatalf("mode = %v, want 0640", info.Mode().Perm())
+	}
+
+	if changed, err := Convert(path, c, nil); err != nil || !changed {
+		t.Fatalf("Convert() to plain = %v, %v", changed, err)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(raw) != `{"k":"v"}` {
+		t.Fatalf("decrypted file = %q", raw)
+	}
+}
+
+func TestParseKey(t *testing.T) {
+	if _, err := ParseKey("00112233445566778899aabbccddeeff00112233445566778899aabbccddeeff"); err != nil {
+		t.Fatalf("ParseKey(hex) error = %v", err)
+	}
+	if _, err := ParseKey("too short"); err == nil {
+		t.Fatal("expected an error for a short key")
+	}
+
+	t.Setenv("TEST_FRIDAY_KEY", "")
+	if _, err := LoadKey(KeySource{Env: "TEST_FRIDAY_KEY"}); !errors.Is(err, ErrNoKey) {
+		t.Fatalf("LoadKey() error = %v, want ErrNoKey", err)
+	}
+	keyFile := filepath.Join(t.TempDir(), "key")
+	text, _ := GenerateKey()
+	if err := os.WriteFile(keyFile, []byte(text+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadKey(KeySource{Env: "TEST_FRIDAY_KEY", File: keyFile}); err != nil {
+		t.Fatalf("LoadKey(file) error = %v", err)
+	}
+}
+
+func TestRecordsBoundToFileAndOffset(t *testing.T) {
+	c := testCipher(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a", "history.jsonl")
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"one\n", "two\n"} {
+		if err := c.AppendFile(path, []byte(line), 0600); err != nil {
+			t.Fatalf("AppendFile() error = %v", err)
+		}
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Swapping the two records breaks their offsets.
+	lines := bytes.SplitAfter(raw[len(header):], []byte("\n"))
+	swapped := append([]byte(header), append(append([]byte{}, lines[1]...), lines[0]...)...)
+	if _, err := c.Open(path, swapped); err == nil {
+		t.Error("expected an error for reordered records")
+	}
+	// The same content under another session's directory does not open.
+	if _, err := c.Open(filepath.Join(dir, "b", "history.jsonl"), raw); err == nil {
+		t.Error("expected an error for a file moved to another directory")
+	}
+	// A moved data directory keeps the parent and base name.
+	if got, err := c.Open(filepath.Join(dir, "moved", "a", "history.jsonl"), raw); err != nil || string(got) != "one\ntwo\n" {
+		t.Errorf("Open() after moving the data directory = %q, %v", got, err)
+	}
+}
+
+func TestUpgradesUnboundFiles(t *testing.T) {
+	c := testCipher(t)
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+
+	// A file written before records were bound to their file and offset.
+	nonce := make([]byte, c.aead.NonceSize())
+	sealed := c.aead.Seal(nonce, nonce, []byte("one\n"), nil)
+	v1 := headerV1 + base64.StdEncoding.EncodeToString(sealed) + "\n"
+	if err := os.WriteFile(path, []byte(v1), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := c.ReadFile(path); err != nil || string(got) != "one\n" {
+		t.Fatalf("ReadFile() = %q, %v", got, err)
+	}
+
+	if err := c.AppendFile(path, []byte("two\n"), 0600); err != nil {
+		t.Fatalf("AppendFile() error = %v", err)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(raw, []byte(header)) {
+		t.Fatalf("file was not rewritten in the current format: %q", raw[:len(header)])
+	}
+	if got, err := c.ReadFile(path); err != nil || string(got) != "one\ntwo\n" {
+		t.Fatalf("ReadFile() after upgrade = %q, %v", got, err)
+	}
+}
@@ -0,0 +1,74 @@
+package crypt
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// ErrNoKey is returned by LoadKey when no source holds a key.
+var ErrNoKey = errors.New("no encryption key configured")
+
+// keyCommandTimeout bounds how long a key command, which may prompt to unlock
+// a keyring, can take.
+const keyCommandTimeout = time.Minute
+
+// KeySource lists where to look for a key, in order.
+type KeySource struct {
+	// Env names an environment variable holding the key.
+	Env string
+	// File is a file holding the key.
+	File string
+	// Command prints the key, such as a lookup in the OS keyring:
+	// ["secret-tool", "lookup", "service", "friday"] or
+	// ["security", "find-generic-password", "-s", "friday", "-w"].
+	Command []string
+}
+
+// LoadKey returns the key of the first source that has one. Keys are base64
+// or hex encoded.
+func LoadKey(src KeySource) ([]byte, error) {
+	if src.Env != "" {
+		if text := os.Getenv(src.Env); text != "" {
+			key, err := ParseKey(text)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", src.Env, err)
+			}
+			return key, nil
+		}
+	}
+	if src.File != "" {
+		data, err := os.ReadFile(src.File)
+		if err != nil {
+			return nil, fmt.Errorf("read key file: %w", err)
+		}
+		key, err := ParseKey(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("key file %s: %w", src.File, err)
+		}
+		return key, nil
+	}
+	if len(src.Command) > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), keyCommandTimeout)
+		defer cancel()
+		var stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, src.Command[0], src.Command[1:]...)
+		cmd.Stdin = os.Stdin
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil {
+			return nil, fmt.Errorf("key command %s: %w: %s", src.Command[0], err, strings.TrimSpace(stderr.String()))
+		}
+		key, err := ParseKey(string(out))
+		if err != nil {
+			return nil, fmt.Errorf("key command %s: %w", src.Command[0], err)
+		}
+		return key, nil
+	}
+	return nil, ErrNoKey
+}